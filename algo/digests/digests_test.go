@@ -6,6 +6,7 @@ package digests_test
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"reflect"
 	"strings"
 	"testing"
@@ -276,3 +277,49 @@ func TestEmptyDigest(t *testing.T) {
 		t.Error("Expected Validate() to return false for a Hash with an empty digest")
 	}
 }
+
+func TestMerkleRoot(t *testing.T) {
+	digest := func(s string) []byte {
+		d := sha256.Sum256([]byte(s))
+		return d[:]
+	}
+	leaf := func(d []byte) []byte {
+		h := sha256.Sum256(append([]byte{0x00}, d...))
+		return h[:]
+	}
+	node := func(l, r []byte) []byte {
+		d := sha256.Sum256(append(append([]byte{0x01}, l...), r...))
+		return d[:]
+	}
+	a, b, c := digest("a"), digest("b"), digest("c")
+	la, lb, lc := leaf(a), leaf(b), leaf(c)
+	for i, tc := range []struct {
+		leaves [][]byte
+		root   []byte
+	}{
+		{[][]byte{a}, la},
+		{[][]byte{a, b}, node(la, lb)},
+		{[][]byte{a, b, c}, node(node(la, lb), lc)},
+		{[][]byte{a, b, c, a}, node(node(la, lb), node(lc, la))},
+	} {
+		root, err := digests.MerkleRoot(digests.SHA256, tc.leaves)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := root, tc.root; !bytes.Equal(got, want) {
+			t.Errorf("%v: got %x, want %x", i, got, want)
+		}
+	}
+	// A leaf whose contents are those of an interior node does not
+	// produce the same root as that interior node.
+	forged := append(append([]byte{0x01}, la...), lb...)
+	if root, _ := digests.MerkleRoot(digests.SHA256, [][]byte{forged}); bytes.Equal(root, node(la, lb)) {
+		t.Errorf("leaf collides with an interior node")
+	}
+	if _, err := digests.MerkleRoot(digests.SHA256, nil); err == nil {
+		t.Errorf("expected an error")
+	}
+	if _, err := digests.MerkleRoot("unknown", [][]byte{a}); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package digests
+
+import (
+	"fmt"
+)
+
+// MerkleRoot computes the root of a binary Merkle tree over the supplied
+// digests, typically the digests of the fixed size blocks of a file. As per
+// RFC 6962, each leaf node is the digest, using the specified algorithm, of
+// a 0x00 byte followed by the supplied digest and each interior node is the
+// digest of a 0x01 byte followed by its left and right children, so that
+// leaves and interior nodes cannot be confused. An unpaired node at the end
+// of a level is promoted, unchanged, to the next level.
+func MerkleRoot(algo string, leaves [][]byte) ([]byte, error) {
+	if len(leaves) == 0 {
+		return nil, fmt.Errorf("no leaves specified")
+	}
+	h := newHashInstance(algo)
+	if h == nil {
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", algo)
+	}
+	level := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		h.Reset()
+		h.Write([]byte{0x00})
+		h.Write(leaf)
+		level[i] = h.Sum(nil)
+	}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			h.Reset()
+			h.Write([]byte{0x01})
+			h.Write(level[i])
+			h.Write(level[i+1])
+			next = append(next, h.Sum(nil))
+		}
+		level = next
+	}
+	return level[0], nil
+}
@@ -3,6 +3,7 @@ module cloudeng.io/aws
 go 1.26.4
 
 require (
+	cloudeng.io/algo v0.0.0-20260818231247-605c3766963e
 	cloudeng.io/cicd v0.0.0-20260527194618-4cb6d4558850
 	cloudeng.io/cmdutil v0.0.0-20260527194618-4cb6d4558850
 	cloudeng.io/errors v0.0.14-0.20260312171538-61fcde6ce278
@@ -42,7 +43,6 @@ require (
 )
 
 require (
-	cloudeng.io/algo v0.0.0-20260818231247-605c3766963e // indirect
 	cloudeng.io/os v0.0.0-20260807191443-11b7f4ecaaa0 // indirect
 	cloudeng.io/sync v0.0.12-0.20260804222138-e9281ed260ba
 	cloudeng.io/sys v0.0.0-20260807191443-11b7f4ecaaa0 // indirect
@@ -11,6 +11,7 @@ import (
 	"io/fs"
 	"path"
 
+	"cloudeng.io/algo/digests"
 	"cloudeng.io/errors"
 	"cloudeng.io/file"
 	"cloudeng.io/file/filewalk"
@@ -189,6 +190,43 @@ func (s3fs *T) XAttr(_ context.Context, _ string, info file.Info) (file.XAttr, e
 	return file.XAttr{}, nil
 }
 
+// KnownDigest returns the digest of an object's contents if it can be
+// determined without reading it, ie. from its ETag if that ETag is known
+// to be the MD5 of the object's contents, or otherwise from its full-object
+// checksum, if any, which is obtained via an additional HEAD request with
+// checksum mode enabled. It allows for S3 objects to be used with
+// cloudeng.io/file/dedup without having to read them.
+func (s3fs *T) KnownDigest(ctx context.Context, name string, info file.Info) (digests.Hash, bool) {
+	v, ok := info.Sys().(s3xattr)
+	if !ok {
+		return digests.Hash{}, false
+	}
+	head, ok := v.obj.(*s3.HeadObjectOutput)
+	if !ok {
+		return digests.Hash{}, false
+	}
+	if dg, err := digestFromHead(head); err == nil && dg.IsSet() {
+		return dg, true
+	}
+	match := cloudpath.AWSS3MatcherSep(name, s3fs.options.delimiter)
+	if len(match.Matched) == 0 || len(match.Key) == 0 {
+		return digests.Hash{}, false
+	}
+	head, err := s3fs.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(match.Volume),
+		Key:          aws.String(match.Key),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return digests.Hash{}, false
+	}
+	dg, err := digestFromHead(head)
+	if err != nil || !dg.IsSet() {
+		return digests.Hash{}, false
+	}
+	return dg, true
+}
+
 func (s3fs *T) SysXAttr(existing any, merge file.XAttr) any {
 	if v, ok := existing.(s3xattr); ok {
 		return s3xattr{owner: merge.User, obj: v.obj}
@@ -0,0 +1,240 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"cloudeng.io/algo/digests"
+	"cloudeng.io/errors"
+	"cloudeng.io/file/largefile"
+	"cloudeng.io/path/cloudpath"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// DefaultLargeFileBlockSize is the default block size used for
+// reading S3 objects via LargeFile.
+const DefaultLargeFileBlockSize = 1024 * 1024 * 8 // Default block size is 8 MiB.
+
+// LargeFile implements largefile.Reader for S3 objects. A HeadObject
+// request is made to determine the size of the object and its checksum,
+// if any, and ranged GetObject requests are used to read the object
+// concurrently. All GetObject requests are conditional on the ETag
+// returned by the HeadObject request so that an object that is modified
+// whilst being read will result in an error rather than corrupt data.
+type LargeFile struct {
+	client        Client
+	name          string
+	bucket        string
+	key           string
+	etag          string
+	contentLength int64
+	blockSize     int
+	digest        digests.Hash
+}
+
+// LargeFileOption represents an option to NewLargeFile.
+type LargeFileOption func(o *largeFileOptions)
+
+type largeFileOptions struct {
+	blockSize int
+	digest    digests.Hash
+}
+
+// WithLargeFileBlockSize sets the block size for reading large files.
+func WithLargeFileBlockSize(blockSize int) LargeFileOption {
+	return func(o *largeFileOptions) {
+		o.blockSize = blockSize
+	}
+}
+
+// WithLargeFileDigest sets the digest for the large file, overriding
+// any digest obtained from the object's checksum.
+func WithLargeFileDigest(dig digests.Hash) LargeFileOption {
+	return func(o *largeFileOptions) {
+		o.digest = dig
+	}
+}
+
+// NewLargeFile returns a largefile.Reader for the specified S3 object.
+// The object's digest is obtained from its full-object SHA512, SHA256,
+// SHA1 or MD5 checksum, if any, or from its ETag if that ETag is known
+// to be the MD5 of the object's contents.
+func NewLargeFile(ctx context.Context, fs *T, name string, opts ...LargeFileOption) (*LargeFile, error) {
+	var o largeFileOptions
+	for _, fn := range opts {
+		fn(&o)
+	}
+	if o.blockSize <= 0 {
+		o.blockSize = DefaultLargeFileBlockSize
+	}
+	match := cloudpath.AWSS3MatcherSep(name, fs.options.delimiter)
+	if len(match.Matched) == 0 || len(match.Key) == 0 {
+		return nil, fmt.Errorf("invalid s3 path: %v", name)
+	}
+	head, err := fs.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(match.Volume),
+		Key:          aws.String(match.Key),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return nil, err
+	}
+	lf := &LargeFile{
+		client:        fs.client,
+		name:          name,
+		bucket:        match.Volume,
+		key:           match.Key,
+		etag:          aws.ToString(head.ETag),
+		contentLength: aws.ToInt64(head.ContentLength),
+		blockSize:     o.blockSize,
+		digest:        o.digest,
+	}
+	if !lf.digest.IsSet() {
+		if lf.digest, err = digestFromHead(head); err != nil {
+			return nil, fmt.Errorf("%v: %w", name, err)
+		}
+	}
+	return lf, nil
+}
+
+// digestFromHead returns the digest for the object, if any, preferring
+// the strongest full-object checksum. Composite checksums, ie. those
+// computed over the parts of a multipart upload, are ignored.
+func digestFromHead(head *s3.HeadObjectOutput) (digests.Hash, error) {
+	if head.ChecksumType != types.ChecksumTypeComposite {
+		for _, cs := range []struct {
+			algo  string
+			value *string
+		}{
+			{digests.SHA512, head.ChecksumSHA512},
+			{digests.SHA256, head.ChecksumSHA256},
+			{digests.SHA1, head.ChecksumSHA1},
+			{digests.MD5, head.ChecksumMD5},
+		} {
+			val := aws.ToString(cs.value)
+			if len(val) == 0 || strings.Contains(val, "-") {
+				continue
+			}
+			d, err := digests.FromBase64(val)
+			if err != nil {
+				return digests.Hash{}, fmt.Errorf("failed to decode %v checksum %q: %w", cs.algo, val, err)
+			}
+			return digests.New(cs.algo, d)
+		}
+	}
+	// The ETag is the MD5 of the object's contents only for objects
+	// that were not created by a multipart upload (whose ETags
+	// contain a '-') and that are not encrypted using SSE-KMS or SSE-C.
+	etag := strings.Trim(aws.ToString(head.ETag), `"`)
+	if len(etag) != 32 || strings.Contains(etag, "-") ||
+		head.ServerSideEncryption == types.ServerSideEncryptionAwsKms ||
+		head.ServerSideEncryption == types.ServerSideEncryptionAwsKmsDsse ||
+		len(aws.ToString(head.SSECustomerAlgorithm)) > 0 {
+		return digests.Hash{}, nil
+	}
+	d, err := digests.FromHex(etag)
+	if err != nil {
+		return digests.Hash{}, nil //nolint:nilerr // Not an MD5 ETag.
+	}
+	return digests.New(digests.MD5, d)
+}
+
+// Name implements largefile.Reader.
+func (lf *LargeFile) Name() string {
+	return lf.name
+}
+
+// ContentLengthAndBlockSize implements largefile.Reader.
+func (lf *LargeFile) ContentLengthAndBlockSize() (int64, int) {
+	return lf.contentLength, lf.blockSize
+}
+
+// Digest implements largefile.Reader.
+func (lf *LargeFile) Digest() digests.Hash {
+	return lf.digest
+}
+
+// GetReader implements largefile.Reader. Throttling and server side
+// (5xx) errors are treated as retryable.
+func (lf *LargeFile) GetReader(ctx context.Context, from, to int64) (io.ReadCloser, largefile.RetryResponse, error) {
+	req := s3.GetObjectInput{
+		Bucket: aws.String(lf.bucket),
+		Key:    aws.String(lf.key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", from, to)),
+	}
+	if len(lf.etag) > 0 {
+		req.IfMatch = aws.String(lf.etag)
+	}
+	res, err := lf.client.GetObject(ctx, &req)
+	if err != nil {
+		return nil, s3RetryResponse{retryable: isRetryable(err)}, err
+	}
+	return res.Body, s3RetryResponse{}, nil
+}
+
+// isRetryable returns true for S3 throttling and server side errors and
+// for transient network errors such as timeouts and reset connections.
+// All other errors are treated as non-retryable.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "SlowDown", "Throttling", "ThrottlingException", "TooManyRequestsException",
+			"RequestTimeout", "RequestTimeTooSkewed", "InternalError", "ServiceUnavailable":
+			return true
+		}
+	}
+	var respErr *smithyhttp.ResponseError
+	if errors.As(err, &respErr) {
+		code := respErr.HTTPStatusCode()
+		return code == http.StatusTooManyRequests || code >= 500
+	}
+	if apiErr != nil {
+		return false
+	}
+	return isTransientNetworkError(err)
+}
+
+func isTransientNetworkError(err error) bool {
+	if errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// s3RetryResponse implements largefile.RetryResponse for S3 API calls.
+type s3RetryResponse struct {
+	retryable bool
+}
+
+// IsRetryable implements largefile.RetryResponse.
+func (r s3RetryResponse) IsRetryable() bool {
+	return r.retryable
+}
+
+// BackoffDuration implements largefile.RetryResponse. It always returns false,
+// indicating that the caller should use a default backoff strategy (e.g., exponential).
+func (r s3RetryResponse) BackoffDuration() (bool, time.Duration) {
+	return false, 0
+}
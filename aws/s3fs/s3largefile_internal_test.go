@@ -0,0 +1,162 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"cloudeng.io/algo/digests"
+	"cloudeng.io/file"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+func TestDigestFromHead(t *testing.T) {
+	md5Hex := "d41d8cd98f00b204e9800998ecf8427e"
+	sha256B64 := "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+	for i, tc := range []struct {
+		head *s3.HeadObjectOutput
+		algo string
+	}{
+		{&s3.HeadObjectOutput{}, ""},
+		{&s3.HeadObjectOutput{ETag: aws.String(`"` + md5Hex + `"`)}, digests.MD5},
+		{&s3.HeadObjectOutput{ETag: aws.String(`"` + md5Hex + `-2"`)}, ""},
+		{&s3.HeadObjectOutput{ETag: aws.String(`"` + md5Hex + `"`),
+			ServerSideEncryption: types.ServerSideEncryptionAwsKms}, ""},
+		{&s3.HeadObjectOutput{ETag: aws.String(`"` + md5Hex + `"`),
+			SSECustomerAlgorithm: aws.String("AES256")}, ""},
+		{&s3.HeadObjectOutput{ETag: aws.String(`"` + md5Hex + `"`),
+			ChecksumSHA256: aws.String(sha256B64)}, digests.SHA256},
+		{&s3.HeadObjectOutput{ChecksumSHA256: aws.String(sha256B64),
+			ChecksumType: types.ChecksumTypeComposite}, ""},
+	} {
+		dg, err := digestFromHead(tc.head)
+		if err != nil {
+			t.Errorf("%v: %v", i, err)
+			continue
+		}
+		if got, want := dg.Algo, tc.algo; got != want {
+			t.Errorf("%v: got %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestKnownDigest(t *testing.T) {
+	ctx := context.Background()
+	md5Hex := "d41d8cd98f00b204e9800998ecf8427e"
+	fs := &T{}
+	info := file.NewInfo("obj", 0, 0, time.Time{}, s3xattr{
+		obj: &s3.HeadObjectOutput{ETag: aws.String(`"` + md5Hex + `"`)},
+	})
+	dg, ok := fs.KnownDigest(ctx, "s3://bucket/obj", info)
+	if !ok {
+		t.Fatal("expected a known digest")
+	}
+	if got, want := dg.Algo, digests.MD5; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := digests.ToHex(dg.Digest), md5Hex; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	info = file.NewInfo("obj", 0, 0, time.Time{}, s3xattr{
+		obj: &s3.HeadObjectOutput{ETag: aws.String(`"` + md5Hex + `-2"`)},
+	})
+	if _, ok := fs.KnownDigest(ctx, "s3://bucket/obj", info); ok {
+		t.Errorf("unexpected known digest for a multipart ETag")
+	}
+	if _, ok := fs.KnownDigest(ctx, "s3://bucket/", file.Info{}); ok {
+		t.Errorf("unexpected known digest")
+	}
+
+	// Stat must not request the object's checksum, rather, KnownDigest
+	// does so for objects without an MD5 ETag.
+	client := &headClient{head: &s3.HeadObjectOutput{
+		ETag: aws.String(`"` + md5Hex + `-2"`),
+	}}
+	info, err := objectHead(ctx, client, "bucket", "obj", "/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := client.req.ChecksumMode; len(got) != 0 {
+		t.Errorf("unexpected checksum mode: %v", got)
+	}
+	client.head = &s3.HeadObjectOutput{
+		ETag:           aws.String(`"` + md5Hex + `-2"`),
+		ChecksumSHA256: aws.String("47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="),
+	}
+	fs = &T{client: client, options: options{delimiter: '/'}}
+	dg, ok = fs.KnownDigest(ctx, "s3://bucket/obj", info)
+	if !ok {
+		t.Fatal("expected a known digest")
+	}
+	if got, want := dg.Algo, digests.SHA256; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := client.req.ChecksumMode, types.ChecksumModeEnabled; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := aws.ToString(client.req.Key), "obj"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+type headClient struct {
+	Client
+	req  *s3.HeadObjectInput
+	head *s3.HeadObjectOutput
+}
+
+func (c *headClient) HeadObject(_ context.Context, req *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	c.req = req
+	return c.head, nil
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsRetryable(t *testing.T) {
+	respErr := func(code int) error {
+		return &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: code}},
+			Err:      errors.New("oops"),
+		}
+	}
+	for i, tc := range []struct {
+		err       error
+		retryable bool
+	}{
+		{context.Canceled, false},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), false},
+		{errors.New("connection reset"), false},
+		{&net.OpError{Op: "read", Err: syscall.ECONNRESET}, true},
+		{fmt.Errorf("wrapped: %w", io.ErrUnexpectedEOF), true},
+		{&url.Error{Op: "Get", Err: timeoutError{}}, true},
+		{&url.Error{Op: "Get", Err: errors.New("no such host")}, false},
+		{&smithy.GenericAPIError{Code: "SlowDown"}, true},
+		{&smithy.GenericAPIError{Code: "NoSuchKey"}, false},
+		{respErr(http.StatusServiceUnavailable), true},
+		{respErr(http.StatusTooManyRequests), true},
+		{respErr(http.StatusPreconditionFailed), false},
+	} {
+		if got, want := isRetryable(tc.err), tc.retryable; got != want {
+			t.Errorf("%v: %v: got %v, want %v", i, tc.err, got, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package s3fs_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec // G501: md5 is used for S3 ETags.
+	"io"
+	"path/filepath"
+	"testing"
+
+	"cloudeng.io/algo/digests"
+	"cloudeng.io/aws/awstestutil"
+	"cloudeng.io/aws/s3fs"
+	"cloudeng.io/file/largefile"
+)
+
+func TestLargeFile(t *testing.T) {
+	awstestutil.SkipAWSTests(t)
+	ctx := context.Background()
+	fs := newS3ObjFS()
+
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	name := "s3://bucket-checkpoint/largefile/data"
+	if err := fs.Put(ctx, name, 0x00, data); err != nil {
+		t.Fatal(err)
+	}
+
+	lf, err := s3fs.NewLargeFile(ctx, fs, name, s3fs.WithLargeFileBlockSize(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, blockSize := lf.ContentLengthAndBlockSize()
+	if got, want := size, int64(len(data)); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := blockSize, 64; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	dg := lf.Digest()
+	if got, want := dg.Algo, digests.MD5; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	sum := md5.Sum(data) //nolint:gosec // G401: md5 is used for S3 ETags.
+	if got, want := dg.Digest, sum[:]; !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+
+	for _, br := range []largefile.ByteRange{{From: 0, To: 63}, {From: 100, To: 199}, {From: 960, To: 999}} {
+		rd, _, err := lf.GetReader(ctx, br.From, br.To)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, err := io.ReadAll(rd)
+		rd.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := buf, data[br.From:br.To+1]; !bytes.Equal(got, want) {
+			t.Errorf("%v: got %v, want %v", br, got, want)
+		}
+	}
+
+	tmpDir := t.TempDir()
+	dataFile, indexFile := filepath.Join(tmpDir, "data"), filepath.Join(tmpDir, "index")
+	if err := largefile.CreateNewFilesForCache(ctx, dataFile, indexFile, size, blockSize, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	dataRW, indexRW, err := largefile.OpenCacheFiles(dataFile, indexFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := largefile.NewLocalDownloadCache(dataRW, indexRW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+	dl, err := largefile.NewCachingDownloader(lf, cache,
+		largefile.WithDownloadConcurrency(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := dl.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Complete {
+		t.Fatalf("download incomplete: %+v", st)
+	}
+	buf := make([]byte, size)
+	if _, err := cache.ReadAt(buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, data) {
+		t.Errorf("downloaded data does not match")
+	}
+
+	_, retry, err := lf.GetReader(ctx, 2000, 3000)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if retry.IsRetryable() {
+		t.Errorf("invalid range should not be retryable: %v", err)
+	}
+
+	if _, err := s3fs.NewLargeFile(ctx, fs, "s3://bucket-checkpoint/largefile/not-there"); !fs.IsNotExist(err) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
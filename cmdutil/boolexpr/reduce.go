@@ -0,0 +1,86 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package boolexpr
+
+// Logic represents the operations used by Reduce to combine the values
+// obtained for the operands in an expression.
+type Logic[V any] interface {
+	And(a, b V) V
+	Or(a, b V) V
+	Not(v V) V
+}
+
+// Reduce reduces an expression to a single value of type V by obtaining
+// a value for each operand using the supplied function and combining them
+// using logic, rather than by evaluating the operands against a value as
+// Eval does. The operators are applied in the same order as Eval applies
+// them, including the short-circuiting of ||. This allows for analyses of an
+// expression, for example, to determine if any value could ever match it using
+// three-valued logic. The zero value of V is returned for an empty expression.
+func Reduce[V any](m T, logic Logic[V], operand func(Operand) V) V {
+	if len(m.items) == 0 {
+		var zero V
+		return zero
+	}
+	r := reducer[V]{logic: logic, operand: operand}
+	return r.run(m.items)
+}
+
+type reducer[V any] struct {
+	logic     Logic[V]
+	operand   func(Operand) V
+	values    []V
+	operators []itemType
+	// ors records the result of every || operation since Eval returns
+	// true as soon as any one of them is true, orLast is true if the most
+	// recent operation was ||.
+	ors    []V
+	orLast bool
+}
+
+func (r *reducer[V]) reduce() {
+	if len(r.values) == 1 && len(r.operators) == 1 && r.operators[0] == notOp {
+		r.operators = r.operators[1:]
+		r.values[0] = r.logic.Not(r.values[0])
+	}
+	if len(r.values) == 2 && len(r.operators) >= 1 {
+		if len(r.operators) >= 2 && r.operators[len(r.operators)-1] == notOp {
+			r.values[1] = r.logic.Not(r.values[1])
+			r.operators = r.operators[:len(r.operators)-1]
+		}
+		switch r.operators[0] {
+		case andOp:
+			r.values = []V{r.logic.And(r.values[0], r.values[1])}
+			r.orLast = false
+		case orOp:
+			r.values = []V{r.logic.Or(r.values[0], r.values[1])}
+			r.ors = append(r.ors, r.values[0])
+			r.orLast = true
+		}
+	}
+}
+
+func (r *reducer[V]) run(items []Item) V {
+	for _, cur := range items {
+		switch cur.typ {
+		case operand:
+			r.values = append(r.values, r.operand(cur.op))
+		case orOp, andOp, notOp:
+			r.operators = append(r.operators, cur.typ)
+		case subExpression:
+			sub := &reducer[V]{logic: r.logic, operand: r.operand}
+			r.values = append(r.values, sub.run(cur.sub))
+		}
+		r.reduce()
+	}
+	result := r.values[0]
+	if r.orLast {
+		r.ors = r.ors[:len(r.ors)-1]
+	}
+	for i := len(r.ors) - 1; i >= 0; i-- {
+		result = r.logic.Or(r.ors[i], result)
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package boolexpr_test
+
+import (
+	"reflect"
+	"testing"
+
+	"cloudeng.io/cmdutil/boolexpr"
+)
+
+type boolLogic struct{}
+
+func (boolLogic) And(a, b bool) bool { return a && b }
+func (boolLogic) Or(a, b bool) bool  { return a || b }
+func (boolLogic) Not(v bool) bool    { return !v }
+
+type namesLogic struct{}
+
+func (namesLogic) And(a, b []string) []string {
+	return append(append(append([]string{"("}, a...), "&&"), append(b, ")")...)
+}
+func (namesLogic) Or(a, b []string) []string {
+	return append(append(append([]string{"("}, a...), "||"), append(b, ")")...)
+}
+func (namesLogic) Not(v []string) []string { return append([]string{"!"}, v...) }
+
+func TestReduce(t *testing.T) {
+	for _, expr := range []string{
+		`foo`, `!foo`,
+		`foo || bar`, `foo && bar`,
+		`foo && bar || baz`, `foo || bar && baz`,
+		`foo && !bar || baz`, `!foo || bar && !baz`,
+		`(foo && bar) || baz`, `!((foo && bar) || baz)`,
+		`(foo && bar) || !(baz && bat)`,
+		`!(!(foo && bar) || !(baz && bat))`,
+		`foo || (bar && (baz || !bat))`,
+	} {
+		m, err := boolexpr.New(parse(expr)...)
+		if err != nil {
+			t.Fatalf("%v: %v", expr, err)
+		}
+		for _, val := range []string{"", "foo", "bar", "baz", "foobar", "batbaz", "foobaz", "barbaz", "foobarbazbat"} {
+			got := boolexpr.Reduce(m, boolLogic{}, func(op boolexpr.Operand) bool {
+				return op.Eval(val)
+			})
+			if want := m.Eval(val); got != want {
+				t.Errorf("%v: %q: got %v, want %v", expr, val, got, want)
+			}
+		}
+	}
+
+	m, err := boolexpr.New(parse(`foo && !(bar || baz)`)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := boolexpr.Reduce(m, namesLogic{}, func(op boolexpr.Operand) []string {
+		return []string{op.String()}
+	})
+	if want := []string{"(", "re=foo", "&&", "!", "(", "re=bar", "||", "re=baz", ")", ")"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got, want := boolexpr.Reduce(boolexpr.T{}, boolLogic{}, nil), false; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
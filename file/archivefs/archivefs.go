@@ -0,0 +1,387 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package archivefs provides read-only implementations of file.FS and
+// filewalk.FS for tar, gzip compressed tar and zip archives. The archives
+// are accessed via an io.ReaderAt and hence may be local files or remote
+// objects, for example, via largefile.RandomReader.
+//
+// The contents of an archive are indexed when it is opened so that all
+// metadata operations (Stat, Lstat, Readlink, XAttr and LevelScanner) are
+// served from memory. The file.Info for each entry reports its mode,
+// modification time and size, and its Sys value is a file.XAttr
+// containing the uid, gid, user and group recorded in the archive, if any.
+// Directories that are implied by the names of the files in an archive
+// but that have no entry of their own are synthesized.
+//
+// All paths are slash separated and relative to the root of the archive,
+// which is denoted by "" or ".", a leading "/" is ignored.
+package archivefs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"time"
+
+	"cloudeng.io/file"
+	"cloudeng.io/file/filewalk"
+	"cloudeng.io/file/internal/fstree"
+)
+
+// Format represents the format of an archive.
+type Format int
+
+const (
+	Tar Format = iota
+	TarGzip
+	Zip
+)
+
+func (f Format) String() string {
+	switch f {
+	case Tar:
+		return "tar"
+	case TarGzip:
+		return "tar.gz"
+	case Zip:
+		return "zip"
+	}
+	return fmt.Sprintf("unknown(%d)", int(f))
+}
+
+// T represents a read-only filesystem backed by an archive.
+type T struct {
+	format Format
+	rd     io.ReaderAt
+	size   int64
+	root   *entry
+}
+
+var (
+	_ file.FS         = (*T)(nil)
+	_ filewalk.FS     = (*T)(nil)
+	_ file.ReadFileFS = (*T)(nil)
+)
+
+type entry struct {
+	name     string
+	mode     fs.FileMode
+	size     int64
+	modTime  time.Time
+	xattr    file.XAttr
+	link     string // target of a symlink or hard link.
+	hardlink bool
+	children map[string]*entry
+
+	// The entry's data is either obtained by calling data, if set, or
+	// is located at offset within the archive.
+	offset int64
+	data   func() (io.ReadCloser, error)
+}
+
+func (e *entry) IsDir() bool {
+	return e.mode.IsDir()
+}
+
+func (e *entry) IsSymlink() bool {
+	return e.mode&fs.ModeSymlink != 0
+}
+
+func (e *entry) Target() string {
+	return e.link
+}
+
+func (e *entry) Mode() fs.FileMode {
+	return e.mode
+}
+
+func (e *entry) Children() map[string]*entry {
+	return e.children
+}
+
+func (e *entry) Info(name string) file.Info {
+	return file.NewInfo(name, e.size, e.mode, e.modTime, e.xattr)
+}
+
+var noXAttr = file.XAttr{UID: -1, GID: -1}
+
+func newDir(name string) *entry {
+	return &entry{
+		name:     name,
+		mode:     fs.ModeDir | 0755,
+		xattr:    noXAttr,
+		children: map[string]*entry{},
+	}
+}
+
+// New returns a new filesystem for the archive accessed via rd, which
+// contains size bytes. The format of the archive is determined from its
+// contents.
+func New(rd io.ReaderAt, size int64) (*T, error) {
+	format, err := Detect(rd, size)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case Zip:
+		return NewZip(rd, size)
+	case TarGzip:
+		return NewTarGzip(rd, size)
+	default:
+		return NewTar(rd, size)
+	}
+}
+
+// Detect determines the format of the archive accessed via rd.
+func Detect(rd io.ReaderAt, size int64) (Format, error) {
+	hdr := make([]byte, 512)
+	n, err := rd.ReadAt(hdr, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	hdr = hdr[:n]
+	switch {
+	case bytes.HasPrefix(hdr, []byte("PK\x03\x04")), bytes.HasPrefix(hdr, []byte("PK\x05\x06")):
+		return Zip, nil
+	case bytes.HasPrefix(hdr, []byte{0x1f, 0x8b}):
+		return TarGzip, nil
+	case len(hdr) >= 262 && bytes.Equal(hdr[257:262], []byte("ustar")):
+		return Tar, nil
+	}
+	// Old style (v7) tar archives have no magic number, rely on the
+	// size being a multiple of the tar block size.
+	if size > 0 && size%512 == 0 {
+		return Tar, nil
+	}
+	return 0, fmt.Errorf("archivefs: unrecognised archive format")
+}
+
+func cleanName(name string) string {
+	name = path.Clean("/" + name)
+	return name[1:]
+}
+
+// add adds the specified entry, creating any missing parent directories.
+// If an entry of the same name already exists it is replaced, but
+// the contents of directories are retained.
+func (t *T) add(name string, e *entry) {
+	comps := fstree.Components(name)
+	if len(comps) == 0 {
+		if e.IsDir() {
+			e.children = t.root.children
+			e.name = ""
+			t.root = e
+		}
+		return
+	}
+	dir := t.root
+	for _, c := range comps[:len(comps)-1] {
+		child, ok := dir.children[c]
+		if !ok || !child.IsDir() {
+			child = newDir(c)
+			dir.children[c] = child
+		}
+		dir = child
+	}
+	base := comps[len(comps)-1]
+	e.name = base
+	if prev, ok := dir.children[base]; ok && prev.IsDir() && e.IsDir() {
+		e.children = prev.children
+	}
+	if e.IsDir() && e.children == nil {
+		e.children = map[string]*entry{}
+	}
+	dir.children[base] = e
+}
+
+// resolveHardlinks arranges for hard links to refer to the data and size
+// of their targets.
+func (t *T) resolveHardlinks(e *entry) {
+	for _, child := range e.children {
+		if child.IsDir() {
+			t.resolveHardlinks(child)
+			continue
+		}
+		if !child.hardlink {
+			continue
+		}
+		target, err := t.lookup(child.link, true)
+		if err != nil || target.IsDir() {
+			continue
+		}
+		child.size = target.size
+		child.offset, child.data = target.offset, target.data
+	}
+}
+
+func (t *T) lookup(name string, follow bool) (*entry, error) {
+	return fstree.Resolve(t.root, fstree.Components(name), follow)
+}
+
+func pathError(op, name string, err error) error {
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// Format returns the format of the archive.
+func (t *T) Format() Format {
+	return t.format
+}
+
+// Scheme implements file.FS and returns the name of the archive format,
+// ie. "tar", "tar.gz" or "zip".
+func (t *T) Scheme() string {
+	return t.format.String()
+}
+
+// Open implements fs.FS.
+func (t *T) Open(name string) (fs.File, error) {
+	return t.OpenCtx(context.Background(), name)
+}
+
+// OpenCtx implements file.FS. Files in uncompressed tar archives and
+// stored (ie. uncompressed) files in zip archives implement io.Seeker and
+// io.ReaderAt. Opening a file in a compressed tar archive requires
+// decompressing the archive up to that file. Directories implement
+// fs.ReadDirFile.
+func (t *T) OpenCtx(_ context.Context, name string) (fs.File, error) {
+	e, err := t.lookup(name, true)
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+	info := e.Info(path.Base("/" + cleanName(name)))
+	if e.IsDir() {
+		return fstree.NewDirHandle(info, fstree.Infos(e)), nil
+	}
+	if e.data == nil {
+		return &sectionHandle{SectionReader: io.NewSectionReader(t.rd, e.offset, e.size), info: info}, nil
+	}
+	rc, err := e.data()
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+	return &fileHandle{ReadCloser: rc, info: info}, nil
+}
+
+// ReadFile implements file.ReadFileFS.
+func (t *T) ReadFile(name string) ([]byte, error) {
+	return t.ReadFileCtx(context.Background(), name)
+}
+
+// ReadFileCtx implements file.ReadFileFS.
+func (t *T) ReadFileCtx(ctx context.Context, name string) ([]byte, error) {
+	f, err := t.OpenCtx(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// Readlink implements file.FS.
+func (t *T) Readlink(_ context.Context, name string) (string, error) {
+	e, err := t.lookup(name, false)
+	if err != nil {
+		return "", pathError("readlink", name, err)
+	}
+	if !e.IsSymlink() {
+		return "", pathError("readlink", name, fs.ErrInvalid)
+	}
+	return e.link, nil
+}
+
+// Stat implements file.FS.
+func (t *T) Stat(_ context.Context, name string) (file.Info, error) {
+	e, err := t.lookup(name, true)
+	if err != nil {
+		return file.Info{}, pathError("stat", name, err)
+	}
+	return e.Info(path.Base("/" + cleanName(name))), nil
+}
+
+// Lstat implements file.FS.
+func (t *T) Lstat(_ context.Context, name string) (file.Info, error) {
+	e, err := t.lookup(name, false)
+	if err != nil {
+		return file.Info{}, pathError("lstat", name, err)
+	}
+	return e.Info(path.Base("/" + cleanName(name))), nil
+}
+
+// Join implements file.FS.
+func (t *T) Join(components ...string) string {
+	return path.Join(components...)
+}
+
+// Base implements file.FS.
+func (t *T) Base(name string) string {
+	return path.Base(name)
+}
+
+// IsPermissionError implements file.FS.
+func (t *T) IsPermissionError(err error) bool {
+	return errors.Is(err, fs.ErrPermission)
+}
+
+// IsNotExist implements file.FS.
+func (t *T) IsNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// XAttr implements file.FS.
+func (t *T) XAttr(_ context.Context, name string, fi file.Info) (file.XAttr, error) {
+	if xattr, ok := fi.Sys().(file.XAttr); ok {
+		return xattr, nil
+	}
+	e, err := t.lookup(name, false)
+	if err != nil {
+		return file.XAttr{}, pathError("xattr", name, err)
+	}
+	return e.xattr, nil
+}
+
+// SysXAttr implements file.FS. The native representation of extended
+// attributes is file.XAttr.
+func (t *T) SysXAttr(_ any, merge file.XAttr) any {
+	return merge
+}
+
+// LevelScanner implements filewalk.FS. The entries are returned in
+// lexicographic order.
+func (t *T) LevelScanner(prefix string) filewalk.LevelScanner {
+	e, err := t.lookup(prefix, true)
+	if err != nil {
+		return fstree.NewScanner(nil, pathError("scan", prefix, err))
+	}
+	if !e.IsDir() {
+		return fstree.NewScanner(nil, pathError("scan", prefix, errors.New("not a directory")))
+	}
+	return fstree.NewScanner(fstree.Entries(e), nil)
+}
+
+type sectionHandle struct {
+	*io.SectionReader
+	info fs.FileInfo
+}
+
+func (f *sectionHandle) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *sectionHandle) Close() error {
+	return nil
+}
+
+type fileHandle struct {
+	io.ReadCloser
+	info fs.FileInfo
+}
+
+func (f *fileHandle) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
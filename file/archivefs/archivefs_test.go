@@ -0,0 +1,351 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package archivefs_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/fs"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"cloudeng.io/file"
+	"cloudeng.io/file/archivefs"
+	"cloudeng.io/file/filewalk"
+	"cloudeng.io/file/matcher"
+)
+
+var modTime = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+type archiveEntry struct {
+	name     string
+	mode     int64
+	typ      byte
+	contents string
+	link     string
+}
+
+// a/ and a/b/ are implied rather than explicitly stored.
+var archiveEntries = []archiveEntry{
+	{name: "top.txt", mode: 0644, typ: tar.TypeReg, contents: "top"},
+	{name: "a/b/c.txt", mode: 0600, typ: tar.TypeReg, contents: "hello world"},
+	{name: "a/b/d.bin", mode: 0755, typ: tar.TypeReg, contents: "binary"},
+	{name: "e/", mode: 0700, typ: tar.TypeDir},
+	{name: "e/f.txt", mode: 0644, typ: tar.TypeReg, contents: "in e"},
+	{name: "link", mode: 0777, typ: tar.TypeSymlink, link: "a/b/c.txt"},
+	{name: "e/hard", mode: 0600, typ: tar.TypeLink, link: "a/b/c.txt"},
+}
+
+func createTar(t *testing.T, compress bool) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	var wr io.Writer = buf
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(buf)
+		wr = gz
+	}
+	tw := tar.NewWriter(wr)
+	for _, e := range archiveEntries {
+		hdr := &tar.Header{
+			Typeflag: e.typ,
+			Name:     e.name,
+			Mode:     e.mode,
+			Size:     int64(len(e.contents)),
+			Linkname: e.link,
+			ModTime:  modTime,
+			Uid:      1001,
+			Gid:      2002,
+			Uname:    "user",
+			Gname:    "group",
+			Format:   tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func createZip(t *testing.T) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for i, e := range archiveEntries {
+		hdr := &zip.FileHeader{
+			Name:     e.name,
+			Modified: modTime,
+			Method:   zip.Deflate,
+		}
+		if i%2 == 0 {
+			hdr.Method = zip.Store
+		}
+		contents := e.contents
+		switch e.typ {
+		case tar.TypeDir:
+			hdr.SetMode(fs.ModeDir | fs.FileMode(e.mode))
+		case tar.TypeSymlink:
+			hdr.SetMode(fs.ModeSymlink | fs.FileMode(e.mode))
+			contents = e.link
+		case tar.TypeLink:
+			// Zip has no notion of hard links.
+			hdr.SetMode(fs.FileMode(e.mode))
+			contents = "hello world"
+		default:
+			hdr.SetMode(fs.FileMode(e.mode))
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func contentsHandler(afs *archivefs.T, mu *sync.Mutex, names *[]string) filewalk.ContentsHandler {
+	return func(_ context.Context, prefix string, contents []filewalk.Entry, err error) error {
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, c := range contents {
+			*names = append(*names, afs.Join(prefix, c.Name))
+		}
+		return nil
+	}
+}
+
+func testArchive(t *testing.T, afs *archivefs.T, format archivefs.Format, hasOwner bool) {
+	ctx := context.Background()
+	if got, want := afs.Format(), format; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := afs.Scheme(), format.String(); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, tc := range []struct {
+		name, contents string
+		mode           fs.FileMode
+	}{
+		{"top.txt", "top", 0644},
+		{"/a/b/c.txt", "hello world", 0600},
+		{"a/b/d.bin", "binary", 0755},
+		{"e/f.txt", "in e", 0644},
+		{"link", "hello world", 0600},
+		{"e/hard", "hello world", 0600},
+	} {
+		data, err := afs.ReadFile(tc.name)
+		if err != nil {
+			t.Errorf("%v: %v", tc.name, err)
+			continue
+		}
+		if got, want := string(data), tc.contents; got != want {
+			t.Errorf("%v: got %v, want %v", tc.name, got, want)
+		}
+		info, err := afs.Stat(ctx, tc.name)
+		if err != nil {
+			t.Errorf("%v: %v", tc.name, err)
+			continue
+		}
+		if got, want := info.Mode(), tc.mode; got != want {
+			t.Errorf("%v: got %v, want %v", tc.name, got, want)
+		}
+		if got, want := info.Size(), int64(len(tc.contents)); got != want {
+			t.Errorf("%v: got %v, want %v", tc.name, got, want)
+		}
+		if got, want := info.ModTime(), modTime; !got.Equal(want) {
+			t.Errorf("%v: got %v, want %v", tc.name, got, want)
+		}
+	}
+
+	info, err := afs.Lstat(ctx, "link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("link is not a symlink: %v", info.Mode())
+	}
+	if target, err := afs.Readlink(ctx, "link"); err != nil || target != "a/b/c.txt" {
+		t.Errorf("unexpected target: %v: %v", target, err)
+	}
+	if _, err := afs.Readlink(ctx, "top.txt"); err == nil {
+		t.Errorf("expected an error")
+	}
+
+	for _, dir := range []string{"", "a", "a/b", "e"} {
+		info, err := afs.Stat(ctx, dir)
+		if err != nil {
+			t.Errorf("%v: %v", dir, err)
+			continue
+		}
+		if !info.IsDir() {
+			t.Errorf("%v: not a directory", dir)
+		}
+	}
+	if info, _ := afs.Stat(ctx, "e"); info.Mode().Perm() != 0700 {
+		t.Errorf("unexpected mode for e: %v", info.Mode())
+	}
+
+	if _, err := afs.Stat(ctx, "a/x"); !afs.IsNotExist(err) {
+		t.Errorf("expected a not-exist error: %v", err)
+	}
+	if _, err := afs.Open("a/b/c.txt/x"); !afs.IsNotExist(err) {
+		t.Errorf("expected a not-exist error: %v", err)
+	}
+
+	info, err = afs.Stat(ctx, "a/b/c.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	xattr, err := afs.XAttr(ctx, "a/b/c.txt", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := file.XAttr{UID: -1, GID: -1}
+	if hasOwner {
+		want = file.XAttr{UID: 1001, GID: 2002, User: "user", Group: "group"}
+	}
+	if got := xattr; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	f, err := afs.Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := f.(fs.ReadDirFile).ReadDir(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "b" || !entries[0].IsDir() {
+		t.Errorf("unexpected entries: %v", entries)
+	}
+	f.Close()
+
+	var mu sync.Mutex
+	var names []string
+	if err := filewalk.ContentsOnly(ctx, afs, "", contentsHandler(afs, &mu, &names), filewalk.WithScanSize(1)); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(names)
+	if got, want := names, []string{"a/b/c.txt", "a/b/d.bin", "e/f.txt", "e/hard", "link", "top.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	expr, err := matcher.New().Parse("name=*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var matched []string
+	for _, name := range names {
+		info, err := afs.Lstat(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expr.Eval(info) {
+			matched = append(matched, name)
+		}
+	}
+	if got, want := matched, []string{"a/b/c.txt", "e/f.txt", "top.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestTar(t *testing.T) {
+	data := createTar(t, false)
+	afs, err := archivefs.NewTar(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testArchive(t, afs, archivefs.Tar, true)
+
+	// Files in uncompressed tar archives support random access.
+	f, err := afs.Open("a/b/c.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	buf := make([]byte, 5)
+	if _, err := f.(io.ReaderAt).ReadAt(buf, 6); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(buf), "world"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestTarGzip(t *testing.T) {
+	data := createTar(t, true)
+	afs, err := archivefs.NewTarGzip(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testArchive(t, afs, archivefs.TarGzip, true)
+}
+
+func TestZip(t *testing.T) {
+	data := createZip(t)
+	afs, err := archivefs.NewZip(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testArchive(t, afs, archivefs.Zip, false)
+}
+
+func TestDetect(t *testing.T) {
+	for _, tc := range []struct {
+		data   []byte
+		format archivefs.Format
+	}{
+		{createTar(t, false), archivefs.Tar},
+		{createTar(t, true), archivefs.TarGzip},
+		{createZip(t), archivefs.Zip},
+	} {
+		rd := bytes.NewReader(tc.data)
+		format, err := archivefs.Detect(rd, int64(len(tc.data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := format, tc.format; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		afs, err := archivefs.New(rd, int64(len(tc.data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := afs.Format(), tc.format; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+	if _, err := archivefs.Detect(bytes.NewReader([]byte("not an archive")), 14); err == nil {
+		t.Errorf("expected an error")
+	}
+}
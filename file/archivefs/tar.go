@@ -0,0 +1,142 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package archivefs
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"cloudeng.io/file"
+)
+
+// NewTar returns a new filesystem for the uncompressed tar archive
+// accessed via rd, which contains size bytes.
+func NewTar(rd io.ReaderAt, size int64) (*T, error) {
+	return newTar(rd, size, Tar)
+}
+
+// NewTarGzip returns a new filesystem for the gzip compressed tar archive
+// accessed via rd, which contains size bytes. Note that the archive must
+// be decompressed up to the point at which a file is stored in order
+// to read that file's contents.
+func NewTarGzip(rd io.ReaderAt, size int64) (*T, error) {
+	return newTar(rd, size, TarGzip)
+}
+
+type countingReader struct {
+	rd io.Reader
+	n  int64
+}
+
+func (cr *countingReader) Read(buf []byte) (int, error) {
+	n, err := cr.rd.Read(buf)
+	cr.n += int64(n)
+	return n, err
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// tarReader returns a tar.Reader for the archive, decompressing it if
+// necessary, along with the number of bytes of the archive that have
+// been read to date.
+func tarReader(rd io.ReaderAt, size int64, format Format) (*tar.Reader, *countingReader, io.Closer, error) {
+	cr := &countingReader{rd: io.NewSectionReader(rd, 0, size)}
+	if format != TarGzip {
+		return tar.NewReader(cr), cr, nopCloser{}, nil
+	}
+	gz, err := gzip.NewReader(cr)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return tar.NewReader(gz), cr, gz, nil
+}
+
+func isSparse(hdr *tar.Header) bool {
+	if hdr.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for k := range hdr.PAXRecords {
+		if strings.HasPrefix(k, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+func newTar(rd io.ReaderAt, size int64, format Format) (*T, error) {
+	tr, cr, closer, err := tarReader(rd, size, format)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	t := &T{format: format, rd: rd, size: size, root: newDir("")}
+	for index := 0; ; index++ {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("archivefs: %v: %w", format, err)
+		}
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		e := &entry{
+			mode:    hdr.FileInfo().Mode(),
+			size:    hdr.Size,
+			modTime: hdr.ModTime,
+			xattr: file.XAttr{
+				UID:   int64(hdr.Uid),
+				GID:   int64(hdr.Gid),
+				User:  hdr.Uname,
+				Group: hdr.Gname,
+			},
+			offset: cr.n,
+		}
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			e.link = hdr.Linkname
+			e.size = int64(len(hdr.Linkname))
+		case tar.TypeLink:
+			e.link = cleanName(hdr.Linkname)
+			e.hardlink = true
+		}
+		if format == TarGzip || isSparse(hdr) {
+			e.data = func() (io.ReadCloser, error) {
+				return openTarEntry(rd, size, format, index)
+			}
+		}
+		t.add(hdr.Name, e)
+	}
+	t.resolveHardlinks(t.root)
+	return t, nil
+}
+
+// openTarEntry returns a reader for the entry with the specified sequence
+// number by reading the archive from the start.
+func openTarEntry(rd io.ReaderAt, size int64, format Format, index int) (io.ReadCloser, error) {
+	tr, _, closer, err := tarReader(rd, size, format)
+	if err != nil {
+		return nil, err
+	}
+	for range index + 1 {
+		if _, err := tr.Next(); err != nil {
+			closer.Close()
+			return nil, err
+		}
+	}
+	return readCloser{Reader: tr, Closer: closer}, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package archivefs
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// maxSymlinkSize is the maximum size of the target of a symbolic link
+// stored in a zip archive.
+const maxSymlinkSize = 4096
+
+// NewZip returns a new filesystem for the zip archive accessed via rd,
+// which contains size bytes. Zip archives do not record uid/gid
+// information and hence the file.XAttr for all entries will have a
+// UID and GID of -1.
+func NewZip(rd io.ReaderAt, size int64) (*T, error) {
+	zr, err := zip.NewReader(rd, size)
+	if err != nil {
+		return nil, fmt.Errorf("archivefs: zip: %w", err)
+	}
+	t := &T{format: Zip, rd: rd, size: size, root: newDir("")}
+	for _, zf := range zr.File {
+		mode := zf.Mode()
+		if strings.HasSuffix(zf.Name, "/") {
+			mode |= fs.ModeDir
+		}
+		e := &entry{
+			mode:    mode,
+			size:    int64(zf.UncompressedSize64),
+			modTime: zf.Modified,
+			xattr:   noXAttr,
+		}
+		switch {
+		case mode.IsDir():
+			e.size = 0
+		case mode&fs.ModeSymlink != 0:
+			target, err := readZipSymlink(zf)
+			if err != nil {
+				return nil, fmt.Errorf("archivefs: zip: %v: %w", zf.Name, err)
+			}
+			e.link = target
+		case zf.Method == zip.Store:
+			offset, err := zf.DataOffset()
+			if err == nil {
+				e.offset = offset
+				break
+			}
+			fallthrough
+		default:
+			e.data = zf.Open
+		}
+		t.add(zf.Name, e)
+	}
+	return t, nil
+}
+
+func readZipSymlink(zf *zip.File) (string, error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	buf, err := io.ReadAll(io.LimitReader(rc, maxSymlinkSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(buf) > maxSymlinkSize {
+		return "", fmt.Errorf("symbolic link target is too long")
+	}
+	return string(buf), nil
+}
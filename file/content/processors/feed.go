@@ -0,0 +1,121 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package processors
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Feed provides support for processing RSS 2.0, RSS 1.0 (RDF) and
+// Atom feeds.
+type Feed struct{}
+
+// FeedFormat identifies the format of a feed.
+type FeedFormat string
+
+const (
+	RSS  FeedFormat = "rss"
+	RDF  FeedFormat = "rdf"
+	Atom FeedFormat = "atom"
+)
+
+// FeedDoc represents a parsed feed.
+type FeedDoc struct {
+	Format FeedFormat
+	links  []string
+}
+
+// atomIgnoredRels are the atom link relations that do not refer to
+// content.
+var atomIgnoredRels = []string{"self", "hub", "edit", "edit-media", "license", "payment"}
+
+// Parse parses an RSS or Atom feed. It returns an error that wraps
+// ErrUnsupportedDocument if the document is not a feed.
+func (fd Feed) Parse(rd io.Reader) (FeedDoc, error) {
+	rd, err := maybeGunzip(rd)
+	if err != nil {
+		return FeedDoc{}, err
+	}
+	dec := newXMLDecoder(rd)
+	root, err := xmlRoot(dec)
+	if err != nil {
+		return FeedDoc{}, err
+	}
+	var doc FeedDoc
+	switch root.Name.Local {
+	case "rss":
+		doc.Format = RSS
+	case "RDF":
+		doc.Format = RDF
+	case "feed":
+		doc.Format = Atom
+	default:
+		return doc, fmt.Errorf("%w: not a feed: root element %q", ErrUnsupportedDocument, root.Name.Local)
+	}
+	stack := []string{root.Name.Local}
+	var text []byte
+	collecting := false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return doc, nil
+			}
+			return doc, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			parent := stack[len(stack)-1]
+			stack = append(stack, t.Name.Local)
+			collecting = doc.start(t, parent)
+			text = text[:0]
+		case xml.CharData:
+			if collecting {
+				text = append(text, t...)
+			}
+		case xml.EndElement:
+			if collecting {
+				doc.links = append(doc.links, string(text))
+				collecting = false
+			}
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+}
+
+// start records any links found in the attributes of se and returns
+// true if the character data contained in se is a link.
+func (doc *FeedDoc) start(se xml.StartElement, parent string) bool {
+	switch se.Name.Local {
+	case "link":
+		if parent != "channel" && parent != "item" && parent != "feed" && parent != "entry" {
+			return false
+		}
+		if href, ok := attr(se, "href"); ok {
+			// Atom links, including atom:link elements in RSS feeds.
+			if rel, _ := attr(se, "rel"); !slices.Contains(atomIgnoredRels, rel) {
+				doc.links = append(doc.links, href)
+			}
+			return false
+		}
+		return doc.Format != Atom
+	case "enclosure":
+		if u, ok := attr(se, "url"); ok && parent == "item" {
+			doc.links = append(doc.links, u)
+		}
+	}
+	return false
+}
+
+// Links returns the links found in the feed, relative links are resolved
+// against base.
+func (doc FeedDoc) Links(base string) []string {
+	return resolveAll(base, doc.links)
+}
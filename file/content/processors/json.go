@@ -0,0 +1,238 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package processors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// JSON provides support for processing JSON documents.
+type JSON struct{}
+
+// JSONDoc represents a parsed JSON document.
+type JSONDoc struct {
+	root any
+}
+
+// Parse parses a JSON document.
+func (js JSON) Parse(rd io.Reader) (JSONDoc, error) {
+	rd, err := maybeGunzip(rd)
+	if err != nil {
+		return JSONDoc{}, err
+	}
+	var doc JSONDoc
+	if err := json.NewDecoder(rd).Decode(&doc.root); err != nil {
+		return JSONDoc{}, err
+	}
+	return doc, nil
+}
+
+// Strings returns the string values selected by path. String values
+// contained in a selected array are also returned.
+func (doc JSONDoc) Strings(path JSONPath) []string {
+	var out []string
+	for _, v := range path.Select(doc.root) {
+		switch tv := v.(type) {
+		case string:
+			out = append(out, tv)
+		case []any:
+			for _, e := range tv {
+				if s, ok := e.(string); ok {
+					out = append(out, s)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// Links returns the string values selected by any of the supplied paths,
+// relative links are resolved against base.
+func (doc JSONDoc) Links(base string, paths ...JSONPath) []string {
+	var out []string
+	for _, p := range paths {
+		out = append(out, doc.Strings(p)...)
+	}
+	return resolveAll(base, out)
+}
+
+// JSONPath represents a JSONPath-style expression used to select values
+// from a JSON document. The supported subset of JSONPath is:
+//
+//	$          the root, which is optional
+//	.name      a member of an object
+//	['name']   a member of an object, quoted to allow for any characters
+//	[n]        the n'th element of an array, negative values index from the end
+//	.* or [*]  all members of an object or elements of an array
+//	..name     recursive descent, ie. all members called name at any depth
+//
+// For example, '$.items[*].url', 'links[0]' or '$..href'.
+type JSONPath struct {
+	expr  string
+	steps []jsonStep
+}
+
+type jsonStep struct {
+	recursive bool
+	wildcard  bool
+	isIndex   bool
+	index     int
+	name      string
+}
+
+// String implements fmt.Stringer.
+func (p JSONPath) String() string {
+	return p.expr
+}
+
+// ParseJSONPath parses a JSONPath-style expression.
+func ParseJSONPath(expr string) (JSONPath, error) {
+	p := JSONPath{expr: expr}
+	rest := strings.TrimPrefix(strings.TrimSpace(expr), "$")
+	if len(rest) > 0 && rest[0] != '.' && rest[0] != '[' {
+		rest = "." + rest
+	}
+	for len(rest) > 0 {
+		var step jsonStep
+		var err error
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			step.recursive = true
+			rest = rest[2:]
+			if strings.HasPrefix(rest, "[") {
+				step, rest, err = parseJSONBracket(rest, step)
+			} else {
+				step, rest, err = parseJSONName(rest, step)
+			}
+		case rest[0] == '.':
+			step, rest, err = parseJSONName(rest[1:], step)
+		case rest[0] == '[':
+			step, rest, err = parseJSONBracket(rest, step)
+		default:
+			err = fmt.Errorf("unexpected character %q", rest[0])
+		}
+		if err != nil {
+			return JSONPath{}, fmt.Errorf("invalid JSONPath %q: %w", expr, err)
+		}
+		p.steps = append(p.steps, step)
+	}
+	return p, nil
+}
+
+func parseJSONName(rest string, step jsonStep) (jsonStep, string, error) {
+	end := strings.IndexAny(rest, ".[")
+	if end < 0 {
+		end = len(rest)
+	}
+	name := rest[:end]
+	switch name {
+	case "":
+		return step, rest, fmt.Errorf("missing member name")
+	case "*":
+		step.wildcard = true
+	default:
+		step.name = name
+	}
+	return step, rest[end:], nil
+}
+
+func parseJSONBracket(rest string, step jsonStep) (jsonStep, string, error) {
+	rest = rest[1:]
+	if len(rest) > 0 && (rest[0] == '\'' || rest[0] == '"') {
+		end := strings.IndexByte(rest[1:], rest[0])
+		if end < 0 || !strings.HasPrefix(rest[end+2:], "]") {
+			return step, rest, fmt.Errorf("unterminated quoted member name")
+		}
+		step.name = rest[1 : end+1]
+		return step, rest[end+3:], nil
+	}
+	end := strings.IndexByte(rest, ']')
+	if end < 0 {
+		return step, rest, fmt.Errorf("missing ]")
+	}
+	sel := strings.TrimSpace(rest[:end])
+	if sel == "*" {
+		step.wildcard = true
+		return step, rest[end+1:], nil
+	}
+	idx, err := strconv.Atoi(sel)
+	if err != nil {
+		return step, rest, fmt.Errorf("invalid array index %q", sel)
+	}
+	step.isIndex, step.index = true, idx
+	return step, rest[end+1:], nil
+}
+
+// Select returns the values selected by the path from v, which is
+// typically the result of unmarshaling a JSON document into an any.
+func (p JSONPath) Select(v any) []any {
+	cur := []any{v}
+	for _, step := range p.steps {
+		var next []any
+		for _, c := range cur {
+			if step.recursive {
+				next = step.descend(c, next)
+				continue
+			}
+			next = step.apply(c, next)
+		}
+		cur = next
+	}
+	return cur
+}
+
+func jsonChildren(v any) []any {
+	switch tv := v.(type) {
+	case map[string]any:
+		children := make([]any, 0, len(tv))
+		for _, k := range slices.Sorted(maps.Keys(tv)) {
+			children = append(children, tv[k])
+		}
+		return children
+	case []any:
+		return tv
+	}
+	return nil
+}
+
+func (s jsonStep) apply(v any, out []any) []any {
+	switch {
+	case s.wildcard:
+		return append(out, jsonChildren(v)...)
+	case s.isIndex:
+		arr, ok := v.([]any)
+		if !ok {
+			return out
+		}
+		idx := s.index
+		if idx < 0 {
+			idx += len(arr)
+		}
+		if idx >= 0 && idx < len(arr) {
+			out = append(out, arr[idx])
+		}
+		return out
+	}
+	if obj, ok := v.(map[string]any); ok {
+		if m, ok := obj[s.name]; ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func (s jsonStep) descend(v any, out []any) []any {
+	out = s.apply(v, out)
+	for _, c := range jsonChildren(v) {
+		out = s.descend(c, out)
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package processors
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Sitemap provides support for processing sitemaps and sitemap indexes
+// as defined by https://www.sitemaps.org/protocol.html. Gzip compressed
+// sitemaps are decompressed transparently.
+type Sitemap struct{}
+
+// SitemapDoc represents a parsed sitemap or sitemap index.
+type SitemapDoc struct {
+	// Index is true for a sitemap index, in which case Locations
+	// refers to other sitemaps rather than to pages.
+	Index bool
+	// Locations contains the <loc> values of all <url> or <sitemap>
+	// entries.
+	Locations []string
+}
+
+// Parse parses a sitemap or sitemap index. It returns an error that
+// wraps ErrUnsupportedDocument if the document is not a sitemap.
+func (sm Sitemap) Parse(rd io.Reader) (SitemapDoc, error) {
+	rd, err := maybeGunzip(rd)
+	if err != nil {
+		return SitemapDoc{}, err
+	}
+	dec := newXMLDecoder(rd)
+	root, err := xmlRoot(dec)
+	if err != nil {
+		return SitemapDoc{}, err
+	}
+	var doc SitemapDoc
+	var entry string
+	switch root.Name.Local {
+	case "urlset":
+		entry = "url"
+	case "sitemapindex":
+		doc.Index, entry = true, "sitemap"
+	default:
+		return doc, fmt.Errorf("%w: not a sitemap: root element %q", ErrUnsupportedDocument, root.Name.Local)
+	}
+	parent := ""
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return doc, nil
+			}
+			return doc, err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			if ee, ok := tok.(xml.EndElement); ok && ee.Name.Local == entry {
+				parent = ""
+			}
+			continue
+		}
+		switch {
+		case se.Name.Local == entry:
+			parent = entry
+		case se.Name.Local == "loc" && parent == entry:
+			var loc string
+			if err := dec.DecodeElement(&loc, &se); err != nil {
+				return doc, err
+			}
+			if loc = strings.TrimSpace(loc); len(loc) > 0 {
+				doc.Locations = append(doc.Locations, loc)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package processors
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// ErrUnsupportedDocument is returned by the Parse methods of the XML based
+// processors when the document is well formed but is not of the type
+// supported by the processor, eg. when an RSS feed is parsed as a sitemap.
+// It allows for multiple processors to be tried for the same content type.
+var ErrUnsupportedDocument = errors.New("unsupported document")
+
+// MaxUncompressedSize is the maximum size of the decompressed contents
+// of a gzip compressed document, it is the limit specified for sitemaps
+// by the sitemap protocol. Larger documents are truncated and hence will
+// fail to parse.
+const MaxUncompressedSize = 50 * 1024 * 1024
+
+// maybeGunzip returns a reader that transparently decompresses rd if
+// it contains gzip compressed data, limiting the decompressed data
+// to MaxUncompressedSize bytes.
+func maybeGunzip(rd io.Reader) (io.Reader, error) {
+	brd := bufio.NewReader(rd)
+	magic, err := brd.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		return brd, nil
+	}
+	zrd, err := gzip.NewReader(brd)
+	if err != nil {
+		return nil, err
+	}
+	return io.LimitReader(zrd, MaxUncompressedSize), nil
+}
+
+// xmlRoot returns the root element of the document being decoded by dec.
+func xmlRoot(dec *xml.Decoder) (xml.StartElement, error) {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return xml.StartElement{}, ErrUnsupportedDocument
+			}
+			return xml.StartElement{}, err
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			return se, nil
+		}
+	}
+}
+
+func newXMLDecoder(rd io.Reader) *xml.Decoder {
+	dec := xml.NewDecoder(rd)
+	dec.Strict = false
+	// Assume that all non-utf-8 charsets are compatible with utf-8
+	// for the purposes of extracting links.
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return dec
+}
+
+func attr(se xml.StartElement, name string) (string, bool) {
+	for _, a := range se.Attr {
+		if a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// resolve returns link resolved relative to base if link is a relative
+// URL and base is not nil.
+func resolve(base *url.URL, link string) string {
+	link = strings.TrimSpace(link)
+	if base == nil || len(link) == 0 {
+		return link
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.IsAbs() {
+		return link
+	}
+	return base.ResolveReference(u).String()
+}
+
+func resolveAll(base string, links []string) []string {
+	u, err := url.Parse(base)
+	if err != nil || len(base) == 0 {
+		u = nil
+	}
+	out := make([]string, 0, len(links))
+	for _, l := range links {
+		if l = resolve(u, l); len(l) > 0 {
+			out = append(out, l)
+		}
+	}
+	return out
+}
@@ -0,0 +1,219 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package processors_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloudeng.io/file/content/processors"
+)
+
+const (
+	sitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.com/</loc>
+    <lastmod>2026-01-01</lastmod>
+  </url>
+  <url>
+    <loc> https://example.com/a?x=1&amp;y=2 </loc>
+  </url>
+</urlset>`
+
+	sitemapIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.com/sitemap1.xml.gz</loc></sitemap>
+  <sitemap><loc>https://example.com/sitemap2.xml</loc></sitemap>
+</sitemapindex>`
+
+	rss = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+  <channel>
+    <title>Example</title>
+    <link>https://example.com/</link>
+    <atom:link href="https://example.com/feed.xml" rel="self"/>
+    <image><link>https://example.com/logo</link></image>
+    <item>
+      <title>One</title>
+      <link>https://example.com/one</link>
+      <enclosure url="/one.mp3" type="audio/mpeg" length="10"/>
+    </item>
+    <item>
+      <link>/two</link>
+    </item>
+  </channel>
+</rss>`
+
+	rdf = `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/">
+  <channel rdf:about="https://example.com/rdf">
+    <link>https://example.com/</link>
+  </channel>
+  <item rdf:about="https://example.com/one">
+    <link>https://example.com/one</link>
+  </item>
+</rdf:RDF>`
+
+	atom = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example</title>
+  <link href="https://example.com/"/>
+  <link rel="self" href="https://example.com/atom.xml"/>
+  <link rel="next" href="/atom.xml?page=2"/>
+  <entry>
+    <title>One</title>
+    <link href="https://example.com/one"/>
+    <link rel="enclosure" href="https://example.com/one.mp3"/>
+    <link rel="edit" href="https://example.com/edit/one"/>
+  </entry>
+</feed>`
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	wr := gzip.NewWriter(&buf)
+	if _, err := wr.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSitemap(t *testing.T) {
+	var sm processors.Sitemap
+	for _, rd := range []*bytes.Reader{
+		bytes.NewReader([]byte(sitemap)),
+		bytes.NewReader(gzipped(t, sitemap)),
+	} {
+		doc, err := sm.Parse(rd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := doc, (processors.SitemapDoc{
+			Locations: []string{"https://example.com/", "https://example.com/a?x=1&y=2"},
+		}); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+
+	doc, err := sm.Parse(strings.NewReader(sitemapIndex))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc, (processors.SitemapDoc{
+		Index:     true,
+		Locations: []string{"https://example.com/sitemap1.xml.gz", "https://example.com/sitemap2.xml"},
+	}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, other := range []string{rss, atom, "<html></html>", ""} {
+		_, err = sm.Parse(strings.NewReader(other))
+		if !errors.Is(err, processors.ErrUnsupportedDocument) {
+			t.Errorf("unexpected or missing error: %v", err)
+		}
+	}
+
+	// Compressed documents are limited to MaxUncompressedSize bytes
+	// once decompressed.
+	padded := strings.Replace(sitemap, "</urlset>",
+		strings.Repeat(" ", processors.MaxUncompressedSize)+"</urlset>", 1)
+	if _, err := sm.Parse(bytes.NewReader(gzipped(t, padded))); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestFeed(t *testing.T) {
+	var fd processors.Feed
+	for _, tc := range []struct {
+		doc    string
+		format processors.FeedFormat
+		links  []string
+	}{
+		{rss, processors.RSS, []string{
+			"https://example.com/",
+			"https://example.com/one",
+			"https://example.com/one.mp3",
+			"https://example.com/two",
+		}},
+		{rdf, processors.RDF, []string{
+			"https://example.com/",
+			"https://example.com/one",
+		}},
+		{atom, processors.Atom, []string{
+			"https://example.com/",
+			"https://example.com/atom.xml?page=2",
+			"https://example.com/one",
+			"https://example.com/one.mp3",
+		}},
+	} {
+		doc, err := fd.Parse(bytes.NewReader(gzipped(t, tc.doc)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := doc.Format, tc.format; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := doc.Links("https://example.com/feed"), tc.links; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", tc.format, got, want)
+		}
+	}
+	_, err := fd.Parse(strings.NewReader(sitemap))
+	if !errors.Is(err, processors.ErrUnsupportedDocument) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	const doc = `{
+  "next": "/page/2",
+  "items": [
+    {"url": "https://example.com/a", "tags": ["x"]},
+    {"url": "/b", "links": {"self": "/b/self", "other": ["/c", 3]}},
+    {"title": "no url"}
+  ]
+}`
+	jd, err := processors.JSON{}.Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		paths []string
+		links []string
+	}{
+		{[]string{"$.items[*].url"}, []string{"https://example.com/a", "https://example.com/b"}},
+		{[]string{"items[0].url", "$.next"}, []string{"https://example.com/a", "https://example.com/page/2"}},
+		{[]string{"$.items[-2]['url']"}, []string{"https://example.com/b"}},
+		{[]string{"$.items[1].links.*"}, []string{"https://example.com/c", "https://example.com/b/self"}},
+		{[]string{"$..self"}, []string{"https://example.com/b/self"}},
+		{[]string{"$..url"}, []string{"https://example.com/a", "https://example.com/b"}},
+		{[]string{"$.items[5].url", "$.missing", "$.items[2].title.x"}, []string{}},
+	} {
+		var paths []processors.JSONPath
+		for _, p := range tc.paths {
+			jp, err := processors.ParseJSONPath(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			paths = append(paths, jp)
+		}
+		if got, want := jd.Links("https://example.com/api", paths...), tc.links; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", tc.paths, got, want)
+		}
+	}
+
+	for _, invalid := range []string{"$.", "$[", "$['x'", "$[x]", "$.a.."} {
+		if _, err := processors.ParseJSONPath(invalid); err == nil {
+			t.Errorf("%v: expected an error", invalid)
+		}
+	}
+}
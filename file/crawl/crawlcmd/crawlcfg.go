@@ -10,13 +10,17 @@ package crawlcmd
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"cloudeng.io/algo/ratecontrol"
 	"cloudeng.io/file/checkpoint"
 	"cloudeng.io/file/content"
 	"cloudeng.io/file/crawl"
 	"cloudeng.io/file/crawl/outlinks"
+	"cloudeng.io/file/crawl/robots"
+	"cloudeng.io/file/crawl/urldedup"
 	"cloudeng.io/file/download"
 	"cloudeng.io/path/cloudpath"
 	"gopkg.in/yaml.v3"
@@ -55,6 +59,113 @@ type DownloadConfig struct {
 	RateControlConfig     RateControl `yaml:",inline"`
 }
 
+// Politeness is the configuration for robots.txt compliance and per-host
+// rate control.
+type Politeness struct {
+	UserAgent     string        `yaml:"user_agent" doc:"the user agent used to fetch robots.txt and to select the robots.txt rules that apply to the crawl"`
+	RespectRobots bool          `yaml:"respect_robots" doc:"if true, robots.txt is fetched for each host and links that it disallows are not followed. Any Crawl-delay it specifies is used to rate limit requests to that host."`
+	MaxCrawlDelay time.Duration `yaml:"max_crawl_delay" doc:"the maximum Crawl-delay that will be honored, larger values are reduced to it. Zero means no maximum."`
+	PerHost       RateControl   `yaml:"per_host" doc:"rate control applied to each host independently of all other hosts"`
+}
+
+// Enabled returns true if any form of robots.txt compliance or per-host
+// rate control is configured.
+func (p Politeness) Enabled() bool {
+	return p.RespectRobots || p.PerHost.Rate.RequestsPerTick > 0 ||
+		p.PerHost.Rate.BytesPerTick > 0 || p.PerHost.ExponentialBackoff.InitialDelay > 0
+}
+
+// NewRobotsCache returns a robots.Cache that uses the supplied client to
+// fetch robots.txt files, or nil if RespectRobots is false.
+func (p Politeness) NewRobotsCache(client *http.Client) *robots.Cache {
+	if !p.RespectRobots {
+		return nil
+	}
+	return robots.NewCache(client, p.UserAgent)
+}
+
+// NewHostControllers returns a crawl.HostControllers that creates a
+// rate controller for each host using the PerHost configuration. If
+// cache is not nil, any Crawl-delay specified in a host's robots.txt, capped
+// at MaxCrawlDelay, replaces the configured requests per tick for that
+// host with one request per Crawl-delay. If a host's robots.txt could not
+// be fetched, its controller is recreated once the robots.txt is due to be
+// fetched again so that any Crawl-delay is honored once it is available.
+func (p Politeness) NewHostControllers(cache *robots.Cache) *crawl.HostControllers {
+	return crawl.NewHostControllers(func(ctx context.Context, name string) (*ratecontrol.Controller, time.Time) {
+		rate := p.PerHost.Rate
+		opts := []ratecontrol.Option{}
+		if rate.BytesPerTick > 0 {
+			opts = append(opts, ratecontrol.WithBytesPerTick(rate.Tick, rate.BytesPerTick))
+		}
+		delay, expires := p.crawlDelay(ctx, cache, name)
+		if delay > 0 {
+			opts = append(opts, ratecontrol.WithRequestsPerTick(delay, 1))
+		} else if rate.RequestsPerTick > 0 {
+			opts = append(opts, ratecontrol.WithRequestsPerTick(rate.Tick, rate.RequestsPerTick))
+		}
+		if backoff := p.PerHost.ExponentialBackoff; backoff.InitialDelay > 0 {
+			opts = append(opts, backoff.BackoffOption())
+		}
+		return ratecontrol.New(opts...), expires
+	})
+}
+
+func (p Politeness) crawlDelay(ctx context.Context, cache *robots.Cache, name string) (time.Duration, time.Time) {
+	if cache == nil {
+		return 0, time.Time{}
+	}
+	rules, expires, err := cache.Lookup(ctx, name)
+	if err != nil {
+		return 0, time.Time{}
+	}
+	if p.MaxCrawlDelay > 0 && rules.CrawlDelay > p.MaxCrawlDelay {
+		return p.MaxCrawlDelay, expires
+	}
+	return rules.CrawlDelay, expires
+}
+
+// Dedup is the configuration for detecting duplicate and near-duplicate
+// outlinks using cloudeng.io/file/crawl/urldedup.
+type Dedup struct {
+	Enabled               bool     `yaml:"enabled" doc:"if true, outlinks are canonicalized and checked against a Bloom filter so that duplicate and near-duplicate links are discarded using a fixed amount of memory. Otherwise each extractor uses an unbounded in-memory set of the links as they appear in the document."`
+	Expected              int      `yaml:"expected" doc:"the number of URLs that the Bloom filter is sized for, defaults to urldedup.DefaultExpected"`
+	FalsePositiveRate     float64  `yaml:"false_positive_rate" doc:"the false positive rate that the Bloom filter is sized for, defaults to urldedup.DefaultFalsePositiveRate"`
+	ExactSet              string   `yaml:"exact_set" doc:"an optional local directory used to store the exact set of URLs seen so that Bloom filter false positives are not discarded. URLs recorded by previous crawls are treated as duplicates and hence it should be removed before starting a new, rather than resumed, crawl."`
+	RemoveQueryParameters []string `yaml:"remove_query_parameters" doc:"the query parameters to be removed when canonicalizing URLs, a trailing '*' matches all parameters with the preceding prefix, defaults to urldedup.DefaultTrackingParameters"`
+}
+
+// NewDeduper returns the urldedup.T specified by the configuration, or
+// nil if Enabled is false.
+func (d Dedup) NewDeduper() (*urldedup.T, error) {
+	if !d.Enabled {
+		return nil, nil
+	}
+	expected, rate := d.Expected, d.FalsePositiveRate
+	if expected <= 0 {
+		expected = urldedup.DefaultExpected
+	}
+	if rate <= 0 {
+		rate = urldedup.DefaultFalsePositiveRate
+	}
+	canonicalizer := urldedup.NewCanonicalizer()
+	if len(d.RemoveQueryParameters) > 0 {
+		canonicalizer.RemoveQueryParameters = d.RemoveQueryParameters
+	}
+	opts := []urldedup.Option{
+		urldedup.WithCanonicalizer(canonicalizer),
+		urldedup.WithBloomFilter(expected, rate),
+	}
+	if len(d.ExactSet) > 0 {
+		set, err := urldedup.NewDiskSet(d.ExactSet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create exact set: %v: %w", d.ExactSet, err)
+		}
+		opts = append(opts, urldedup.WithExactSet(set))
+	}
+	return urldedup.New(opts...), nil
+}
+
 // Each crawl may specify its own cache directory and configuration. This
 // will be used to store the results of the crawl. The ServiceSpecific
 // field is intended to be parametized to some service specific configuration
@@ -64,12 +175,13 @@ type DownloadConfig struct {
 // using the scheme of the Downloads path (e.g s3://... would imply an AWS
 // specific configuration).
 type CrawlCacheConfig struct {
-	Downloads         string    `yaml:"downloads" doc:"the prefix/directory to use for the cache of downloaded documents. This is an absolute path the root directory of the crawl."`
-	ClearBeforeCrawl  bool      `yaml:"clear_before_crawl" doc:"if true, the cache and checkpoint will be cleared before the crawl starts."`
-	Checkpoint        string    `yaml:"checkpoint" doc:"the location of any checkpoint data used to resume a crawl, this is an absolute path."`
-	ShardingPrefixLen int       `yaml:"sharding_prefix_len" doc:"the number of characters of the filename to use for sharding the cache. This is intended to avoid filesystem limits on the number of files in a directory."`
-	Concurrency       int       `yaml:"concurrency" doc:"the number of concurrent operations to use when reading/writing to the cache."`
-	ServiceConfig     yaml.Node `yaml:"service_config,omitempty" doc:"cache service specific configuration, eg. AWS specific configuration"`
+	Downloads          string        `yaml:"downloads" doc:"the prefix/directory to use for the cache of downloaded documents. This is an absolute path the root directory of the crawl."`
+	ClearBeforeCrawl   bool          `yaml:"clear_before_crawl" doc:"if true, the cache and checkpoint will be cleared before the crawl starts."`
+	Checkpoint         string        `yaml:"checkpoint" doc:"the location of any checkpoint data used to resume a crawl, this is an absolute path."`
+	CheckpointInterval time.Duration `yaml:"checkpoint_interval" doc:"the interval at which the crawl frontier is checkpointed, defaults to one minute."`
+	ShardingPrefixLen  int           `yaml:"sharding_prefix_len" doc:"the number of characters of the filename to use for sharding the cache. This is intended to avoid filesystem limits on the number of files in a directory."`
+	Concurrency        int           `yaml:"concurrency" doc:"the number of concurrent operations to use when reading/writing to the cache."`
+	ServiceConfig      yaml.Node     `yaml:"service_config,omitempty" doc:"cache service specific configuration, eg. AWS specific configuration"`
 }
 
 // DownloadPath returns the expanded downloads path.
@@ -119,9 +231,13 @@ type Config struct {
 	FollowRules   []string         `yaml:"follow" doc:"a set of regular expressions that will be used to determine which links to follow. The regular expressions are applied to the full URL."`
 	RewriteRules  []string         `yaml:"rewrite" doc:"a set of regular expressions that will be used to rewrite links. The regular expressions are applied to the full URL."`
 	Download      DownloadConfig   `yaml:"download" doc:"the configuration for downloading documents"`
+	Politeness    Politeness       `yaml:"politeness" doc:"the configuration for robots.txt compliance and per-host rate control"`
 	NumExtractors int              `yaml:"num_extractors" doc:"the number of concurrent link extractors to use"`
-	Extractors    []content.Type   `yaml:"extractors" doc:"the content types to extract links from"`
+	Extractors    []content.Type   `yaml:"extractors" doc:"the content types to extract links from, sitemaps, RSS/Atom feeds and JSON documents are supported by default for the XML, RSS, Atom, gzip and JSON content types"`
+	JSONLinks     []string         `yaml:"json_links" doc:"JSONPath-style expressions used to locate links in JSON documents, eg. $.items[*].url"`
 	Cache         CrawlCacheConfig `yaml:"cache" doc:"the configuration for the cache of downloaded documents"`
+	Dedup         Dedup            `yaml:"dedup" doc:"the configuration for detecting duplicate and near-duplicate outlinks"`
+	Recrawl       bool             `yaml:"recrawl" doc:"if set, documents already in the crawl cache are only downloaded again if they have changed, as determined using the ETag, Last-Modified and content digest recorded when they were last downloaded; unchanged documents are read from the cache"`
 }
 
 // NewLinkProcessor creates a outlinks.RegexpProcessor using the
@@ -141,12 +257,16 @@ func (c Config) NewLinkProcessor() (*outlinks.RegexpProcessor, error) {
 // SeedsByScheme returns the crawl seeds grouped by their scheme and any seeds
 // that are not recognised by the supplied cloudpath.MatcherSpec.
 func (c Config) SeedsByScheme(matchers cloudpath.MatcherSpec) (map[string][]cloudpath.Match, []string) {
+	return matchesByScheme(matchers, c.Seeds)
+}
+
+func matchesByScheme(matchers cloudpath.MatcherSpec, names []string) (map[string][]cloudpath.Match, []string) {
 	matches := map[string][]cloudpath.Match{}
 	rejected := []string{}
-	for _, seed := range c.Seeds {
-		match := matchers.Match(seed)
+	for _, name := range names {
+		match := matchers.Match(name)
 		if len(match.Matched) == 0 {
-			rejected = append(rejected, seed)
+			rejected = append(rejected, name)
 			continue
 		}
 		scheme := match.Scheme
@@ -162,9 +282,18 @@ func (c Config) CreateSeedCrawlRequests(
 	ctx context.Context,
 	factories map[string]FSFactory,
 	seeds map[string][]cloudpath.Match,
+) ([]download.Request, error) {
+	return createRequests(ctx, factories, seeds, 0)
+}
+
+func createRequests(
+	ctx context.Context,
+	factories map[string]FSFactory,
+	matches map[string][]cloudpath.Match,
+	depth int,
 ) ([]download.Request, error) {
 	requests := []download.Request{}
-	for scheme, matched := range seeds {
+	for scheme, matched := range matches {
 		factory, ok := factories[scheme]
 		if !ok {
 			return nil, fmt.Errorf("no file.FSFactory for scheme: %v", scheme)
@@ -176,7 +305,7 @@ func (c Config) CreateSeedCrawlRequests(
 		var req crawl.SimpleRequest
 		req.FS = container
 		req.Mode = 0600
-		req.Depth = 0
+		req.Depth = depth
 		for _, match := range matched {
 			req.Filenames = append(req.Filenames, match.Matched)
 		}
@@ -186,8 +315,11 @@ func (c Config) CreateSeedCrawlRequests(
 }
 
 // ExtractorRegistry returns a content.Registry containing the outlinks.Extractor
-// that can be used with outlinks.Extract.
-func (c Config) ExtractorRegistry(avail map[content.Type]outlinks.Extractor) (*content.Registry[outlinks.Extractor], error) {
+// that can be used with outlinks.Extract. The extractors in avail are used
+// in preference to the built-in extractors for sitemaps, RSS/Atom feeds and
+// JSON documents. The supplied options, eg. outlinks.WithDeduper, are
+// used to create the built-in extractors.
+func (c Config) ExtractorRegistry(avail map[content.Type]outlinks.Extractor, opts ...outlinks.Option) (*content.Registry[outlinks.Extractor], error) {
 	reg := content.NewRegistry[outlinks.Extractor]()
 	for _, ctype := range c.Extractors {
 		_, _, _, err := content.ParseTypeFull(ctype)
@@ -198,11 +330,47 @@ func (c Config) ExtractorRegistry(avail map[content.Type]outlinks.Extractor) (*c
 			if err := reg.RegisterHandlers(ctype, extractor); err != nil {
 				return nil, err
 			}
+			continue
+		}
+		extractors, err := c.builtinExtractors(ctype, opts)
+		if err != nil {
+			return nil, err
+		}
+		if len(extractors) == 0 {
+			continue
+		}
+		if err := reg.RegisterHandlers(ctype, extractors...); err != nil {
+			return nil, err
 		}
 	}
 	return reg, nil
 }
 
+func (c Config) builtinExtractors(ctype content.Type, opts []outlinks.Option) ([]outlinks.Extractor, error) {
+	typ, err := content.ParseType(ctype)
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case "application/xml", "text/xml":
+		return []outlinks.Extractor{outlinks.NewSitemap(opts...), outlinks.NewFeed(opts...)}, nil
+	case "application/rss+xml", "application/atom+xml", "application/rdf+xml":
+		return []outlinks.Extractor{outlinks.NewFeed(opts...)}, nil
+	case "application/gzip", "application/x-gzip":
+		return []outlinks.Extractor{outlinks.NewSitemap(opts...)}, nil
+	case "application/json":
+		if len(c.JSONLinks) == 0 {
+			return nil, nil
+		}
+		js, err := outlinks.NewJSON(c.JSONLinks, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return []outlinks.Extractor{js}, nil
+	}
+	return nil, nil
+}
+
 // NewRateController creates a new rate controller based on the values
 // contained in RateControl.
 func (c RateControl) NewRateController() (*ratecontrol.Controller, error) {
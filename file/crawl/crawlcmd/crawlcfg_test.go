@@ -6,7 +6,10 @@ package crawlcmd_test
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -14,7 +17,10 @@ import (
 	"cloudeng.io/cmdutil/cmdyaml"
 	"cloudeng.io/file"
 	"cloudeng.io/file/content"
+	"cloudeng.io/file/crawl"
 	"cloudeng.io/file/crawl/crawlcmd"
+	"cloudeng.io/file/crawl/outlinks"
+	"cloudeng.io/file/crawl/robots"
 	"cloudeng.io/file/filetestutil"
 	"cloudeng.io/path/cloudpath"
 )
@@ -130,6 +136,131 @@ func TestRateControl(t *testing.T) {
 
 }
 
+const crawlsPolitenessSpec = `
+name: test
+politeness:
+  user_agent: mybot/1.0
+  respect_robots: true
+  max_crawl_delay: 200ms
+  per_host:
+    rate_control:
+      tick: 1s
+      requests_per_tick: 100
+`
+
+func TestPoliteness(t *testing.T) {
+	ctx := context.Background()
+	var crawl crawlcmd.Config
+	if err := cmdyaml.ParseConfigs(&crawl, []byte(crawlsPolitenessSpec)); err != nil {
+		t.Fatal(err)
+	}
+	politeness := crawl.Politeness
+	if got, want := politeness, (crawlcmd.Politeness{
+		UserAgent:     "mybot/1.0",
+		RespectRobots: true,
+		MaxCrawlDelay: 200 * time.Millisecond,
+		PerHost: crawlcmd.RateControl{
+			Rate: crawlcmd.Rate{Tick: time.Second, RequestsPerTick: 100},
+		},
+	}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !politeness.Enabled() || (crawlcmd.Politeness{}).Enabled() {
+		t.Errorf("unexpected value for Enabled")
+	}
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("User-agent: *\nCrawl-delay: 10\n"))
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.NotFoundHandler())
+	defer fast.Close()
+
+	cache := politeness.NewRobotsCache(slow.Client())
+	hosts := politeness.NewHostControllers(cache)
+	defer hosts.Stop()
+
+	if hosts.Controller(ctx, slow.URL+"/a") != hosts.Controller(ctx, slow.URL+"/b") {
+		t.Errorf("expected the same controller for the same host")
+	}
+
+	elapsed := func(name string, n int) time.Duration {
+		rc := hosts.Controller(ctx, name)
+		start := time.Now()
+		for range n {
+			if err := rc.Wait(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return time.Since(start)
+	}
+
+	// The slow host's crawl delay of 10s is capped at 200ms and hence
+	// 3 requests must take at least 400ms, whereas the fast host is
+	// limited to 100 requests per second.
+	if got, want := elapsed(slow.URL+"/c", 3), 350*time.Millisecond; got < want {
+		t.Errorf("got %v, want >= %v", got, want)
+	}
+	if got, want := elapsed(fast.URL+"/c", 3), 350*time.Millisecond; got >= want {
+		t.Errorf("got %v, want < %v", got, want)
+	}
+}
+
+func TestPolitenessRobotsRetry(t *testing.T) {
+	ctx := context.Background()
+	politeness := crawlcmd.Politeness{
+		RespectRobots: true,
+		MaxCrawlDelay: 200 * time.Millisecond,
+	}
+	var failed atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if !failed.Swap(true) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("User-agent: *\nCrawl-delay: 10\n"))
+	}))
+	defer srv.Close()
+
+	cache := robots.NewCache(srv.Client(), "", robots.WithRetryInterval(50*time.Millisecond))
+	hosts := politeness.NewHostControllers(cache)
+	defer hosts.Stop()
+
+	elapsed := func(rc *ratecontrol.Controller, n int) time.Duration {
+		start := time.Now()
+		for range n {
+			if err := rc.Wait(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return time.Since(start)
+	}
+
+	// The first fetch of robots.txt fails and hence no Crawl-delay
+	// is applied.
+	first := hosts.Controller(ctx, srv.URL+"/a")
+	if got, want := elapsed(first, 3), 350*time.Millisecond; got >= want {
+		t.Errorf("got %v, want < %v", got, want)
+	}
+	if hosts.Controller(ctx, srv.URL+"/b") != first {
+		t.Errorf("expected the same controller before the retry interval")
+	}
+
+	// Once the retry interval has elapsed the robots.txt is fetched
+	// again and the Crawl-delay applied.
+	time.Sleep(100 * time.Millisecond)
+	second := hosts.Controller(ctx, srv.URL+"/c")
+	if second == first {
+		t.Fatalf("expected a new controller after the retry interval")
+	}
+	if got, want := elapsed(second, 3), 350*time.Millisecond; got < want {
+		t.Errorf("got %v, want >= %v", got, want)
+	}
+	if hosts.Controller(ctx, srv.URL+"/d") != second {
+		t.Errorf("expected the same controller once robots.txt has been fetched")
+	}
+}
+
 type dummyFSFactory struct {
 	called, scheme string
 }
@@ -212,3 +343,105 @@ func TestCrawlRequests(t *testing.T) {
 	}
 
 }
+
+func TestExtractorRegistry(t *testing.T) {
+	var crawl crawlcmd.Config
+	crawl.Extractors = []content.Type{
+		"text/html;charset=utf-8",
+		"text/xml;charset=utf-8",
+		"application/atom+xml",
+		"application/json",
+		"text/plain",
+	}
+	html := outlinks.NewHTML()
+	reg, err := crawl.ExtractorRegistry(map[content.Type]outlinks.Extractor{
+		"text/html;charset=utf-8": html,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	contentTypes := func(ctype content.Type) []content.Type {
+		handlers, _ := reg.LookupHandlers(ctype)
+		var out []content.Type
+		for _, h := range handlers {
+			out = append(out, h.ContentType())
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		ctype content.Type
+		types []content.Type
+	}{
+		{"text/html;charset=utf-8", []content.Type{"text/html"}},
+		{"text/xml;charset=utf-8", []content.Type{"application/xml", "application/rss+xml"}},
+		{"application/atom+xml", []content.Type{"application/rss+xml"}},
+		{"application/json", nil},
+		{"text/plain", nil},
+	} {
+		if got, want := contentTypes(tc.ctype), tc.types; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", tc.ctype, got, want)
+		}
+	}
+
+	crawl.JSONLinks = []string{"$.items[*].url"}
+	reg, err = crawl.ExtractorRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := contentTypes("application/json"), []content.Type{"application/json"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	crawl.JSONLinks = []string{"$.items["}
+	if _, err := crawl.ExtractorRegistry(nil); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestExtractorRegistryDedup(t *testing.T) {
+	var cfg crawlcmd.Config
+	spec := `
+  extractors: [text/xml]
+  dedup:
+    enabled: true
+    expected: 1000
+    false_positive_rate: 0.01
+    exact_set: ` + t.TempDir() + `
+    remove_query_parameters: [session]
+`
+	if err := cmdyaml.ParseConfigs(&cfg, []byte(spec)); err != nil {
+		t.Fatal(err)
+	}
+	deduper, err := cfg.Dedup.NewDeduper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg, err := cfg.ExtractorRegistry(nil, outlinks.WithDeduper(deduper))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlers, err := reg.LookupHandlers("text/xml")
+	if err != nil || len(handlers) != 2 {
+		t.Fatalf("unexpected handlers: %v: %v", handlers, err)
+	}
+	dl := outlinks.Download{Request: crawl.SimpleRequest{}}
+	// Near-duplicates are detected and the deduper is shared by the
+	// sitemap and feed extractors.
+	req := handlers[0].Request(0, dl, []string{
+		"https://example.com/a", "https://example.com/a?session=1", "https://example.com/b?utm_source=x"})
+	if got, want := req.Names(), []string{"https://example.com/a", "https://example.com/b?utm_source=x"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	req = handlers[1].Request(0, dl, []string{"https://example.com/a/", "https://example.com/c"})
+	if got, want := req.Names(), []string{"https://example.com/c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := deduper.Stats().Duplicates, int64(2); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	cfg.Dedup.Enabled = false
+	if deduper, err := cfg.Dedup.NewDeduper(); err != nil || deduper != nil {
+		t.Errorf("unexpected deduper: %v: %v", deduper, err)
+	}
+}
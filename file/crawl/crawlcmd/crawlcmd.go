@@ -7,15 +7,18 @@ package crawlcmd
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 
 	"cloudeng.io/errors"
 	"cloudeng.io/file"
+	"cloudeng.io/file/checkpoint"
 	"cloudeng.io/file/content"
 	"cloudeng.io/file/content/stores"
 	"cloudeng.io/file/crawl"
 	"cloudeng.io/file/crawl/outlinks"
+	"cloudeng.io/file/crawl/robots"
 	"cloudeng.io/file/download"
 	"cloudeng.io/logging/ctxlog"
 	"cloudeng.io/path"
@@ -30,6 +33,7 @@ type Crawler struct {
 	displayOutlinks bool
 	displayProgress bool
 	cache           stores.T
+	frontier        *crawl.Frontier
 }
 
 // FSFactory is a function that returns a file.FS used to crawl
@@ -39,7 +43,9 @@ type FSFactory func(context.Context) (file.FS, error)
 // Resources contains the resources required by the crawler.
 type Resources struct {
 	// Extractors are used to extract outlinks from crawled documents
-	// based on their content type.
+	// based on their content type. The deduper specified by the Dedup
+	// configuration is only used by the built-in extractors, these
+	// extractors must be created with outlinks.WithDeduper if required.
 	Extractors map[content.Type]outlinks.Extractor
 	// CrawlStoreFactories are used to create file.FS instances for
 	// the files being crawled based on their scheme.
@@ -47,6 +53,9 @@ type Resources struct {
 	// ContentStoreFactory is a function that returns a content.FS used to store
 	// the downloaded content.
 	NewContentFS func(context.Context, CrawlCacheConfig) (content.FS, error)
+	// HTTPClient is used to fetch robots.txt files, http.DefaultClient
+	// is used if it is nil.
+	HTTPClient *http.Client
 }
 
 // NewCrawler creates a new crawler instance using the supplied configuration
@@ -88,7 +97,29 @@ func (c *Crawler) run(ctx context.Context, downloads string) error {
 		return fmt.Errorf("unable to determine a file system for seeds: %v", rejected)
 	}
 
-	requests, err := c.config.CreateSeedCrawlRequests(ctx, c.resources.CrawlStoreFactories, seedsByScheme)
+	factories := c.resources.CrawlStoreFactories
+	var checkpointOp checkpoint.Operation
+	if len(c.config.Cache.Checkpoint) > 0 {
+		checkpointOp = checkpoint.NewDirectoryOperation()
+		frontier, resuming, err := loadFrontier(ctx, c.config.Cache, checkpointOp)
+		if err != nil {
+			return fmt.Errorf("failed to load crawl checkpoint: %v: %v", c.config.Cache.CheckpointPath(), err)
+		}
+		c.frontier = frontier
+		if resuming {
+			if !c.config.Recrawl {
+				// Reuse any documents already in the cache rather than
+				// downloading them again, when re-crawling conditional
+				// downloads are used instead.
+				factories = c.cachedFactories(downloads)
+			}
+			if err := c.resumeRequests(ctx, factories, frontier); err != nil {
+				return err
+			}
+		}
+	}
+
+	requests, err := c.config.CreateSeedCrawlRequests(ctx, factories, seedsByScheme)
 	if err != nil {
 		return err
 	}
@@ -99,33 +130,73 @@ func (c *Crawler) run(ctx context.Context, downloads string) error {
 		go displayProgress(ctx, c.config.Name, progressCh)
 	}
 
-	dlFactory := c.config.Download.NewFactory(progressCh)
+	var dlOpts []download.Option
+	var robotsCache *robots.Cache
+	if politeness := c.config.Politeness; politeness.Enabled() {
+		robotsCache = politeness.NewRobotsCache(c.resources.HTTPClient)
+		hosts := politeness.NewHostControllers(robotsCache)
+		defer hosts.Stop()
+		// Backoff is performed for a host when it indicates that it is
+		// rate limiting the crawl.
+		dlOpts = append(dlOpts, download.WithRateControllerFor(hosts.Controller, download.ErrTooManyRequests))
+	}
+	if c.config.Recrawl {
+		dlOpts = append(dlOpts, download.WithRecrawl(c.previousDownload(downloads)))
+	}
+
+	dlFactory := c.config.Download.NewFactory(progressCh, dlOpts...)
 
 	reqCh, crawledCh := c.config.Download.Depth0Chans()
 
 	extractorErrCh := make(chan outlinks.Errors, 100)
 
-	crawler := crawl.New(crawl.WithNumExtractors(c.config.NumExtractors),
-		crawl.WithCrawlDepth(c.config.Depth))
+	crawlOpts := []crawl.Option{
+		crawl.WithNumExtractors(c.config.NumExtractors),
+		crawl.WithCrawlDepth(c.config.Depth),
+	}
+	checkpointDone := make(chan struct{})
+	if c.frontier != nil {
+		crawlOpts = append(crawlOpts, crawl.WithFrontier(c.frontier))
+		go checkpointFrontier(ctx, c.config.Cache.CheckpointInterval, c.frontier, checkpointOp, checkpointDone)
+	}
+	crawler := crawl.New(crawlOpts...)
 
 	linkProcessor, err := c.config.NewLinkProcessor()
 	if err != nil {
 		return fmt.Errorf("failed to compile link processing rules: %v", err)
 	}
 
-	extractorRegistry, err := c.config.ExtractorRegistry(c.resources.Extractors)
+	logger := ctxlog.Logger(ctx)
+	var extractorOpts []outlinks.Option
+	deduper, err := c.config.Dedup.NewDeduper()
+	if err != nil {
+		return fmt.Errorf("failed to create outlink deduper: %v", err)
+	}
+	if deduper != nil {
+		extractorOpts = append(extractorOpts, outlinks.WithDeduper(deduper))
+		defer func() {
+			logger.Info("crawl: outlink dedup", "stats", deduper.Stats().String())
+		}()
+	}
+
+	extractorRegistry, err := c.config.ExtractorRegistry(c.resources.Extractors, extractorOpts...)
 	if err != nil {
 		return fmt.Errorf("failed to create extractor registry: %v", err)
 	}
 
-	extractor := outlinks.NewExtractors(extractorErrCh, linkProcessor, extractorRegistry)
+	var processor outlinks.Process = linkProcessor
+	if robotsCache != nil {
+		processor = outlinks.Chain{linkProcessor, robotsCache.NewProcessor(ctx)}
+	}
+
+	extractor := outlinks.NewExtractors(extractorErrCh, processor, extractorRegistry)
 
 	var errs errors.M
 	var wg sync.WaitGroup
 	wg.Add(3)
 
 	go func(ch chan crawl.Crawled) {
-		errs.Append(c.saveCrawled(ctx, downloads, c.config.Name, ch))
+		errs.Append(c.saveCrawled(ctx, downloads, ch))
 		wg.Done()
 	}(crawledCh)
 
@@ -147,7 +218,6 @@ func (c *Crawler) run(ctx context.Context, downloads string) error {
 		}
 	}()
 
-	logger := ctxlog.Logger(ctx)
 	go func() {
 		for err := range extractorErrCh {
 			if len(err.Errors) > 0 {
@@ -160,17 +230,88 @@ func (c *Crawler) run(ctx context.Context, downloads string) error {
 
 	wg.Wait()
 	close(extractorErrCh)
+	close(checkpointDone)
 	errs.Append(c.cache.Finish(ctx))
+	if c.frontier != nil {
+		if err := errs.Err(); err != nil {
+			// Save the frontier so that the crawl can be resumed.
+			_, err := saveFrontier(context.WithoutCancel(ctx), c.frontier, checkpointOp)
+			errs.Append(err)
+			return errs.Err()
+		}
+		return completeFrontier(ctx, c.config.Cache, checkpointOp)
+	}
 	return errs.Err()
 }
 
-func (c Crawler) saveCrawled(ctx context.Context, downloads, name string, crawledCh chan crawl.Crawled) error {
+// cacheLocation returns the prefix and name used to store the download
+// for the specified name in the crawl cache.
+func (c *Crawler) cacheLocation(downloads, name string) (prefix, suffix string) {
 	sharder := path.NewSharder(
 		path.WithSHA1PrefixLength(c.config.Cache.ShardingPrefixLen))
-	join := c.cache.FS().Join
+	prefix, suffix = sharder.Assign(c.config.Name + name)
+	return c.cache.FS().Join(downloads, prefix), suffix
+}
+
+// cachedFactories returns FSFactories that wrap those supplied in the
+// crawler's resources so that documents already in the crawl cache
+// are read from it rather than being downloaded again.
+func (c *Crawler) cachedFactories(downloads string) map[string]FSFactory {
+	lookup := func(ctx context.Context, name string) (content.Object[[]byte, download.Result], bool) {
+		return c.lookupCached(ctx, downloads, name)
+	}
+	factories := make(map[string]FSFactory, len(c.resources.CrawlStoreFactories))
+	for scheme, factory := range c.resources.CrawlStoreFactories {
+		factories[scheme] = func(ctx context.Context) (file.FS, error) {
+			fs, err := factory(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return &cachedFS{FS: fs, lookup: lookup}, nil
+		}
+	}
+	return factories
+}
+
+// lookupCached returns the object stored in the crawl cache for the
+// specified name, if any. Objects that record a download error are
+// ignored.
+func (c *Crawler) lookupCached(ctx context.Context, downloads, name string) (content.Object[[]byte, download.Result], bool) {
+	var obj content.Object[[]byte, download.Result]
+	prefix, suffix := c.cacheLocation(downloads, name)
+	if _, err := obj.Load(ctx, c.cache, prefix, suffix); err != nil || obj.Response.Err != nil {
+		return obj, false
+	}
+	return obj, true
+}
+
+// previousDownload returns a download.Previous that returns the results
+// of the downloads stored in the crawl cache by a prior crawl.
+func (c *Crawler) previousDownload(downloads string) download.Previous {
+	return func(ctx context.Context, name string) (download.Result, bool) {
+		obj, ok := c.lookupCached(ctx, downloads, name)
+		if !ok {
+			return download.Result{}, false
+		}
+		prev := obj.Response
+		prev.Contents = obj.Value
+		return prev, true
+	}
+}
+
+func (c *Crawler) saveCrawled(ctx context.Context, downloads string, crawledCh chan crawl.Crawled) error {
 	logger := ctxlog.Logger(ctx)
 	written := 0
+	statuses := map[download.Status]int{}
 	defer func() {
+		if c.config.Recrawl {
+			logger.Info("crawl: done", "total written", written,
+				"new", statuses[download.StatusNew],
+				"changed", statuses[download.StatusChanged],
+				"unchanged", statuses[download.StatusUnchanged],
+				"gone", statuses[download.StatusGone])
+			return
+		}
 		logger.Info("crawl: done", "total written", written)
 	}()
 
@@ -184,24 +325,33 @@ func (c Crawler) saveCrawled(ctx context.Context, downloads, name string, crawle
 			}
 		}
 		objs := crawl.CrawledObjects(crawled)
+		stored := make([]string, 0, len(objs))
 		for _, obj := range objs {
 			dld := obj.Response
-			if dld.Err != nil {
+			statuses[dld.Status]++
+			if dld.Err != nil && dld.Status != download.StatusGone {
 				logger.Error("crawl: download error", "name", dld.Name, "error", dld.Err)
 				continue
 			}
-			prefix, suffix := sharder.Assign(name + dld.Name)
-			prefix = join(downloads, prefix)
-			logger.Info("crawl: downloaded", "name", dld.Name, "prefix", prefix, "suffix", suffix)
+			// Objects that are gone are stored so that the cache
+			// no longer contains their previous contents.
+			prefix, suffix := c.cacheLocation(downloads, dld.Name)
+			logger.Info("crawl: downloaded", "name", dld.Name, "status", dld.Status, "prefix", prefix, "suffix", suffix)
 			if err := obj.Store(ctx, c.cache, prefix, suffix, content.GOBObjectEncoding, content.GOBObjectEncoding); err != nil {
 				logger.Error("crawl: failed to write", "name", dld.Name, "prefix", prefix, "suffix", suffix, "error", err)
 				continue
 			}
+			stored = append(stored, dld.Name)
 			written++
 			if written%100 == 0 {
 				logger.Info("crawl: progress", "written", written)
 			}
 		}
+		if c.frontier != nil {
+			// Only names that were stored are done, failed downloads
+			// remain pending so that they are retried on resume.
+			c.frontier.Done(crawled.Depth, stored...)
+		}
 	}
 	return nil
 }
@@ -5,22 +5,32 @@
 package crawlcmd_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"slices"
 	"sort"
+	"sync"
 	"testing"
 	"time"
 
+	"cloudeng.io/algo/ratecontrol"
 	"cloudeng.io/cmdutil/cmdyaml"
 	"cloudeng.io/file"
+	"cloudeng.io/file/checkpoint"
 	"cloudeng.io/file/content"
+	"cloudeng.io/file/content/stores"
+	"cloudeng.io/file/crawl"
 	"cloudeng.io/file/crawl/crawlcmd"
 	"cloudeng.io/file/crawl/outlinks"
+	"cloudeng.io/file/download"
 	"cloudeng.io/file/filetestutil"
 	"cloudeng.io/file/filewalk/filewalktestutil"
 	"cloudeng.io/file/localfs"
@@ -112,6 +122,10 @@ func TestCrawlCmd(t *testing.T) {
 
 	expectedDirs, expectedFiles := expectedOutput(writeFS, config.Name,
 		writeRoot, downloads, config.Seeds...)
+	// The checkpoint directory is removed once the crawl completes.
+	if _, err := os.Stat(config.Cache.CheckpointPath()); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("checkpoint directory was not removed: %v", err)
+	}
 
 	lfs := localfs.New()
 	prefixes, contents, err := filewalktestutil.WalkContents(ctx, lfs, tmpDir)
@@ -146,6 +160,428 @@ func TestCrawlCmd(t *testing.T) {
 	}
 }
 
+func TestCrawlCmdResume(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	writeFS := localfs.New()
+
+	config := crawlcmd.Config{
+		Name:  "test",
+		Depth: 0,
+		Seeds: []string{"rand1", "rand6"},
+		Download: crawlcmd.DownloadConfig{
+			DownloadFactoryConfig: crawlcmd.DownloadFactoryConfig{
+				DefaultConcurrency: 1,
+			},
+		},
+		Cache: crawlcmd.CrawlCacheConfig{
+			Downloads:         filepath.Join(tmpDir, "crawled"),
+			Checkpoint:        filepath.Join(tmpDir, "checkpoint"),
+			ShardingPrefixLen: 1,
+		},
+	}
+	downloads := config.Cache.DownloadPath()
+	if err := config.Cache.PrepareDownloads(ctx, writeFS); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a checkpoint for an interrupted crawl that downloaded rand1,
+	// but not rand2, and for which rand2 was stored in the cache before
+	// the crawl was interrupted.
+	op := checkpoint.NewDirectoryOperation()
+	if err := op.Init(ctx, config.Cache.CheckpointPath()); err != nil {
+		t.Fatal(err)
+	}
+	state, err := json.Marshal(crawl.FrontierState{
+		Pending: map[int][]string{0: {"rand2"}},
+		Done:    []string{"rand1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := op.Checkpoint(ctx, "-frontier", state); err != nil {
+		t.Fatal(err)
+	}
+	sharder := path.NewSharder(path.WithSHA1PrefixLength(1))
+	cache := stores.NewSync(writeFS)
+	location := func(name string) (string, string) {
+		p, f := sharder.Assign(config.Name + name)
+		return writeFS.Join(downloads, p), f
+	}
+	cached := content.Object[[]byte, download.Result]{
+		Type:     "text/plain",
+		Value:    []byte("cached contents"),
+		Response: download.Result{Name: "rand2"},
+	}
+	prefix, suffix := location("rand2")
+	if err := cached.Store(ctx, cache, prefix, suffix, content.GOBObjectEncoding, content.GOBObjectEncoding); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := crawlcmd.NewCrawler(config, crawlcmd.Resources{
+		Extractors:          map[content.Type]outlinks.Extractor{},
+		CrawlStoreFactories: map[string]crawlcmd.FSFactory{"unix": (&randfs{}).NewFS},
+		NewContentFS: func(_ context.Context, _ crawlcmd.CrawlCacheConfig) (content.FS, error) {
+			return writeFS, nil
+		},
+	})
+	if err := cmd.Run(ctx, false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"rand1", "rand2", "rand6"} {
+		var obj content.Object[[]byte, download.Result]
+		prefix, suffix := location(name)
+		_, err := obj.Load(ctx, cache, prefix, suffix)
+		if name == "rand1" {
+			if err == nil {
+				t.Errorf("%v: should not have been downloaded again", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		if name == "rand2" && !bytes.Equal(obj.Value, cached.Value) {
+			t.Errorf("%v: cached contents were not reused: %q", name, obj.Value)
+		}
+	}
+
+	// The checkpoint directory is removed when the crawl completes.
+	if _, err := os.Stat(config.Cache.CheckpointPath()); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("checkpoint directory was not removed: %v", err)
+	}
+}
+
+// failingFS fails to open the file named bad and blocks opening the
+// file named block until its context is canceled.
+type failingFS struct {
+	file.FS
+}
+
+func (f *failingFS) OpenCtx(ctx context.Context, name string) (fs.File, error) {
+	switch name {
+	case "bad":
+		return nil, fmt.Errorf("service unavailable")
+	case "s3://bucket/block":
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return f.FS.OpenCtx(ctx, name)
+}
+
+func TestCrawlCmdResumeFailed(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	writeFS := localfs.New()
+
+	config := crawlcmd.Config{
+		Name:  "test",
+		Depth: 0,
+		Seeds: []string{"rand1", "bad", "s3://bucket/block"},
+		Download: crawlcmd.DownloadConfig{
+			DownloadFactoryConfig: crawlcmd.DownloadFactoryConfig{
+				DefaultConcurrency: 2,
+			},
+		},
+		Cache: crawlcmd.CrawlCacheConfig{
+			Downloads:          filepath.Join(tmpDir, "crawled"),
+			Checkpoint:         filepath.Join(tmpDir, "checkpoint"),
+			CheckpointInterval: time.Millisecond * 10,
+			ShardingPrefixLen:  1,
+		},
+	}
+	downloads := config.Cache.DownloadPath()
+	if err := config.Cache.PrepareDownloads(ctx, writeFS); err != nil {
+		t.Fatal(err)
+	}
+
+	newCrawler := func(newFS crawlcmd.FSFactory) *crawlcmd.Crawler {
+		return crawlcmd.NewCrawler(config, crawlcmd.Resources{
+			Extractors:          map[content.Type]outlinks.Extractor{},
+			CrawlStoreFactories: map[string]crawlcmd.FSFactory{"unix": newFS, "s3": newFS},
+			NewContentFS: func(_ context.Context, _ crawlcmd.CrawlCacheConfig) (content.FS, error) {
+				return writeFS, nil
+			},
+		})
+	}
+	failing := func(ctx context.Context) (file.FS, error) {
+		fs, err := (&randfs{}).NewFS(ctx)
+		return &failingFS{FS: fs}, err
+	}
+
+	// Interrupt the crawl once rand1 has been checkpointed as done, by
+	// which time the download of bad has failed.
+	op := checkpoint.NewDirectoryOperation()
+	if err := op.Init(ctx, config.Cache.CheckpointPath()); err != nil {
+		t.Fatal(err)
+	}
+	latestState := func() crawl.FrontierState {
+		var state crawl.FrontierState
+		data, err := op.Latest(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &state); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return state
+	}
+	cctx, cancel := context.WithCancel(ctx)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- newCrawler(failing).Run(cctx, false, false)
+	}()
+	for !slices.Contains(latestState().Done, "rand1") {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-errCh; err == nil {
+		t.Fatal("expected an error for an interrupted crawl")
+	}
+
+	state := latestState()
+	if got, want := state.Done, []string{"rand1"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := state.Pending[0], []string{"bad", "s3://bucket/block"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// The failed and interrupted downloads are retried on resume.
+	if err := newCrawler((&randfs{}).NewFS).Run(ctx, false, false); err != nil {
+		t.Fatal(err)
+	}
+	sharder := path.NewSharder(path.WithSHA1PrefixLength(1))
+	cache := stores.NewSync(writeFS)
+	for _, name := range config.Seeds {
+		var obj content.Object[[]byte, download.Result]
+		p, f := sharder.Assign(config.Name + name)
+		if _, err := obj.Load(ctx, cache, writeFS.Join(downloads, p), f); err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		if obj.Response.Err != nil {
+			t.Errorf("%v: %v", name, obj.Response.Err)
+		}
+	}
+}
+
+// throttledFS rate limits the first two attempts to open any file on
+// the busy.example host.
+type throttledFS struct {
+	file.FS
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+func (f *throttledFS) OpenCtx(ctx context.Context, name string) (fs.File, error) {
+	if crawl.Host(name) == "https://busy.example" {
+		f.mu.Lock()
+		f.attempts[name]++
+		attempts := f.attempts[name]
+		f.mu.Unlock()
+		if attempts <= 2 {
+			return nil, fmt.Errorf("%v: %w", name, download.ErrTooManyRequests)
+		}
+	}
+	return f.FS.OpenCtx(ctx, name)
+}
+
+func TestCrawlCmdPerHostBackoff(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	writeFS := localfs.New()
+
+	config := crawlcmd.Config{
+		Name:  "test",
+		Depth: 0,
+		Seeds: []string{"https://busy.example/a", "https://quiet.example/b"},
+		Download: crawlcmd.DownloadConfig{
+			DownloadFactoryConfig: crawlcmd.DownloadFactoryConfig{
+				DefaultConcurrency: 1,
+			},
+		},
+		Politeness: crawlcmd.Politeness{
+			PerHost: crawlcmd.RateControl{
+				ExponentialBackoff: crawlcmd.ExponentialBackoff{
+					ExponentialBackoffConfig: ratecontrol.ExponentialBackoffConfig{
+						InitialDelay: time.Millisecond,
+						Steps:        5,
+					},
+				},
+			},
+		},
+		Cache: crawlcmd.CrawlCacheConfig{
+			Downloads:         filepath.Join(tmpDir, "crawled"),
+			ShardingPrefixLen: 1,
+		},
+	}
+	downloads := config.Cache.DownloadPath()
+	if err := config.Cache.PrepareDownloads(ctx, writeFS); err != nil {
+		t.Fatal(err)
+	}
+
+	src := rand.NewSource(time.Now().UnixMicro())
+	throttled := &throttledFS{
+		FS:       filetestutil.NewMockFS(filetestutil.FSWithRandomContents(src, 1024)),
+		attempts: map[string]int{},
+	}
+	cmd := crawlcmd.NewCrawler(config, crawlcmd.Resources{
+		Extractors: map[content.Type]outlinks.Extractor{},
+		CrawlStoreFactories: map[string]crawlcmd.FSFactory{
+			"https": func(context.Context) (file.FS, error) { return throttled, nil },
+		},
+		NewContentFS: func(_ context.Context, _ crawlcmd.CrawlCacheConfig) (content.FS, error) {
+			return writeFS, nil
+		},
+	})
+	if err := cmd.Run(ctx, false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	sharder := path.NewSharder(path.WithSHA1PrefixLength(1))
+	cache := stores.NewSync(writeFS)
+	for _, tc := range []struct {
+		name    string
+		retries int
+	}{
+		{"https://busy.example/a", 2},
+		{"https://quiet.example/b", 0},
+	} {
+		var obj content.Object[[]byte, download.Result]
+		p, f := sharder.Assign(config.Name + tc.name)
+		if _, err := obj.Load(ctx, cache, writeFS.Join(downloads, p), f); err != nil {
+			t.Errorf("%v: %v", tc.name, err)
+			continue
+		}
+		if err := obj.Response.Err; err != nil {
+			t.Errorf("%v: %v", tc.name, err)
+		}
+		if got, want := obj.Response.Retries, tc.retries; got != want {
+			t.Errorf("%v: got %v, want %v", tc.name, got, want)
+		}
+	}
+}
+
+type recrawlFS struct {
+	file.FS
+}
+
+func (f *recrawlFS) OpenCtx(ctx context.Context, name string) (fs.File, error) {
+	if name == "gone" {
+		return nil, fs.ErrNotExist
+	}
+	return f.FS.OpenCtx(ctx, name)
+}
+
+func (f *recrawlFS) OpenIfChanged(ctx context.Context, name string, validators download.Validators) (fs.File, error) {
+	if validators.ETag == `"same"` {
+		return nil, download.ErrNotModified
+	}
+	return f.OpenCtx(ctx, name)
+}
+
+func TestCrawlCmdRecrawl(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	writeFS := localfs.New()
+
+	config := crawlcmd.Config{
+		Name:    "test",
+		Depth:   0,
+		Seeds:   []string{"same", "changed", "gone", "new"},
+		Recrawl: true,
+		Download: crawlcmd.DownloadConfig{
+			DownloadFactoryConfig: crawlcmd.DownloadFactoryConfig{
+				DefaultConcurrency: 1,
+			},
+		},
+		Cache: crawlcmd.CrawlCacheConfig{
+			Downloads:         filepath.Join(tmpDir, "crawled"),
+			ShardingPrefixLen: 1,
+		},
+	}
+	downloads := config.Cache.DownloadPath()
+	if err := config.Cache.PrepareDownloads(ctx, writeFS); err != nil {
+		t.Fatal(err)
+	}
+
+	// Populate the cache as if by a prior crawl.
+	sharder := path.NewSharder(path.WithSHA1PrefixLength(1))
+	cache := stores.NewSync(writeFS)
+	location := func(name string) (string, string) {
+		p, f := sharder.Assign(config.Name + name)
+		return writeFS.Join(downloads, p), f
+	}
+	for name, etag := range map[string]string{"same": `"same"`, "changed": `"old"`, "gone": `"gone"`} {
+		cached := content.Object[[]byte, download.Result]{
+			Type:  "text/plain",
+			Value: []byte("cached " + name),
+			Response: download.Result{
+				Name:       name,
+				Validators: download.Validators{ETag: etag, Digest: download.Digest([]byte("cached " + name))},
+			},
+		}
+		prefix, suffix := location(name)
+		if err := cached.Store(ctx, cache, prefix, suffix, content.GOBObjectEncoding, content.GOBObjectEncoding); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	contents := bytes.Repeat([]byte("x"), 10)
+	cmd := crawlcmd.NewCrawler(config, crawlcmd.Resources{
+		Extractors: map[content.Type]outlinks.Extractor{},
+		CrawlStoreFactories: map[string]crawlcmd.FSFactory{
+			"unix": func(context.Context) (file.FS, error) {
+				return &recrawlFS{FS: filetestutil.NewMockFS(filetestutil.FSWithConstantContents([]byte("x"), 10))}, nil
+			},
+		},
+		NewContentFS: func(_ context.Context, _ crawlcmd.CrawlCacheConfig) (content.FS, error) {
+			return writeFS, nil
+		},
+	})
+	if err := cmd.Run(ctx, false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		status   download.Status
+		contents []byte
+	}{
+		{"same", download.StatusUnchanged, []byte("cached same")},
+		{"changed", download.StatusChanged, contents},
+		{"gone", download.StatusGone, nil},
+		{"new", download.StatusNew, contents},
+	} {
+		var obj content.Object[[]byte, download.Result]
+		prefix, suffix := location(tc.name)
+		if _, err := obj.Load(ctx, cache, prefix, suffix); err != nil {
+			t.Errorf("%v: %v", tc.name, err)
+			continue
+		}
+		if got, want := obj.Response.Status, tc.status; got != want {
+			t.Errorf("%v: got %v, want %v", tc.name, got, want)
+		}
+		if got, want := obj.Value, tc.contents; !bytes.Equal(got, want) {
+			t.Errorf("%v: got %q, want %q", tc.name, got, want)
+		}
+		if tc.status == download.StatusGone {
+			if obj.Response.Err == nil {
+				t.Errorf("%v: expected an error", tc.name)
+			}
+			continue
+		}
+		if got, want := obj.Response.Validators.Digest, download.Digest(tc.contents); got != want {
+			t.Errorf("%v: got %v, want %v", tc.name, got, want)
+		}
+	}
+}
+
 func ExampleCrawlCacheConfig() {
 	type cloudConfig struct {
 		Region string `yaml:"region"`
@@ -12,17 +12,20 @@ import (
 )
 
 // NewFactory returns a new instance of a crawl.DownloaderFactory which is
-// parametised via its DownloadFactoryConfig receiver.
-func (df DownloadFactoryConfig) NewFactory(ch chan<- download.Progress) crawl.DownloaderFactory {
+// parametised via its DownloadFactoryConfig receiver. The supplied options
+// are passed to every downloader created by the factory.
+func (df DownloadFactoryConfig) NewFactory(ch chan<- download.Progress, opts ...download.Option) crawl.DownloaderFactory {
 	return &downloaderFactory{
 		DownloadFactoryConfig: df,
 		ProgressChan:          ch,
+		opts:                  opts,
 	}
 }
 
 type downloaderFactory struct {
 	DownloadFactoryConfig
 	ProgressChan chan<- download.Progress
+	opts         []download.Option
 }
 
 func (df DownloadFactoryConfig) depthOrDefault(depth int, values []int, def int) int {
@@ -44,7 +47,8 @@ func (df *downloaderFactory) New(_ context.Context, depth int) (
 	dlChanSize := df.depthOrDefault(depth, df.PerDepthCrawledChanSizes, df.DefaultCrawledChanSize)
 	inputCh = make(chan download.Request, reqChanSize)
 	outputCh = make(chan download.Downloaded, dlChanSize)
-	downloader = download.New(download.WithNumDownloaders(concurrency))
+	opts := append([]download.Option{download.WithNumDownloaders(concurrency)}, df.opts...)
+	downloader = download.New(opts...)
 	return
 }
 
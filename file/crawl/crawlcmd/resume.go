@@ -0,0 +1,176 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package crawlcmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"cloudeng.io/file"
+	"cloudeng.io/file/checkpoint"
+	"cloudeng.io/file/content"
+	"cloudeng.io/file/crawl"
+	"cloudeng.io/file/download"
+	"cloudeng.io/logging/ctxlog"
+	"cloudeng.io/path/cloudpath"
+)
+
+const (
+	frontierCheckpointLabel = "-frontier"
+
+	// checkpointLockFile is the name of the lock file created in the
+	// checkpoint directory by checkpoint.NewDirectoryOperation.
+	checkpointLockFile = "lock"
+
+	// DefaultCheckpointInterval is the interval used to checkpoint the
+	// crawl frontier when CrawlCacheConfig.CheckpointInterval is not set.
+	DefaultCheckpointInterval = time.Minute
+)
+
+// loadFrontier initializes the checkpoint operation and returns a
+// crawl.Frontier created from the latest checkpoint, if any, and a
+// boolean indicating whether the crawl is being resumed.
+func loadFrontier(ctx context.Context, cfg CrawlCacheConfig, op checkpoint.Operation) (*crawl.Frontier, bool, error) {
+	if err := cfg.PrepareCheckpoint(ctx, op); err != nil {
+		return nil, false, err
+	}
+	data, err := op.Latest(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data) == 0 {
+		return crawl.NewFrontier(crawl.FrontierState{}), false, nil
+	}
+	var state crawl.FrontierState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, false, fmt.Errorf("invalid crawl checkpoint: %v: %w", cfg.CheckpointPath(), err)
+	}
+	return crawl.NewFrontier(state), true, nil
+}
+
+// saveFrontier writes a checkpoint containing the current state of the
+// frontier, removes all prior checkpoints and returns the state that
+// was saved.
+func saveFrontier(ctx context.Context, frontier *crawl.Frontier, op checkpoint.Operation) (crawl.FrontierState, error) {
+	state := frontier.State()
+	data, err := json.Marshal(state)
+	if err != nil {
+		return state, err
+	}
+	if _, err := op.Checkpoint(ctx, frontierCheckpointLabel, data); err != nil {
+		return state, err
+	}
+	return state, op.Compact(ctx, frontierCheckpointLabel)
+}
+
+// completeFrontier removes all checkpoints once the crawl has completed
+// successfully, followed by the checkpoint directory itself and its lock
+// file. The directory is only removed if it is then empty.
+func completeFrontier(ctx context.Context, cfg CrawlCacheConfig, op checkpoint.Operation) error {
+	if err := op.Complete(ctx); err != nil {
+		return err
+	}
+	dir := cfg.CheckpointPath()
+	for _, p := range []string{filepath.Join(dir, checkpointLockFile), dir} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkpointFrontier periodically saves the frontier until ctx is
+// canceled or done is closed.
+func checkpointFrontier(ctx context.Context, interval time.Duration, frontier *crawl.Frontier, op checkpoint.Operation, done <-chan struct{}) {
+	if interval <= 0 {
+		interval = DefaultCheckpointInterval
+	}
+	logger := ctxlog.Logger(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		state, err := saveFrontier(ctx, frontier, op)
+		if err != nil {
+			logger.Error("crawl: failed to checkpoint frontier", "error", err)
+			continue
+		}
+		logger.Info("crawl: checkpointed frontier", "done", len(state.Done), "progress", state.Progress)
+	}
+}
+
+// resumeRequests creates requests for the names that were pending when
+// the crawl was checkpointed and arranges for them to be issued at the
+// depth at which they were discovered.
+func (c *Crawler) resumeRequests(ctx context.Context, factories map[string]FSFactory, frontier *crawl.Frontier) error {
+	for depth, names := range frontier.Pending() {
+		if depth > c.config.Depth {
+			continue
+		}
+		byScheme, rejected := matchesByScheme(cloudpath.DefaultMatchers, names)
+		if len(rejected) > 0 {
+			return fmt.Errorf("unable to determine a file system for pending names: %v", rejected)
+		}
+		requests, err := createRequests(ctx, factories, byScheme, depth)
+		if err != nil {
+			return err
+		}
+		frontier.Resume(depth, requests...)
+	}
+	return nil
+}
+
+// cachedFS is a file.FS that returns documents that have already been
+// downloaded to the crawl cache, rather than downloading them again.
+type cachedFS struct {
+	file.FS
+	lookup func(ctx context.Context, name string) (content.Object[[]byte, download.Result], bool)
+}
+
+// Open implements fs.FS.
+func (cfs *cachedFS) Open(name string) (fs.File, error) {
+	return cfs.OpenCtx(context.Background(), name)
+}
+
+// OpenCtx implements file.FS.
+func (cfs *cachedFS) OpenCtx(ctx context.Context, name string) (fs.File, error) {
+	if obj, ok := cfs.lookup(ctx, name); ok {
+		mode, modTime := fs.FileMode(0600), time.Time{}
+		if fi := obj.Response.FileInfo; fi != nil {
+			mode, modTime = fi.Mode(), fi.ModTime()
+		}
+		return &cachedFile{
+			Reader: bytes.NewReader(obj.Value),
+			info:   file.NewInfo(name, int64(len(obj.Value)), mode, modTime, nil),
+		}, nil
+	}
+	return cfs.FS.OpenCtx(ctx, name)
+}
+
+type cachedFile struct {
+	*bytes.Reader
+	info file.Info
+}
+
+func (f *cachedFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *cachedFile) Close() error {
+	return nil
+}
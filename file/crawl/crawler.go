@@ -20,6 +20,7 @@ type Option func(o *options)
 type options struct {
 	concurrency int
 	depth       int
+	frontier    *Frontier
 }
 
 // WithNumExtractors sets the number of extractors to run.
@@ -36,6 +37,16 @@ func WithCrawlDepth(depth int) Option {
 	}
 }
 
+// WithFrontier requests that the crawl record its progress in the supplied
+// Frontier, see Frontier for details. Any requests supplied to
+// Frontier.Resume are issued before those read from the crawl's input
+// or extracted from downloaded documents.
+func WithFrontier(f *Frontier) Option {
+	return func(o *options) {
+		o.frontier = f
+	}
+}
+
 type crawler struct {
 	options
 }
@@ -131,7 +142,7 @@ func (cr *crawler) crawlAtDepth(ctx context.Context,
 	wg := &sync.WaitGroup{}
 	wg.Add(4)
 	go func() {
-		pipe(ctx, input, dlInput)
+		cr.issueRequests(ctx, depth, input, dlInput)
 		close(dlInput)
 		wg.Done()
 	}()
@@ -189,6 +200,48 @@ func pipe[T any](ctx context.Context, inputCh <-chan T, outputCh chan<- T) {
 	}
 }
 
+// issueRequests forwards requests from input to the downloader, first
+// issuing any resumed requests and, if a Frontier is being used, removing
+// any names that have already been issued or downloaded.
+func (cr *crawler) issueRequests(ctx context.Context, depth int, input <-chan download.Request, dlInput chan<- download.Request) {
+	if cr.frontier == nil {
+		pipe(ctx, input, dlInput)
+		return
+	}
+	issue := func(req download.Request) bool {
+		req = withNames(req, cr.frontier.issue(depth, req.Names()))
+		if req == nil {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case dlInput <- req:
+		}
+		return true
+	}
+	for _, req := range cr.frontier.takeResumed(depth) {
+		if !issue(req) {
+			return
+		}
+	}
+	for {
+		var req download.Request
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return
+		case req, ok = <-input:
+			if !ok {
+				return
+			}
+		}
+		if !issue(req) {
+			return
+		}
+	}
+}
+
 func (cr *crawler) handleExtractedLinks(ctx context.Context, crawledCh <-chan Crawled, downloaderCh chan<- download.Request, output chan<- Crawled) {
 	for {
 		var crawled Crawled
@@ -201,6 +254,11 @@ func (cr *crawler) handleExtractedLinks(ctx context.Context, crawledCh <-chan Cr
 				return
 			}
 		}
+		if cr.frontier != nil {
+			// Record the outlinks before forwarding them so that they
+			// are not lost if the crawl is checkpointed.
+			crawled.Outlinks = cr.frontier.crawled(crawled, downloaderCh != nil)
+		}
 		// Forward crawled downloads to the user.
 		select {
 		case <-ctx.Done():
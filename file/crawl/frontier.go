@@ -0,0 +1,235 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package crawl
+
+import (
+	"slices"
+	"sync"
+
+	"cloudeng.io/file/download"
+)
+
+// FrontierState is the serializable state of a Frontier. It is intended
+// to be checkpointed periodically so that an interrupted crawl can be
+// resumed.
+type FrontierState struct {
+	// Pending lists, by depth, the names that have been discovered but
+	// whose downloads have not been completed (see Frontier.Done).
+	Pending map[int][]string `json:"pending,omitempty"`
+	// Done lists the names whose downloads have been completed.
+	Done []string `json:"done,omitempty"`
+	// Progress records the number of downloads completed at each depth.
+	Progress []int64 `json:"progress,omitempty"`
+}
+
+type frontierState uint8
+
+const (
+	queued  frontierState = iota // discovered, but not yet sent to a downloader.
+	issued                       // sent to a downloader.
+	crawled                      // downloaded and its outlinks recorded.
+	done                         // processed by the consumer of the crawl.
+)
+
+type frontierEntry struct {
+	depth int
+	state frontierState
+}
+
+// Frontier records the names seen by a crawl, those that are pending
+// and the progress made at each depth so that the crawl can be
+// checkpointed and resumed. A Frontier is used via the WithFrontier
+// option and is safe for concurrent use.
+//
+// When a Frontier is used, the crawler will not download any name more
+// than once and all outlinks are recorded as pending before being
+// forwarded to the downloader for the next depth. Names are only
+// considered to be done when Done is called for them, typically once
+// their downloads have been durably stored. Names that have been
+// downloaded, but for which Done has not been called, for example
+// because the download failed, are treated as pending by State and
+// hence retried when the crawl is resumed.
+type Frontier struct {
+	mu       sync.Mutex
+	names    map[string]frontierEntry   // GUARDED_BY(mu)
+	progress []int64                    // GUARDED_BY(mu)
+	resumed  map[int][]download.Request // GUARDED_BY(mu)
+}
+
+// NewFrontier creates a new Frontier, initialized with the supplied state,
+// which will typically have been obtained from a previous call to State.
+// Use a zero value FrontierState for a new crawl.
+func NewFrontier(state FrontierState) *Frontier {
+	f := &Frontier{
+		names:    make(map[string]frontierEntry, len(state.Done)),
+		progress: slices.Clone(state.Progress),
+		resumed:  map[int][]download.Request{},
+	}
+	for _, name := range state.Done {
+		f.names[name] = frontierEntry{state: done}
+	}
+	for depth, names := range state.Pending {
+		for _, name := range names {
+			f.names[name] = frontierEntry{depth: depth, state: queued}
+		}
+	}
+	return f
+}
+
+// Pending returns the names, by depth, that are pending.
+func (f *Frontier) Pending() map[int][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pendingLocked()
+}
+
+func (f *Frontier) pendingLocked() map[int][]string {
+	pending := map[int][]string{}
+	for name, e := range f.names {
+		if e.state != done {
+			pending[e.depth] = append(pending[e.depth], name)
+		}
+	}
+	for _, names := range pending {
+		slices.Sort(names)
+	}
+	return pending
+}
+
+// State returns the current state of the Frontier.
+func (f *Frontier) State() FrontierState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	state := FrontierState{
+		Pending:  f.pendingLocked(),
+		Progress: slices.Clone(f.progress),
+	}
+	for name, e := range f.names {
+		if e.state == done {
+			state.Done = append(state.Done, name)
+		}
+	}
+	slices.Sort(state.Done)
+	return state
+}
+
+// Seen returns true if the specified name has been seen by the Frontier.
+func (f *Frontier) Seen(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.names[name]
+	return ok
+}
+
+// Resume arranges for the supplied requests to be issued to the
+// downloader for the specified depth when the crawl is run. It is
+// intended for resuming the pending names returned by Pending. Names
+// that have already been issued or downloaded are ignored.
+func (f *Frontier) Resume(depth int, requests ...download.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.resumed[depth] = append(f.resumed[depth], requests...)
+}
+
+// Done records that the downloads of the specified names, crawled at
+// the specified depth, have been completed and updates the progress for
+// that depth. Callers should only call Done for names whose downloads
+// succeeded and have been stored so that failed downloads remain pending.
+func (f *Frontier) Done(depth int, names ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.progress) <= depth {
+		f.progress = append(f.progress, 0)
+	}
+	for _, name := range names {
+		f.names[name] = frontierEntry{depth: depth, state: done}
+		f.progress[depth]++
+	}
+}
+
+// takeResumed returns, and forgets, the requests to be resumed at the
+// specified depth.
+func (f *Frontier) takeResumed(depth int) []download.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.resumed[depth]
+	delete(f.resumed, depth)
+	return r
+}
+
+// issue returns the subset of names that should be downloaded at the
+// specified depth, that is, those that are new or queued.
+func (f *Frontier) issue(depth int, names []string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		if e, ok := f.names[name]; ok && e.state != queued {
+			continue
+		}
+		f.names[name] = frontierEntry{depth: depth, state: issued}
+		out = append(out, name)
+	}
+	return out
+}
+
+// crawled records the downloads in the supplied Crawled item and, if
+// follow is true, queues any of its outlinks that have not been seen
+// before at the next depth. It returns the outlinks that were queued.
+func (f *Frontier) crawled(c Crawled, follow bool) []download.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, dl := range c.Downloads {
+		if e, ok := f.names[dl.Name]; !ok || e.state < crawled {
+			f.names[dl.Name] = frontierEntry{depth: c.Depth, state: crawled}
+		}
+	}
+	if !follow {
+		return c.Outlinks
+	}
+	var out []download.Request
+	for _, req := range c.Outlinks {
+		names := make([]string, 0, len(req.Names()))
+		for _, name := range req.Names() {
+			if _, ok := f.names[name]; ok {
+				continue
+			}
+			f.names[name] = frontierEntry{depth: c.Depth + 1, state: queued}
+			names = append(names, name)
+		}
+		if req = withNames(req, names); req != nil {
+			out = append(out, req)
+		}
+	}
+	return out
+}
+
+// withNames returns a request for the specified names using the same
+// container as req, req itself if it contains exactly the specified
+// names or nil if there are no names.
+func withNames(req download.Request, names []string) download.Request {
+	if len(names) == 0 {
+		return nil
+	}
+	if slices.Equal(names, req.Names()) {
+		return req
+	}
+	var depth int
+	switch r := req.(type) {
+	case SimpleRequest:
+		depth = r.Depth
+	case *SimpleRequest:
+		depth = r.Depth
+	}
+	return SimpleRequest{
+		SimpleRequest: download.SimpleRequest{
+			RequestedBy: req.Requester(),
+			FS:          req.Container(),
+			Filenames:   names,
+			Mode:        req.FileMode(),
+		},
+		Depth: depth,
+	}
+}
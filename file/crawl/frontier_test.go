@@ -0,0 +1,129 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package crawl_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"cloudeng.io/file"
+	"cloudeng.io/file/crawl"
+	"cloudeng.io/file/download"
+	"cloudeng.io/file/filetestutil"
+)
+
+type sharedExtractor struct{}
+
+func (sharedExtractor) Extract(_ context.Context, depth int, downloaded download.Downloaded) []download.Request {
+	req := &crawl.SimpleRequest{Depth: depth}
+	req.FS = downloaded.Request.Container()
+	for _, dl := range downloaded.Downloads {
+		req.Filenames = append(req.Filenames, dl.Name+"-a", dl.Name+"-b", "shared")
+	}
+	return []download.Request{req}
+}
+
+func crawlWithFrontier(ctx context.Context, t *testing.T, frontier *crawl.Frontier, readFS file.FS, done func(crawl.Crawled) bool, seeds ...string) []string {
+	inputCh := make(chan download.Request, 10)
+	outputCh := make(chan crawl.Crawled, 10)
+	crawler := crawl.New(
+		crawl.WithCrawlDepth(2),
+		crawl.WithFrontier(frontier))
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- crawler.Run(ctx, &dlFactory{numDownloaders: 2}, sharedExtractor{}, inputCh, outputCh)
+	}()
+	req := &crawl.SimpleRequest{}
+	req.FS = readFS
+	req.Filenames = seeds
+	inputCh <- req
+	close(inputCh)
+	var downloaded []string
+	for crawled := range outputCh {
+		for _, dl := range crawled.Downloads {
+			downloaded = append(downloaded, dl.Name)
+		}
+		if done(crawled) {
+			for _, dl := range crawled.Downloads {
+				frontier.Done(crawled.Depth, dl.Name)
+			}
+		}
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(downloaded)
+	return downloaded
+}
+
+func TestFrontier(t *testing.T) {
+	ctx := context.Background()
+	src := rand.NewSource(time.Now().UnixMicro())
+	readFS := filetestutil.NewMockFS(filetestutil.FSWithRandomContents(src, 1024))
+
+	depth1 := []string{"s0-a", "s0-b", "s1-a", "s1-b", "shared"}
+	depth2 := []string{}
+	for _, n := range depth1 {
+		depth2 = append(depth2, n+"-a", n+"-b")
+	}
+	slices.Sort(depth2)
+
+	// Simulate an interrupted crawl by only calling Done for depth 0.
+	frontier := crawl.NewFrontier(crawl.FrontierState{})
+	downloaded := crawlWithFrontier(ctx, t, frontier, readFS,
+		func(c crawl.Crawled) bool { return c.Depth == 0 }, "s0", "s1", "s0")
+	all := slices.Concat([]string{"s0", "s1"}, depth1, depth2)
+	slices.Sort(all)
+	if got, want := downloaded, all; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	buf, err := json.Marshal(frontier.State())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state crawl.FrontierState
+	if err := json.Unmarshal(buf, &state); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := state, (crawl.FrontierState{
+		Pending:  map[int][]string{1: depth1, 2: depth2},
+		Done:     []string{"s0", "s1"},
+		Progress: []int64{2},
+	}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Resume the crawl, the seeds should not be downloaded again.
+	frontier = crawl.NewFrontier(state)
+	for depth, names := range frontier.Pending() {
+		req := &crawl.SimpleRequest{Depth: depth}
+		req.FS = readFS
+		req.Filenames = names
+		frontier.Resume(depth, req)
+	}
+	downloaded = crawlWithFrontier(ctx, t, frontier, readFS,
+		func(crawl.Crawled) bool { return true }, "s0", "s1")
+	rest := slices.Concat(depth1, depth2)
+	slices.Sort(rest)
+	if got, want := downloaded, rest; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	state = frontier.State()
+	if got, want := len(state.Pending), 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := state.Done, all; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := state.Progress, []int64{2, 5, 10}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package crawl
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"cloudeng.io/algo/ratecontrol"
+)
+
+// HostControllers maintains a separate ratecontrol.Controller for each
+// host so that a slow or rate limited host does not stall downloads from
+// other hosts. Its Controller method can be used with
+// download.WithRateControllerFor.
+type HostControllers struct {
+	newController NewHostController
+	mu            sync.Mutex
+	controllers   map[string]*hostController // GUARDED_BY(mu)
+}
+
+// NewHostController is called to create the controller for a host. If the
+// returned time is not zero the controller is provisional, for example
+// because it was created without access to the host's robots.txt, and will
+// be replaced, by calling NewHostController again, the first time that
+// it is requested after that time.
+type NewHostController func(ctx context.Context, name string) (*ratecontrol.Controller, time.Time)
+
+type hostController struct {
+	mu         sync.Mutex
+	created    bool                    // GUARDED_BY(mu)
+	refresh    time.Time               // GUARDED_BY(mu)
+	controller *ratecontrol.Controller // GUARDED_BY(mu)
+	// replaced controllers may still be in use and are stopped by
+	// HostControllers.Stop.
+	replaced []*ratecontrol.Controller // GUARDED_BY(mu)
+}
+
+// NewHostControllers returns a new HostControllers that calls newController
+// to create the controller for each host the first time that a name
+// on that host is encountered. The name supplied to newController is the
+// name that was first encountered on that host, not the host itself.
+func NewHostControllers(newController NewHostController) *HostControllers {
+	return &HostControllers{
+		newController: newController,
+		controllers:   map[string]*hostController{},
+	}
+}
+
+// Host returns the host, including the scheme and port, for the specified
+// name. Names that are not URLs or that do not contain a host share the
+// empty host.
+func Host(name string) string {
+	u, err := url.Parse(name)
+	if err != nil || len(u.Host) == 0 {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// Controller returns the controller for the host of the specified name.
+func (hc *HostControllers) Controller(ctx context.Context, name string) *ratecontrol.Controller {
+	host := Host(name)
+	hc.mu.Lock()
+	c, ok := hc.controllers[host]
+	if !ok {
+		c = &hostController{}
+		hc.controllers[host] = c
+	}
+	hc.mu.Unlock()
+	// Creating a controller may be slow, eg. if it requires fetching
+	// robots.txt, and must not block access to other hosts.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.created || (!c.refresh.IsZero() && time.Now().After(c.refresh)) {
+		if c.controller != nil {
+			c.replaced = append(c.replaced, c.controller)
+		}
+		c.controller, c.refresh = hc.newController(ctx, name)
+		c.created = true
+	}
+	return c.controller
+}
+
+// Stop calls Stop on all of the controllers created so far.
+func (hc *HostControllers) Stop() {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	for _, c := range hc.controllers {
+		c.mu.Lock()
+		for _, rc := range append(c.replaced, c.controller) {
+			if rc != nil {
+				rc.Stop()
+			}
+		}
+		c.mu.Unlock()
+	}
+}
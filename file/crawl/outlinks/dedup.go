@@ -0,0 +1,84 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package outlinks
+
+import (
+	"sync"
+
+	"cloudeng.io/file/crawl"
+	"cloudeng.io/file/download"
+)
+
+// Deduper is the interface used by extractors to detect duplicate links.
+// IsDup should return true if link has been seen before, ie. has been used
+// as an argument to IsDup. Implementations must be safe for concurrent use.
+// See cloudeng.io/file/crawl/urldedup for a scalable implementation that
+// also detects near-duplicate URLs.
+type Deduper interface {
+	IsDup(link string) bool
+}
+
+// Option represents an option for configuring the extractors provided
+// by this package.
+type Option func(o *options)
+
+type options struct {
+	deduper Deduper
+}
+
+// WithDeduper sets the Deduper used by an extractor. The default is
+// an unbounded in-memory set of the links as they appear in the
+// document. A single Deduper may be shared by multiple extractors.
+func WithDeduper(d Deduper) Option {
+	return func(o *options) {
+		o.deduper = d
+	}
+}
+
+// dedup provides the duplicate detection and request creation that
+// is common to all of the extractors in this package.
+type dedup struct {
+	deduper Deduper
+	mu      sync.Mutex
+	dups    map[string]struct{} // GUARDED_BY(mu)
+}
+
+func (d *dedup) init(opts []Option) {
+	var o options
+	for _, fn := range opts {
+		fn(&o)
+	}
+	d.deduper = o.deduper
+	if d.deduper == nil {
+		d.dups = make(map[string]struct{})
+	}
+}
+
+func (d *dedup) isDup(link string) bool {
+	if d.deduper != nil {
+		return d.deduper.IsDup(link)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.dups[link]; ok {
+		return true
+	}
+	d.dups[link] = struct{}{}
+	return false
+}
+
+func (d *dedup) request(depth int, download Download, outlinks []string) download.Request {
+	var request crawl.SimpleRequest
+	request.RequestedBy = download.Download.Name
+	request.Depth = depth
+	request.FS = download.Request.Container()
+	for _, out := range outlinks {
+		if d.isDup(out) {
+			continue
+		}
+		request.Filenames = append(request.Filenames, out)
+	}
+	return request
+}
@@ -107,3 +107,50 @@ func TestGenericExtractor(t *testing.T) {
 		t.Errorf("got %v, want %v", got, want)
 	}
 }
+
+func TestFeedExtractors(t *testing.T) {
+	ctx := context.Background()
+	errCh := make(chan outlinks.Errors, 10)
+
+	reg := content.NewRegistry[outlinks.Extractor]()
+	if err := reg.RegisterHandlers("text/xml;charset=utf-8", outlinks.NewSitemap(), outlinks.NewFeed()); err != nil {
+		t.Fatal(err)
+	}
+	js, err := outlinks.NewJSON([]string{"$.links[*]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.RegisterHandlers("application/json", js); err != nil {
+		t.Fatal(err)
+	}
+	ext := outlinks.NewExtractors(errCh, &outlinks.PassthroughProcessor{}, reg)
+
+	downloaded := download.Downloaded{
+		Request: download.SimpleRequest{
+			FS: filetestutil.WrapEmbedFS(htmlExamples),
+		},
+		Downloads: []download.Result{
+			{Name: "https://example.com/sitemap.xml", Contents: []byte(`<urlset><url><loc>https://example.com/a</loc></url></urlset>`)},
+			{Name: "https://example.com/feed.xml", Contents: []byte(`<rss><channel><item><link>/b</link></item></channel></rss>`)},
+			{Name: "https://example.com/links.json", Contents: []byte(`{"links": ["/c", "/b"]}`)},
+		},
+	}
+
+	var extracted []string
+	for _, req := range ext.Extract(ctx, 0, downloaded) {
+		extracted = append(extracted, req.Names()...)
+	}
+	close(errCh)
+	for errs := range errCh {
+		t.Errorf("unexpected error: %v", errs)
+	}
+	// Links are deduplicated per extractor.
+	if got, want := extracted, []string{
+		"https://example.com/a",
+		"https://example.com/b",
+		"https://example.com/c",
+		"https://example.com/b",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
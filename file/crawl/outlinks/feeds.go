@@ -0,0 +1,142 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package outlinks
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"cloudeng.io/file/content"
+	"cloudeng.io/file/content/processors"
+	"cloudeng.io/file/download"
+)
+
+// Sitemap is an outlink extractor for sitemaps and sitemap indexes,
+// including gzip compressed ones. The outlinks for a sitemap index are
+// the sitemaps that it refers to and hence crawling a sitemap index
+// to a depth of at least one will crawl the pages listed in those
+// sitemaps. Documents that are not sitemaps are ignored so that Sitemap
+// may be registered alongside Feed for generic XML content types such
+// as application/xml.
+type Sitemap struct {
+	dedup
+}
+
+// NewSitemap returns a new Sitemap extractor.
+func NewSitemap(opts ...Option) *Sitemap {
+	sm := &Sitemap{}
+	sm.init(opts)
+	return sm
+}
+
+// ContentType implements Extractor.ContentType.
+func (sm *Sitemap) ContentType() content.Type {
+	return "application/xml"
+}
+
+// Outlinks implements Extractor.Outlinks.
+func (sm *Sitemap) Outlinks(_ context.Context, _ int, download Download, contents io.Reader) ([]string, error) {
+	if download.Download.Err != nil {
+		return nil, nil
+	}
+	doc, err := processors.Sitemap{}.Parse(contents)
+	if err != nil {
+		if errors.Is(err, processors.ErrUnsupportedDocument) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return doc.Locations, nil
+}
+
+// Request implements Extractor.Request.
+func (sm *Sitemap) Request(depth int, download Download, outlinks []string) download.Request {
+	return sm.request(depth, download, outlinks)
+}
+
+// Feed is an outlink extractor for RSS and Atom feeds. Documents that
+// are not feeds are ignored so that Feed may be registered alongside
+// Sitemap for generic XML content types such as application/xml.
+type Feed struct {
+	dedup
+}
+
+// NewFeed returns a new Feed extractor.
+func NewFeed(opts ...Option) *Feed {
+	fd := &Feed{}
+	fd.init(opts)
+	return fd
+}
+
+// ContentType implements Extractor.ContentType.
+func (fd *Feed) ContentType() content.Type {
+	return "application/rss+xml"
+}
+
+// Outlinks implements Extractor.Outlinks.
+func (fd *Feed) Outlinks(_ context.Context, _ int, download Download, contents io.Reader) ([]string, error) {
+	if download.Download.Err != nil {
+		return nil, nil
+	}
+	doc, err := processors.Feed{}.Parse(contents)
+	if err != nil {
+		if errors.Is(err, processors.ErrUnsupportedDocument) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return doc.Links(download.Download.Name), nil
+}
+
+// Request implements Extractor.Request.
+func (fd *Feed) Request(depth int, download Download, outlinks []string) download.Request {
+	return fd.request(depth, download, outlinks)
+}
+
+// JSON is an outlink extractor for JSON documents that uses JSONPath-style
+// expressions to locate the links within a document, see
+// processors.JSONPath for the supported syntax.
+type JSON struct {
+	dedup
+	paths []processors.JSONPath
+}
+
+// NewJSON returns a new JSON extractor that extracts the string values
+// selected by the supplied JSONPath-style expressions.
+func NewJSON(paths []string, opts ...Option) (*JSON, error) {
+	js := &JSON{}
+	for _, p := range paths {
+		jp, err := processors.ParseJSONPath(p)
+		if err != nil {
+			return nil, err
+		}
+		js.paths = append(js.paths, jp)
+	}
+	js.init(opts)
+	return js, nil
+}
+
+// ContentType implements Extractor.ContentType.
+func (js *JSON) ContentType() content.Type {
+	return "application/json"
+}
+
+// Outlinks implements Extractor.Outlinks.
+func (js *JSON) Outlinks(_ context.Context, _ int, download Download, contents io.Reader) ([]string, error) {
+	if download.Download.Err != nil {
+		return nil, nil
+	}
+	doc, err := processors.JSON{}.Parse(contents)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Links(download.Download.Name, js.paths...), nil
+}
+
+// Request implements Extractor.Request.
+func (js *JSON) Request(depth int, download Download, outlinks []string) download.Request {
+	return js.request(depth, download, outlinks)
+}
@@ -7,25 +7,23 @@ package outlinks
 import (
 	"context"
 	"io"
-	"sync"
 
 	"cloudeng.io/file/content"
 	"cloudeng.io/file/content/processors"
-	"cloudeng.io/file/crawl"
 	"cloudeng.io/file/download"
 )
 
 // HTML is an outlink extractor for HTML documents. It implements
 // both crawl.Outlinks and outlinks.Extractor.
 type HTML struct {
-	mu   sync.Mutex
-	dups map[string]struct{}
+	dedup
 }
 
-func NewHTML() *HTML {
-	return &HTML{
-		dups: make(map[string]struct{}),
-	}
+// NewHTML returns a new HTML extractor.
+func NewHTML(opts ...Option) *HTML {
+	ho := &HTML{}
+	ho.init(opts)
+	return ho
 }
 
 func (ho *HTML) ContentType() content.Type {
@@ -35,13 +33,7 @@ func (ho *HTML) ContentType() content.Type {
 // IsDup returns true if link has been seen before (ie. has been used as an
 // argument to IsDup).
 func (ho *HTML) IsDup(link string) bool {
-	ho.mu.Lock()
-	defer ho.mu.Unlock()
-	if _, ok := ho.dups[link]; ok {
-		return true
-	}
-	ho.dups[link] = struct{}{}
-	return false
+	return ho.isDup(link)
 }
 
 // HREFs returns the hrefs found in the provided HTML document.
@@ -63,15 +55,5 @@ func (ho *HTML) Outlinks(_ context.Context, _ int, download Download, contents i
 
 // Request implements Extractor.Request.
 func (ho *HTML) Request(depth int, download Download, outlinks []string) download.Request {
-	var request crawl.SimpleRequest
-	request.RequestedBy = download.Download.Name
-	request.Depth = depth
-	request.FS = download.Request.Container()
-	for _, out := range outlinks {
-		if ho.IsDup(out) {
-			continue
-		}
-		request.Filenames = append(request.Filenames, out)
-	}
-	return request
+	return ho.request(depth, download, outlinks)
 }
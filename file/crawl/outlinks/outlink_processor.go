@@ -110,3 +110,14 @@ type PassthroughProcessor struct{}
 func (pp *PassthroughProcessor) Process(outlinks []string) []string {
 	return outlinks
 }
+
+// Chain implements Process by applying each of its Process
+// implementations in turn, the output of one being the input to the next.
+type Chain []Process
+
+func (c Chain) Process(outlinks []string) []string {
+	for _, p := range c {
+		outlinks = p.Process(outlinks)
+	}
+	return outlinks
+}
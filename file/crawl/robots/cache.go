@@ -0,0 +1,221 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package robots
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// MaxSize is the maximum number of bytes of a robots.txt file that will
+// be read, any remaining content is ignored.
+const MaxSize = 500 * 1024
+
+const (
+	// DefaultRetryInterval is the default interval after which a
+	// robots.txt that could not be fetched is fetched again.
+	DefaultRetryInterval = time.Minute
+	// DefaultFetchTimeout is the default timeout used when fetching a
+	// robots.txt file.
+	DefaultFetchTimeout = 30 * time.Second
+)
+
+// Cache fetches and caches the robots.txt rules for each host that it is
+// asked about. It is safe for concurrent use and a host's robots.txt is
+// fetched by at most one caller at a time. A robots.txt that is fetched
+// successfully, or that cannot be found, is cached for the lifetime of
+// the Cache, whereas one that cannot be fetched, eg. due to a network
+// error or a 5xx status, is fetched again once the retry interval has
+// elapsed.
+type Cache struct {
+	client        *http.Client
+	userAgent     string
+	retryInterval time.Duration
+	fetchTimeout  time.Duration
+	mu            sync.Mutex
+	hosts         map[string]*entry // GUARDED_BY(mu)
+}
+
+type entry struct {
+	mu      sync.Mutex
+	fetched bool      // GUARDED_BY(mu)
+	expires time.Time // GUARDED_BY(mu), zero if the rules never expire.
+	rules   Rules     // GUARDED_BY(mu)
+}
+
+// CacheOption represents an option to NewCache.
+type CacheOption func(*Cache)
+
+// WithRetryInterval sets the interval after which a robots.txt that
+// could not be fetched will be fetched again, until then all paths on
+// its host are disallowed. The default is DefaultRetryInterval.
+func WithRetryInterval(interval time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.retryInterval = interval
+	}
+}
+
+// WithFetchTimeout sets the timeout used when fetching a robots.txt
+// file. The default is DefaultFetchTimeout.
+func WithFetchTimeout(timeout time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.fetchTimeout = timeout
+	}
+}
+
+// NewCache returns a new Cache that uses the supplied client to fetch
+// robots.txt files and that returns the rules that apply to userAgent.
+// The userAgent is also sent as the User-Agent header when fetching
+// robots.txt. If client is nil, http.DefaultClient is used.
+func NewCache(client *http.Client, userAgent string, opts ...CacheOption) *Cache {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	c := &Cache{
+		client:        client,
+		userAgent:     userAgent,
+		retryInterval: DefaultRetryInterval,
+		fetchTimeout:  DefaultFetchTimeout,
+		hosts:         map[string]*entry{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Rules returns the rules that apply to the host of the supplied URL,
+// fetching the host's robots.txt if it has not already been fetched.
+// As per RFC 9309, a robots.txt that cannot be found (ie. any 4xx status)
+// allows all paths whereas one that cannot be fetched for any other
+// reason disallows all paths. Rules returns an error only if rawURL
+// cannot be parsed or is not an http or https URL.
+func (c *Cache) Rules(ctx context.Context, rawURL string) (Rules, error) {
+	rules, _, err := c.Lookup(ctx, rawURL)
+	return rules, err
+}
+
+// Lookup is like Rules, but also returns the time at which the rules
+// expire, ie. the time after which the host's robots.txt will be fetched
+// again because it could not be fetched. The expiry time is zero if the
+// rules are cached for the lifetime of the Cache.
+func (c *Cache) Lookup(ctx context.Context, rawURL string) (Rules, time.Time, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Rules{}, time.Time{}, err
+	}
+	return c.lookup(ctx, u)
+}
+
+func (c *Cache) rulesFor(ctx context.Context, u *url.URL) (Rules, error) {
+	rules, _, err := c.lookup(ctx, u)
+	return rules, err
+}
+
+func (c *Cache) lookup(ctx context.Context, u *url.URL) (Rules, time.Time, error) {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Rules{}, time.Time{}, fmt.Errorf("robots: unsupported scheme: %q", u.Scheme)
+	}
+	host := u.Scheme + "://" + u.Host
+	c.mu.Lock()
+	e, ok := c.hosts[host]
+	if !ok {
+		e = &entry{}
+		c.hosts[host] = e
+	}
+	c.mu.Unlock()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.fetched && (e.expires.IsZero() || time.Now().Before(e.expires)) {
+		return e.rules, e.expires, nil
+	}
+	rules, ok := c.fetch(ctx, host+"/robots.txt")
+	e.fetched, e.rules, e.expires = true, rules, time.Time{}
+	if !ok {
+		e.expires = time.Now().Add(c.retryInterval)
+	}
+	return e.rules, e.expires, nil
+}
+
+// fetch fetches and parses the specified robots.txt, it returns false
+// if the robots.txt could not be fetched and should be retried. The
+// fetch is not canceled if ctx is canceled since ctx is typically
+// that of the request that first encountered the host and the result is
+// shared with all other requests for the same host.
+func (c *Cache) fetch(ctx context.Context, robotsURL string) (Rules, bool) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.fetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return DisallowAll(), true
+	}
+	if len(c.userAgent) > 0 {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	resp, err := c.client.Do(req) //nolint:gosec // G704 is overly restrictive here.
+	if err != nil {
+		return DisallowAll(), false
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		buf, err := io.ReadAll(io.LimitReader(resp.Body, MaxSize))
+		if err != nil {
+			return DisallowAll(), false
+		}
+		return Parse(buf, c.userAgent), true
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return AllowAll(), true
+	}
+	return DisallowAll(), false
+}
+
+// Allowed returns true if the supplied URL may be crawled. URLs that
+// cannot be parsed or are not http or https URLs are always allowed.
+func (c *Cache) Allowed(ctx context.Context, rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+	rules, err := c.rulesFor(ctx, u)
+	if err != nil {
+		return true
+	}
+	return rules.AllowedURL(u)
+}
+
+// Filter returns the subset of links that may be crawled.
+func (c *Cache) Filter(ctx context.Context, links []string) []string {
+	out := make([]string, 0, len(links))
+	for _, link := range links {
+		if c.Allowed(ctx, link) {
+			out = append(out, link)
+		}
+	}
+	return out
+}
+
+// NewProcessor returns an implementation of outlinks.Process that
+// filters out links that are disallowed by the robots.txt for their host.
+// The supplied context is used when fetching robots.txt files.
+func (c *Cache) NewProcessor(ctx context.Context) *Processor {
+	return &Processor{ctx: ctx, cache: c}
+}
+
+// Processor implements outlinks.Process, see Cache.NewProcessor.
+type Processor struct {
+	ctx   context.Context
+	cache *Cache
+}
+
+// Process implements outlinks.Process.
+func (p *Processor) Process(links []string) []string {
+	return p.cache.Filter(p.ctx, links)
+}
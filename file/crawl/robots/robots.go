@@ -0,0 +1,208 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package robots provides support for parsing robots.txt files, as
+// specified by RFC 9309, and for fetching and caching them on a per-host
+// basis so that a crawler can determine which URLs it is allowed to
+// download and how long it should wait between requests to the same host.
+package robots
+
+import (
+	"bufio"
+	"bytes"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type rule struct {
+	pattern string
+	allow   bool
+}
+
+// Rules represents the set of rules in a robots.txt file that apply to
+// a specific user agent.
+type Rules struct {
+	rules []rule
+	// CrawlDelay is the value of the Crawl-delay directive, zero if none
+	// was specified.
+	CrawlDelay time.Duration
+}
+
+// AllowAll returns Rules that allow all paths.
+func AllowAll() Rules {
+	return Rules{}
+}
+
+// DisallowAll returns Rules that disallow all paths other than
+// /robots.txt itself.
+func DisallowAll() Rules {
+	return Rules{rules: []rule{{pattern: "/", allow: false}}}
+}
+
+type group struct {
+	agents     []string
+	rules      []rule
+	crawlDelay time.Duration
+}
+
+// productToken returns the product token for the supplied user agent,
+// eg. 'mybot' for 'MyBot/1.0 (+https://example.com/bot)'.
+func productToken(userAgent string) string {
+	ua := strings.TrimSpace(userAgent)
+	if idx := strings.IndexAny(ua, "/ "); idx >= 0 {
+		ua = ua[:idx]
+	}
+	return strings.ToLower(ua)
+}
+
+// Parse parses the contents of a robots.txt file and returns the rules
+// that apply to the specified user agent. The rules in all of the groups
+// whose user-agent line matches the product token of userAgent
+// (case-insensitively) are combined, if there are no such groups then
+// the rules in the '*' groups are used. Lines that cannot be parsed
+// are ignored.
+func Parse(contents []byte, userAgent string) Rules {
+	groups := parseGroups(contents)
+	token := productToken(userAgent)
+	var matched, wildcard []*group
+	for _, g := range groups {
+		switch {
+		case len(token) > 0 && slices.Contains(g.agents, token):
+			matched = append(matched, g)
+		case slices.Contains(g.agents, "*"):
+			wildcard = append(wildcard, g)
+		}
+	}
+	if len(matched) == 0 {
+		matched = wildcard
+	}
+	var rules Rules
+	for _, g := range matched {
+		rules.rules = append(rules.rules, g.rules...)
+		if g.crawlDelay > rules.CrawlDelay {
+			rules.CrawlDelay = g.crawlDelay
+		}
+	}
+	// Longest patterns first, with allow taking precedence over
+	// disallow for patterns of the same length.
+	sort.SliceStable(rules.rules, func(i, j int) bool {
+		ri, rj := rules.rules[i], rules.rules[j]
+		if len(ri.pattern) != len(rj.pattern) {
+			return len(ri.pattern) > len(rj.pattern)
+		}
+		return ri.allow && !rj.allow
+	})
+	return rules
+}
+
+func parseGroups(contents []byte) []*group {
+	var groups []*group
+	var current *group
+	inAgents := false
+	sc := bufio.NewScanner(bytes.NewReader(contents))
+	for sc.Scan() {
+		line := sc.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if key == "user-agent" {
+			if !inAgents {
+				current = &group{}
+				groups = append(groups, current)
+				inAgents = true
+			}
+			current.agents = append(current.agents, productToken(value))
+			continue
+		}
+		inAgents = false
+		if current == nil {
+			continue
+		}
+		switch key {
+		case "allow", "disallow":
+			if len(value) == 0 {
+				// An empty disallow (or allow) imposes no restriction.
+				continue
+			}
+			current.rules = append(current.rules, rule{pattern: value, allow: key == "allow"})
+		case "crawl-delay":
+			secs, err := strconv.ParseFloat(value, 64)
+			if err != nil || secs < 0 {
+				continue
+			}
+			current.crawlDelay = time.Duration(secs * float64(time.Second))
+		}
+	}
+	return groups
+}
+
+// Allowed returns true if the specified path, which may include a query,
+// is allowed by the rules. The longest matching rule determines the
+// result, with allow rules taking precedence over disallow rules of the
+// same length. Paths that match no rule, as well as /robots.txt itself,
+// are always allowed.
+func (r Rules) Allowed(path string) bool {
+	if len(path) == 0 {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+	for _, rl := range r.rules {
+		if match(rl.pattern, path) {
+			return rl.allow
+		}
+	}
+	return true
+}
+
+// AllowedURL is like Allowed except that it accepts a URL.
+func (r Rules) AllowedURL(u *url.URL) bool {
+	path := u.EscapedPath()
+	if len(u.RawQuery) > 0 {
+		path += "?" + u.RawQuery
+	}
+	return r.Allowed(path)
+}
+
+// match returns true if path matches the supplied pattern. Patterns
+// are matched as prefixes and may contain '*' to match any sequence
+// of characters and a trailing '$' to anchor the match at the end of
+// the path.
+func match(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	path = path[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || len(path) == 0
+	}
+	last := len(parts) - 1
+	for _, p := range parts[1:last] {
+		idx := strings.Index(path, p)
+		if idx < 0 {
+			return false
+		}
+		path = path[idx+len(p):]
+	}
+	if anchored {
+		return strings.HasSuffix(path, parts[last])
+	}
+	return strings.Contains(path, parts[last])
+}
@@ -0,0 +1,171 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package robots_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloudeng.io/file/crawl/outlinks"
+	"cloudeng.io/file/crawl/robots"
+)
+
+const robotsTxt = `
+# comment
+User-agent: *
+Disallow: /private/
+Allow: /private/public.html
+Disallow: /*.pdf$
+Crawl-delay: 2
+
+User-agent: MyBot
+User-agent: other
+Disallow: /nobots
+Disallow: /a/*/c
+Allow: /nobots/ok
+Disallow:
+Crawl-delay: 0.5
+`
+
+func TestParse(t *testing.T) {
+	wildcard := robots.Parse([]byte(robotsTxt), "SomeBot/2.0")
+	mine := robots.Parse([]byte(robotsTxt), "MyBot/1.0 (+https://example.com)")
+	for _, tc := range []struct {
+		rules robots.Rules
+		path  string
+		allow bool
+	}{
+		{wildcard, "/", true},
+		{wildcard, "/private/", false},
+		{wildcard, "/private/x.html", false},
+		{wildcard, "/private/public.html", true},
+		{wildcard, "/doc.pdf", false},
+		{wildcard, "/doc.pdf?x=y", true},
+		{wildcard, "/robots.txt", true},
+		{wildcard, "/nobots", true},
+		{mine, "/private/", true},
+		{mine, "/nobots", false},
+		{mine, "/nobots/x", false},
+		{mine, "/nobots/ok", true},
+		{mine, "/a/b/c", false},
+		{mine, "/a/c", true},
+		{robots.DisallowAll(), "/anything", false},
+		{robots.DisallowAll(), "/robots.txt", true},
+		{robots.AllowAll(), "/anything", true},
+	} {
+		if got, want := tc.rules.Allowed(tc.path), tc.allow; got != want {
+			t.Errorf("%v: got %v, want %v", tc.path, got, want)
+		}
+	}
+	if got, want := wildcard.CrawlDelay, 2*time.Second; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := mine.CrawlDelay, 500*time.Millisecond; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	var fetches, failures atomic.Int64
+	var userAgent atomic.Value
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		userAgent.Store(r.Header.Get("User-Agent"))
+		w.Write([]byte(robotsTxt))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		failures.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	cache := robots.NewCache(srv.Client(), "MyBot/1.0")
+	links := []string{
+		srv.URL + "/",
+		srv.URL + "/nobots/x",
+		srv.URL + "/nobots/ok",
+		srv.URL + "/private/x",
+		missing.URL + "/nobots/x",
+		failing.URL + "/x",
+		failing.URL + "/y",
+		"s3://bucket/nobots",
+	}
+	processor := outlinks.Chain{&outlinks.PassthroughProcessor{}, cache.NewProcessor(ctx)}
+	if got, want := processor.Process(links), []string{
+		srv.URL + "/",
+		srv.URL + "/nobots/ok",
+		srv.URL + "/private/x",
+		missing.URL + "/nobots/x",
+		"s3://bucket/nobots",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := fetches.Load(), int64(1); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := failures.Load(), int64(1); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := userAgent.Load().(string), "MyBot/1.0"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	rules, err := cache.Rules(ctx, srv.URL+"/anything")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rules.CrawlDelay, 500*time.Millisecond; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := cache.Rules(ctx, "s3://bucket/x"); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestCacheRetry(t *testing.T) {
+	var fetches atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if fetches.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(robotsTxt))
+	}))
+	defer srv.Close()
+
+	cache := robots.NewCache(srv.Client(), "MyBot/1.0", robots.WithRetryInterval(10*time.Millisecond))
+
+	// A canceled context must not prevent robots.txt from being fetched.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The first fetch fails and hence everything is disallowed until the
+	// retry interval has elapsed.
+	if cache.Allowed(ctx, srv.URL+"/x") {
+		t.Errorf("expected %v to be disallowed", srv.URL+"/x")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if !cache.Allowed(ctx, srv.URL+"/x") {
+		t.Errorf("expected %v to be allowed", srv.URL+"/x")
+	}
+	if cache.Allowed(ctx, srv.URL+"/nobots") {
+		t.Errorf("expected %v to be disallowed", srv.URL+"/nobots")
+	}
+	if got, want := fetches.Load(), int64(2); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package urldedup
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"math/bits"
+
+	"cloudeng.io/algo/container/bitmap"
+)
+
+// Bloom is a Bloom filter backed by a bitmap.T. It is not safe for
+// concurrent use.
+type Bloom struct {
+	bits   bitmap.T
+	size   int // number of bits.
+	hashes int // number of hash functions.
+}
+
+// NewBloom returns a Bloom filter sized to hold the expected number of
+// items with the specified false positive rate.
+func NewBloom(expected int, falsePositiveRate float64) *Bloom {
+	if expected <= 0 {
+		expected = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.01
+	}
+	m := math.Ceil(-float64(expected) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := int(math.Round(m / float64(expected) * math.Ln2))
+	return &Bloom{
+		bits:   bitmap.New(int(m)),
+		size:   int(m),
+		hashes: max(k, 1),
+	}
+}
+
+// Size returns the number of bits and the number of hash functions used
+// by the filter.
+func (b *Bloom) Size() (bits, hashes int) {
+	return b.size, b.hashes
+}
+
+// hash returns the 128 bit FNV-1a hash of key as two 64 bit values.
+func hash(key string) (uint64, uint64) {
+	h := fnv.New128a()
+	h.Write([]byte(key))
+	var sum [16]byte
+	h.Sum(sum[:0])
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:])
+}
+
+// mix is the splitmix64 finalizer, it is used to improve the distribution
+// of the FNV hash for similar keys.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+func bloomHash(key string) (uint64, uint64) {
+	return bloomHashes(hash(key))
+}
+
+// bloomHashes returns the values used to derive the filter's hash
+// functions from the FNV hash of a key.
+func bloomHashes(h1, h2 uint64) (uint64, uint64) {
+	return mix(h1), mix(h2) | 1
+}
+
+// Add adds key to the filter and returns true if it may have been added
+// before, ie. if all of its bits were already set.
+func (b *Bloom) Add(key string) bool {
+	return b.addHashes(hash(key))
+}
+
+// addHashes is like Add, but for a key whose FNV hash is h1, h2.
+func (b *Bloom) addHashes(h1, h2 uint64) bool {
+	h1, h2 = bloomHashes(h1, h2)
+	present := true
+	for i := range b.hashes {
+		idx := int((h1 + uint64(i)*h2) % uint64(b.size))
+		if !b.bits.IsSetUnsafe(idx) {
+			present = false
+			b.bits.SetUnsafe(idx)
+		}
+	}
+	return present
+}
+
+// Contains returns true if key may have been added to the filter and
+// false if it definitely has not been.
+func (b *Bloom) Contains(key string) bool {
+	h1, h2 := bloomHash(key)
+	for i := range b.hashes {
+		if !b.bits.IsSetUnsafe(int((h1 + uint64(i)*h2) % uint64(b.size))) {
+			return false
+		}
+	}
+	return true
+}
+
+// EstimatedFalsePositiveRate returns the false positive rate implied by
+// the fraction of bits that are currently set.
+func (b *Bloom) EstimatedFalsePositiveRate() float64 {
+	set := 0
+	for _, w := range b.bits {
+		set += bits.OnesCount64(w)
+	}
+	return math.Pow(float64(set)/float64(b.size), float64(b.hashes))
+}
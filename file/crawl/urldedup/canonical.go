@@ -0,0 +1,124 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package urldedup
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"cloudeng.io/text/textutil"
+)
+
+// Canonicalizer converts URLs to a canonical form so that near-duplicate
+// URLs, ie. those that differ only in ways that do not affect the
+// document they refer to, are treated as identical. All URLs have their
+// scheme and host lowercased, default ports (80 for http, 443 for https)
+// and fragments removed and an empty path replaced with '/'. The
+// remaining transformations are configurable.
+type Canonicalizer struct {
+	// RemoveQueryParameters lists the query parameters to be removed,
+	// a trailing '*' matches all parameters with the preceding prefix,
+	// eg. 'utm_*'.
+	RemoveQueryParameters []string
+	// SortQuery requests that query parameters be sorted by name.
+	SortQuery bool
+	// RemoveTrailingSlash requests that a trailing slash be removed
+	// from all paths other than '/'.
+	RemoveTrailingSlash bool
+	// Rewrites are applied, using ReplaceAllStringFirst, to the
+	// canonicalized URL.
+	Rewrites textutil.RewriteRules
+}
+
+// DefaultTrackingParameters are commonly used query parameters that
+// are used for tracking and do not affect the document referred to.
+var DefaultTrackingParameters = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "msclkid", "mc_cid", "mc_eid", "_ga", "yclid",
+}
+
+// NewCanonicalizer returns a Canonicalizer that removes the
+// DefaultTrackingParameters, sorts query parameters and removes
+// trailing slashes.
+func NewCanonicalizer() *Canonicalizer {
+	return &Canonicalizer{
+		RemoveQueryParameters: DefaultTrackingParameters,
+		SortQuery:             true,
+		RemoveTrailingSlash:   true,
+	}
+}
+
+func (c *Canonicalizer) removeParameter(name string) bool {
+	for _, p := range c.RemoveQueryParameters {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+			continue
+		}
+		if name == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Canonicalize returns the canonical form of the supplied link. Links that
+// cannot be parsed as URLs are subject to the Rewrites only.
+func (c *Canonicalizer) Canonicalize(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return c.Rewrites.ReplaceAllStringFirst(link)
+	}
+	u.Fragment, u.RawFragment = "", ""
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if len(port) > 0 {
+		host += ":" + port
+	}
+	u.Host = host
+	if len(u.Host) > 0 && len(u.Path) == 0 {
+		u.Path = "/"
+	}
+	if c.RemoveTrailingSlash && len(u.Path) > 1 {
+		u.Path = strings.TrimRight(u.Path, "/")
+		if len(u.Path) == 0 {
+			u.Path = "/"
+		}
+		u.RawPath = ""
+	}
+	if len(u.RawQuery) > 0 {
+		u.RawQuery = c.query(u.RawQuery)
+	}
+	return c.Rewrites.ReplaceAllStringFirst(u.String())
+}
+
+func (c *Canonicalizer) query(raw string) string {
+	params := strings.Split(raw, "&")
+	out := make([]string, 0, len(params))
+	for _, p := range params {
+		if len(p) == 0 {
+			continue
+		}
+		name, _, _ := strings.Cut(p, "=")
+		if n, err := url.QueryUnescape(name); err == nil {
+			name = n
+		}
+		if c.removeParameter(name) {
+			continue
+		}
+		out = append(out, p)
+	}
+	if c.SortQuery {
+		slices.Sort(out)
+	}
+	return strings.Join(out, "&")
+}
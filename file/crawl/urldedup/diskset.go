@@ -0,0 +1,304 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package urldedup
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// Set represents an exact set of keys that is used to confirm, or
+// refute, the matches reported by a Bloom filter.
+type Set interface {
+	// Contains returns true if key is in the set.
+	Contains(key string) (bool, error)
+	// Add adds key to the set.
+	Add(key string) error
+}
+
+// DefaultMergeThreshold is the default number of recently added hashes
+// that a DiskSet shard will hold before merging them into its sorted file.
+const DefaultMergeThreshold = 4096
+
+// DiskSetOption represents an option to NewDiskSet.
+type DiskSetOption func(*DiskSet)
+
+// WithMergeThreshold sets the number of recently added hashes that each
+// shard holds in memory, and in its log, before they are merged into its
+// sorted file. The default is DefaultMergeThreshold.
+func WithMergeThreshold(n int) DiskSetOption {
+	return func(s *DiskSet) {
+		s.mergeThreshold = max(n, 1)
+	}
+}
+
+// DiskSet is an implementation of Set that stores the 128 bit FNV-1a hash
+// of each key in one of 256 shards, selected by the first byte of the
+// hash, in a local directory. Each shard consists of a file of sorted
+// hashes and an append-only log of recently added hashes that is also
+// held in memory. Lookups consult the recent hashes and then binary search
+// the sorted file and hence require O(log n) small reads. The log is
+// merged into the sorted file once it reaches the merge threshold, which
+// bounds the memory used to 256 times that threshold. The use of a hash
+// means that the set is exact only up to the (very low) probability of a
+// 128 bit hash collision. DiskSet is safe for concurrent use.
+type DiskSet struct {
+	dir            string
+	mergeThreshold int
+	shards         [256]diskShard
+}
+
+type record [16]byte
+
+type diskShard struct {
+	mu     sync.Mutex
+	loaded bool                // GUARDED_BY(mu)
+	recent map[record]struct{} // GUARDED_BY(mu)
+}
+
+// NewDiskSet returns a DiskSet that stores its data in dir, creating it
+// if necessary. Any existing data in dir is retained so that a set can
+// be reused across multiple runs.
+func NewDiskSet(dir string, opts ...DiskSetOption) (*DiskSet, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	s := &DiskSet{dir: dir, mergeThreshold: DefaultMergeThreshold}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+func newRecord(h1, h2 uint64) record {
+	var rec record
+	binary.BigEndian.PutUint64(rec[:8], h1)
+	binary.BigEndian.PutUint64(rec[8:], h2)
+	return rec
+}
+
+func (s *DiskSet) filenames(n int) (sorted, log string) {
+	return filepath.Join(s.dir, fmt.Sprintf("%02x.set", n)),
+		filepath.Join(s.dir, fmt.Sprintf("%02x.log", n))
+}
+
+// lockedShard returns the locked shard for rec, loading its log if
+// necessary. The caller must unlock the shard.
+func (s *DiskSet) lockedShard(rec record) (*diskShard, int, error) {
+	n := int(rec[0])
+	shard := &s.shards[n]
+	shard.mu.Lock()
+	if shard.loaded {
+		return shard, n, nil
+	}
+	shard.recent = map[record]struct{}{}
+	_, log := s.filenames(n)
+	err := readRecords(log, func(rec record) {
+		shard.recent[rec] = struct{}{}
+	})
+	if err != nil {
+		shard.mu.Unlock()
+		return nil, n, err
+	}
+	shard.loaded = true
+	return shard, n, nil
+}
+
+// Contains implements Set.
+func (s *DiskSet) Contains(key string) (bool, error) {
+	rec := newRecord(hash(key))
+	shard, n, err := s.lockedShard(rec)
+	if err != nil {
+		return false, err
+	}
+	defer shard.mu.Unlock()
+	if _, ok := shard.recent[rec]; ok {
+		return true, nil
+	}
+	sorted, _ := s.filenames(n)
+	return search(sorted, rec)
+}
+
+// Add implements Set.
+func (s *DiskSet) Add(key string) error {
+	rec := newRecord(hash(key))
+	shard, n, err := s.lockedShard(rec)
+	if err != nil {
+		return err
+	}
+	defer shard.mu.Unlock()
+	if _, ok := shard.recent[rec]; ok {
+		return nil
+	}
+	sorted, log := s.filenames(n)
+	if found, err := search(sorted, rec); err != nil || found {
+		return err
+	}
+	f, err := os.OpenFile(log, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(rec[:]); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	shard.recent[rec] = struct{}{}
+	if len(shard.recent) < s.mergeThreshold {
+		return nil
+	}
+	if err := merge(sorted, shard.recent); err != nil {
+		return err
+	}
+	if err := os.Remove(log); err != nil {
+		return err
+	}
+	shard.recent = map[record]struct{}{}
+	return nil
+}
+
+// rangeHashes calls fn for the hash of every key in the set.
+func (s *DiskSet) rangeHashes(fn func(h1, h2 uint64)) error {
+	for n := range s.shards {
+		shard, _, err := s.lockedShard(record{byte(n)})
+		if err != nil {
+			return err
+		}
+		sorted, _ := s.filenames(n)
+		visit := func(rec record) {
+			fn(binary.BigEndian.Uint64(rec[:8]), binary.BigEndian.Uint64(rec[8:]))
+		}
+		err = readRecords(sorted, visit)
+		for rec := range shard.recent {
+			visit(rec)
+		}
+		shard.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRecords calls fn for every record in filename, a file that does
+// not exist contains no records.
+func readRecords(filename string, fn func(record)) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	rd := bufio.NewReaderSize(f, 64*1024)
+	var rec record
+	for {
+		if _, err := io.ReadFull(rd, rec[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("%v: %w", filename, err)
+		}
+		fn(rec)
+	}
+}
+
+// search performs a binary search for rec in the sorted file filename.
+func search(filename string, rec record) (bool, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	var buf record
+	lo, hi := int64(0), fi.Size()/int64(len(rec))
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if _, err := f.ReadAt(buf[:], mid*int64(len(rec))); err != nil {
+			return false, fmt.Errorf("%v: %w", filename, err)
+		}
+		switch bytes.Compare(buf[:], rec[:]) {
+		case 0:
+			return true, nil
+		case -1:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+	return false, nil
+}
+
+// merge merges the supplied records into the sorted file filename, which
+// is replaced atomically.
+func merge(filename string, recent map[record]struct{}) error {
+	added := make([]record, 0, len(recent))
+	for rec := range recent {
+		added = append(added, rec)
+	}
+	slices.SortFunc(added, func(a, b record) int {
+		return bytes.Compare(a[:], b[:])
+	})
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	wr := bufio.NewWriterSize(tmp, 64*1024)
+	var werr error
+	write := func(rec record) {
+		if werr == nil {
+			_, werr = wr.Write(rec[:])
+		}
+	}
+	// The existing records are already sorted, so merge the two.
+	err = readRecords(filename, func(rec record) {
+		for len(added) > 0 && bytes.Compare(added[0][:], rec[:]) < 0 {
+			write(added[0])
+			added = added[1:]
+		}
+		if len(added) > 0 && added[0] == rec {
+			added = added[1:]
+		}
+		write(rec)
+	})
+	for _, rec := range added {
+		write(rec)
+	}
+	if err == nil {
+		err = werr
+	}
+	if err == nil {
+		err = wr.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
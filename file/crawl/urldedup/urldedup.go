@@ -0,0 +1,212 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package urldedup provides scalable detection of duplicate URLs for use
+// by crawlers. URLs are first canonicalized so that near-duplicates are
+// detected and then checked against a Bloom filter whose memory use is
+// fixed regardless of the number of URLs seen. An optional exact set, such
+// as DiskSet, is used to confirm the matches reported by the Bloom filter
+// so that false positives do not cause URLs to be incorrectly discarded;
+// the rate at which such false positives occur is reported via Stats.
+package urldedup
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Option represents an option for configuring a T.
+type Option func(o *options)
+
+type options struct {
+	canonicalizer     *Canonicalizer
+	expected          int
+	falsePositiveRate float64
+	exact             Set
+}
+
+// WithCanonicalizer sets the Canonicalizer to use, the default
+// is that returned by NewCanonicalizer.
+func WithCanonicalizer(c *Canonicalizer) Option {
+	return func(o *options) {
+		o.canonicalizer = c
+	}
+}
+
+// WithBloomFilter sizes the Bloom filter to hold the expected number of
+// URLs with the specified false positive rate. The defaults are
+// DefaultExpected and DefaultFalsePositiveRate.
+func WithBloomFilter(expected int, falsePositiveRate float64) Option {
+	return func(o *options) {
+		o.expected = expected
+		o.falsePositiveRate = falsePositiveRate
+	}
+}
+
+// WithExactSet sets the exact Set used to confirm the matches reported
+// by the Bloom filter. If no exact set is specified then URLs that are
+// false positives will be reported as duplicates. If the set is a
+// DiskSet then the Bloom filter is populated from its existing contents
+// by New so that URLs recorded by a previous run are detected as
+// duplicates; for other sets the Bloom filter starts out empty and
+// only URLs seen by the T are detected.
+func WithExactSet(s Set) Option {
+	return func(o *options) {
+		o.exact = s
+	}
+}
+
+const (
+	// DefaultExpected is the default number of URLs that the Bloom
+	// filter is sized for.
+	DefaultExpected = 10_000_000
+	// DefaultFalsePositiveRate is the default false positive rate for
+	// the Bloom filter.
+	DefaultFalsePositiveRate = 0.001
+)
+
+// Stats records the number of URLs checked for duplicates and the
+// number of false positives reported by the Bloom filter.
+type Stats struct {
+	// Checked is the number of calls to IsDup.
+	Checked int64
+	// Added is the number of URLs that were not duplicates.
+	Added int64
+	// Duplicates is the number of URLs that were duplicates.
+	Duplicates int64
+	// FalsePositives is the number of URLs that the Bloom filter
+	// reported as seen but that were not in the exact set. It is always
+	// zero if no exact set is used.
+	FalsePositives int64
+	// Errors is the number of errors encountered accessing the exact set.
+	// URLs are treated as duplicates if the exact set cannot be checked,
+	// but as new if they cannot be added to it, in which case they may
+	// be reported as new again.
+	Errors int64
+	// EstimatedFalsePositiveRate is the false positive rate implied by
+	// the current occupancy of the Bloom filter.
+	EstimatedFalsePositiveRate float64
+}
+
+// FalsePositiveRate returns the observed false positive rate, ie. the
+// fraction of new URLs that the Bloom filter reported as seen.
+func (s Stats) FalsePositiveRate() float64 {
+	if s.Added == 0 {
+		return 0
+	}
+	return float64(s.FalsePositives) / float64(s.Added)
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("checked: %v, added: %v, duplicates: %v, false positives: %v (%.6f, estimated %.6f), errors: %v",
+		s.Checked, s.Added, s.Duplicates, s.FalsePositives, s.FalsePositiveRate(), s.EstimatedFalsePositiveRate, s.Errors)
+}
+
+// T provides duplicate detection for URLs and implements
+// outlinks.Deduper. It is safe for concurrent use, and accesses to the
+// exact set, if any, for different URLs may proceed concurrently.
+type T struct {
+	opts  options
+	mu    sync.Mutex
+	bloom *Bloom // GUARDED_BY(mu)
+	stats Stats  // GUARDED_BY(mu)
+	// keys serializes IsDup calls for the same canonical URL, selected
+	// by the first byte of its hash, whilst the exact set is accessed.
+	keys [256]sync.Mutex
+}
+
+// New returns a new instance of T.
+func New(opts ...Option) *T {
+	d := &T{}
+	d.opts.expected = DefaultExpected
+	d.opts.falsePositiveRate = DefaultFalsePositiveRate
+	for _, fn := range opts {
+		fn(&d.opts)
+	}
+	if d.opts.canonicalizer == nil {
+		d.opts.canonicalizer = NewCanonicalizer()
+	}
+	d.bloom = NewBloom(d.opts.expected, d.opts.falsePositiveRate)
+	if ds, ok := d.opts.exact.(*DiskSet); ok {
+		if err := ds.rangeHashes(func(h1, h2 uint64) { d.bloom.addHashes(h1, h2) }); err != nil {
+			d.stats.Errors++
+		}
+	}
+	return d
+}
+
+// Canonicalize returns the canonical form of link as used for
+// duplicate detection.
+func (d *T) Canonicalize(link string) string {
+	return d.opts.canonicalizer.Canonicalize(link)
+}
+
+// IsDup returns true if the canonical form of link has been seen before,
+// ie. has been used as an argument to IsDup.
+func (d *T) IsDup(link string) bool {
+	key := d.Canonicalize(link)
+	h1, h2 := hash(key)
+	if d.opts.exact == nil {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.stats.Checked++
+		if d.bloom.addHashes(h1, h2) {
+			d.stats.Duplicates++
+			return true
+		}
+		d.stats.Added++
+		return false
+	}
+	keyMu := &d.keys[h1>>56]
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	d.mu.Lock()
+	d.stats.Checked++
+	present := d.bloom.addHashes(h1, h2)
+	d.mu.Unlock()
+	if !present {
+		err := d.opts.exact.Add(key)
+		d.update(func(s *Stats) {
+			s.Added++
+			if err != nil {
+				s.Errors++
+			}
+		})
+		return false
+	}
+	found, err := d.opts.exact.Contains(key)
+	if err != nil || found {
+		d.update(func(s *Stats) {
+			s.Duplicates++
+			if err != nil {
+				s.Errors++
+			}
+		})
+		return true
+	}
+	err = d.opts.exact.Add(key)
+	d.update(func(s *Stats) {
+		s.FalsePositives++
+		s.Added++
+		if err != nil {
+			s.Errors++
+		}
+	})
+	return false
+}
+
+func (d *T) update(fn func(*Stats)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn(&d.stats)
+}
+
+// Stats returns the current statistics.
+func (d *T) Stats() Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := d.stats
+	s.EstimatedFalsePositiveRate = d.bloom.EstimatedFalsePositiveRate()
+	return s
+}
@@ -0,0 +1,271 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package urldedup_test
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"cloudeng.io/file/crawl/outlinks"
+	"cloudeng.io/file/crawl/urldedup"
+	"cloudeng.io/text/textutil"
+)
+
+func TestCanonicalize(t *testing.T) {
+	c := urldedup.NewCanonicalizer()
+	for _, tc := range []struct {
+		in, out string
+	}{
+		{"https://Example.COM", "https://example.com/"},
+		{"HTTPS://example.com:443/a/b/#frag", "https://example.com/a/b"},
+		{"http://example.com:80/", "http://example.com/"},
+		{"http://example.com:8080/x", "http://example.com:8080/x"},
+		{"https://example.com/p?b=2&utm_source=x&a=1&fbclid=y", "https://example.com/p?a=1&b=2"},
+		{"https://example.com/p?utm_medium=x", "https://example.com/p"},
+		{"https://[::1]:443/", "https://[::1]/"},
+		{"/relative/path/", "/relative/path"},
+	} {
+		if got, want := c.Canonicalize(tc.in), tc.out; got != want {
+			t.Errorf("%v: got %v, want %v", tc.in, got, want)
+		}
+	}
+
+	rw, err := textutil.NewRewriteRules(`s%^http://%https://%`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = &urldedup.Canonicalizer{Rewrites: rw}
+	if got, want := c.Canonicalize("http://example.com/a/?x=1#f"), "https://example.com/a/?x=1"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestBloom(t *testing.T) {
+	b := urldedup.NewBloom(1000, 0.01)
+	if m, k := b.Size(); m < 9000 || k != 7 {
+		t.Errorf("unexpected size: %v %v", m, k)
+	}
+	for i := range 1000 {
+		b.Add(fmt.Sprintf("key-%v", i))
+	}
+	for i := range 1000 {
+		if !b.Contains(fmt.Sprintf("key-%v", i)) {
+			t.Fatalf("key-%v: not found", i)
+		}
+	}
+	fp := 0
+	for i := range 10000 {
+		if b.Contains(fmt.Sprintf("other-%v", i)) {
+			fp++
+		}
+	}
+	if fp > 300 {
+		t.Errorf("too many false positives: %v", fp)
+	}
+	if r := b.EstimatedFalsePositiveRate(); r <= 0 || r > 0.03 {
+		t.Errorf("unexpected estimated false positive rate: %v", r)
+	}
+}
+
+func TestDedup(t *testing.T) {
+	set, err := urldedup.NewDiskSet(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deduper outlinks.Deduper = urldedup.New(urldedup.WithExactSet(set), urldedup.WithBloomFilter(100, 0.01))
+	d := deduper.(*urldedup.T)
+	for _, tc := range []struct {
+		link string
+		dup  bool
+	}{
+		{"https://example.com/a", false},
+		{"https://EXAMPLE.com:443/a/#x", true},
+		{"https://example.com/a?utm_campaign=z", true},
+		{"https://example.com/b", false},
+	} {
+		if got, want := d.IsDup(tc.link), tc.dup; got != want {
+			t.Errorf("%v: got %v, want %v", tc.link, got, want)
+		}
+	}
+
+	// Overfill the bloom filter to force false positives, all of which
+	// must be detected using the exact set.
+	for i := range 2000 {
+		if d.IsDup(fmt.Sprintf("https://example.com/page/%v", i)) {
+			t.Fatalf("%v: unexpected duplicate", i)
+		}
+	}
+	for i := range 2000 {
+		if !d.IsDup(fmt.Sprintf("https://example.com/page/%v", i)) {
+			t.Fatalf("%v: expected duplicate", i)
+		}
+	}
+	stats := d.Stats()
+	if got, want := stats.Checked, int64(4004); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := stats.Added, int64(2002); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := stats.Duplicates, int64(2002); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if stats.FalsePositives == 0 || stats.FalsePositiveRate() == 0 || stats.Errors != 0 {
+		t.Errorf("unexpected stats: %v", stats)
+	}
+	if stats.EstimatedFalsePositiveRate < 0.5 {
+		t.Errorf("unexpected stats: %v", stats)
+	}
+
+	// Without an exact set, false positives are reported as duplicates.
+	d = urldedup.New(urldedup.WithBloomFilter(10, 0.1))
+	dups := 0
+	for i := range 1000 {
+		if d.IsDup(fmt.Sprintf("https://example.com/page/%v", i)) {
+			dups++
+		}
+	}
+	if stats := d.Stats(); dups == 0 || stats.Duplicates != int64(dups) || stats.FalsePositives != 0 {
+		t.Errorf("unexpected stats: %v: %v", dups, stats)
+	}
+}
+
+func TestHTMLDeduper(t *testing.T) {
+	ho := outlinks.NewHTML(outlinks.WithDeduper(urldedup.New(urldedup.WithBloomFilter(100, 0.01))))
+	if ho.IsDup("https://example.com/x") || !ho.IsDup("https://example.com/x#y") {
+		t.Errorf("near-duplicate was not detected")
+	}
+	ho = outlinks.NewHTML()
+	if ho.IsDup("https://example.com/x") || ho.IsDup("https://example.com/x#y") {
+		t.Errorf("unexpected duplicate")
+	}
+}
+
+func TestDiskSet(t *testing.T) {
+	dir := t.TempDir()
+	set, err := urldedup.NewDiskSet(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 100 {
+		if err := set.Add(fmt.Sprintf("key-%v", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A new instance should see the existing contents.
+	set, err = urldedup.NewDiskSet(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 100 {
+		found, err := set.Contains(fmt.Sprintf("key-%v", i))
+		if err != nil || !found {
+			t.Errorf("key-%v: %v, %v", i, found, err)
+		}
+		found, err = set.Contains(fmt.Sprintf("other-%v", i))
+		if err != nil || found {
+			t.Errorf("other-%v: %v, %v", i, found, err)
+		}
+	}
+}
+
+func TestDiskSetMerge(t *testing.T) {
+	dir := t.TempDir()
+	set, err := urldedup.NewDiskSet(dir, urldedup.WithMergeThreshold(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 1000 {
+		if err := set.Add(fmt.Sprintf("key-%v", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Adding existing keys must not change the set.
+	for i := range 1000 {
+		if err := set.Add(fmt.Sprintf("key-%v", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, threshold := range []int{3, urldedup.DefaultMergeThreshold} {
+		set, err = urldedup.NewDiskSet(dir, urldedup.WithMergeThreshold(threshold))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range 1000 {
+			found, err := set.Contains(fmt.Sprintf("key-%v", i))
+			if err != nil || !found {
+				t.Errorf("key-%v: %v, %v", i, found, err)
+			}
+			found, err = set.Contains(fmt.Sprintf("other-%v", i))
+			if err != nil || found {
+				t.Errorf("other-%v: %v, %v", i, found, err)
+			}
+		}
+	}
+	// Each sorted file must contain each key exactly once.
+	size := int64(0)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		size += fi.Size()
+	}
+	if got, want := size, int64(1000*16); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDedupReuse(t *testing.T) {
+	dir := t.TempDir()
+	for run := range 2 {
+		set, err := urldedup.NewDiskSet(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := urldedup.New(urldedup.WithExactSet(set), urldedup.WithBloomFilter(1000, 0.001))
+		for i := range 100 {
+			link := fmt.Sprintf("https://example.com/page/%v", i)
+			if got, want := d.IsDup(link), run > 0; got != want {
+				t.Errorf("run %v: %v: got %v, want %v", run, link, got, want)
+			}
+		}
+		if stats := d.Stats(); stats.FalsePositives != 0 || stats.Errors != 0 {
+			t.Errorf("run %v: unexpected stats: %v", run, stats)
+		}
+	}
+}
+
+func TestDedupConcurrent(t *testing.T) {
+	set, err := urldedup.NewDiskSet(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := urldedup.New(urldedup.WithExactSet(set), urldedup.WithBloomFilter(10, 0.1))
+	var added atomic.Int64
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				if !d.IsDup(fmt.Sprintf("https://example.com/page/%v", i)) {
+					added.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if got, want := added.Load(), int64(200); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
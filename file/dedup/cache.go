@@ -0,0 +1,127 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package dedup
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CacheKey identifies a digest stored in a DigestCache. A cached digest
+// is only used if the file's size and modification time are unchanged.
+type CacheKey struct {
+	Scheme  string    `json:"scheme"`
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	Algo    string    `json:"algo"`
+}
+
+func (k CacheKey) normalize() CacheKey {
+	k.ModTime = k.ModTime.UTC()
+	return k
+}
+
+// DigestCache represents a cache of full digests. Implementations must
+// be safe for concurrent use.
+type DigestCache interface {
+	Get(key CacheKey) ([]byte, bool)
+	Put(key CacheKey, digest []byte)
+}
+
+// Cache is an in-memory implementation of DigestCache that can be saved
+// to, and loaded from, a local file.
+type Cache struct {
+	mu      sync.Mutex
+	digests map[CacheKey][]byte
+}
+
+var _ DigestCache = (*Cache)(nil)
+
+// NewCache returns a new, empty, Cache.
+func NewCache() *Cache {
+	return &Cache{digests: map[CacheKey][]byte{}}
+}
+
+type cacheEntry struct {
+	CacheKey
+	Digest []byte `json:"digest"`
+}
+
+// LoadCache loads a Cache previously written by Save. An empty Cache is
+// returned if the file does not exist.
+func LoadCache(filename string) (*Cache, error) {
+	c := NewCache()
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return c, nil
+		}
+		return nil, err
+	}
+	var entries []cacheEntry
+	if err := json.Unmarshal(buf, &entries); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		c.digests[e.normalize()] = e.Digest
+	}
+	return c, nil
+}
+
+// Save writes the contents of the cache to the specified file.
+func (c *Cache) Save(filename string) error {
+	c.mu.Lock()
+	entries := make([]cacheEntry, 0, len(c.digests))
+	for k, v := range c.digests {
+		entries = append(entries, cacheEntry{CacheKey: k, Digest: v})
+	}
+	c.mu.Unlock()
+	buf, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// Len returns the number of digests in the cache.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.digests)
+}
+
+// Get implements DigestCache.
+func (c *Cache) Get(key CacheKey) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, ok := c.digests[key.normalize()]
+	return d, ok
+}
+
+// Put implements DigestCache.
+func (c *Cache) Put(key CacheKey, digest []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.digests[key.normalize()] = digest
+}
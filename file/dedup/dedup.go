@@ -0,0 +1,539 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package dedup provides support for finding duplicate files within one or
+// more filewalk.FS hierarchies. Candidate duplicates are found by first
+// grouping files by size, then by a digest of their initial contents (the
+// partial digest) and finally by a digest of their entire contents. Full
+// digests may be cached across runs, keyed by a file's path, size and
+// modification time, and may also be obtained directly from filesystems
+// that implement KnownDigester, for example, S3 objects with an MD5 ETag.
+package dedup
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"cloudeng.io/algo/digests"
+	"cloudeng.io/file"
+	"cloudeng.io/file/diskusage"
+	"cloudeng.io/file/filewalk"
+	"cloudeng.io/file/filewalk/asyncstat"
+	"cloudeng.io/sync/errgroup"
+)
+
+// Root represents a prefix within a filesystem to be searched for
+// duplicates.
+type Root struct {
+	FS     filewalk.FS
+	Prefix string
+}
+
+// KnownDigester may be implemented by filesystems that are able to provide
+// the digest of a file's contents without reading it, for example, from
+// metadata returned by a Stat operation. A known digest is only used if
+// it is computed using the algorithm configured via WithAlgorithm.
+type KnownDigester interface {
+	KnownDigest(ctx context.Context, name string, info file.Info) (digests.Hash, bool)
+}
+
+// File represents a single file within a set of duplicates.
+type File struct {
+	FS   filewalk.FS
+	Name string
+	Info file.Info
+}
+
+// Set represents a set of files with identical contents.
+type Set struct {
+	Size   int64
+	Algo   string
+	Digest []byte
+	Files  []File
+	// Wasted is the storage, as computed by the diskusage.Calculator
+	// configured via WithCalculator, that would be recovered by removing
+	// all but one of the files in the set. Files that are hard links to
+	// the same underlying file are not counted as wasting storage.
+	Wasted int64
+}
+
+// Stats records statistics for a call to Find.
+type Stats struct {
+	Files         int64 // Number of files examined.
+	Candidates    int64 // Number of files with the same size as another file.
+	PartialHashes int64 // Number of partial digests computed.
+	FullHashes    int64 // Number of full digests computed.
+	CacheHits     int64 // Number of full digests obtained from the cache.
+	KnownDigests  int64 // Number of full digests obtained via KnownDigester.
+}
+
+// Report is returned by Find.
+type Report struct {
+	// Sets is ordered by decreasing wasted storage.
+	Sets   []Set
+	Wasted int64
+	Stats  Stats
+	// Errors records errors encountered for individual prefixes or files,
+	// which are excluded from the search.
+	Errors []error
+}
+
+// Option represents an option to New.
+type Option func(o *options)
+
+type options struct {
+	algo        string
+	partialSize int64
+	minSize     int64
+	concurrency int
+	calculator  diskusage.Calculator
+	cache       DigestCache
+	walkOpts    []filewalk.Option
+	statOpts    []asyncstat.Option
+}
+
+const (
+	// DefaultAlgorithm is the default digest algorithm.
+	DefaultAlgorithm = digests.SHA256
+	// DefaultPartialSize is the default number of bytes used to
+	// compute partial digests.
+	DefaultPartialSize = 4096
+	// DefaultConcurrency is the default number of files that are
+	// hashed concurrently.
+	DefaultConcurrency = 8
+)
+
+// WithAlgorithm sets the digest algorithm to be used, it must be one
+// of those supported by the digests package.
+func WithAlgorithm(algo string) Option {
+	return func(o *options) {
+		o.algo = algo
+	}
+}
+
+// WithPartialSize sets the number of bytes read from the start of each file
+// to compute its partial digest. Files of this size or smaller are
+// only hashed once.
+func WithPartialSize(size int64) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.partialSize = size
+		}
+	}
+}
+
+// WithMinSize sets the minimum size of files to be considered, the default
+// is 1, ie. empty files are ignored.
+func WithMinSize(size int64) Option {
+	return func(o *options) {
+		o.minSize = size
+	}
+}
+
+// WithConcurrency sets the number of files that are hashed concurrently.
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithCalculator sets the diskusage.Calculator used to compute wasted
+// storage, the default is diskusage.Identity.
+func WithCalculator(calc diskusage.Calculator) Option {
+	return func(o *options) {
+		o.calculator = calc
+	}
+}
+
+// WithDigestCache sets the cache used to store full digests.
+func WithDigestCache(cache DigestCache) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
+
+// WithWalkOptions sets the options used for the walk of each root.
+func WithWalkOptions(opts ...filewalk.Option) Option {
+	return func(o *options) {
+		o.walkOpts = append(o.walkOpts, opts...)
+	}
+}
+
+// WithAsyncStatOptions sets the options used for the asyncstat.T used to
+// obtain the file.Info for the contents of each prefix.
+func WithAsyncStatOptions(opts ...asyncstat.Option) Option {
+	return func(o *options) {
+		o.statOpts = append(o.statOpts, opts...)
+	}
+}
+
+// T is used to find duplicate files.
+type T struct {
+	options
+}
+
+// New returns a new instance of T.
+func New(opts ...Option) *T {
+	t := &T{}
+	t.algo = DefaultAlgorithm
+	t.partialSize = DefaultPartialSize
+	t.minSize = 1
+	t.concurrency = DefaultConcurrency
+	t.calculator = diskusage.NewIdentity()
+	for _, fn := range opts {
+		fn(&t.options)
+	}
+	return t
+}
+
+type candidate struct {
+	fs      filewalk.FS
+	name    string
+	info    file.Info
+	xattr   file.XAttr
+	partial []byte
+	full    []byte
+}
+
+// inode returns a key that identifies the underlying file for filesystems
+// that support hard links, or "" otherwise.
+func (c *candidate) inode() string {
+	if c.xattr.FileID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d:%d", c.fs.Scheme(), c.xattr.Device, c.xattr.FileID)
+}
+
+type finder struct {
+	*T
+	mu     sync.Mutex
+	errs   []error
+	stats  Stats
+	bySize map[int64][]*candidate
+}
+
+func (f *finder) recordError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errs = append(f.errs, err)
+}
+
+// Find searches the specified roots for duplicate files. Errors encountered
+// for individual prefixes or files are recorded in the returned Report,
+// other errors, including context cancelation, are returned.
+func (t *T) Find(ctx context.Context, roots ...Root) (Report, error) {
+	if !digests.IsSupported(t.algo) {
+		return Report{}, fmt.Errorf("unsupported hash algorithm: %s", t.algo)
+	}
+	f := &finder{T: t, bySize: map[int64][]*candidate{}}
+	for _, root := range roots {
+		w := &walker{finder: f, fs: root.FS, stat: asyncstat.New(root.FS, t.statOpts...)}
+		if err := filewalk.New(root.FS, w, t.walkOpts...).Walk(ctx, root.Prefix); err != nil {
+			return Report{}, err
+		}
+	}
+	var groups [][]*candidate
+	for _, group := range f.bySize {
+		if len(group) > 1 {
+			f.stats.Candidates += int64(len(group))
+			groups = append(groups, group)
+		}
+	}
+	sets, err := f.process(ctx, groups)
+	if err != nil {
+		return Report{}, err
+	}
+	report := Report{Sets: sets, Stats: f.stats, Errors: f.errs}
+	for _, s := range sets {
+		report.Wasted += s.Wasted
+	}
+	return report, nil
+}
+
+type walker struct {
+	*finder
+	fs   filewalk.FS
+	stat *asyncstat.T
+}
+
+func (w *walker) Prefix(_ context.Context, _ *struct{}, prefix string, _ file.Info, err error) (bool, file.InfoList, error) {
+	if err != nil {
+		w.recordError(fmt.Errorf("%v: %w", prefix, err))
+		return true, nil, nil
+	}
+	return false, nil, nil
+}
+
+func (w *walker) Contents(ctx context.Context, _ *struct{}, prefix string, contents []filewalk.Entry) (file.InfoList, error) {
+	children, all, err := w.stat.Process(ctx, prefix, contents)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []*candidate
+	for _, info := range all {
+		if !info.Mode().IsRegular() || info.Size() < w.minSize {
+			continue
+		}
+		name := w.fs.Join(prefix, info.Name())
+		xattr, err := w.fs.XAttr(ctx, name, info)
+		if err != nil {
+			w.recordError(fmt.Errorf("%v: %w", name, err))
+			continue
+		}
+		candidates = append(candidates, &candidate{fs: w.fs, name: name, info: info, xattr: xattr})
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stats.Files += int64(len(candidates))
+	for _, c := range candidates {
+		w.bySize[c.info.Size()] = append(w.bySize[c.info.Size()], c)
+	}
+	return children, nil
+}
+
+func (w *walker) Done(_ context.Context, _ *struct{}, prefix string, err error) error {
+	if err != nil {
+		w.recordError(fmt.Errorf("%v: %w", prefix, err))
+	}
+	return nil
+}
+
+// process computes the digests for each group of files of the same size
+// and returns the resulting sets of duplicates.
+func (f *finder) process(ctx context.Context, groups [][]*candidate) ([]Set, error) {
+	var partial, full []*candidate
+	var partialGroups [][]*candidate
+	for _, group := range groups {
+		if f.lookupDigests(ctx, group) {
+			full = append(full, unhashed(group)...)
+			continue
+		}
+		partialGroups = append(partialGroups, group)
+		partial = append(partial, group...)
+	}
+	if err := f.hash(ctx, partial, true); err != nil {
+		return nil, err
+	}
+	for _, group := range partialGroups {
+		for _, same := range groupBy(group, func(c *candidate) []byte { return c.partial }) {
+			if len(same) < 2 {
+				continue
+			}
+			if same[0].info.Size() <= f.partialSize {
+				for _, c := range same {
+					c.full = c.partial
+					if f.cache != nil {
+						f.cache.Put(f.cacheKey(c), c.full)
+					}
+				}
+				continue
+			}
+			full = append(full, same...)
+		}
+	}
+	if err := f.hash(ctx, full, false); err != nil {
+		return nil, err
+	}
+	var sets []Set
+	for _, group := range groups {
+		for _, same := range groupBy(group, func(c *candidate) []byte { return c.full }) {
+			if s, ok := f.newSet(same); ok {
+				sets = append(sets, s)
+			}
+		}
+	}
+	slices.SortFunc(sets, func(a, b Set) int {
+		if n := cmp.Compare(b.Wasted, a.Wasted); n != 0 {
+			return n
+		}
+		return bytes.Compare(a.Digest, b.Digest)
+	})
+	return sets, nil
+}
+
+// lookupDigests obtains full digests from KnownDigester implementations
+// and the cache, returning true if any were found.
+func (f *finder) lookupDigests(ctx context.Context, group []*candidate) bool {
+	found := false
+	for _, c := range group {
+		if kd, ok := c.fs.(KnownDigester); ok {
+			if h, ok := kd.KnownDigest(ctx, c.name, c.info); ok && h.Algo == f.algo && len(h.Digest) > 0 {
+				c.full = h.Digest
+				f.stats.KnownDigests++
+				found = true
+				continue
+			}
+		}
+		if f.cache == nil {
+			continue
+		}
+		if digest, ok := f.cache.Get(f.cacheKey(c)); ok {
+			c.full = digest
+			f.stats.CacheHits++
+			found = true
+		}
+	}
+	return found
+}
+
+func (f *finder) cacheKey(c *candidate) CacheKey {
+	return CacheKey{
+		Scheme:  c.fs.Scheme(),
+		Path:    c.name,
+		Size:    c.info.Size(),
+		ModTime: c.info.ModTime(),
+		Algo:    f.algo,
+	}
+}
+
+func unhashed(group []*candidate) []*candidate {
+	var r []*candidate
+	for _, c := range group {
+		if c.full == nil {
+			r = append(r, c)
+		}
+	}
+	return r
+}
+
+// groupBy groups the candidates that have a digest by that digest,
+// candidates without a digest are ignored.
+func groupBy(group []*candidate, digest func(*candidate) []byte) [][]*candidate {
+	byDigest := map[string][]*candidate{}
+	var keys []string
+	for _, c := range group {
+		d := digest(c)
+		if d == nil {
+			continue
+		}
+		k := string(d)
+		if _, ok := byDigest[k]; !ok {
+			keys = append(keys, k)
+		}
+		byDigest[k] = append(byDigest[k], c)
+	}
+	r := make([][]*candidate, 0, len(keys))
+	for _, k := range keys {
+		r = append(r, byDigest[k])
+	}
+	return r
+}
+
+// hash computes the partial or full digests for the supplied candidates
+// concurrently. Files that are hard links to the same underlying file are
+// only hashed once. Candidates whose digest cannot be computed are
+// recorded as errors and have no digest.
+func (f *finder) hash(ctx context.Context, candidates []*candidate, partial bool) error {
+	byInode := map[string][]*candidate{}
+	var unique []*candidate
+	for _, c := range candidates {
+		inode := c.inode()
+		if len(inode) > 0 {
+			if prev, ok := byInode[inode]; ok {
+				byInode[inode] = append(prev, c)
+				continue
+			}
+			byInode[inode] = []*candidate{c}
+		}
+		unique = append(unique, c)
+	}
+	g, ctx := errgroup.WithContext(ctx)
+	g = errgroup.WithConcurrency(g, f.concurrency)
+	for _, c := range unique {
+		g.Go(func() error {
+			digest, err := f.digest(ctx, c, partial)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				f.recordError(fmt.Errorf("%v: %w", c.name, err))
+				return nil
+			}
+			links := []*candidate{c}
+			if inode := c.inode(); len(inode) > 0 {
+				links = byInode[inode]
+			}
+			for _, l := range links {
+				if partial {
+					l.partial = digest
+					continue
+				}
+				l.full = digest
+				if f.cache != nil {
+					f.cache.Put(f.cacheKey(l), digest)
+				}
+			}
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			if partial {
+				f.stats.PartialHashes++
+			} else {
+				f.stats.FullHashes++
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+func (f *finder) digest(ctx context.Context, c *candidate, partial bool) ([]byte, error) {
+	h, err := digests.New(f.algo, nil)
+	if err != nil {
+		return nil, err
+	}
+	rd, err := c.fs.OpenCtx(ctx, c.name)
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+	if partial {
+		_, err = io.CopyN(h, rd, f.partialSize)
+		if err == io.EOF {
+			err = nil
+		}
+	} else {
+		_, err = io.Copy(h, rd)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func (f *finder) newSet(files []*candidate) (Set, bool) {
+	inodes := map[string]bool{}
+	distinct := 0
+	for _, c := range files {
+		inode := c.inode()
+		if len(inode) == 0 || !inodes[inode] {
+			distinct++
+		}
+		inodes[inode] = true
+	}
+	if distinct < 2 {
+		return Set{}, false
+	}
+	s := Set{
+		Size:   files[0].info.Size(),
+		Algo:   f.algo,
+		Digest: files[0].full,
+		Files:  make([]File, len(files)),
+	}
+	for i, c := range files {
+		s.Files[i] = File{FS: c.fs, Name: c.name, Info: c.info}
+	}
+	slices.SortFunc(s.Files, func(a, b File) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	s.Wasted = int64(distinct-1) * f.calculator.Calculate(s.Size, files[0].xattr.Blocks)
+	return s, true
+}
@@ -0,0 +1,301 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package dedup_test
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // G401: Use of weak cryptographic primitive
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"cloudeng.io/algo/digests"
+	"cloudeng.io/file"
+	"cloudeng.io/file/dedup"
+	"cloudeng.io/file/diskusage"
+	"cloudeng.io/file/localfs"
+	"cloudeng.io/file/memfs"
+)
+
+// a/x, b/x and c/y are duplicates, a/p and b/p share the same partial
+// digest but differ, d/small1 and d/small2 are small duplicates and
+// d/unique has the same size as the x files.
+const spec = `
+name: /
+entries:
+  - dir:
+      name: a
+      entries:
+        - file:
+            name: x
+            contents: "0123456789abcdef-dup"
+        - file:
+            name: p
+            contents: "0123456789abcdef-one"
+  - dir:
+      name: b
+      entries:
+        - file:
+            name: x
+            contents: "0123456789abcdef-dup"
+        - file:
+            name: p
+            contents: "0123456789abcdef-two"
+  - dir:
+      name: c
+      entries:
+        - file:
+            name: y
+            contents: "0123456789abcdef-dup"
+  - dir:
+      name: d
+      entries:
+        - file:
+            name: small1
+            contents: "abc"
+        - file:
+            name: small2
+            contents: "abc"
+        - file:
+            name: unique
+            contents: "0123456789abcdef-uni"
+        - file:
+            name: empty1
+        - file:
+            name: empty2
+`
+
+func newFS(t *testing.T) *memfs.T {
+	t.Helper()
+	mfs := memfs.New()
+	if err := mfs.LoadYAML(spec); err != nil {
+		t.Fatal(err)
+	}
+	return mfs
+}
+
+func setNames(report dedup.Report) [][]string {
+	var names [][]string
+	for _, s := range report.Sets {
+		var n []string
+		for _, f := range s.Files {
+			n = append(n, f.Name)
+		}
+		names = append(names, n)
+	}
+	return names
+}
+
+func TestFind(t *testing.T) {
+	ctx := context.Background()
+	mfs := newFS(t)
+	report, err := dedup.New(dedup.WithPartialSize(8)).Find(ctx, dedup.Root{FS: mfs, Prefix: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := setNames(report), [][]string{
+		{"/a/x", "/b/x", "/c/y"},
+		{"/d/small1", "/d/small2"},
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := report.Wasted, int64(2*20+3); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := report.Sets[0].Wasted, int64(40); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := report.Stats, (dedup.Stats{
+		Files:         8,
+		Candidates:    8,
+		PartialHashes: 8,
+		FullHashes:    6,
+	}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	h, _ := digests.New(digests.SHA256, nil)
+	h.Write([]byte("abc"))
+	if got, want := report.Sets[1].Digest, h.Sum(nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+
+	report, err = dedup.New(dedup.WithMinSize(0), dedup.WithCalculator(diskusage.NewRoundup(10))).Find(ctx,
+		dedup.Root{FS: mfs, Prefix: "/a"}, dedup.Root{FS: mfs, Prefix: "/d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := setNames(report), [][]string{
+		{"/d/small1", "/d/small2"},
+		{"/d/empty1", "/d/empty2"},
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := report.Wasted, int64(20); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	mfs := newFS(t)
+	mfs.InjectFault("/c/y", memfs.OpOpen, os.ErrPermission, -1)
+	mfs.InjectFault("/b", memfs.OpScan, os.ErrPermission, -1)
+	report, err := dedup.New().Find(ctx, dedup.Root{FS: mfs, Prefix: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := setNames(report), [][]string{{"/d/small1", "/d/small2"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(report.Errors), 2; got != want {
+		t.Fatalf("got %v, want %v: %v", got, want, report.Errors)
+	}
+	for _, err := range report.Errors {
+		if !errors.Is(err, os.ErrPermission) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if _, err := dedup.New(dedup.WithAlgorithm("crc")).Find(ctx); err == nil {
+		t.Errorf("expected an error")
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := dedup.New().Find(cctx, dedup.Root{FS: mfs, Prefix: "/"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a context canceled error: %v", err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	mfs := newFS(t)
+	filename := filepath.Join(t.TempDir(), "cache.json")
+	cache, err := dedup.LoadCache(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := dedup.New(dedup.WithDigestCache(cache)).Find(ctx, dedup.Root{FS: mfs, Prefix: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cache.Len(), 5; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := cache.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	cache, err = dedup.LoadCache(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := dedup.New(dedup.WithDigestCache(cache)).Find(ctx, dedup.Root{FS: mfs, Prefix: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := setNames(second), setNames(first); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := second.Stats.CacheHits, int64(5); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// The remaining files of the same size as those with cached digests
+	// are hashed in full.
+	if got, want := second.Stats.PartialHashes+second.Stats.FullHashes, int64(3); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Modifying a file invalidates its cached digest.
+	if err := mfs.WriteFile("/b/x", []byte("0123456789abcdef-new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	third, err := dedup.New(dedup.WithDigestCache(cache)).Find(ctx, dedup.Root{FS: mfs, Prefix: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := setNames(third), [][]string{
+		{"/a/x", "/c/y"},
+		{"/d/small1", "/d/small2"},
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+type knownFS struct {
+	*memfs.T
+}
+
+func (k *knownFS) KnownDigest(ctx context.Context, name string, _ file.Info) (digests.Hash, bool) {
+	data, err := k.ReadFileCtx(ctx, name)
+	if err != nil {
+		return digests.Hash{}, false
+	}
+	sum := md5.Sum(data) //nolint:gosec // G401: Use of weak cryptographic primitive
+	h, _ := digests.New(digests.MD5, sum[:])
+	return h, true
+}
+
+func TestKnownDigest(t *testing.T) {
+	ctx := context.Background()
+	kfs := &knownFS{T: newFS(t)}
+	report, err := dedup.New(dedup.WithAlgorithm(digests.MD5)).Find(ctx, dedup.Root{FS: kfs, Prefix: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := setNames(report), [][]string{
+		{"/a/x", "/b/x", "/c/y"},
+		{"/d/small1", "/d/small2"},
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := report.Stats.KnownDigests, int64(8); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := report.Stats.PartialHashes+report.Stats.FullHashes, int64(0); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Known digests are ignored if the algorithm differs.
+	report, err = dedup.New().Find(ctx, dedup.Root{FS: kfs, Prefix: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := report.Stats.KnownDigests, int64(0); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(report.Sets), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestHardlinks(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	write := func(name, contents string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a", "duplicate")
+	write("b", "duplicate")
+	write("c", "hardlinked")
+	if err := os.Link(filepath.Join(dir, "c"), filepath.Join(dir, "d")); err != nil {
+		t.Skipf("hard links not supported: %v", err)
+	}
+	report, err := dedup.New().Find(ctx, dedup.Root{FS: localfs.New(), Prefix: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := setNames(report), [][]string{
+		{filepath.Join(dir, "a"), filepath.Join(dir, "b")},
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := report.Stats.PartialHashes, int64(3); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
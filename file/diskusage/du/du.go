@@ -0,0 +1,340 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package du provides support for analyzing the disk usage of any
+// filewalk.FS in the manner of the unix du command. The usage of each
+// prefix (directory) is totaled, including that of all of the prefixes
+// beneath it, using a diskusage.Calculator, optionally restricted to
+// files that match a matcher expression. The largest files and prefixes
+// are tracked and the results may be displayed as a tree, a flat table or
+// JSON and may be saved so that the results of two runs can be compared.
+package du
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"cloudeng.io/algo/container/heap"
+	"cloudeng.io/cmdutil/boolexpr"
+	"cloudeng.io/file"
+	"cloudeng.io/file/diskusage"
+	"cloudeng.io/file/filewalk"
+	"cloudeng.io/file/filewalk/asyncstat"
+)
+
+// Totals represents the disk usage of a prefix, including that of all of
+// the prefixes beneath it.
+type Totals struct {
+	Files int64 `json:"files"`
+	Dirs  int64 `json:"dirs"`
+	// Bytes is the apparent size of all files.
+	Bytes int64 `json:"bytes"`
+	// Usage is the storage used by all files as computed by the
+	// configured diskusage.Calculator.
+	Usage int64 `json:"usage"`
+}
+
+func (t *Totals) add(o Totals) {
+	t.Files += o.Files
+	t.Dirs += o.Dirs
+	t.Bytes += o.Bytes
+	t.Usage += o.Usage
+}
+
+// Prefix represents the disk usage of a single prefix.
+type Prefix struct {
+	Path string `json:"path"`
+	// Parent is the path of the prefix's parent, or "" for roots.
+	Parent string `json:"parent,omitempty"`
+	Totals
+}
+
+// Item represents a single file or prefix and its size.
+type Item struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+	Usage int64  `json:"usage"`
+}
+
+// Report contains the results of a disk usage analysis.
+type Report struct {
+	When       time.Time `json:"when"`
+	Roots      []string  `json:"roots"`
+	Calculator string    `json:"calculator"`
+	Filter     string    `json:"filter,omitempty"`
+	Totals     Totals    `json:"totals"`
+	// Prefixes is ordered by path.
+	Prefixes []Prefix `json:"prefixes"`
+	// TopFiles and TopPrefixes are ordered by decreasing usage.
+	TopFiles    []Item   `json:"top_files,omitempty"`
+	TopPrefixes []Item   `json:"top_prefixes,omitempty"`
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// Option represents an option to New.
+type Option func(o *options)
+
+type options struct {
+	calculator diskusage.Calculator
+	filter     boolexpr.T
+	hasFilter  bool
+	topN       int
+	walkOpts   []filewalk.Option
+	statOpts   []asyncstat.Option
+}
+
+// DefaultTopN is the default number of largest files and prefixes that
+// are tracked.
+const DefaultTopN = 10
+
+// WithCalculator sets the diskusage.Calculator used to compute the storage
+// used by each file, the default is diskusage.Identity.
+func WithCalculator(calc diskusage.Calculator) Option {
+	return func(o *options) {
+		o.calculator = calc
+	}
+}
+
+// WithFilter restricts the analysis to files that match the supplied
+// expression, typically created using matcher.New. All prefixes are
+// traversed regardless of the expression. The values passed to the
+// expression implement the interfaces required by all of the operands
+// provided by the matcher package.
+func WithFilter(expr boolexpr.T) Option {
+	return func(o *options) {
+		o.filter = expr
+		o.hasFilter = true
+	}
+}
+
+// WithTopN sets the number of largest files and prefixes to be tracked.
+func WithTopN(n int) Option {
+	return func(o *options) {
+		o.topN = n
+	}
+}
+
+// WithWalkOptions sets the options used for the walk.
+func WithWalkOptions(opts ...filewalk.Option) Option {
+	return func(o *options) {
+		o.walkOpts = append(o.walkOpts, opts...)
+	}
+}
+
+// WithAsyncStatOptions sets the options used for the asyncstat.T used to
+// obtain the file.Info for the contents of each prefix.
+func WithAsyncStatOptions(opts ...asyncstat.Option) Option {
+	return func(o *options) {
+		o.statOpts = append(o.statOpts, opts...)
+	}
+}
+
+// T is used to analyze disk usage.
+type T struct {
+	options
+	fs filewalk.FS
+}
+
+// New returns a new instance of T for the specified filesystem.
+func New(fs filewalk.FS, opts ...Option) *T {
+	t := &T{fs: fs}
+	t.calculator = diskusage.NewIdentity()
+	t.topN = DefaultTopN
+	for _, fn := range opts {
+		fn(&t.options)
+	}
+	return t
+}
+
+// Analyze walks the specified roots and returns a Report of their disk
+// usage. Errors encountered for individual prefixes or files are recorded
+// in the Report, other errors, including context cancelation, are returned.
+func (t *T) Analyze(ctx context.Context, roots ...string) (Report, error) {
+	h := &handler{
+		T:        t,
+		stat:     asyncstat.New(t.fs, t.statOpts...),
+		prefixes: map[string]*Prefix{},
+		inodes:   map[inode]bool{},
+		topFiles: heap.NewMinMax[int64, Item](),
+		topDirs:  heap.NewMinMax[int64, Item](),
+	}
+	when := time.Now()
+	if err := filewalk.New(t.fs, h, t.walkOpts...).Walk(ctx, roots...); err != nil {
+		if ctx.Err() != nil {
+			return Report{}, err
+		}
+		h.errs = append(h.errs, err.Error())
+	}
+	r := Report{
+		When:        when,
+		Roots:       roots,
+		Calculator:  t.calculator.String(),
+		Prefixes:    make([]Prefix, 0, len(h.prefixes)),
+		TopFiles:    popAll(h.topFiles),
+		TopPrefixes: popAll(h.topDirs),
+		Errors:      h.errs,
+	}
+	if t.hasFilter {
+		r.Filter = t.filter.String()
+	}
+	for _, p := range h.prefixes {
+		r.Prefixes = append(r.Prefixes, *p)
+	}
+	slices.SortFunc(r.Prefixes, func(a, b Prefix) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	for _, root := range roots {
+		if p, ok := h.prefixes[root]; ok {
+			r.Totals.add(p.Totals)
+		}
+	}
+	return r, nil
+}
+
+func popAll(h *heap.MinMax[int64, Item]) []Item {
+	items := make([]Item, 0, h.Len())
+	for h.Len() > 0 {
+		_, v := h.PopMax()
+		items = append(items, v)
+	}
+	return items
+}
+
+type inode struct {
+	device, fileID uint64
+}
+
+type handler struct {
+	*T
+	stat *asyncstat.T
+
+	mu       sync.Mutex
+	prefixes map[string]*Prefix
+	inodes   map[inode]bool
+	topFiles *heap.MinMax[int64, Item]
+	topDirs  *heap.MinMax[int64, Item]
+	errs     []string
+}
+
+type prefixState struct {
+	totals   Totals
+	children []string
+}
+
+func (h *handler) recordError(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errs = append(h.errs, err.Error())
+}
+
+func (h *handler) Prefix(_ context.Context, _ *prefixState, _ string, _ file.Info, err error) (bool, file.InfoList, error) {
+	return false, nil, err
+}
+
+// value is passed to the filter expression.
+type value struct {
+	file.Info
+	ctx   context.Context
+	fs    filewalk.FS
+	path  string
+	xattr file.XAttr
+}
+
+func (v value) Path() string {
+	return v.path
+}
+
+func (v value) XAttr() file.XAttr {
+	return v.xattr
+}
+
+// SymlinkTarget reads the target of a symbolic link only when it is
+// required by the filter expression.
+func (v value) SymlinkTarget() string {
+	target, err := v.fs.Readlink(v.ctx, v.path)
+	if err != nil {
+		return ""
+	}
+	return target
+}
+
+func (h *handler) Contents(ctx context.Context, state *prefixState, prefix string, contents []filewalk.Entry) (file.InfoList, error) {
+	children, all, err := h.stat.Process(ctx, prefix, contents)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		state.children = append(state.children, h.fs.Join(prefix, child.Name()))
+	}
+	state.totals.Dirs += int64(len(children))
+	for _, info := range all {
+		if info.IsDir() {
+			continue
+		}
+		name := h.fs.Join(prefix, info.Name())
+		xattr, err := h.fs.XAttr(ctx, name, info)
+		if err != nil {
+			h.recordError(fmt.Errorf("%v: %w", name, err))
+			continue
+		}
+		if h.hasFilter && !h.filter.Eval(value{Info: info, ctx: ctx, fs: h.fs, path: name, xattr: xattr}) {
+			continue
+		}
+		if xattr.Hardlinks > 1 && h.seen(inode{xattr.Device, xattr.FileID}) {
+			continue
+		}
+		usage := h.calculator.Calculate(info.Size(), xattr.Blocks)
+		state.totals.Files++
+		state.totals.Bytes += info.Size()
+		state.totals.Usage += usage
+		h.push(h.topFiles, Item{Path: name, Bytes: info.Size(), Usage: usage})
+	}
+	return children, nil
+}
+
+// seen returns true if the specified inode has already been counted.
+func (h *handler) seen(ino inode) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.inodes[ino] {
+		return true
+	}
+	h.inodes[ino] = true
+	return false
+}
+
+func (h *handler) push(mm *heap.MinMax[int64, Item], item Item) {
+	if h.topN <= 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	mm.PushMaxN(item.Usage, item, h.topN)
+}
+
+func (h *handler) Done(_ context.Context, state *prefixState, prefix string, err error) error {
+	if err != nil {
+		h.recordError(err)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	p := &Prefix{Path: prefix, Totals: state.totals}
+	// Done is called for all of a prefix's children before it is called
+	// for the prefix itself.
+	for _, child := range state.children {
+		if c, ok := h.prefixes[child]; ok {
+			c.Parent = prefix
+			p.Totals.add(c.Totals)
+		}
+	}
+	h.prefixes[prefix] = p
+	if h.topN > 0 {
+		h.topDirs.PushMaxN(p.Usage, Item{Path: prefix, Bytes: p.Bytes, Usage: p.Usage}, h.topN)
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package du_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloudeng.io/file/diskusage"
+	"cloudeng.io/file/diskusage/du"
+	"cloudeng.io/file/matcher"
+	"cloudeng.io/file/memfs"
+)
+
+const spec = `
+name: /root
+entries:
+  - file:
+      name: top.txt
+      size: 100
+  - dir:
+      name: a
+      entries:
+        - file:
+            name: f1.log
+            size: 1000
+        - file:
+            name: f2.txt
+            size: 2000
+        - dir:
+            name: b
+            entries:
+              - file:
+                  name: f3.log
+                  size: 5000
+  - dir:
+      name: c
+      entries:
+        - file:
+            name: f4.txt
+            size: 10
+  - dir:
+      name: empty
+`
+
+func newFS(t *testing.T) *memfs.T {
+	t.Helper()
+	mfs := memfs.New()
+	if err := mfs.LoadYAML(spec); err != nil {
+		t.Fatal(err)
+	}
+	return mfs
+}
+
+func prefixes(r du.Report) map[string]du.Totals {
+	m := map[string]du.Totals{}
+	for _, p := range r.Prefixes {
+		m[p.Path] = p.Totals
+	}
+	return m
+}
+
+func paths(items []du.Item) []string {
+	var p []string
+	for _, it := range items {
+		p = append(p, it.Path)
+	}
+	return p
+}
+
+func TestAnalyze(t *testing.T) {
+	ctx := context.Background()
+	mfs := newFS(t)
+	r, err := du.New(mfs, du.WithTopN(2)).Analyze(ctx, "/root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := prefixes(r), map[string]du.Totals{
+		"/root":       {Files: 5, Dirs: 4, Bytes: 8110, Usage: 8110},
+		"/root/a":     {Files: 3, Dirs: 1, Bytes: 8000, Usage: 8000},
+		"/root/a/b":   {Files: 1, Bytes: 5000, Usage: 5000},
+		"/root/c":     {Files: 1, Bytes: 10, Usage: 10},
+		"/root/empty": {},
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := r.Totals, (du.Totals{Files: 5, Dirs: 4, Bytes: 8110, Usage: 8110}); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := paths(r.TopFiles), []string{"/root/a/b/f3.log", "/root/a/f2.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := paths(r.TopPrefixes), []string{"/root", "/root/a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := r.Calculator, "identity"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	expr, err := matcher.New().Parse("name=*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = du.New(mfs, du.WithFilter(expr), du.WithCalculator(diskusage.NewRoundup(1024))).Analyze(ctx, "/root/a", "/root/c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := prefixes(r), map[string]du.Totals{
+		"/root/a":   {Files: 1, Dirs: 1, Bytes: 2000, Usage: 2048},
+		"/root/a/b": {},
+		"/root/c":   {Files: 1, Bytes: 10, Usage: 1024},
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := r.Totals, (du.Totals{Files: 2, Dirs: 1, Bytes: 2010, Usage: 3072}); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := r.Filter, "name=*.txt"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSymlinkTargetFilter(t *testing.T) {
+	ctx := context.Background()
+	mfs := newFS(t)
+	if err := mfs.Symlink("/root/a/f1.log", "/root/c/l1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mfs.Symlink("/root/top.txt", "/root/c/l2"); err != nil {
+		t.Fatal(err)
+	}
+	expr, err := matcher.New().Parse("symlink-target=/root/a/*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := du.New(mfs, du.WithFilter(expr)).Analyze(ctx, "/root/c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.Totals.Files, int64(1); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	mfs := newFS(t)
+	mfs.InjectFault("/root/a/b", memfs.OpScan, os.ErrPermission, -1)
+	r, err := du.New(mfs).Analyze(ctx, "/root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(r.Errors), 1; got != want {
+		t.Fatalf("got %v, want %v: %v", got, want, r.Errors)
+	}
+	if got, want := r.Totals.Bytes, int64(3110); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := du.New(mfs).Analyze(cctx, "/root"); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestOutput(t *testing.T) {
+	ctx := context.Background()
+	mfs := newFS(t)
+	r, err := du.New(mfs).Analyze(ctx, "/root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := r.WriteTree(&out, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), `  7.92 KiB  /root
+  7.81 KiB    a
+   10.00 B    c
+    0.00 B    empty
+`; got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	out.Reset()
+	if err := r.WriteTable(&out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if got, want := strings.Fields(lines[2]), []string{"7.81", "KiB", "8000", "3", "1", "/root/a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	out.Reset()
+	if err := r.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	var decoded du.Report
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := decoded.Prefixes, r.Prefixes; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	ctx := context.Background()
+	mfs := newFS(t)
+	filename := filepath.Join(t.TempDir(), "du.json")
+	previous, err := du.New(mfs).Analyze(ctx, "/root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := previous.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	previous, err = du.Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mfs.WriteFile("/root/c/new", make([]byte, 500), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := mfs.RemoveAll("/root/a/b"); err != nil {
+		t.Fatal(err)
+	}
+	current, err := du.New(mfs).Analyze(ctx, "/root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := du.Compare(previous, current)
+	growth := map[string]int64{}
+	for _, c := range changes {
+		growth[c.Path] = c.Growth
+	}
+	if want := map[string]int64{
+		"/root/c":   500,
+		"/root/a":   -5000,
+		"/root/a/b": -5000,
+		"/root":     -4500,
+	}; !reflect.DeepEqual(growth, want) {
+		t.Errorf("got %v, want %v", growth, want)
+	}
+	if got, want := changes[0].Path, "/root/c"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := changes[len(changes)-1].Current, (du.Totals{}); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	var out bytes.Buffer
+	if err := du.WriteChanges(&out, changes); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "+500.00 B") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+}
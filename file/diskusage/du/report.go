@@ -0,0 +1,202 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package du
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"cloudeng.io/file/diskusage"
+)
+
+// WriteJSON writes the report to w as indented JSON.
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteTree writes the prefixes in the report to w as a tree, with the
+// children of each prefix ordered by decreasing usage. Prefixes deeper
+// than depth below the roots are not displayed unless depth is negative.
+func (r Report) WriteTree(w io.Writer, depth int) error {
+	children := map[string][]Prefix{}
+	byPath := map[string]Prefix{}
+	for _, p := range r.Prefixes {
+		children[p.Parent] = append(children[p.Parent], p)
+		byPath[p.Path] = p
+	}
+	for _, c := range children {
+		slices.SortFunc(c, func(a, b Prefix) int {
+			if n := cmp.Compare(b.Usage, a.Usage); n != 0 {
+				return n
+			}
+			return strings.Compare(a.Path, b.Path)
+		})
+	}
+	var write func(p Prefix, name string, level int) error
+	write = func(p Prefix, name string, level int) error {
+		if _, err := fmt.Fprintf(w, "%10s  %s%s\n", fmt.Sprint(diskusage.Binary(p.Usage)), strings.Repeat("  ", level), name); err != nil {
+			return err
+		}
+		if depth >= 0 && level >= depth {
+			return nil
+		}
+		for _, c := range children[p.Path] {
+			if err := write(c, relative(p.Path, c.Path), level+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range r.Roots {
+		p, ok := byPath[root]
+		if !ok {
+			continue
+		}
+		if err := write(p, root, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// relative returns the path of child relative to its parent.
+func relative(parent, child string) string {
+	rel := strings.TrimPrefix(child, parent)
+	if len(rel) > 1 && len(rel) < len(child) {
+		rel = rel[1:]
+	}
+	return rel
+}
+
+// WriteTable writes the prefixes in the report to w as a flat table
+// ordered by path, followed by the largest files and prefixes.
+func (r Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "usage\tbytes\tfiles\tdirs\t\tpath\n")
+	for _, p := range r.Prefixes {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t\t%v\n",
+			diskusage.Binary(p.Usage), p.Bytes, p.Files, p.Dirs, p.Path)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if err := writeItems(w, "largest files", r.TopFiles); err != nil {
+		return err
+	}
+	return writeItems(w, "largest prefixes", r.TopPrefixes)
+}
+
+func writeItems(w io.Writer, title string, items []Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	fmt.Fprintf(w, "\n%v:\n", title)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	for _, it := range items {
+		fmt.Fprintf(tw, "%v\t%v\t\t%v\n", diskusage.Binary(it.Usage), it.Bytes, it.Path)
+	}
+	return tw.Flush()
+}
+
+// Save writes the report, as JSON, to the specified file.
+func (r Report) Save(filename string) error {
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// Load reads a report previously written by Save.
+func Load(filename string) (Report, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return Report{}, err
+	}
+	var r Report
+	if err := json.Unmarshal(buf, &r); err != nil {
+		return Report{}, fmt.Errorf("%v: %w", filename, err)
+	}
+	return r, nil
+}
+
+// Change represents the change in disk usage of a single prefix between
+// two reports.
+type Change struct {
+	Path     string `json:"path"`
+	Previous Totals `json:"previous"`
+	Current  Totals `json:"current"`
+	// Growth is the change in usage, it is negative if usage has shrunk.
+	Growth int64 `json:"growth"`
+}
+
+// Compare compares the prefixes in two reports and returns the prefixes
+// whose totals have changed ordered by decreasing growth, ie. the prefixes
+// that have grown the most first and those that have shrunk the most last.
+// Prefixes that appear in only one of the reports are compared against
+// zero totals.
+func Compare(previous, current Report) []Change {
+	prev := make(map[string]Totals, len(previous.Prefixes))
+	for _, p := range previous.Prefixes {
+		prev[p.Path] = p.Totals
+	}
+	var changes []Change
+	for _, p := range current.Prefixes {
+		pt := prev[p.Path]
+		delete(prev, p.Path)
+		if pt == p.Totals {
+			continue
+		}
+		changes = append(changes, Change{Path: p.Path, Previous: pt, Current: p.Totals, Growth: p.Usage - pt.Usage})
+	}
+	for path, pt := range prev {
+		changes = append(changes, Change{Path: path, Previous: pt, Growth: -pt.Usage})
+	}
+	slices.SortFunc(changes, func(a, b Change) int {
+		if n := cmp.Compare(b.Growth, a.Growth); n != 0 {
+			return n
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+	return changes
+}
+
+// WriteChanges writes the supplied changes to w as a table.
+func WriteChanges(w io.Writer, changes []Change) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "growth\tprevious\tcurrent\t\tpath\n")
+	for _, c := range changes {
+		sign := "+"
+		growth := c.Growth
+		if growth < 0 {
+			sign, growth = "-", -growth
+		}
+		fmt.Fprintf(tw, "%s%v\t%v\t%v\t\t%v\n", sign, diskusage.Binary(growth),
+			diskusage.Binary(c.Previous.Usage), diskusage.Binary(c.Current.Usage), c.Path)
+	}
+	return tw.Flush()
+}
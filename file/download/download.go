@@ -33,6 +33,12 @@ type Result struct {
 	Retries int
 	// Error encountered during the download.
 	Err error
+	// Validators for the downloaded file, these are used for
+	// conditional downloads when re-crawling.
+	Validators Validators
+	// Status of the download relative to any previous download of the
+	// same file, it is always StatusUnknown unless WithRecrawl is used.
+	Status Status
 }
 
 // Downloaded represents all of the downloads in response
@@ -16,6 +16,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -31,20 +32,32 @@ type Progress struct {
 	// Downloaded is the total number of items downloaded so far.
 	Downloaded int64
 	// Outstanding is the current size of the input channel for items
-	// yet to be downloaded.
+	// yet to be downloaded plus, when WithRateControllerFor is used, the
+	// number of requests that have been read from the input channel but
+	// not yet downloaded.
 	Outstanding int64
 }
 
+// ErrTooManyRequests may be returned, typically wrapped, by a container
+// (file.FS) implementation to indicate that it is being rate limited, for
+// example when an HTTP server responds with http.StatusTooManyRequests.
+// It is intended for use as the retry error for WithRateController or
+// WithRateControllerFor.
+var ErrTooManyRequests = errors.New("too many requests")
+
 // Option is used to configure the behaviour of a newly created Downloader.
 type Option func(*options)
 
 type options struct {
 	rateController   *ratecontrol.Controller
+	rateControllerFn func(ctx context.Context, name string) *ratecontrol.Controller
 	backoffErr       error
 	concurrency      int
+	maxQueued        int
 	progressInterval time.Duration
 	progressCh       chan<- Progress
 	progressClose    bool
+	previous         Previous
 }
 
 type downloader struct {
@@ -64,6 +77,24 @@ func WithNumDownloaders(concurrency int) Option {
 	}
 }
 
+// DefaultQueuedPerDownloader is the number of requests, per downloader
+// (see WithNumDownloaders), that may be queued by default when
+// WithRateControllerFor is used.
+const DefaultQueuedPerDownloader = 100
+
+// WithMaxQueued limits the number of requests that are read from the
+// input channel and queued, or are being downloaded, when
+// WithRateControllerFor is used. Once the limit is reached no more requests
+// are read from the input channel until a queued request has been
+// downloaded, thus preserving the backpressure provided by the input
+// channel. The default is DefaultQueuedPerDownloader times the number of
+// downloaders.
+func WithMaxQueued(n int) Option {
+	return func(o *options) {
+		o.maxQueued = n
+	}
+}
+
 // WithRateController sets the rate controller to use to enforce rate
 // control. Backoff will be triggered if the supplied error is returned
 // by the container (file.FS) implementation.
@@ -74,6 +105,27 @@ func WithRateController(rc *ratecontrol.Controller, retryErr error) Option {
 	}
 }
 
+// WithRateControllerFor sets a function that is called to obtain the
+// rate controller to use for each object to be downloaded, for example, to
+// implement per-host rate control when crawling the web. The controller
+// returned, if not nil, is used in addition to any set by WithRateController
+// and backoff is performed using it rather than the global controller.
+// Requests are split by controller and the objects for each controller are
+// downloaded in order by a goroutine dedicated to that controller, so that
+// waiting on one controller does not delay downloads that use other
+// controllers; WithNumDownloaders limits the number of concurrent downloads
+// across all controllers. Backoff will be triggered if the supplied error,
+// if not nil, is returned by the container (file.FS) implementation, it
+// replaces any error set by WithRateController.
+func WithRateControllerFor(fn func(ctx context.Context, name string) *ratecontrol.Controller, retryErr error) Option {
+	return func(o *options) {
+		if retryErr != nil {
+			o.backoffErr = retryErr
+		}
+		o.rateControllerFn = fn
+	}
+}
+
 // WithProgress requests that progress messages are sent over the
 // supplid channel. If close is true the progress channel will be closed
 // when the downloader has finished. Close should be set to false if the same
@@ -86,6 +138,20 @@ func WithProgress(interval time.Duration, ch chan<- Progress, closeProgress bool
 	}
 }
 
+// WithRecrawl enables incremental re-downloads. The supplied function is
+// used to look up the result of a previous download of each object. If
+// one exists, and the container implements ConditionalFS, a conditional
+// download is made using the previous result's Validators and an
+// unmodified object is returned as the previous result, including its
+// Contents, with its Status set to StatusUnchanged. Otherwise, each result's
+// Status is set to StatusNew, StatusChanged (or StatusUnchanged if the
+// digest of the contents is unchanged) or StatusGone as appropriate.
+func WithRecrawl(previous Previous) Option {
+	return func(o *options) {
+		o.previous = previous
+	}
+}
+
 // New creates a new instance of a download.T.
 func New(opts ...Option) T {
 	dl := &downloader{}
@@ -104,13 +170,18 @@ func (dl *downloader) Run(ctx context.Context,
 	input <-chan Request,
 	output chan<- Downloaded) error {
 
-	var grp errgroup.T
-	for i := 0; i < dl.concurrency; i++ {
-		grp.Go(func() error {
-			return dl.runner(ctx, input, output)
-		})
+	var err error
+	if dl.rateControllerFn != nil {
+		err = dl.runQueued(ctx, input, output)
+	} else {
+		var grp errgroup.T
+		for i := 0; i < dl.concurrency; i++ {
+			grp.Go(func() error {
+				return dl.runner(ctx, input, output)
+			})
+		}
+		err = grp.Wait()
 	}
-	err := grp.Wait()
 
 	dl.ticker.Stop()
 	close(output)
@@ -160,7 +231,7 @@ func (dl *downloader) runner(ctx context.Context, input <-chan Request, output c
 			// ignore empty requests.
 			continue
 		}
-		downloaded, err := dl.downloadObjects(ctx, request)
+		downloaded, err := dl.downloadObjects(ctx, request, nil, nil)
 		if err != nil {
 			return err
 		}
@@ -173,13 +244,16 @@ func (dl *downloader) runner(ctx context.Context, input <-chan Request, output c
 	}
 }
 
-func (dl *downloader) downloadObjects(ctx context.Context, request Request) (Downloaded, error) {
+// downloadObjects downloads the objects in request using rc, if not nil,
+// in addition to the downloader's rate controller. If slots is not nil
+// a slot is held whilst each object is being downloaded.
+func (dl *downloader) downloadObjects(ctx context.Context, request Request, rc *ratecontrol.Controller, slots slots) (Downloaded, error) {
 	download := Downloaded{
 		Request:   request,
 		Downloads: make([]Result, 0, len(request.Names())),
 	}
 	for _, name := range request.Names() {
-		status, err := dl.downloadObject(ctx, request.Container(), name)
+		status, err := dl.downloadObject(ctx, request.Container(), name, rc, slots)
 		if err != nil {
 			return download, err
 		}
@@ -188,24 +262,61 @@ func (dl *downloader) downloadObjects(ctx context.Context, request Request) (Dow
 	return download, nil
 }
 
-func (dl *downloader) downloadObject(ctx context.Context, downloadFS file.FS, name string) (Result, error) {
+func (dl *downloader) downloadObject(ctx context.Context, downloadFS file.FS, name string, rc *ratecontrol.Controller, slots slots) (Result, error) {
 	result := Result{}
+	backoff := dl.rateController.Backoff()
+	if rc != nil {
+		if err := rc.Wait(ctx); err != nil {
+			return result, err
+		}
+		backoff = rc.Backoff()
+	}
 	if err := dl.rateController.Wait(ctx); err != nil {
 		return result, err
 	}
-	backoff := dl.rateController.Backoff()
+	if err := slots.acquire(ctx); err != nil {
+		return result, err
+	}
+	held := true
+	defer func() {
+		if held {
+			slots.release()
+		}
+	}()
+	var prev Result
+	var hasPrev bool
+	if dl.previous != nil {
+		prev, hasPrev = dl.previous(ctx, name)
+	}
 	for {
-		rd, err := downloadFS.OpenCtx(ctx, name)
+		rd, err := dl.open(ctx, downloadFS, name, prev, hasPrev)
 		result.Retries = backoff.Retries()
 		result.Err = err
 		result.Name = name
 		if err != nil {
-			if errors.Is(err, dl.backoffErr) {
+			if errors.Is(err, ErrNotModified) {
+				prev.Name = name
+				prev.Retries = result.Retries
+				prev.Err = nil
+				prev.Status = StatusUnchanged
+				return prev, nil
+			}
+			if dl.backoffErr != nil && errors.Is(err, dl.backoffErr) {
+				// Don't hold a download slot whilst backing off.
+				slots.release()
+				held = false
 				if done, err := backoff.Wait(ctx, nil); done {
 					return result, err
 				}
+				if err := slots.acquire(ctx); err != nil {
+					return result, err
+				}
+				held = true
 				continue
 			}
+			if hasPrev && downloadFS.IsNotExist(err) {
+				result.Status = StatusGone
+			}
 			return result, nil
 		}
 		fi, err := rd.Stat()
@@ -226,6 +337,44 @@ func (dl *downloader) downloadObject(ctx context.Context, downloadFS file.FS, na
 			return result, nil
 		}
 		result.Contents = wr.Bytes()
+		result.Validators = validators(rd, fi, result.Contents, dl.previous != nil)
+		result.Status = status(prev, hasPrev, result.Validators, dl.previous != nil)
 		return result, nil
 	}
 }
+
+// open opens the named object, using a conditional download if a
+// previous result is available and the container supports it.
+func (dl *downloader) open(ctx context.Context, downloadFS file.FS, name string, prev Result, hasPrev bool) (fs.File, error) {
+	if cfs, ok := downloadFS.(ConditionalFS); ok && hasPrev && prev.Validators.Conditional() {
+		return cfs.OpenIfChanged(ctx, name, prev.Validators)
+	}
+	return downloadFS.OpenCtx(ctx, name)
+}
+
+// validators returns the validators for a downloaded object, the digest
+// of its contents is only computed when recrawling.
+func validators(rd fs.File, fi fs.FileInfo, contents []byte, recrawl bool) Validators {
+	var v Validators
+	if vf, ok := rd.(ValidatorsFile); ok {
+		v = vf.Validators()
+	} else {
+		v.LastModified = fi.ModTime()
+	}
+	if recrawl {
+		v.Digest = Digest(contents)
+	}
+	return v
+}
+
+func status(prev Result, hasPrev bool, current Validators, recrawl bool) Status {
+	switch {
+	case !recrawl:
+		return StatusUnknown
+	case !hasPrev:
+		return StatusNew
+	case prev.Validators.Digest == current.Digest:
+		return StatusUnchanged
+	}
+	return StatusChanged
+}
@@ -180,6 +180,145 @@ func TestDownloadRetries(t *testing.T) {
 	}
 }
 
+func TestDownloadRateControllerFor(t *testing.T) {
+	ctx := context.Background()
+
+	numRetries := 2
+	src := rand.NewSource(time.Now().UnixMicro())
+	readFS := filetestutil.NewMockFS(filetestutil.FSWithRandomContentsAfterRetry(src, 8192, numRetries, &retryError{}))
+	input := make(chan download.Request, 10)
+	output := make(chan download.Downloaded, 10)
+
+	var mu sync.Mutex
+	names := map[string]int{}
+	rc := ratecontrol.New(ratecontrol.WithExponentialBackoff(time.Microsecond, 10, false))
+	downloader := download.New(download.WithRateControllerFor(
+		func(_ context.Context, name string) *ratecontrol.Controller {
+			mu.Lock()
+			defer mu.Unlock()
+			names[name]++
+			return rc
+		}, &retryError{}))
+	downloaded, err := runDownloader(ctx, downloader, readFS, input, output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkForDownloadErrors(t, downloaded)
+	if got, want := len(names), 1000; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, d := range downloaded {
+		for _, c := range d.Downloads {
+			if got, want := c.Retries, numRetries; got != want {
+				t.Fatalf("%v: got %v, want %v", c.Name, got, want)
+			}
+		}
+	}
+}
+
+func TestDownloadRateControllerForSlowHost(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	src := rand.NewSource(time.Now().UnixMicro())
+	readFS := filetestutil.NewMockFS(filetestutil.FSWithRandomContents(src, 1024))
+	input := make(chan download.Request, 200)
+	output := make(chan download.Downloaded, 10)
+
+	// The slow host allows a single request per hour, the downloads
+	// from the fast host must not be stalled behind it.
+	slow := ratecontrol.New(ratecontrol.WithRequestsPerTick(time.Hour, 1))
+	fast := ratecontrol.New()
+	downloader := download.New(
+		download.WithNumDownloaders(2),
+		download.WithRateControllerFor(
+			func(_ context.Context, name string) *ratecontrol.Controller {
+				if strings.HasPrefix(name, "slow") {
+					return slow
+				}
+				return fast
+			}, nil))
+
+	nFast := 100
+	for i := range 10 {
+		input <- download.SimpleRequest{FS: readFS, Filenames: []string{fmt.Sprintf("slow-%v", i)}}
+	}
+	for i := range nFast {
+		// Requests that span both hosts are split.
+		input <- download.SimpleRequest{FS: readFS, Filenames: []string{fmt.Sprintf("fast-%v", i), fmt.Sprintf("slow-mixed-%v", i)}}
+	}
+	close(input)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- downloader.Run(ctx, input, output)
+	}()
+
+	fastDownloads := 0
+	for downloaded := range output {
+		for _, dl := range downloaded.Downloads {
+			if dl.Err != nil {
+				t.Errorf("%v: %v", dl.Name, dl.Err)
+			}
+			if strings.HasPrefix(dl.Name, "fast") {
+				fastDownloads++
+			}
+			if got, want := len(downloaded.Request.Names()), 1; got != want {
+				t.Errorf("%v: got %v, want %v", downloaded.Request.Names(), got, want)
+			}
+		}
+		if fastDownloads == nFast {
+			cancel()
+		}
+	}
+	if got, want := fastDownloads, nFast; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := <-errCh; err == nil || !errors.Is(err, context.Canceled) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
+
+func TestDownloadRateControllerForMaxQueued(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	src := rand.NewSource(time.Now().UnixMicro())
+	readFS := filetestutil.NewMockFS(filetestutil.FSWithRandomContents(src, 1024))
+	input := make(chan download.Request, 10)
+	output := make(chan download.Downloaded, 10)
+
+	// Only the first request can be downloaded, the remainder are
+	// queued until the limit is reached.
+	slow := ratecontrol.New(ratecontrol.WithRequestsPerTick(time.Hour, 1))
+	downloader := download.New(
+		download.WithNumDownloaders(2),
+		download.WithMaxQueued(3),
+		download.WithRateControllerFor(
+			func(_ context.Context, _ string) *ratecontrol.Controller {
+				return slow
+			}, nil))
+
+	for i := range cap(input) {
+		input <- download.SimpleRequest{FS: readFS, Filenames: []string{fmt.Sprintf("slow-%v", i)}}
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- downloader.Run(ctx, input, output)
+	}()
+
+	<-output
+	time.Sleep(100 * time.Millisecond)
+	if got, want := len(input), cap(input)-4; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	cancel()
+	if err := <-errCh; err == nil || !errors.Is(err, context.Canceled) {
+		t.Errorf("missing or unexpected error: %v", err)
+	}
+}
+
 func TestDownloadProgress(t *testing.T) {
 	ctx := context.Background()
 
@@ -258,3 +397,104 @@ func TestDownloadErrors(t *testing.T) {
 		}
 	}
 }
+
+type conditionalFS struct {
+	file.FS
+	gone map[string]bool
+}
+
+func (cfs *conditionalFS) OpenCtx(ctx context.Context, name string) (fs.File, error) {
+	if cfs.gone[name] {
+		return nil, fs.ErrNotExist
+	}
+	return cfs.FS.OpenCtx(ctx, name)
+}
+
+func (cfs *conditionalFS) OpenIfChanged(ctx context.Context, name string, validators download.Validators) (fs.File, error) {
+	if validators.ETag == `"same"` {
+		return nil, fmt.Errorf("%v: %w", name, download.ErrNotModified)
+	}
+	return cfs.OpenCtx(ctx, name)
+}
+
+func TestDownloadRecrawl(t *testing.T) {
+	ctx := context.Background()
+	contents := bytes.Repeat([]byte("ab"), 10)
+	readFS := &conditionalFS{
+		FS:   filetestutil.NewMockFS(filetestutil.FSWithConstantContents([]byte("ab"), 10)),
+		gone: map[string]bool{"gone": true},
+	}
+	previous := map[string]download.Result{
+		"unchanged":   {Contents: []byte("cached"), Validators: download.Validators{ETag: `"same"`}},
+		"same-digest": {Validators: download.Validators{ETag: `"old"`, Digest: download.Digest(contents)}},
+		"changed":     {Validators: download.Validators{LastModified: time.Now(), Digest: "old"}},
+		"gone":        {Contents: []byte("cached")},
+	}
+	names := []string{"new", "unchanged", "same-digest", "changed", "gone"}
+
+	run := func(opts ...download.Option) map[string]download.Result {
+		input := make(chan download.Request, 1)
+		output := make(chan download.Downloaded, 1)
+		input <- download.SimpleRequest{FS: readFS, Filenames: names}
+		close(input)
+		if err := download.New(opts...).Run(ctx, input, output); err != nil {
+			t.Fatal(err)
+		}
+		results := map[string]download.Result{}
+		for dl := range output {
+			for _, r := range dl.Downloads {
+				results[r.Name] = r
+			}
+		}
+		return results
+	}
+
+	results := run(download.WithRecrawl(func(_ context.Context, name string) (download.Result, bool) {
+		r, ok := previous[name]
+		return r, ok
+	}))
+	for _, tc := range []struct {
+		name     string
+		status   download.Status
+		contents string
+	}{
+		{"new", download.StatusNew, string(contents)},
+		{"unchanged", download.StatusUnchanged, "cached"},
+		{"same-digest", download.StatusUnchanged, string(contents)},
+		{"changed", download.StatusChanged, string(contents)},
+		{"gone", download.StatusGone, ""},
+	} {
+		r := results[tc.name]
+		if got, want := r.Status, tc.status; got != want {
+			t.Errorf("%v: got %v, want %v", tc.name, got, want)
+		}
+		if got, want := string(r.Contents), tc.contents; got != want {
+			t.Errorf("%v: got %v, want %v", tc.name, got, want)
+		}
+		if got, want := r.Name, tc.name; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+	if err := results["gone"].Err; !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	if got, want := results["new"].Validators.Digest, download.Digest(contents); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Without WithRecrawl, all objects are downloaded, the status
+	// is unknown and no digest is computed.
+	results = run()
+	for _, name := range names[:4] {
+		r := results[name]
+		if got, want := r.Status, download.StatusUnknown; got != want {
+			t.Errorf("%v: got %v, want %v", name, got, want)
+		}
+		if got, want := string(r.Contents), string(contents); got != want {
+			t.Errorf("%v: got %v, want %v", name, got, want)
+		}
+		if r.Validators.LastModified.IsZero() || len(r.Validators.Digest) != 0 {
+			t.Errorf("%v: unexpected validators: %v", name, r.Validators)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"cloudeng.io/algo/ratecontrol"
+	"cloudeng.io/errors"
+	"cloudeng.io/sync/errgroup"
+)
+
+// slots is used to limit the number of concurrent downloads, a nil
+// slots imposes no limit.
+type slots chan struct{}
+
+func (s slots) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case s <- struct{}{}:
+		return nil
+	}
+}
+
+func (s slots) release() {
+	if s != nil {
+		<-s
+	}
+}
+
+// subRequest is a Request for a subset of the names in another Request.
+type subRequest struct {
+	Request
+	names []string
+}
+
+// Names implements Request.
+func (r subRequest) Names() []string {
+	return r.names
+}
+
+// queuedRequest is a request, or a subRequest, that has been queued.
+// done is called once it has been downloaded.
+type queuedRequest struct {
+	Request
+	done func()
+}
+
+// controllerQueue holds the requests, in order, that use the same
+// rate controller.
+type controllerQueue struct {
+	rc       *ratecontrol.Controller
+	requests []queuedRequest // GUARDED_BY(controllerQueues.mu)
+}
+
+// controllerQueues is used when a rate controller is obtained for each
+// object (see WithRateControllerFor). Requests are split by rate
+// controller and each controller's requests are downloaded by a goroutine
+// dedicated to that controller so that waiting on one controller, for
+// example for a host with a long Crawl-delay, does not prevent downloads
+// that use other controllers from making progress. The goroutine exits
+// once its queue is empty. The number of requests read from the input
+// channel but not yet downloaded is limited by queued (see WithMaxQueued)
+// so that the input channel continues to provide backpressure.
+type controllerQueues struct {
+	dl      *downloader
+	slots   slots
+	queued  slots
+	nqueued int64 // updated using atomic.
+	grp     *errgroup.T
+	mu      sync.Mutex
+	queues  map[*ratecontrol.Controller]*controllerQueue // GUARDED_BY(mu)
+}
+
+func (dl *downloader) runQueued(ctx context.Context, input <-chan Request, output chan<- Downloaded) error {
+	grp, ctx := errgroup.WithContext(ctx)
+	maxQueued := dl.maxQueued
+	if maxQueued <= 0 {
+		maxQueued = dl.concurrency * DefaultQueuedPerDownloader
+	}
+	cq := &controllerQueues{
+		dl:     dl,
+		slots:  make(slots, dl.concurrency),
+		queued: make(slots, max(maxQueued, 1)),
+		grp:    grp,
+		queues: map[*ratecontrol.Controller]*controllerQueue{},
+	}
+	// Obtaining a rate controller may be slow, eg. if it requires fetching
+	// robots.txt, and hence multiple dispatchers are used.
+	var dispatchers errgroup.T
+	for i := 0; i < dl.concurrency; i++ {
+		dispatchers.Go(func() error {
+			return cq.dispatch(ctx, input, output)
+		})
+	}
+	// All goroutines are started by the dispatchers and hence they must
+	// be waited on first.
+	var errs errors.M
+	errs.Append(dispatchers.Wait())
+	errs.Append(grp.Wait())
+	return errs.Err()
+}
+
+func (cq *controllerQueues) dispatch(ctx context.Context, input <-chan Request, output chan<- Downloaded) error {
+	for {
+		// Acquire a queue slot before reading from the input channel.
+		if err := cq.queued.acquire(ctx); err != nil {
+			return err
+		}
+		var request Request
+		var ok bool
+		select {
+		case <-ctx.Done():
+			cq.queued.release()
+			return ctx.Err()
+		case request, ok = <-input:
+			if !ok {
+				cq.queued.release()
+				return nil
+			}
+		}
+		if len(request.Names()) == 0 {
+			// ignore empty requests.
+			cq.queued.release()
+			continue
+		}
+		atomic.AddInt64(&cq.nqueued, 1)
+		controllers, requests := cq.split(ctx, request)
+		// The slot is released once all of the requests that request
+		// was split into have been downloaded.
+		remaining := int64(len(requests))
+		done := func() {
+			if atomic.AddInt64(&remaining, -1) == 0 {
+				atomic.AddInt64(&cq.nqueued, -1)
+				cq.queued.release()
+			}
+		}
+		for i, rc := range controllers {
+			cq.enqueue(ctx, rc, queuedRequest{Request: requests[i], done: done}, input, output)
+		}
+	}
+}
+
+// split splits request into a request for the names that use each
+// rate controller, request itself is used if all of its names use the
+// same controller.
+func (cq *controllerQueues) split(ctx context.Context, request Request) ([]*ratecontrol.Controller, []Request) {
+	var controllers []*ratecontrol.Controller
+	names := map[*ratecontrol.Controller][]string{}
+	for _, name := range request.Names() {
+		rc := cq.dl.rateControllerFn(ctx, name)
+		if _, ok := names[rc]; !ok {
+			controllers = append(controllers, rc)
+		}
+		names[rc] = append(names[rc], name)
+	}
+	if len(controllers) == 1 {
+		return controllers, []Request{request}
+	}
+	requests := make([]Request, len(controllers))
+	for i, rc := range controllers {
+		requests[i] = subRequest{Request: request, names: names[rc]}
+	}
+	return controllers, requests
+}
+
+func (cq *controllerQueues) enqueue(ctx context.Context, rc *ratecontrol.Controller, request queuedRequest, input <-chan Request, output chan<- Downloaded) {
+	cq.mu.Lock()
+	q, ok := cq.queues[rc]
+	if !ok {
+		q = &controllerQueue{rc: rc}
+		cq.queues[rc] = q
+	}
+	q.requests = append(q.requests, request)
+	cq.mu.Unlock()
+	if !ok {
+		cq.grp.Go(func() error {
+			return cq.drain(ctx, q, input, output)
+		})
+	}
+}
+
+func (cq *controllerQueues) next(q *controllerQueue) (queuedRequest, bool) {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+	if len(q.requests) == 0 {
+		delete(cq.queues, q.rc)
+		return queuedRequest{}, false
+	}
+	request := q.requests[0]
+	q.requests[0] = queuedRequest{}
+	q.requests = q.requests[1:]
+	return request, true
+}
+
+func (cq *controllerQueues) drain(ctx context.Context, q *controllerQueue, input <-chan Request, output chan<- Downloaded) error {
+	for {
+		request, ok := cq.next(q)
+		if !ok {
+			return nil
+		}
+		downloaded, err := cq.dl.downloadObjects(ctx, request.Request, q.rc, cq.slots)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case output <- downloaded:
+		}
+		request.done()
+		cq.dl.updateProgess(len(downloaded.Downloads), len(input)+int(atomic.LoadInt64(&cq.nqueued)))
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"time"
+)
+
+// ErrNotModified is returned by ConditionalFS.OpenIfChanged when the
+// object has not changed.
+var ErrNotModified = errors.New("not modified")
+
+// Validators are used to determine whether an object has changed since
+// it was last downloaded.
+type Validators struct {
+	// ETag is the entity tag, including any quotes and weak prefix,
+	// as returned by the server, if any.
+	ETag string
+	// LastModified is the time at which the object was last modified,
+	// as reported by the server, if any.
+	LastModified time.Time
+	// Digest is the hex encoded SHA-256 digest of the object's contents,
+	// it is only set when WithRecrawl is used.
+	Digest string
+}
+
+// Conditional returns true if the validators can be used for a
+// conditional download, ie. if either ETag or LastModified is set.
+func (v Validators) Conditional() bool {
+	return len(v.ETag) > 0 || !v.LastModified.IsZero()
+}
+
+// Digest returns the hex encoded SHA-256 digest of contents as used
+// for Validators.Digest.
+func Digest(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
+
+// ConditionalFS may be implemented by a file.FS that supports conditional
+// downloads, for example, using HTTP's If-None-Match and If-Modified-Since
+// headers.
+type ConditionalFS interface {
+	// OpenIfChanged is like file.FS.OpenCtx except that it returns an
+	// error that wraps ErrNotModified if the object has not changed
+	// relative to the supplied validators.
+	OpenIfChanged(ctx context.Context, name string, validators Validators) (fs.File, error)
+}
+
+// ValidatorsFile may be implemented by the fs.File returned by a file.FS
+// to provide the ETag and LastModified validators for the file. If it is
+// not implemented the file's modification time is used as LastModified.
+type ValidatorsFile interface {
+	Validators() Validators
+}
+
+// Status records the state of a downloaded object relative to a previous
+// download of the same object.
+type Status int
+
+const (
+	// StatusUnknown is used when there is no previous download to compare
+	// against, ie. when WithRecrawl is not used.
+	StatusUnknown Status = iota
+	// StatusNew indicates that the object was not previously downloaded.
+	StatusNew
+	// StatusChanged indicates that the object has changed since it was
+	// previously downloaded.
+	StatusChanged
+	// StatusUnchanged indicates that the object has not changed since it
+	// was previously downloaded, the Result's Contents are those of the
+	// previous download.
+	StatusUnchanged
+	// StatusGone indicates that an object that was previously downloaded
+	// no longer exists.
+	StatusGone
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusNew:
+		return "new"
+	case StatusChanged:
+		return "changed"
+	case StatusUnchanged:
+		return "unchanged"
+	case StatusGone:
+		return "gone"
+	}
+	return "unknown"
+}
+
+// Previous is used to look up the result of a previous download of the
+// named object, including its contents. It returns false if there is no
+// such previous download.
+type Previous func(ctx context.Context, name string) (Result, bool)
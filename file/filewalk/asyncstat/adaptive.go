@@ -0,0 +1,189 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package asyncstat
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithAdaptive enables auto-tuning of the async threshold and of the number
+// of concurrent asynchronous stats based on the observed latency of Stat or
+// Lstat operations. The threshold is chosen so that directories are
+// processed synchronously when the latency of each operation is low
+// enough that it is not worth paying the overhead of issuing them
+// asynchronously. The number of concurrent stats is increased whilst the
+// latency remains close to the lowest latency observed and is halved
+// when it exceeds BackoffFactor times that latency, as is typically the
+// case when a network or FUSE filesystem becomes overloaded. The values
+// supplied via WithAsyncThreshold and WithAsyncStats are used as the
+// initial threshold and the maximum number of concurrent stats
+// respectively. The current settings are available via Configuration.
+func WithAdaptive() Option {
+	return func(o *options) {
+		o.adaptive = true
+	}
+}
+
+var (
+	// AsyncOverhead is the estimated cost of issuing the stats for a
+	// directory asynchronously rather than synchronously that is used when
+	// WithAdaptive is specified.
+	AsyncOverhead = 100 * time.Microsecond
+	// BackoffFactor is the factor by which the latency of stat operations
+	// must increase over the lowest observed latency before the number
+	// of concurrent stats is reduced when WithAdaptive is specified.
+	BackoffFactor = 2.0
+	// MaxAdaptiveThreshold is the largest async threshold that will be
+	// chosen when WithAdaptive is specified.
+	MaxAdaptiveThreshold = 100
+)
+
+// latency accumulates the latency of the stat operations issued for
+// a single directory.
+type latency struct {
+	n, total atomic.Int64
+}
+
+func (l *latency) add(d time.Duration) {
+	l.n.Add(1)
+	l.total.Add(int64(d))
+}
+
+// tuner implements the auto-tuning enabled by WithAdaptive.
+type tuner struct {
+	mu          sync.Mutex
+	maxStats    int
+	threshold   int
+	concurrency int
+	latency     time.Duration // moving average of the latency.
+	baseline    time.Duration // the lowest moving average observed.
+	limiter     *limiter
+}
+
+func newTuner(threshold, maxStats int, l *limiter) *tuner {
+	concurrency := max(1, maxStats/4)
+	l.setLimit(concurrency)
+	return &tuner{
+		maxStats:    maxStats,
+		threshold:   threshold,
+		concurrency: concurrency,
+		limiter:     l,
+	}
+}
+
+func (t *tuner) asyncThreshold() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.threshold
+}
+
+// update incorporates the latencies observed for a single directory,
+// async is true if the stats were issued asynchronously.
+func (t *tuner) update(l *latency, async bool) {
+	n := l.n.Load()
+	if n == 0 {
+		return
+	}
+	mean := time.Duration(l.total.Load() / n)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.latency == 0 {
+		t.latency, t.baseline = mean, mean
+	} else {
+		t.latency += (mean - t.latency) / 4
+	}
+	// Allow the baseline to drift upwards slowly so that a permanent
+	// change in the filesystem's latency is eventually accepted.
+	if t.latency < t.baseline {
+		t.baseline = t.latency
+	} else {
+		t.baseline += (t.latency - t.baseline) / 64
+	}
+	t.threshold = MaxAdaptiveThreshold
+	if t.latency > 0 {
+		t.threshold = int(min(int64(MaxAdaptiveThreshold), max(1, int64(AsyncOverhead/t.latency))))
+	}
+	if !async {
+		return
+	}
+	if float64(t.latency) > float64(t.baseline)*BackoffFactor {
+		t.concurrency = max(1, t.concurrency/2)
+	} else {
+		t.concurrency = min(t.maxStats, t.concurrency+max(1, t.concurrency/8))
+	}
+	t.limiter.setLimit(t.concurrency)
+}
+
+func (t *tuner) configuration(cfg *Configuration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cfg.AsyncStats = t.concurrency
+	cfg.AsyncThreshold = t.threshold
+	cfg.Latency = t.latency
+	cfg.BaselineLatency = t.baseline
+}
+
+// limiter limits the number of concurrent stats, its limit may be
+// changed whilst in use.
+type limiter struct {
+	mu       sync.Mutex
+	limit    int
+	inflight int
+	waiters  int
+	ch       chan struct{} // closed when capacity may have become available.
+}
+
+func newLimiter(limit int) *limiter {
+	return &limiter{limit: limit, ch: make(chan struct{})}
+}
+
+func (l *limiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.inflight < l.limit {
+			l.inflight++
+			l.mu.Unlock()
+			return nil
+		}
+		l.waiters++
+		ch := l.ch
+		l.mu.Unlock()
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			l.mu.Lock()
+			l.waiters--
+			l.mu.Unlock()
+			return ctx.Err()
+		}
+		l.mu.Lock()
+		l.waiters--
+		l.mu.Unlock()
+	}
+}
+
+func (l *limiter) done() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inflight--
+	l.notify()
+}
+
+func (l *limiter) setLimit(limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+	l.notify()
+}
+
+func (l *limiter) notify() {
+	if l.waiters > 0 {
+		close(l.ch)
+		l.ch = make(chan struct{})
+	}
+}
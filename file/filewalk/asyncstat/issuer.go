@@ -19,7 +19,8 @@ type T struct {
 	fs      filewalk.FS
 	statFn  func(ctx context.Context, filename string) (file.Info, error)
 	opts    options
-	limitCh chan struct{}
+	limiter *limiter
+	tuner   *tuner
 }
 
 // Option is used to configure an asyncstat.T.
@@ -29,6 +30,7 @@ type options struct {
 	asyncThreshold int
 	asyncStats     int
 	useStat        bool
+	adaptive       bool
 	errLogger      ErrorLogger
 	latencyTracker LatencyTracker
 }
@@ -124,7 +126,10 @@ func New(fs filewalk.FS, opts ...Option) *T {
 	if is.opts.useStat {
 		is.statFn = fs.Stat
 	}
-	is.initLimiter()
+	is.limiter = newLimiter(is.opts.asyncStats)
+	if is.opts.adaptive {
+		is.tuner = newTuner(is.opts.asyncThreshold, is.opts.asyncStats, is.limiter)
+	}
 	return is
 }
 
@@ -132,15 +137,33 @@ func New(fs filewalk.FS, opts ...Option) *T {
 // children as filewalk.Entry and the list of stat/lstat results as
 // a file.InfoList.
 func (is *T) Process(ctx context.Context, prefix string, entries []filewalk.Entry) (children, all file.InfoList, err error) {
-	if len(entries) < is.opts.asyncThreshold {
-		return is.sync(ctx, prefix, entries)
+	if is.tuner == nil {
+		if len(entries) < is.opts.asyncThreshold {
+			return is.sync(ctx, prefix, entries, nil)
+		}
+		return is.async(ctx, prefix, entries, nil)
 	}
-	return is.async(ctx, prefix, entries)
+	lat := &latency{}
+	async := len(entries) >= is.tuner.asyncThreshold()
+	if async {
+		children, all, err = is.async(ctx, prefix, entries, lat)
+	} else {
+		children, all, err = is.sync(ctx, prefix, entries, lat)
+	}
+	is.tuner.update(lat, async)
+	return
 }
 
-func (is *T) callStat(ctx context.Context, filename string) (file.Info, error) {
+func (is *T) callStat(ctx context.Context, filename string, lat *latency) (file.Info, error) {
 	start := is.opts.latencyTracker.Before()
+	var began time.Time
+	if lat != nil {
+		began = time.Now()
+	}
 	info, err := is.statFn(ctx, filename)
+	if lat != nil {
+		lat.add(time.Since(began))
+	}
 	is.opts.latencyTracker.After(start)
 	if err != nil {
 		is.opts.errLogger(ctx, filename, err)
@@ -148,7 +171,7 @@ func (is *T) callStat(ctx context.Context, filename string) (file.Info, error) {
 	return info, err
 }
 
-func (is *T) sync(ctx context.Context, prefix string, entries []filewalk.Entry) (children, all file.InfoList, err error) {
+func (is *T) sync(ctx context.Context, prefix string, entries []filewalk.Entry, lat *latency) (children, all file.InfoList, err error) {
 	for _, entry := range entries {
 		select {
 		case <-ctx.Done():
@@ -156,7 +179,7 @@ func (is *T) sync(ctx context.Context, prefix string, entries []filewalk.Entry)
 		default:
 		}
 		filename := is.fs.Join(prefix, entry.Name)
-		info, err := is.callStat(ctx, filename)
+		info, err := is.callStat(ctx, filename, lat)
 		if err != nil {
 			continue
 		}
@@ -168,32 +191,12 @@ func (is *T) sync(ctx context.Context, prefix string, entries []filewalk.Entry)
 	return children, all, nil
 }
 
-func (is *T) initLimiter() {
-	is.limitCh = make(chan struct{}, is.opts.asyncStats)
-	for i := 0; i < cap(is.limitCh); i++ {
-		is.limitCh <- struct{}{}
-	}
-}
-
-func (is *T) wait(ctx context.Context) error {
-	select {
-	case <-is.limitCh:
-	case <-ctx.Done():
-		return ctx.Err()
-	}
-	return nil
-}
-
-func (is *T) done() {
-	is.limitCh <- struct{}{}
-}
-
 type lstatResult struct {
 	info file.Info
 	err  error
 }
 
-func (is *T) async(ctx context.Context, prefix string, entries []filewalk.Entry) (children, all file.InfoList, err error) {
+func (is *T) async(ctx context.Context, prefix string, entries []filewalk.Entry, lat *latency) (children, all file.InfoList, err error) {
 	concurrency := min(is.opts.asyncStats, len(entries))
 	g, _ := errgroup.WithContext(ctx)
 	g = errgroup.WithConcurrency(g, concurrency)
@@ -206,14 +209,14 @@ func (is *T) async(ctx context.Context, prefix string, entries []filewalk.Entry)
 		name := entry.Name
 		item := seq.NextItem(lstatResult{})
 		filename := is.fs.Join(prefix, name)
-		if err = is.wait(ctx); err != nil {
+		if err = is.limiter.wait(ctx); err != nil {
 			return
 		}
 		g.Go(func() error {
-			info, err := is.callStat(ctx, filename)
+			info, err := is.callStat(ctx, filename, lat)
 			item.V = lstatResult{info, err}
 			ch <- item
-			is.done()
+			is.limiter.done()
 			return nil
 		})
 	}
@@ -241,14 +244,29 @@ func (is *T) async(ctx context.Context, prefix string, entries []filewalk.Entry)
 	return
 }
 
+// Configuration represents the current configuration of an asyncstat.T.
+// When WithAdaptive is specified, AsyncStats and AsyncThreshold are the
+// values currently chosen by the auto-tuning, MaxAsyncStats is the upper
+// bound on AsyncStats and Latency and BaselineLatency are the moving average
+// and lowest observed latency of Stat or Lstat operations.
 type Configuration struct {
-	AsyncStats     int
-	AsyncThreshold int
+	AsyncStats      int
+	AsyncThreshold  int
+	Adaptive        bool
+	MaxAsyncStats   int
+	Latency         time.Duration
+	BaselineLatency time.Duration
 }
 
 func (is *T) Configuration() Configuration {
-	return Configuration{
+	cfg := Configuration{
 		AsyncStats:     is.opts.asyncStats,
 		AsyncThreshold: is.opts.asyncThreshold,
+		Adaptive:       is.tuner != nil,
+		MaxAsyncStats:  is.opts.asyncStats,
+	}
+	if is.tuner != nil {
+		is.tuner.configuration(&cfg)
 	}
+	return cfg
 }
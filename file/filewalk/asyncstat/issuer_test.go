@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -249,3 +250,70 @@ func TestASyncIssue(t *testing.T) {
 		t.Fatalf("is.Process took (%v) longer than would be expected if async ops were issued (%v)", took, latency.took/10)
 	}
 }
+
+type slowFS struct {
+	filewalk.FS
+	delay atomic.Int64
+}
+
+func (s *slowFS) Lstat(ctx context.Context, path string) (file.Info, error) {
+	time.Sleep(time.Duration(s.delay.Load()))
+	return s.FS.Lstat(ctx, path)
+}
+
+func TestAdaptive(t *testing.T) {
+	ctx := context.Background()
+	fs := &slowFS{FS: localfs.New()}
+	tmpdir := t.TempDir()
+	for i := range 50 {
+		if err := os.WriteFile(fs.Join(tmpdir, fmt.Sprintf("file%v", i)), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, infos := entriesFromDir(t, tmpdir, false)
+
+	is := asyncstat.New(fs, asyncstat.WithAdaptive(), asyncstat.WithAsyncStats(40))
+	cfg := is.Configuration()
+	if got, want := cfg.Adaptive, true; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := cfg.AsyncStats, 10; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := cfg.MaxAsyncStats, 40; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	run := func(n int) asyncstat.Configuration {
+		t.Helper()
+		for range n {
+			children, all, err := is.Process(ctx, tmpdir, entries)
+			if err != nil {
+				t.Fatal(err)
+			}
+			verifyEntries(t, "adaptive", children, all, infos)
+		}
+		return is.Configuration()
+	}
+
+	// Slow stats should always be issued asynchronously and the
+	// concurrency should grow to the maximum.
+	fs.delay.Store(int64(time.Millisecond))
+	cfg = run(20)
+	if got, want := cfg.AsyncThreshold, 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := cfg.AsyncStats, 40; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if cfg.Latency < time.Millisecond {
+		t.Errorf("latency too low: %v", cfg.Latency)
+	}
+
+	// A slowdown should lead to a backoff.
+	fs.delay.Store(int64(10 * time.Millisecond))
+	cfg = run(3)
+	if got, want := cfg.AsyncStats, 20; got > want {
+		t.Errorf("got %v, want <= %v", got, want)
+	}
+}
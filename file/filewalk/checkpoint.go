@@ -0,0 +1,206 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package filewalk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"cloudeng.io/file"
+)
+
+// DefaultCheckpointInterval is the default interval between checkpoints
+// used when WithCheckpoint is called with a zero interval.
+var DefaultCheckpointInterval = time.Minute
+
+// Checkpointer is the subset of checkpoint.Operation used to record the
+// progress of a walk. If it also implements
+// Compact(ctx context.Context, label string) error then Compact is
+// called periodically to discard older checkpoints.
+type Checkpointer interface {
+	Checkpoint(ctx context.Context, label string, data []byte) (id string, err error)
+	Latest(ctx context.Context) ([]byte, error)
+	Complete(ctx context.Context) error
+}
+
+type compacter interface {
+	Compact(ctx context.Context, label string) error
+}
+
+// WithCheckpoint requests that the progress of the walk be recorded, at
+// most once per interval, using the supplied Checkpointer, which is
+// typically an initialized checkpoint.Operation. A resumed walk skips
+// completed prefixes and rescans pending or failed ones, calling
+// Handler.Done for a prefix once it completes. The checkpoints are
+// removed via Complete once all of the roots have been completed.
+func WithCheckpoint(op Checkpointer, interval time.Duration) Option {
+	return func(o *options) {
+		o.checkpoint = op
+		o.checkpointInterval = interval
+	}
+}
+
+// walkState is the state recorded in a checkpoint. The completed
+// set is kept small by removing the prefixes immediately beneath
+// a prefix once that prefix is itself completed. The incomplete set
+// contains prefixes that encountered errors, or that have a descendant
+// that did, and is used to prevent their ancestors from being completed.
+type walkState struct {
+	op       Checkpointer
+	interval time.Duration
+
+	mu          sync.Mutex
+	last        time.Time
+	checkpoints int
+	completed   map[string]struct{}
+	pending     map[string]struct{}
+	incomplete  map[string]struct{}
+}
+
+type checkpointState struct {
+	Roots     []string `json:"roots"`
+	Completed []string `json:"completed"`
+	Pending   []string `json:"pending,omitempty"`
+}
+
+// compactPeriod is the number of checkpoints between calls
+// to Compact.
+const compactPeriod = 10
+
+func newWalkState(op Checkpointer, interval time.Duration) *walkState {
+	if interval == 0 {
+		interval = DefaultCheckpointInterval
+	}
+	return &walkState{
+		op:         op,
+		interval:   interval,
+		last:       time.Now(),
+		completed:  map[string]struct{}{},
+		pending:    map[string]struct{}{},
+		incomplete: map[string]struct{}{},
+	}
+}
+
+func (ws *walkState) restore(ctx context.Context) error {
+	buf, err := ws.op.Latest(ctx)
+	if err != nil || len(buf) == 0 {
+		return err
+	}
+	var prev checkpointState
+	if err := json.Unmarshal(buf, &prev); err != nil {
+		return fmt.Errorf("failed to decode checkpoint: %w", err)
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for _, p := range prev.Completed {
+		ws.completed[p] = struct{}{}
+	}
+	return nil
+}
+
+// start returns false if the specified prefix has already been completed
+// and otherwise records it as pending.
+func (ws *walkState) start(prefix string) bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if _, ok := ws.completed[prefix]; ok {
+		return false
+	}
+	ws.pending[prefix] = struct{}{}
+	return true
+}
+
+// stopped is called for prefixes for which Handler.Prefix returned stop.
+func (ws *walkState) stopped(prefix string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	delete(ws.pending, prefix)
+}
+
+// done records the prefix as completed, along with all of its children,
+// and writes a checkpoint if the checkpoint interval has elapsed. A prefix
+// is recorded as incomplete rather than completed if err is not nil or
+// if any of its children are incomplete.
+func (ws *walkState) done(ctx context.Context, fs FS, roots []string, prefix string, children []file.Info, err error) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	delete(ws.pending, prefix)
+	incomplete := err != nil
+	for _, child := range children {
+		cp := fs.Join(prefix, child.Name())
+		if _, ok := ws.incomplete[cp]; ok {
+			incomplete = true
+			delete(ws.incomplete, cp)
+		}
+	}
+	if incomplete {
+		ws.incomplete[prefix] = struct{}{}
+	} else {
+		for _, child := range children {
+			delete(ws.completed, fs.Join(prefix, child.Name()))
+		}
+		ws.completed[prefix] = struct{}{}
+	}
+	if time.Since(ws.last) < ws.interval {
+		return nil
+	}
+	return ws.checkpointLocked(ctx, roots)
+}
+
+func (ws *walkState) isComplete(roots []string) bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for _, r := range roots {
+		if _, ok := ws.completed[r]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (ws *walkState) checkpoint(ctx context.Context, roots []string) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.checkpointLocked(ctx, roots)
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (ws *walkState) checkpointLocked(ctx context.Context, roots []string) error {
+	// Record the checkpoint even if the context has been canceled so that
+	// an interrupted walk can be resumed.
+	ctx = context.WithoutCancel(ctx)
+	buf, err := json.Marshal(checkpointState{
+		Roots:     roots,
+		Completed: sortedKeys(ws.completed),
+		Pending:   sortedKeys(ws.pending),
+	})
+	if err != nil {
+		return err
+	}
+	ws.last = time.Now()
+	label := fmt.Sprintf("-%d-completed", len(ws.completed))
+	if _, err := ws.op.Checkpoint(ctx, label, buf); err != nil {
+		return fmt.Errorf("failed to checkpoint walk: %w", err)
+	}
+	ws.checkpoints++
+	if c, ok := ws.op.(compacter); ok && ws.checkpoints%compactPeriod == 0 {
+		if err := c.Compact(ctx, label); err != nil {
+			return fmt.Errorf("failed to compact checkpoints: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package filewalk_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"cloudeng.io/file"
+	"cloudeng.io/file/checkpoint"
+	"cloudeng.io/file/filewalk"
+	"cloudeng.io/file/localfs"
+)
+
+type doneCounter struct {
+	sync.Mutex
+	fs       filewalk.FS
+	done     map[string]int
+	cancelAt int
+	cancel   func()
+	ncalls   int
+	contents map[string]int
+}
+
+func (d *doneCounter) Prefix(_ context.Context, _ *struct{}, _ string, _ file.Info, err error) (bool, file.InfoList, error) {
+	return false, nil, err
+}
+
+func (d *doneCounter) Contents(ctx context.Context, _ *struct{}, prefix string, contents []filewalk.Entry) (file.InfoList, error) {
+	d.Lock()
+	d.contents[prefix]++
+	d.Unlock()
+	children := file.InfoList{}
+	for _, de := range contents {
+		if !de.IsDir() {
+			continue
+		}
+		info, err := d.fs.Lstat(ctx, d.fs.Join(prefix, de.Name))
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, info)
+	}
+	return children, nil
+}
+
+func (d *doneCounter) Done(_ context.Context, _ *struct{}, prefix string, err error) error {
+	d.Lock()
+	defer d.Unlock()
+	d.done[prefix]++
+	d.ncalls++
+	if d.ncalls == d.cancelAt {
+		d.cancel()
+	}
+	return err
+}
+
+func createCheckpointTree(t *testing.T, root string, depth, width int) []string {
+	t.Helper()
+	dirs := []string{root}
+	if depth == 0 {
+		return dirs
+	}
+	for i := range width {
+		dir := filepath.Join(root, fmt.Sprintf("d%v", i))
+		if err := os.Mkdir(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "f"), []byte("f"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		dirs = append(dirs, createCheckpointTree(t, dir, depth-1, width)...)
+	}
+	return dirs
+}
+
+func TestCheckpoint(t *testing.T) {
+	tmpDir := t.TempDir()
+	root := filepath.Join(tmpDir, "tree")
+	if err := os.Mkdir(root, 0700); err != nil {
+		t.Fatal(err)
+	}
+	dirs := createCheckpointTree(t, root, 3, 4)
+
+	op := checkpoint.NewDirectoryOperation()
+	if err := op.Init(context.Background(), filepath.Join(tmpDir, "checkpoint")); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := localfs.New()
+	dc := &doneCounter{
+		fs:       fs,
+		done:     map[string]int{},
+		contents: map[string]int{},
+	}
+
+	for _, cancelAt := range []int{10, 30, 0} {
+		ctx, cancel := context.WithCancel(context.Background())
+		dc.cancel, dc.cancelAt, dc.ncalls = cancel, cancelAt, 0
+		wk := filewalk.New(fs, dc, filewalk.WithConcurrentScans(2), filewalk.WithCheckpoint(op, 1))
+		err := wk.Walk(ctx, root)
+		cancel()
+		if cancelAt == 0 {
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected a cancellation error: %v", err)
+		}
+		buf, err := op.Latest(context.Background())
+		if err != nil || len(buf) == 0 {
+			t.Fatalf("missing checkpoint: %v", err)
+		}
+	}
+
+	if got, want := len(dc.done), len(dirs); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, dir := range dirs {
+		if got, want := dc.done[dir], 1; got != want {
+			t.Errorf("%v: got %v, want %v", dir, got, want)
+		}
+	}
+	// Some prefixes will have been scanned more than once, but not all.
+	rescanned := 0
+	for _, n := range dc.contents {
+		if n > 1 {
+			rescanned++
+		}
+	}
+	if rescanned == 0 || rescanned == len(dirs) {
+		t.Errorf("unexpected number of rescanned prefixes: %v", rescanned)
+	}
+	if buf, err := op.Latest(context.Background()); err != nil || buf != nil {
+		t.Errorf("unexpected checkpoint: %s: %v", buf, err)
+	}
+}
+
+type blockingCounter struct {
+	*doneCounter
+	block string
+}
+
+func (b *blockingCounter) Contents(ctx context.Context, state *struct{}, prefix string, contents []filewalk.Entry) (file.InfoList, error) {
+	if prefix == b.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return b.doneCounter.Contents(ctx, state, prefix, contents)
+}
+
+func TestCheckpointDeadline(t *testing.T) {
+	tmpDir := t.TempDir()
+	root := filepath.Join(tmpDir, "tree")
+	if err := os.Mkdir(root, 0700); err != nil {
+		t.Fatal(err)
+	}
+	dirs := createCheckpointTree(t, root, 2, 3)
+
+	op := checkpoint.NewDirectoryOperation()
+	if err := op.Init(context.Background(), filepath.Join(tmpDir, "checkpoint")); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := localfs.New()
+	dc := &doneCounter{
+		fs:       fs,
+		done:     map[string]int{},
+		contents: map[string]int{},
+	}
+	bc := &blockingCounter{doneCounter: dc, block: filepath.Join(root, "d1")}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	err := filewalk.New(fs, bc, filewalk.WithCheckpoint(op, 1)).Walk(ctx, root)
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline exceeded error: %v", err)
+	}
+	if got, want := dc.done[bc.block], 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	bc.block = ""
+	if err := filewalk.New(fs, bc, filewalk.WithCheckpoint(op, 1)).Walk(context.Background(), root); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		if got, want := dc.done[dir], 1; got != want {
+			t.Errorf("%v: got %v, want %v", dir, got, want)
+		}
+	}
+	if buf, err := op.Latest(context.Background()); err != nil || buf != nil {
+		t.Errorf("unexpected checkpoint: %s: %v", buf, err)
+	}
+}
+
+type failingCounter struct {
+	*doneCounter
+	fail string
+}
+
+func (f *failingCounter) Prefix(ctx context.Context, state *struct{}, prefix string, info file.Info, err error) (bool, file.InfoList, error) {
+	if prefix == f.fail {
+		return false, nil, os.ErrPermission
+	}
+	return f.doneCounter.Prefix(ctx, state, prefix, info, err)
+}
+
+func TestCheckpointErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	root := filepath.Join(tmpDir, "tree")
+	if err := os.Mkdir(root, 0700); err != nil {
+		t.Fatal(err)
+	}
+	dirs := createCheckpointTree(t, root, 2, 3)
+
+	op := checkpoint.NewDirectoryOperation()
+	if err := op.Init(context.Background(), filepath.Join(tmpDir, "checkpoint")); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := localfs.New()
+	dc := &doneCounter{
+		fs:       fs,
+		done:     map[string]int{},
+		contents: map[string]int{},
+	}
+	failed := filepath.Join(root, "d1", "d2")
+	fc := &failingCounter{doneCounter: dc, fail: failed}
+
+	err := filewalk.New(fs, fc, filewalk.WithCheckpoint(op, 1)).Walk(context.Background(), root)
+	if !errors.Is(err, os.ErrPermission) {
+		t.Fatalf("expected a permission error: %v", err)
+	}
+	// The checkpoint must remain since the walk did not complete.
+	if buf, err := op.Latest(context.Background()); err != nil || len(buf) == 0 {
+		t.Fatalf("missing checkpoint: %v", err)
+	}
+
+	fc.fail = ""
+	if err := filewalk.New(fs, fc, filewalk.WithCheckpoint(op, 1)).Walk(context.Background(), root); err != nil {
+		t.Fatal(err)
+	}
+	// The failed prefix and its ancestors are retried, all others
+	// are skipped.
+	retried := map[string]bool{root: true, filepath.Join(root, "d1"): true, failed: true}
+	for _, dir := range dirs {
+		want := 1
+		if retried[dir] {
+			want = 2
+		}
+		if got := dc.done[dir]; got != want {
+			t.Errorf("%v: got %v, want %v", dir, got, want)
+		}
+	}
+	if buf, err := op.Latest(context.Background()); err != nil || buf != nil {
+		t.Errorf("unexpected checkpoint: %s: %v", buf, err)
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package incremental provides support for incremental walks of a
+// filewalk.FS whereby the contents of each prefix (directory) are compared
+// against a snapshot recorded by a previous walk in order to determine
+// which files and prefixes have been added, removed or modified.
+package incremental
+
+import (
+	"context"
+	"fmt"
+
+	"cloudeng.io/file"
+	"cloudeng.io/file/filewalk"
+	"cloudeng.io/file/filewalk/asyncstat"
+)
+
+// EventType represents the type of a change.
+type EventType int
+
+const (
+	Added EventType = iota
+	Removed
+	Modified
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	}
+	return fmt.Sprintf("unknown(%d)", int(t))
+}
+
+// Event represents a change to a single file or prefix within a prefix.
+type Event struct {
+	Type   EventType
+	Prefix string
+	// Info is the current file.Info for Added and Modified events and
+	// the previously recorded file.Info for Removed events.
+	Info file.Info
+	// Previous is the previously recorded file.Info for Modified events.
+	Previous file.Info
+}
+
+// EventHandler is called with the events for a single prefix, possibly
+// concurrently for different prefixes. An error returned by the handler
+// is recorded and returned by the walk and the snapshot for that prefix
+// is not updated so that the same events will be reported by a
+// subsequent walk.
+type EventHandler func(ctx context.Context, prefix string, events []Event) error
+
+// UnchangedDetector may be implemented by filesystems for which it is safe
+// to determine that a prefix, and all of the prefixes and files beneath it,
+// are unchanged by comparing its current file.Info against that recorded
+// by a previous walk. For example, a filesystem whose directory modification
+// times are updated whenever any of their descendants are changed.
+// Note that this is not the case for POSIX filesystems where the modification
+// time of a directory only reflects changes to its immediate entries.
+type UnchangedDetector interface {
+	Unchanged(prefix string, previous, current file.Info) bool
+}
+
+// UnchangedFunc is a function that implements UnchangedDetector.
+type UnchangedFunc func(prefix string, previous, current file.Info) bool
+
+// Unchanged implements UnchangedDetector.
+func (fn UnchangedFunc) Unchanged(prefix string, previous, current file.Info) bool {
+	return fn(prefix, previous, current)
+}
+
+// Option represents an option to New.
+type Option func(o *options)
+
+type options struct {
+	unchanged UnchangedDetector
+	statOpts  []asyncstat.Option
+}
+
+// WithUnchangedDetector sets the UnchangedDetector to be used, overriding
+// any implemented by the filesystem. Prefixes that are determined to be
+// unchanged are not traversed and no events are reported for them.
+func WithUnchangedDetector(d UnchangedDetector) Option {
+	return func(o *options) {
+		o.unchanged = d
+	}
+}
+
+// WithAsyncStatOptions sets the options used for the asyncstat.T used to
+// obtain the file.Info for the contents of each prefix.
+func WithAsyncStatOptions(opts ...asyncstat.Option) Option {
+	return func(o *options) {
+		o.statOpts = append(o.statOpts, opts...)
+	}
+}
+
+// Handler implements filewalk.Handler to compare the current contents of
+// each prefix against the snapshot recorded by a previous walk, reporting
+// the differences to an EventHandler and recording the new snapshot. When
+// a prefix is removed, or replaced by a file, Removed events are reported
+// for all of its previously recorded contents, recursively, and their
+// snapshots are deleted. Modified events are reported for files whose
+// size, mode or modification time have changed and for prefixes whose
+// mode has changed; changes to the contents of a prefix are reported via
+// events for that prefix.
+type Handler struct {
+	options
+	fs     filewalk.FS
+	store  Store
+	events EventHandler
+	stat   *asyncstat.T
+}
+
+var _ filewalk.Handler[PrefixState] = (*Handler)(nil)
+
+// PrefixState is the per-prefix state used by Handler.
+type PrefixState struct {
+	info     file.Info
+	previous Snapshot
+	found    bool
+	current  file.InfoList
+}
+
+// New returns a new Handler for use with filewalk.New.
+func New(fs filewalk.FS, store Store, events EventHandler, opts ...Option) *Handler {
+	h := &Handler{fs: fs, store: store, events: events}
+	if d, ok := fs.(UnchangedDetector); ok {
+		h.unchanged = d
+	}
+	for _, fn := range opts {
+		fn(&h.options)
+	}
+	h.stat = asyncstat.New(fs, h.statOpts...)
+	return h
+}
+
+// Prefix implements filewalk.Handler.
+func (h *Handler) Prefix(ctx context.Context, state *PrefixState, prefix string, info file.Info, err error) (bool, file.InfoList, error) {
+	if err != nil {
+		return false, nil, err
+	}
+	state.info = info
+	state.previous, state.found, err = h.store.Get(ctx, prefix)
+	if err != nil {
+		return false, nil, err
+	}
+	if state.found && h.unchanged != nil && h.unchanged.Unchanged(prefix, state.previous.Info, info) {
+		return true, nil, nil
+	}
+	return false, nil, nil
+}
+
+// Contents implements filewalk.Handler.
+func (h *Handler) Contents(ctx context.Context, state *PrefixState, prefix string, contents []filewalk.Entry) (file.InfoList, error) {
+	children, all, err := h.stat.Process(ctx, prefix, contents)
+	if err != nil {
+		return nil, err
+	}
+	state.current = append(state.current, all...)
+	return children, nil
+}
+
+// Done implements filewalk.Handler.
+func (h *Handler) Done(ctx context.Context, state *PrefixState, prefix string, err error) error {
+	if err != nil {
+		return err
+	}
+	events := Diff(prefix, state.previous.Contents, state.current)
+	if len(events) > 0 {
+		if err := h.events(ctx, prefix, events); err != nil {
+			return err
+		}
+	}
+	for _, ev := range events {
+		if ev.Type == Removed && ev.Info.IsDir() {
+			if err := h.removeTree(ctx, h.fs.Join(prefix, ev.Info.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return h.store.Put(ctx, prefix, Snapshot{Info: state.info, Contents: state.current})
+}
+
+// removeTree reports Removed events for the recorded contents of the
+// specified prefix, and of all of the prefixes beneath it, and deletes
+// their snapshots.
+func (h *Handler) removeTree(ctx context.Context, prefix string) error {
+	snap, ok, err := h.store.Get(ctx, prefix)
+	if err != nil || !ok {
+		return err
+	}
+	if len(snap.Contents) > 0 {
+		events := make([]Event, len(snap.Contents))
+		for i, info := range snap.Contents {
+			events[i] = Event{Type: Removed, Prefix: prefix, Info: info}
+		}
+		if err := h.events(ctx, prefix, events); err != nil {
+			return err
+		}
+	}
+	for _, info := range snap.Contents {
+		if info.IsDir() {
+			if err := h.removeTree(ctx, h.fs.Join(prefix, info.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return h.store.Delete(ctx, prefix)
+}
+
+// Diff compares the previous and current contents of a prefix and returns
+// the events that represent the differences between them. Added and
+// Modified events are returned in the order they appear in current,
+// followed by Removed events in the order they appear in previous. A change
+// of type, eg. from a file to a directory, is reported as a Removed event
+// followed by an Added event.
+func Diff(prefix string, previous, current file.InfoList) []Event {
+	prev := make(map[string]file.Info, len(previous))
+	for _, info := range previous {
+		prev[info.Name()] = info
+	}
+	var events, removed []Event
+	seen := make(map[string]bool, len(current))
+	for _, info := range current {
+		seen[info.Name()] = true
+		p, ok := prev[info.Name()]
+		switch {
+		case !ok:
+			events = append(events, Event{Type: Added, Prefix: prefix, Info: info})
+		case p.Type() != info.Type():
+			removed = append(removed, Event{Type: Removed, Prefix: prefix, Info: p})
+			events = append(events, Event{Type: Added, Prefix: prefix, Info: info})
+		case modified(p, info):
+			events = append(events, Event{Type: Modified, Prefix: prefix, Info: info, Previous: p})
+		}
+	}
+	for _, info := range previous {
+		if !seen[info.Name()] {
+			removed = append(removed, Event{Type: Removed, Prefix: prefix, Info: info})
+		}
+	}
+	return append(events, removed...)
+}
+
+func modified(previous, current file.Info) bool {
+	if previous.Mode() != current.Mode() {
+		return true
+	}
+	if current.IsDir() {
+		return false
+	}
+	return previous.Size() != current.Size() || !previous.ModTime().Equal(current.ModTime())
+}
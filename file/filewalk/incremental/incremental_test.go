@@ -0,0 +1,244 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package incremental_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"cloudeng.io/file"
+	"cloudeng.io/file/filewalk"
+	"cloudeng.io/file/filewalk/incremental"
+	"cloudeng.io/file/localfs"
+)
+
+type collector struct {
+	sync.Mutex
+	root   string
+	events []string
+}
+
+func (c *collector) handle(_ context.Context, prefix string, events []incremental.Event) error {
+	c.Lock()
+	defer c.Unlock()
+	for _, ev := range events {
+		rel, _ := filepath.Rel(c.root, filepath.Join(prefix, ev.Info.Name()))
+		if ev.Info.IsDir() {
+			rel += "/"
+		}
+		c.events = append(c.events, fmt.Sprintf("%v %v", ev.Type, filepath.ToSlash(rel)))
+	}
+	return nil
+}
+
+func (c *collector) get() []string {
+	c.Lock()
+	defer c.Unlock()
+	ev := c.events
+	c.events = nil
+	slices.Sort(ev)
+	return ev
+}
+
+func writeFile(t *testing.T, name, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func walk(ctx context.Context, t *testing.T, store incremental.Store, c *collector, opts ...incremental.Option) {
+	t.Helper()
+	fs := localfs.New()
+	h := incremental.New(fs, store, c.handle, opts...)
+	if err := filewalk.New(fs, h).Walk(ctx, c.root); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIncremental(t *testing.T) {
+	ctx := context.Background()
+	for _, store := range []incremental.Store{
+		incremental.NewMemStore(),
+		must(incremental.NewDirStore(filepath.Join(t.TempDir(), "store"))),
+	} {
+		root := t.TempDir()
+		c := &collector{root: root}
+		writeFile(t, filepath.Join(root, "a"), "a")
+		writeFile(t, filepath.Join(root, "b", "c"), "c")
+		writeFile(t, filepath.Join(root, "b", "d", "e"), "e")
+		writeFile(t, filepath.Join(root, "f", "g"), "g")
+
+		walk(ctx, t, store, c)
+		if got, want := c.get(), []string{
+			"added a", "added b/", "added b/c", "added b/d/", "added b/d/e", "added f/", "added f/g",
+		}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		// No changes.
+		walk(ctx, t, store, c)
+		if got := c.get(); len(got) != 0 {
+			t.Errorf("unexpected events: %v", got)
+		}
+
+		writeFile(t, filepath.Join(root, "a"), "aa")
+		writeFile(t, filepath.Join(root, "b", "d", "h"), "h")
+		if err := os.RemoveAll(filepath.Join(root, "b", "c")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(filepath.Join(root, "f"), 0750); err != nil {
+			t.Fatal(err)
+		}
+		walk(ctx, t, store, c)
+		if got, want := c.get(), []string{
+			"added b/d/h", "modified a", "modified f/", "removed b/c",
+		}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+
+		// Removing a directory reports all of its previous contents as
+		// removed.
+		if err := os.RemoveAll(filepath.Join(root, "b")); err != nil {
+			t.Fatal(err)
+		}
+		// Replace a directory with a file.
+		if err := os.RemoveAll(filepath.Join(root, "f")); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(root, "f"), "f")
+		walk(ctx, t, store, c)
+		if got, want := c.get(), []string{
+			"added f", "removed b/", "removed b/d/", "removed b/d/e", "removed b/d/h", "removed f/", "removed f/g",
+		}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if ms, ok := store.(*incremental.MemStore); ok {
+			if got, want := ms.Len(), 1; got != want {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		}
+	}
+}
+
+func TestUnchanged(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	c := &collector{root: root}
+	writeFile(t, filepath.Join(root, "a", "b"), "b")
+	writeFile(t, filepath.Join(root, "c", "d"), "d")
+	store := incremental.NewMemStore()
+
+	// Treat every directory other than the root as unchanged if its
+	// modification time is unchanged.
+	skipped := 0
+	unchanged := incremental.UnchangedFunc(func(prefix string, previous, current file.Info) bool {
+		if prefix == root || !previous.ModTime().Equal(current.ModTime()) {
+			return false
+		}
+		skipped++
+		return true
+	})
+	walk(ctx, t, store, c, incremental.WithUnchangedDetector(unchanged))
+	c.get()
+	if got, want := skipped, 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Changing a file within a directory without changing the directory's
+	// modification time is not detected.
+	writeFile(t, filepath.Join(root, "a", "b"), "bb")
+	writeFile(t, filepath.Join(root, "c", "e"), "e")
+	walk(ctx, t, store, c, incremental.WithUnchangedDetector(unchanged))
+	if got, want := c.get(), []string{"added c/e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := skipped, 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	now := time.Now()
+	f := func(name string, size int64) file.Info {
+		return file.NewInfo(name, size, 0600, now, nil)
+	}
+	d := func(name string) file.Info {
+		return file.NewInfo(name, 0, os.ModeDir|0700, now, nil)
+	}
+	prev := file.InfoList{f("a", 1), f("b", 1), d("c"), f("d", 1), d("e")}
+	cur := file.InfoList{f("a", 1), f("b", 2), d("c"), d("d"), f("e", 1), f("f", 1)}
+	var got []string
+	for _, ev := range incremental.Diff("p", prev, cur) {
+		got = append(got, fmt.Sprintf("%v %v/%v %v", ev.Type, ev.Prefix, ev.Info.Name(), ev.Info.IsDir()))
+	}
+	want := []string{
+		"modified p/b false",
+		"added p/d true",
+		"added p/e false",
+		"added p/f false",
+		"removed p/d false",
+		"removed p/e true",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	snap := incremental.Snapshot{
+		Info: file.NewInfo("dir", 0, os.ModeDir|0700, now, nil),
+		Contents: file.InfoList{
+			file.NewInfo("a", 10, 0600, now, nil),
+			file.NewInfo("b", 0, os.ModeDir|0700, now, nil),
+		},
+	}
+	for _, store := range []incremental.Store{
+		incremental.NewMemStore(),
+		must(incremental.NewDirStore(t.TempDir())),
+	} {
+		if _, ok, err := store.Get(ctx, "/a/b"); err != nil || ok {
+			t.Errorf("unexpected snapshot: %v %v", ok, err)
+		}
+		if err := store.Put(ctx, "/a/b", snap); err != nil {
+			t.Fatal(err)
+		}
+		got, ok, err := store.Get(ctx, "/a/b")
+		if err != nil || !ok {
+			t.Fatalf("missing snapshot: %v %v", ok, err)
+		}
+		if !reflect.DeepEqual(got, snap) {
+			t.Errorf("got %v, want %v", got, snap)
+		}
+		if err := store.Delete(ctx, "/a/b"); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok, err := store.Get(ctx, "/a/b"); err != nil || ok {
+			t.Errorf("unexpected snapshot: %v %v", ok, err)
+		}
+		if err := store.Delete(ctx, "/a/b"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
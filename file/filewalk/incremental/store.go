@@ -0,0 +1,193 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package incremental
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"cloudeng.io/file"
+)
+
+// Snapshot represents the state of a single prefix, ie. directory, as
+// recorded by a previous walk.
+type Snapshot struct {
+	Info     file.Info     // The file.Info for the prefix itself.
+	Contents file.InfoList // The contents of the prefix, files and directories.
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler using the binary
+// encodings provided by file.Info and file.InfoList.
+func (s Snapshot) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(100 * (len(s.Contents) + 1))
+	buf.WriteByte(0x1) // version
+	if err := s.Info.AppendBinary(&buf); err != nil {
+		return nil, err
+	}
+	if err := s.Contents.AppendBinary(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (s *Snapshot) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("incremental.Snapshot: insufficient data")
+	}
+	if data[0] != 0x1 {
+		return fmt.Errorf("incremental.Snapshot: invalid version of binary encoding: got %x, want %x", data[0], 0x1)
+	}
+	data, err := s.Info.DecodeBinary(data[1:])
+	if err != nil {
+		return err
+	}
+	s.Contents, _, err = file.DecodeBinaryInfoList(data)
+	return err
+}
+
+// Store represents a store of per-prefix snapshots. Implementations must
+// be safe for concurrent use.
+type Store interface {
+	// Get returns the snapshot for the specified prefix, if any.
+	Get(ctx context.Context, prefix string) (Snapshot, bool, error)
+	// Put records the snapshot for the specified prefix.
+	Put(ctx context.Context, prefix string, snapshot Snapshot) error
+	// Delete removes the snapshot, if any, for the specified prefix.
+	Delete(ctx context.Context, prefix string) error
+}
+
+// MemStore is an in-memory implementation of Store.
+type MemStore struct {
+	mu        sync.Mutex
+	snapshots map[string][]byte
+}
+
+// NewMemStore returns a new in-memory Store.
+func NewMemStore() *MemStore {
+	return &MemStore{snapshots: map[string][]byte{}}
+}
+
+// Get implements Store.
+func (m *MemStore) Get(_ context.Context, prefix string) (Snapshot, bool, error) {
+	m.mu.Lock()
+	buf, ok := m.snapshots[prefix]
+	m.mu.Unlock()
+	if !ok {
+		return Snapshot{}, false, nil
+	}
+	var s Snapshot
+	if err := s.UnmarshalBinary(buf); err != nil {
+		return Snapshot{}, false, err
+	}
+	return s, true, nil
+}
+
+// Put implements Store.
+func (m *MemStore) Put(_ context.Context, prefix string, snapshot Snapshot) error {
+	buf, err := snapshot.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.snapshots[prefix] = buf
+	return nil
+}
+
+// Delete implements Store.
+func (m *MemStore) Delete(_ context.Context, prefix string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.snapshots, prefix)
+	return nil
+}
+
+// Len returns the number of snapshots in the store.
+func (m *MemStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.snapshots)
+}
+
+// DirStore is an implementation of Store that stores each snapshot in
+// a separate file within a local directory. The filename used for each
+// prefix is derived from the sha256 hash of the prefix.
+type DirStore struct {
+	dir string
+}
+
+// NewDirStore returns a new DirStore that uses the specified directory,
+// which will be created if necessary.
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DirStore{dir: dir}, nil
+}
+
+func (d *DirStore) filename(prefix string) string {
+	sum := sha256.Sum256([]byte(prefix))
+	h := hex.EncodeToString(sum[:])
+	return filepath.Join(d.dir, h[:2], h[2:])
+}
+
+// Get implements Store.
+func (d *DirStore) Get(_ context.Context, prefix string) (Snapshot, bool, error) {
+	buf, err := os.ReadFile(d.filename(prefix))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Snapshot{}, false, nil
+		}
+		return Snapshot{}, false, err
+	}
+	var s Snapshot
+	if err := s.UnmarshalBinary(buf); err != nil {
+		return Snapshot{}, false, fmt.Errorf("%v: %w", prefix, err)
+	}
+	return s, true, nil
+}
+
+// Put implements Store. The snapshot is written to a temporary file
+// that is then renamed.
+func (d *DirStore) Put(_ context.Context, prefix string, snapshot Snapshot) error {
+	buf, err := snapshot.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	filename := d.filename(prefix)
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
+
+// Delete implements Store.
+func (d *DirStore) Delete(_ context.Context, prefix string) error {
+	if err := os.Remove(d.filename(prefix)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
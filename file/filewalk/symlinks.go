@@ -0,0 +1,165 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package filewalk
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"time"
+
+	"cloudeng.io/file"
+)
+
+var (
+	// ErrSymlinkLoop is reported for symbolic links that refer to one of
+	// the prefixes that contain them.
+	ErrSymlinkLoop = errors.New("symbolic link loop")
+	// ErrDanglingSymlink is reported for symbolic links whose targets
+	// do not exist.
+	ErrDanglingSymlink = errors.New("dangling symbolic link")
+)
+
+// WithFollowSymlinks requests that symbolic links to prefixes/directories
+// be followed. Links are resolved using Stat and the prefixes they refer to
+// are traversed as if they were contained in the prefix containing the
+// link, under the name of the link, and count towards the depth limit set
+// by WithDepth in the same way. Roots that are symbolic links are also
+// followed.
+//
+// Cycles are detected by comparing the device and file ids, as reported by
+// FS.XAttr, of the target of each link with those of the prefixes that
+// contain it. Filesystems that do not report file ids cannot detect
+// cycles and WithDepth should be used to bound the walk.
+//
+// Links that refer to a containing prefix, links whose targets do not exist
+// and links that cannot be resolved are reported to the Handler via a call
+// to Prefix with the file.Info for the link itself and a *SymlinkError;
+// such links are never traversed. As for all other prefixes, Done will be
+// called unless Prefix returns true for stop and the walk will only fail
+// if the Handler returns an error.
+func WithFollowSymlinks() Option {
+	return func(o *options) {
+		o.followSymlinks = true
+	}
+}
+
+// SymlinkError is used to report symbolic links that cannot be followed.
+type SymlinkError struct {
+	Path   string
+	Target string
+	Err    error
+}
+
+// Error implements error.
+func (e *SymlinkError) Error() string {
+	return e.Path + " -> " + e.Target + ": " + e.Err.Error()
+}
+
+// Unwrap implements errors.Unwrap.
+func (e *SymlinkError) Unwrap() error {
+	return e.Err
+}
+
+type fileID struct {
+	device, id uint64
+}
+
+// ancestor records the file ids of the prefixes that lead to the current
+// prefix.
+type ancestor struct {
+	id     fileID
+	parent *ancestor
+}
+
+func (a *ancestor) contains(id fileID) bool {
+	for ; a != nil; a = a.parent {
+		if a.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// push returns a new ancestor for the specified prefix, or parent if
+// the prefix's file id is not available.
+func (w *Walker[T]) push(ctx context.Context, parent *ancestor, path string, info file.Info) *ancestor {
+	id, ok := w.fileID(ctx, path, info)
+	if !ok {
+		return parent
+	}
+	return &ancestor{id: id, parent: parent}
+}
+
+func (w *Walker[T]) fileID(ctx context.Context, path string, info file.Info) (fileID, bool) {
+	xattr, err := w.fs.XAttr(ctx, path, info)
+	if err != nil || xattr.FileID == 0 {
+		return fileID{}, false
+	}
+	return fileID{device: xattr.Device, id: xattr.FileID}, true
+}
+
+// symlink represents a symbolic link that cannot be followed.
+type symlink struct {
+	path string
+	info file.Info
+	err  error
+}
+
+// followSymlinks resolves the symbolic links in entries, returning the
+// file.Info, named for the link, of those that refer to prefixes that
+// can be traversed and those that cannot be followed.
+func (w *Walker[T]) followSymlinks(ctx context.Context, prefix string, anc *ancestor, entries []Entry) ([]file.Info, []symlink) {
+	var children []file.Info
+	var failed []symlink
+	for _, e := range entries {
+		if e.Type&fs.ModeSymlink == 0 {
+			continue
+		}
+		path := w.fs.Join(prefix, e.Name)
+		info, err := w.fs.Stat(ctx, path)
+		if err == nil && !info.IsDir() {
+			continue
+		}
+		if err == nil {
+			if id, ok := w.fileID(ctx, path, info); ok && anc.contains(id) {
+				err = ErrSymlinkLoop
+			}
+		} else if w.fs.IsNotExist(err) {
+			err = ErrDanglingSymlink
+		}
+		if err == nil {
+			children = append(children, file.NewInfo(e.Name, info.Size(), info.Mode(), info.ModTime(), info.Sys()))
+			continue
+		}
+		linfo, lerr := w.fs.Lstat(ctx, path)
+		if lerr != nil {
+			linfo = file.NewInfo(e.Name, 0, fs.ModeSymlink, time.Time{}, nil)
+		}
+		target, _ := w.fs.Readlink(ctx, path)
+		failed = append(failed, symlink{
+			path: path,
+			info: linfo,
+			err:  &SymlinkError{Path: path, Target: target, Err: err},
+		})
+	}
+	return children, failed
+}
+
+// reportSymlink reports a symbolic link that cannot be followed to the
+// Handler.
+func (w *Walker[T]) reportSymlink(ctx context.Context, link symlink, depth int) {
+	if w.opts.depth >= 0 && depth > w.opts.depth {
+		return
+	}
+	var state T
+	stop, _, _ := w.handler.Prefix(ctx, &state, link.path, link.info, link.err)
+	if stop {
+		return
+	}
+	if err := w.handler.Done(ctx, &state, link.path, link.err); err != nil {
+		w.errs.Append(&Error{link.path, err})
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package filewalk_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloudeng.io/file"
+	"cloudeng.io/file/filewalk"
+	"cloudeng.io/file/localfs"
+)
+
+type linkRecorder struct {
+	sync.Mutex
+	fs       filewalk.FS
+	root     string
+	prefixes []string
+	files    []string
+	errs     map[string]error
+}
+
+func (l *linkRecorder) rel(path string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(path, l.root), string(filepath.Separator))
+}
+
+func (l *linkRecorder) Prefix(_ context.Context, _ *struct{}, prefix string, _ file.Info, err error) (bool, file.InfoList, error) {
+	l.Lock()
+	defer l.Unlock()
+	if err != nil {
+		l.errs[l.rel(prefix)] = err
+		return true, nil, nil
+	}
+	l.prefixes = append(l.prefixes, l.rel(prefix))
+	return false, nil, nil
+}
+
+func (l *linkRecorder) Contents(ctx context.Context, _ *struct{}, prefix string, contents []filewalk.Entry) (file.InfoList, error) {
+	l.Lock()
+	defer l.Unlock()
+	var children file.InfoList
+	for _, e := range contents {
+		path := l.fs.Join(prefix, e.Name)
+		if !e.IsDir() {
+			l.files = append(l.files, l.rel(path))
+			continue
+		}
+		info, err := l.fs.Lstat(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, info)
+	}
+	return children, nil
+}
+
+func (l *linkRecorder) Done(_ context.Context, _ *struct{}, _ string, _ error) error {
+	return nil
+}
+
+func (l *linkRecorder) sorted() ([]string, []string) {
+	sort.Strings(l.prefixes)
+	sort.Strings(l.files)
+	return l.prefixes, l.files
+}
+
+func createLinkTree(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"root/a/b", "other/c"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, dir), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{"root/a/f1", "root/a/b/f2", "other/f3", "other/c/f4"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, f), []byte(f), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, l := range []struct{ target, link string }{
+		{"../../other", "root/a/other"},
+		{"..", "root/a/b/loop"},
+		{"missing", "root/dangling"},
+		{"f1", "root/a/file-link"},
+	} {
+		if err := os.Symlink(l.target, filepath.Join(tmpDir, l.link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tmpDir
+}
+
+func TestFollowSymlinks(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := createLinkTree(t)
+	root := filepath.Join(tmpDir, "root")
+	fs := localfs.New()
+
+	newRecorder := func() *linkRecorder {
+		return &linkRecorder{fs: fs, root: root, errs: map[string]error{}}
+	}
+
+	lr := newRecorder()
+	if err := filewalk.New(fs, lr).Walk(ctx, root); err != nil {
+		t.Fatal(err)
+	}
+	prefixes, files := lr.sorted()
+	if got, want := prefixes, []string{"", "a", "a/b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := files, []string{"a/b/f2", "a/b/loop", "a/f1", "a/file-link", "a/other", "dangling"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	lr = newRecorder()
+	if err := filewalk.New(fs, lr, filewalk.WithFollowSymlinks()).Walk(ctx, root); err != nil {
+		t.Fatal(err)
+	}
+	prefixes, files = lr.sorted()
+	if got, want := prefixes, []string{"", "a", "a/b", "a/other", "a/other/c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := files, []string{"a/b/f2", "a/b/loop", "a/f1", "a/file-link", "a/other", "a/other/c/f4", "a/other/f3", "dangling"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(lr.errs), 2; got != want {
+		t.Fatalf("got %v, want %v: %v", got, want, lr.errs)
+	}
+	if err := lr.errs["a/b/loop"]; !errors.Is(err, filewalk.ErrSymlinkLoop) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	var serr *filewalk.SymlinkError
+	if err := lr.errs["dangling"]; !errors.Is(err, filewalk.ErrDanglingSymlink) || !errors.As(err, &serr) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got, want := serr.Target, "missing"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Links count as a single level.
+	lr = newRecorder()
+	if err := filewalk.New(fs, lr, filewalk.WithFollowSymlinks(), filewalk.WithDepth(2)).Walk(ctx, root); err != nil {
+		t.Fatal(err)
+	}
+	prefixes, _ = lr.sorted()
+	if got, want := prefixes, []string{"", "a", "a/b", "a/other"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(lr.errs), 1; got != want {
+		t.Errorf("got %v, want %v: %v", got, want, lr.errs)
+	}
+
+	// Roots that are symlinks are followed.
+	lr = newRecorder()
+	lr.root = filepath.Join(root, "a", "other")
+	if err := filewalk.New(fs, lr, filewalk.WithFollowSymlinks()).Walk(ctx, lr.root); err != nil {
+		t.Fatal(err)
+	}
+	prefixes, _ = lr.sorted()
+	if got, want := prefixes, []string{"", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
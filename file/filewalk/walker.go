@@ -29,6 +29,8 @@ type Walker[T any] struct {
 	handler  Handler[T]
 	errs     *errors.M
 	nSyncOps int64
+	roots    []string
+	state    *walkState
 }
 
 // Option represents options accepted by Walker.
@@ -38,6 +40,10 @@ type options struct {
 	concurrentScans int
 	scanSize        int
 	depth           int
+	followSymlinks  bool
+
+	checkpoint         Checkpointer
+	checkpointInterval time.Duration
 }
 
 // WithConcurrentScans can be used to change the number of prefixes/directories
@@ -152,22 +158,29 @@ func (e *Error) As(target any) bool {
 	return errors.As(e.Err, target)
 }
 
-func (w *Walker[T]) processLevel(ctx context.Context, state *T, path string) ([]file.Info, error) {
+func (w *Walker[T]) processLevel(ctx context.Context, state *T, path string, anc *ancestor) ([]file.Info, []symlink, error) {
 	var nextLevel []file.Info
+	var failed []symlink
 	sc := w.fs.LevelScanner(path)
 	for sc.Scan(ctx, w.opts.scanSize) {
-		children, err := w.handler.Contents(ctx, state, path, sc.Contents())
+		contents := sc.Contents()
+		children, err := w.handler.Contents(ctx, state, path, contents)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return nil, nil, ctx.Err()
 		default:
 		}
 		nextLevel = append(nextLevel, children...)
+		if w.opts.followSymlinks {
+			links, bad := w.followSymlinks(ctx, path, anc, contents)
+			nextLevel = append(nextLevel, links...)
+			failed = append(failed, bad...)
+		}
 	}
-	return nextLevel, sc.Err()
+	return nextLevel, failed, sc.Err()
 }
 
 // Handler is implemented by clients of Walker to process the results of
@@ -177,7 +190,10 @@ type Handler[T any] interface {
 
 	// Prefix is called to determine if a given level in the filesystem hiearchy
 	// should be further examined or traversed. The file.Info is obtained via a call
-	// to Lstat and hence will refer to a symlink itself if the prefix is a symlink.
+	// to Lstat and hence will refer to a symlink itself if the prefix is a symlink,
+	// unless WithFollowSymlinks is specified, in which case the file.Info
+	// for a followed symlink refers to its target. Symlinks that cannot be
+	// followed are reported via a *SymlinkError.
 	// If stop is true then traversal stops at this point. If a list of Entry's
 	// is returned then this list is traversed directly rather than obtaining
 	// the children from the filesystem. This allows for both exclusions and
@@ -202,7 +218,8 @@ type Handler[T any] interface {
 // Walk traverses the hierarchies specified by each of the roots calling
 // prefixFn and entriesFn as it goes. prefixFn will always be called
 // before entriesFn for the same prefix, but no other ordering guarantees
-// are provided.
+// are provided. If a Checkpointer was supplied via WithCheckpoint
+// then the walk will resume from the most recent checkpoint, if any.
 func (w *Walker[T]) Walk(ctx context.Context, roots ...string) error {
 	w.errs = &errors.M{}
 	rootCtx := ctx
@@ -210,6 +227,15 @@ func (w *Walker[T]) Walk(ctx context.Context, roots ...string) error {
 		w.opts.concurrentScans = runtime.GOMAXPROCS(-1)
 	}
 
+	w.roots = roots
+	w.state = nil
+	if w.opts.checkpoint != nil {
+		w.state = newWalkState(w.opts.checkpoint, w.opts.checkpointInterval)
+		if err := w.state.restore(ctx); err != nil {
+			return err
+		}
+	}
+
 	walkers, _ := errgroup.WithContext(rootCtx)
 	walkers = errgroup.WithConcurrency(walkers, w.opts.concurrentScans)
 
@@ -220,9 +246,15 @@ func (w *Walker[T]) Walk(ctx context.Context, roots ...string) error {
 	}
 
 	for _, root := range roots {
-		rootInfo, rootErr := w.fs.Lstat(ctx, root)
+		var rootInfo file.Info
+		var rootErr error
+		if w.opts.followSymlinks {
+			rootInfo, rootErr = w.fs.Stat(ctx, root)
+		} else {
+			rootInfo, rootErr = w.fs.Lstat(ctx, root)
+		}
 		walkers.Go(func() error {
-			return w.walkPrefix(ctx, root, 0, rootInfo, rootErr, walkerLimitCh)
+			return w.walkPrefix(ctx, root, 0, rootInfo, rootErr, nil, walkerLimitCh)
 		})
 	}
 
@@ -240,14 +272,24 @@ func (w *Walker[T]) Walk(ctx context.Context, roots ...string) error {
 		<-waitCh
 	case <-waitCh:
 	}
+	if w.state != nil {
+		if w.state.isComplete(roots) {
+			w.errs.Append(w.opts.checkpoint.Complete(ctx))
+		} else {
+			w.errs.Append(w.state.checkpoint(ctx, roots))
+		}
+	}
 	return w.errs.Err()
 }
 
-func (w *Walker[T]) walkChildren(ctx context.Context, path string, depth int, children []file.Info, limitCh chan struct{}) error {
+func (w *Walker[T]) walkChildren(ctx context.Context, path string, depth int, children []file.Info, anc *ancestor, limitCh chan struct{}) error {
 	var wg sync.WaitGroup
-	wg.Add(len(children))
 	depth++
 
+	// Note that any goroutines that have been started must be waited for
+	// before returning so that Handler.Done is never called for a child
+	// after the walk has returned.
+
 	// Take care to catch all context cancellations as quickly as possible
 	// to avoid unnecessary work.
 	for _, child := range children {
@@ -257,41 +299,51 @@ func (w *Walker[T]) walkChildren(ctx context.Context, path string, depth int, ch
 			// This branch may not be taken even if the context is cancelled,
 			// so we check below also. However, we also need to check here
 			// for the case when limitCh is empty and the context is cancelled.
+			wg.Wait()
 			return ctx.Err()
 		default:
 			// no concurreny is available fallback to sync.
 			atomic.AddInt64(&w.nSyncOps, 1)
 			p := w.fs.Join(path, child.Name())
-			if err := w.walkPrefix(ctx, p, depth, child, nil, limitCh); err != nil {
+			if err := w.walkPrefix(ctx, p, depth, child, nil, anc, limitCh); err != nil {
+				wg.Wait()
 				return err
 			}
-			wg.Done()
 			continue
 		}
 		select {
 		case <-ctx.Done():
+			limitCh <- struct{}{}
+			wg.Wait()
 			return ctx.Err()
 		default:
 		}
+		wg.Add(1)
 		go func() {
-			_ = w.walkPrefix(ctx, w.fs.Join(path, child.Name()), depth, child, nil, limitCh)
+			_ = w.walkPrefix(ctx, w.fs.Join(path, child.Name()), depth, child, nil, anc, limitCh)
 			wg.Done()
 			limitCh <- struct{}{}
 		}()
 	}
 	wg.Wait()
+	if w.state != nil {
+		// When checkpointing, ensure that a prefix is not treated as
+		// complete if any of its children were interrupted.
+		return ctx.Err()
+	}
 	return nil
 }
 
-func (w *Walker[T]) handleDone(ctx context.Context, state *T, path string, err error) {
-	err = w.handler.Done(ctx, state, path, err)
-	if err == nil {
-		return
+func (w *Walker[T]) handleDone(ctx context.Context, state *T, path string, children []file.Info, err error) {
+	if derr := w.handler.Done(ctx, state, path, err); derr != nil {
+		w.errs.Append(&Error{path, derr})
+	}
+	if w.state != nil {
+		w.errs.Append(w.state.done(ctx, w.fs, w.roots, path, children, err))
 	}
-	w.errs.Append(&Error{path, err})
 }
 
-func (w *Walker[T]) walkPrefix(ctx context.Context, path string, depth int, info file.Info, err error, limitCh chan struct{}) error {
+func (w *Walker[T]) walkPrefix(ctx context.Context, path string, depth int, info file.Info, err error, anc *ancestor, limitCh chan struct{}) error {
 	if w.opts.depth >= 0 && depth > w.opts.depth {
 		return nil
 	}
@@ -301,28 +353,43 @@ func (w *Walker[T]) walkPrefix(ctx context.Context, path string, depth int, info
 		return ctx.Err()
 	default:
 	}
+	if w.state != nil && !w.state.start(path) {
+		return nil
+	}
 	stop, children, err := w.handler.Prefix(ctx, &state, path, info, err)
 	if stop {
+		if w.state != nil {
+			w.state.stopped(path)
+		}
 		return nil
 	}
 	if err != nil {
-		w.handleDone(ctx, &state, path, err)
+		w.handleDone(ctx, &state, path, nil, err)
 		return nil
 	}
+	if w.opts.followSymlinks {
+		anc = w.push(ctx, anc, path, info)
+	}
 	if len(children) == 0 {
-		children, err = w.processLevel(ctx, &state, path)
+		var failed []symlink
+		children, failed, err = w.processLevel(ctx, &state, path, anc)
 		if err != nil {
-			if errors.Is(err, context.Canceled) {
+			if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				// Leave the prefix pending so that it is traversed
+				// again when a checkpointed walk is resumed.
 				return err
 			}
-			w.handleDone(ctx, &state, path, err)
+			w.handleDone(ctx, &state, path, nil, err)
 			return nil
 		}
+		for _, link := range failed {
+			w.reportSymlink(ctx, link, depth+1)
+		}
 	}
-	if err := w.walkChildren(ctx, path, depth, children, limitCh); err != nil {
+	if err := w.walkChildren(ctx, path, depth, children, anc, limitCh); err != nil {
 		return err
 	}
-	w.handleDone(ctx, &state, path, nil)
+	w.handleDone(ctx, &state, path, children, nil)
 	return nil
 }
 
@@ -5,6 +5,7 @@ go 1.26.4
 require (
 	cloudeng.io/algo v0.0.0-20260818231247-605c3766963e
 	cloudeng.io/cmdutil v0.0.0-20260527194618-4cb6d4558850
+	cloudeng.io/datetime v0.0.0-20260527194618-4cb6d4558850
 	cloudeng.io/errors v0.0.14-0.20260312171538-61fcde6ce278
 	cloudeng.io/logging v0.0.0-20260806150854-f21c21e021b8
 	cloudeng.io/os v0.0.0-20260807191443-11b7f4ecaaa0
@@ -30,6 +31,8 @@ replace cloudeng.io/algo => ../algo
 
 replace cloudeng.io/cmdutil => ../cmdutil
 
+replace cloudeng.io/datetime => ../datetime
+
 replace cloudeng.io/errors => ../errors
 
 replace cloudeng.io/logging => ../logging
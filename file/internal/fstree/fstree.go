@@ -0,0 +1,206 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package fstree provides support for the in-memory trees of files,
+// directories and symbolic links used by the memfs and archivefs
+// filesystems. It provides path resolution, including following symbolic
+// links, and the directory listing, filewalk.LevelScanner and
+// fs.ReadDirFile implementations that they share.
+package fstree
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"cloudeng.io/file"
+	"cloudeng.io/file/filewalk"
+)
+
+// MaxSymlinks is the maximum number of symbolic links that will be followed
+// when resolving a path.
+const MaxSymlinks = 40
+
+// ErrTooManySymlinks is returned when more than MaxSymlinks symbolic links
+// are encountered when resolving a path.
+var ErrTooManySymlinks = errors.New("too many levels of symbolic links")
+
+// Node represents a file, directory or symbolic link in a tree.
+type Node[N any] interface {
+	IsDir() bool
+	IsSymlink() bool
+	// Target returns the target of a symbolic link.
+	Target() string
+	// Mode returns the node's mode.
+	Mode() fs.FileMode
+	// Children returns the contents of a directory.
+	Children() map[string]N
+	// Info returns the file.Info for the node using the supplied name.
+	Info(name string) file.Info
+}
+
+// Components returns the components of the cleaned, slash separated,
+// name. Absolute and relative names are treated identically and the
+// root, ie. "/", "." or "", has no components.
+func Components(name string) []string {
+	name = path.Clean("/" + name)
+	if name == "/" {
+		return nil
+	}
+	return strings.Split(name[1:], "/")
+}
+
+// Resolve returns the node for the path specified by comps relative to
+// root, following symbolic links for all but the final component and for
+// the final component only if follow is true. Relative symbolic links
+// are interpreted relative to the directory that contains them.
+func Resolve[N Node[N]](root N, comps []string, follow bool) (N, error) {
+	return resolve(root, comps, follow, 0)
+}
+
+func resolve[N Node[N]](root N, comps []string, follow bool, links int) (N, error) {
+	var zero N
+	n := root
+	for i, c := range comps {
+		if !n.IsDir() {
+			return zero, fs.ErrNotExist
+		}
+		child, ok := n.Children()[c]
+		if !ok {
+			return zero, fs.ErrNotExist
+		}
+		if child.IsSymlink() && (i < len(comps)-1 || follow) {
+			if links++; links > MaxSymlinks {
+				return zero, ErrTooManySymlinks
+			}
+			target := child.Target()
+			if !path.IsAbs(target) {
+				target = path.Join("/", path.Join(comps[:i]...), target)
+			}
+			return resolve(root, append(Components(target), comps[i+1:]...), follow, links)
+		}
+		n = child
+	}
+	return n, nil
+}
+
+// SortedNames returns the names of the contents of the directory n in
+// lexicographic order.
+func SortedNames[N Node[N]](n N) []string {
+	return slices.Sorted(maps.Keys(n.Children()))
+}
+
+// Entries returns the filewalk.Entry for each of the contents of the
+// directory n in lexicographic order.
+func Entries[N Node[N]](n N) []filewalk.Entry {
+	names := SortedNames(n)
+	children := n.Children()
+	entries := make([]filewalk.Entry, len(names))
+	for i, name := range names {
+		entries[i] = filewalk.Entry{Name: name, Type: children[name].Mode().Type()}
+	}
+	return entries
+}
+
+// Infos returns the fs.FileInfo for each of the contents of the directory
+// n in lexicographic order.
+func Infos[N Node[N]](n N) []fs.FileInfo {
+	names := SortedNames(n)
+	children := n.Children()
+	infos := make([]fs.FileInfo, len(names))
+	for i, name := range names {
+		infos[i] = children[name].Info(name)
+	}
+	return infos
+}
+
+// Scanner implements filewalk.LevelScanner for a fixed set of entries.
+type Scanner struct {
+	entries  []filewalk.Entry
+	pos, end int
+	err      error
+}
+
+// NewScanner returns a Scanner for the supplied entries. If err is not
+// nil then Scan will always return false and Err will return err.
+func NewScanner(entries []filewalk.Entry, err error) *Scanner {
+	return &Scanner{entries: entries, err: err}
+}
+
+// Scan implements filewalk.LevelScanner.
+func (s *Scanner) Scan(ctx context.Context, n int) bool {
+	if s.err != nil {
+		return false
+	}
+	if err := ctx.Err(); err != nil {
+		s.err = err
+		return false
+	}
+	s.pos = s.end
+	if s.pos >= len(s.entries) {
+		return false
+	}
+	s.end = min(s.pos+n, len(s.entries))
+	return true
+}
+
+// Contents implements filewalk.LevelScanner.
+func (s *Scanner) Contents() []filewalk.Entry {
+	return s.entries[s.pos:s.end]
+}
+
+// Err implements filewalk.LevelScanner.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// DirHandle implements fs.ReadDirFile for a fixed set of entries.
+type DirHandle struct {
+	info    fs.FileInfo
+	entries []fs.FileInfo
+	pos     int
+}
+
+// NewDirHandle returns a DirHandle for the directory described by info
+// with the supplied entries.
+func NewDirHandle(info fs.FileInfo, entries []fs.FileInfo) *DirHandle {
+	return &DirHandle{info: info, entries: entries}
+}
+
+// Stat implements fs.File.
+func (d *DirHandle) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+// Read implements fs.File and always returns an error.
+func (d *DirHandle) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+// Close implements fs.File.
+func (d *DirHandle) Close() error {
+	return nil
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *DirHandle) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.pos:]
+	if n > 0 {
+		if len(remaining) == 0 {
+			return nil, io.EOF
+		}
+		remaining = remaining[:min(n, len(remaining))]
+	}
+	d.pos += len(remaining)
+	des := make([]fs.DirEntry, len(remaining))
+	for i, e := range remaining {
+		des[i] = fs.FileInfoToDirEntry(e)
+	}
+	return des, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package largefile
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// adaptiveConcurrency implements an AIMD (additive increase, multiplicative
+// decrease) controller for the number of concurrent in-flight requests.
+// The limit is increased by one for every 'limit' successful requests that
+// complete without a congestion signal and is halved when congestion is
+// detected. Congestion is signaled by retryable errors (e.g. throttling or
+// server errors) or by the observed per-byte latency rising well above the
+// best recent latency. The best latency decays (increases) slightly with
+// every sample so that an unusually fast early sample, or a lasting change
+// in network conditions, does not lead to persistent false congestion
+// signals. Only one decrease is allowed per 'round' of
+// requests to avoid overreacting to a burst of failures from requests that
+// were already in flight.
+type adaptiveConcurrency struct {
+	mu          sync.Mutex
+	minLimit    int
+	maxLimit    int
+	limit       int
+	inFlight    int
+	successes   int           // successful requests since the last increase.
+	sinceChange int           // completed requests since the last decrease.
+	bestLatency float64       // best recent per-byte latency, in ns.
+	avgLatency  float64       // exponentially weighted per-byte latency, in ns.
+	notify      chan struct{} // closed when a slot may have become available.
+	onChange    func(limit int)
+}
+
+const (
+	// latencyWeight is the weight given to each new latency sample.
+	latencyWeight = 0.2
+	// latencyThreshold is the multiple of the best observed latency
+	// above which the average latency is considered to indicate congestion.
+	latencyThreshold = 2.0
+	// bestLatencyDecay is the fraction by which the best latency is
+	// increased for each new sample before being compared to it.
+	bestLatencyDecay = 0.01
+)
+
+func newAdaptiveConcurrency(minLimit, maxLimit int, onChange func(int)) *adaptiveConcurrency {
+	minLimit = max(minLimit, 1)
+	maxLimit = max(maxLimit, minLimit)
+	ac := &adaptiveConcurrency{
+		minLimit: minLimit,
+		maxLimit: maxLimit,
+		limit:    minLimit,
+		// Allow the first congestion signal to take effect immediately.
+		sinceChange: minLimit,
+		notify:      make(chan struct{}),
+		onChange:    onChange,
+	}
+	if onChange != nil {
+		onChange(ac.limit)
+	}
+	return ac
+}
+
+// Limit returns the current concurrency limit.
+func (ac *adaptiveConcurrency) Limit() int {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	return ac.limit
+}
+
+// acquire blocks until the number of in-flight requests is below the
+// current limit or the context is done.
+func (ac *adaptiveConcurrency) acquire(ctx context.Context) error {
+	if ac == nil {
+		return nil
+	}
+	for {
+		ac.mu.Lock()
+		if ac.inFlight < ac.limit {
+			ac.inFlight++
+			ac.mu.Unlock()
+			return nil
+		}
+		ch := ac.notify
+		ac.mu.Unlock()
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release records the completion of a request that transferred the
+// specified number of bytes in the specified duration. A failed request
+// should be released with a size of zero.
+func (ac *adaptiveConcurrency) release(size int64, duration time.Duration) {
+	if ac == nil {
+		return
+	}
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.inFlight--
+	ac.sinceChange++
+	defer ac.wakeLocked()
+	if size <= 0 {
+		return
+	}
+	latency := float64(duration.Nanoseconds()) / float64(size)
+	ac.bestLatency *= 1 + bestLatencyDecay
+	if ac.bestLatency == 0 || latency < ac.bestLatency {
+		ac.bestLatency = latency
+	}
+	if ac.avgLatency == 0 {
+		ac.avgLatency = latency
+	} else {
+		ac.avgLatency = latencyWeight*latency + (1-latencyWeight)*ac.avgLatency
+	}
+	if ac.avgLatency > latencyThreshold*ac.bestLatency {
+		ac.decreaseLocked()
+		return
+	}
+	ac.successes++
+	if ac.successes >= ac.limit && ac.limit < ac.maxLimit {
+		ac.successes = 0
+		ac.setLimitLocked(ac.limit + 1)
+	}
+}
+
+// congested records a congestion signal, such as a retryable error.
+func (ac *adaptiveConcurrency) congested() {
+	if ac == nil {
+		return
+	}
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.decreaseLocked()
+}
+
+func (ac *adaptiveConcurrency) decreaseLocked() {
+	ac.successes = 0
+	if ac.sinceChange < ac.limit {
+		// Already decreased during this round.
+		return
+	}
+	ac.sinceChange = 0
+	// Forget the latency history so that the average is recomputed
+	// at the new concurrency level.
+	ac.avgLatency = 0
+	ac.setLimitLocked(max(ac.limit/2, ac.minLimit))
+}
+
+func (ac *adaptiveConcurrency) setLimitLocked(limit int) {
+	if limit == ac.limit {
+		return
+	}
+	ac.limit = limit
+	if ac.onChange != nil {
+		ac.onChange(limit)
+	}
+	ac.wakeLocked()
+}
+
+func (ac *adaptiveConcurrency) wakeLocked() {
+	close(ac.notify)
+	ac.notify = make(chan struct{})
+}
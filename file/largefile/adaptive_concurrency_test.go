@@ -0,0 +1,212 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package largefile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"cloudeng.io/algo/digests"
+	"cloudeng.io/algo/ratecontrol"
+)
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	ctx := context.Background()
+	var changes []int
+	ac := newAdaptiveConcurrency(2, 8, func(l int) { changes = append(changes, l) })
+
+	complete := func(n int, latency time.Duration) {
+		for range n {
+			if err := ac.acquire(ctx); err != nil {
+				t.Fatal(err)
+			}
+			ac.release(100, latency)
+		}
+	}
+
+	// Additive increase: one per round of 'limit' successes.
+	complete(2, time.Millisecond)
+	if got, want := ac.Limit(), 3; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	complete(3+4+5+6+7+100, time.Millisecond)
+	if got, want := ac.Limit(), 8; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Multiplicative decrease, at most once per round.
+	ac.congested()
+	ac.congested()
+	if got, want := ac.Limit(), 4; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	complete(4, 10*time.Millisecond) // latency increase.
+	if got, want := ac.Limit(), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	complete(2, 10*time.Millisecond)
+	ac.congested()
+	if got, want := ac.Limit(), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := changes, []int{2, 3, 4, 5, 6, 7, 8, 4, 2}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// acquire blocks until a slot is available.
+	for range ac.Limit() {
+		if err := ac.acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := ac.acquire(cctx); err != context.DeadlineExceeded {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ac.acquire(ctx)
+	}()
+	ac.release(0, 0)
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+
+	// The best latency decays so that a single fast request does not
+	// prevent the limit from increasing indefinitely.
+	ac = newAdaptiveConcurrency(2, 8, nil)
+	complete(1, time.Millisecond)
+	complete(300, 10*time.Millisecond)
+	if got, want := ac.Limit(), 8; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	var nilac *adaptiveConcurrency
+	if err := nilac.acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	nilac.release(1, 1)
+	nilac.congested()
+}
+
+type retryableResponse struct{}
+
+func (retryableResponse) IsRetryable() bool { return true }
+
+func (retryableResponse) BackoffDuration() (bool, time.Duration) { return false, 0 }
+
+// backoffReader fails the first request for each of the offsets in
+// failAt with a retryable error.
+type backoffReader struct {
+	data      []byte
+	blockSize int
+	mu        sync.Mutex
+	failAt    map[int64]bool
+}
+
+func (r *backoffReader) Name() string { return "backoffReader" }
+
+func (r *backoffReader) ContentLengthAndBlockSize() (int64, int) {
+	return int64(len(r.data)), r.blockSize
+}
+
+func (r *backoffReader) Digest() digests.Hash { return digests.Hash{} }
+
+func (r *backoffReader) GetReader(_ context.Context, from, to int64) (io.ReadCloser, RetryResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failAt[from] {
+		delete(r.failAt, from)
+		return nil, retryableResponse{}, errors.New("mock retryable failure")
+	}
+	return io.NopCloser(bytes.NewReader(r.data[from : to+1])), retryableResponse{}, nil
+}
+
+// observingLimiter calls onBackoff whenever a request backs off.
+type observingLimiter struct {
+	onBackoff func()
+}
+
+func (o *observingLimiter) Wait(context.Context) error { return nil }
+
+func (o *observingLimiter) BytesTransferred(int) {}
+
+func (o *observingLimiter) Backoff() ratecontrol.Backoff {
+	return &observingBackoff{onBackoff: o.onBackoff}
+}
+
+type observingBackoff struct {
+	ratecontrol.NoBackoff
+	onBackoff func()
+	retries   int
+}
+
+func (o *observingBackoff) Wait(context.Context, any) (bool, error) {
+	o.onBackoff()
+	o.retries++
+	return false, nil
+}
+
+func (o *observingBackoff) Retries() int { return o.retries }
+
+func TestStreamingDownloaderAdaptiveConcurrency(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	data := make([]byte, 64*20)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	rd := &backoffReader{data: data, blockSize: 64, failAt: map[int64]bool{0: true, 128: true, 640: true}}
+
+	// With a single request allowed in flight, a request that holds on to
+	// its slot whilst backing off would block all other requests, and
+	// itself when it retries.
+	var dl *StreamingDownloader
+	var inFlight []int
+	limiter := &observingLimiter{onBackoff: func() {
+		dl.limiter.mu.Lock()
+		defer dl.limiter.mu.Unlock()
+		inFlight = append(inFlight, dl.limiter.inFlight)
+	}}
+	dl = NewStreamingDownloader(rd,
+		WithDownloadAdaptiveConcurrency(1, 1),
+		WithDownloadRateController(limiter))
+
+	var buf bytes.Buffer
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(&buf, dl.Reader())
+		errCh <- err
+	}()
+	st, err := dl.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("streamed data does not match")
+	}
+	if got, want := inFlight, []int{0, 0, 0}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := st.DownloadRetries, int64(3); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := st.Concurrency, int64(1); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := dl.limiter.inFlight, 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
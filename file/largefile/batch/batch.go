@@ -0,0 +1,642 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package batch provides a Manager for downloading a batch of large files,
+// using largefile, under a single concurrency and bandwidth budget. The state
+// of the batch may be persisted via a checkpoint.Operation so that a batch
+// that is interrupted, or that encounters errors, can be resumed by a
+// subsequent run.
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+
+	"cloudeng.io/algo/digests"
+	"cloudeng.io/algo/ratecontrol"
+	"cloudeng.io/errors"
+	"cloudeng.io/file/checkpoint"
+	"cloudeng.io/file/largefile"
+	"cloudeng.io/sync/errgroup"
+)
+
+// ErrDigestMismatch is returned when the digest of a downloaded file
+// does not match its expected digest.
+var ErrDigestMismatch = errors.New("digest mismatch")
+
+// Mode determines how files are downloaded.
+type Mode int
+
+const (
+	// Streaming uses a largefile.StreamingDownloader to write directly
+	// to a temporary file that is renamed to the destination on completion,
+	// or to the writer returned by the Creator supplied via WithCreator.
+	// An interrupted download must be restarted from the beginning.
+	Streaming Mode = iota
+	// Caching uses a largefile.CachingDownloader and LocalDownloadCache
+	// whose data file is renamed to the destination on completion. An
+	// interrupted download is resumed from the blocks already downloaded.
+	Caching
+)
+
+const (
+	// PartialDownloadSuffix is appended to the destination of a file
+	// to obtain the name of the file used whilst it is being downloaded.
+	PartialDownloadSuffix = ".partialdownload"
+	// PartialIndexSuffix is appended to the name of the partial download
+	// to obtain the name of the index file used in Caching mode.
+	PartialIndexSuffix = ".index"
+)
+
+// Opener is called to obtain a largefile.Reader for each source, for
+// example httpfs.NewLargeFile.
+type Opener func(ctx context.Context, source string) (largefile.Reader, error)
+
+// Creator is called to obtain the writer for each destination when
+// downloading in Streaming mode, for example to write directly to an
+// object store.
+type Creator func(ctx context.Context, destination string) (io.WriteCloser, error)
+
+// Option represents an option to New.
+type Option func(o *options)
+
+type options struct {
+	concurrency   int
+	requests      int
+	mode          Mode
+	creator       Creator
+	rateControl   ratecontrol.Limiter
+	checkpoint    checkpoint.Operation
+	progressCh    chan<- Progress
+	logger        *slog.Logger
+	downloadOpts  []largefile.DownloadOption
+	compactPeriod int
+}
+
+// WithConcurrency sets the number of files to be downloaded concurrently.
+// The default is 4. Note that this limits the number of files, not requests;
+// the number of requests issued for each file is set via
+// largefile.WithDownloadConcurrency using WithDownloadOptions and the
+// number issued across all files may be bounded using WithRequestConcurrency.
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		o.concurrency = n
+	}
+}
+
+// WithRequestConcurrency sets the maximum number of byte range requests
+// that may be in flight across all of the files in the batch. The default,
+// 0, imposes no limit beyond that implied by WithConcurrency and
+// largefile.WithDownloadConcurrency.
+func WithRequestConcurrency(n int) Option {
+	return func(o *options) {
+		o.requests = n
+	}
+}
+
+// WithMode sets the mode used to download files, the default is Streaming.
+func WithMode(m Mode) Option {
+	return func(o *options) {
+		o.mode = m
+	}
+}
+
+// WithCreator requests that, in Streaming mode, each file be written
+// straight through to the writer returned by c for its destination rather
+// than to a local temporary file. The writer is closed once the file has
+// been downloaded and its digest, if any, verified. If the download fails
+// the writer is closed using CloseWithError, if it implements it as
+// io.PipeWriter does, so that it can discard the partial file.
+func WithCreator(c Creator) Option {
+	return func(o *options) {
+		o.creator = c
+	}
+}
+
+// WithRateController sets the rate controller that is shared by all of
+// the downloads in the batch and hence represents the bandwidth and
+// request budget for the entire batch.
+func WithRateController(rc ratecontrol.Limiter) Option {
+	return func(o *options) {
+		o.rateControl = rc
+	}
+}
+
+// WithCheckpoint sets the checkpoint.Operation, which must already
+// be initialized, used to record the state of the batch as each file
+// is completed. A subsequent call to Run, in this or another process,
+// will skip any files that were successfully downloaded. The checkpoints
+// are removed once all of the files in the batch have been downloaded.
+func WithCheckpoint(op checkpoint.Operation) Option {
+	return func(o *options) {
+		o.checkpoint = op
+	}
+}
+
+// WithProgress sets the channel used to report aggregated progress for
+// the batch. Updates are sent on a best-effort basis and will be dropped
+// if the channel is not ready to receive them.
+func WithProgress(ch chan<- Progress) Option {
+	return func(o *options) {
+		o.progressCh = ch
+	}
+}
+
+// WithLogger sets the logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithDownloadOptions sets the options used for every download. Note
+// that the rate controller and progress options are overridden by the
+// Manager.
+func WithDownloadOptions(opts ...largefile.DownloadOption) Option {
+	return func(o *options) {
+		o.downloadOpts = append(o.downloadOpts, opts...)
+	}
+}
+
+// Progress represents the aggregated progress of a batch.
+type Progress struct {
+	Files           int   // Total number of files in the batch.
+	Completed       int   // Files downloaded, including by previous runs.
+	Failed          int   // Files that failed to download in this run.
+	Active          int   // Files currently being downloaded.
+	CompletedBytes  int64 // Total size of the completed files.
+	DownloadedBytes int64 // Bytes downloaded by this run.
+	Retries         int64 // Retries made by this run.
+}
+
+// Manager manages the download of a batch of files.
+type Manager struct {
+	options
+	opener Opener
+	slots  chan struct{} // Non-nil if WithRequestConcurrency is used.
+
+	mu          sync.Mutex
+	state       []entryState
+	stats       map[int]largefile.DownloadStats
+	progress    Progress
+	checkpoints int
+}
+
+type entryState struct {
+	Entry
+	Done  bool   `json:"done,omitempty"`
+	Size  int64  `json:"size,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// New creates a new Manager for the specified entries. Each entry must
+// have a unique destination.
+func New(opener Opener, entries []Entry, opts ...Option) (*Manager, error) {
+	m := &Manager{
+		opener: opener,
+		stats:  map[int]largefile.DownloadStats{},
+	}
+	m.concurrency = 4
+	m.compactPeriod = 32
+	for _, opt := range opts {
+		opt(&m.options)
+	}
+	if m.logger == nil {
+		m.logger = slog.New(slog.DiscardHandler)
+	}
+	m.logger = m.logger.With("pkg", "cloudeng.io/file/largefile/batch")
+	if m.requests > 0 {
+		m.slots = make(chan struct{}, m.requests)
+	}
+	if m.creator != nil && m.mode != Streaming {
+		return nil, fmt.Errorf("WithCreator can only be used in Streaming mode")
+	}
+	seen := map[string]bool{}
+	m.state = make([]entryState, len(entries))
+	for i, e := range entries {
+		if len(e.Source) == 0 || len(e.Destination) == 0 {
+			return nil, fmt.Errorf("entry %d: source and destination must be specified", i)
+		}
+		if seen[e.Destination] {
+			return nil, fmt.Errorf("entry %d: duplicate destination: %v", i, e.Destination)
+		}
+		seen[e.Destination] = true
+		if _, err := e.hash(); err != nil {
+			return nil, fmt.Errorf("entry %d: %v: %w", i, e.Destination, err)
+		}
+		m.state[i].Entry = e
+	}
+	m.progress.Files = len(entries)
+	return m, nil
+}
+
+// Run downloads all of the files in the batch that have not already been
+// downloaded according to the most recent checkpoint, if any. It returns
+// the final progress for the batch and an error that wraps the errors
+// encountered for each file that failed to download.
+func (m *Manager) Run(ctx context.Context) (Progress, error) {
+	if err := m.restore(ctx); err != nil {
+		return m.Progress(), err
+	}
+	g, gctx := errgroup.WithContext(ctx)
+	g = errgroup.WithConcurrency(g, m.concurrency)
+	errs := &errors.M{}
+	for i := range m.state {
+		if m.state[i].Done {
+			continue
+		}
+		if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			size, err := m.download(gctx, i)
+			if err != nil {
+				m.logger.Info("download failed", "source", m.state[i].Source, "destination", m.state[i].Destination, "error", err)
+				errs.Append(fmt.Errorf("%v: %w", m.state[i].Destination, err))
+			}
+			// Checkpoint failures are fatal and will cancel the batch.
+			return m.record(gctx, i, size, err)
+		})
+	}
+	errs.Append(g.Wait())
+	errs.Append(ctx.Err())
+	err := errs.Err()
+	if err == nil && m.checkpoint != nil {
+		err = m.checkpoint.Complete(ctx)
+	}
+	return m.Progress(), err
+}
+
+// Progress returns the current progress of the batch.
+func (m *Manager) Progress() Progress {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.progress
+}
+
+func (m *Manager) restore(ctx context.Context) error {
+	if m.checkpoint == nil {
+		return nil
+	}
+	buf, err := m.checkpoint.Latest(ctx)
+	if err != nil || len(buf) == 0 {
+		return err
+	}
+	var prev []entryState
+	if err := json.Unmarshal(buf, &prev); err != nil {
+		return fmt.Errorf("failed to decode checkpoint: %w", err)
+	}
+	done := map[Entry]int64{}
+	for _, st := range prev {
+		if st.Done {
+			done[st.Entry] = st.Size
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.state {
+		st := &m.state[i]
+		if size, ok := done[st.Entry]; ok && !st.Done {
+			st.Done, st.Size = true, size
+			m.progress.Completed++
+			m.progress.CompletedBytes += size
+		}
+	}
+	m.sendLocked()
+	return nil
+}
+
+func (m *Manager) record(ctx context.Context, i int, size int64, err error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := &m.state[i]
+	m.progress.Active--
+	if err == nil {
+		st.Done, st.Size, st.Error = true, size, ""
+		m.progress.Completed++
+		m.progress.CompletedBytes += size
+	} else {
+		st.Error = err.Error()
+		m.progress.Failed++
+	}
+	m.sendLocked()
+	if m.checkpoint == nil {
+		return nil
+	}
+	// Record the checkpoint even if the context has been canceled so that
+	// an interrupted batch can be resumed.
+	ctx = context.WithoutCancel(ctx)
+	buf, err := json.Marshal(m.state)
+	if err != nil {
+		return err
+	}
+	label := fmt.Sprintf("-%d-of-%d", m.progress.Completed, m.progress.Files)
+	if _, err := m.checkpoint.Checkpoint(ctx, label, buf); err != nil {
+		return fmt.Errorf("failed to checkpoint batch: %w", err)
+	}
+	m.checkpoints++
+	if m.checkpoints%m.compactPeriod == 0 {
+		if err := m.checkpoint.Compact(ctx, label); err != nil {
+			return fmt.Errorf("failed to compact checkpoints: %w", err)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) started() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.progress.Active++
+	m.sendLocked()
+}
+
+func (m *Manager) update(i int, st largefile.DownloadStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats[i] = st
+	m.progress.DownloadedBytes, m.progress.Retries = 0, 0
+	for _, st := range m.stats {
+		m.progress.DownloadedBytes += st.DownloadedBytes
+		m.progress.Retries += st.DownloadRetries
+	}
+	m.sendLocked()
+}
+
+func (m *Manager) sendLocked() {
+	if m.progressCh == nil {
+		return
+	}
+	select {
+	case m.progressCh <- m.progress:
+	default:
+	}
+}
+
+func (m *Manager) download(ctx context.Context, i int) (int64, error) {
+	m.started()
+	e := m.state[i].Entry
+	rd, err := m.opener(ctx, e.Source)
+	if err != nil {
+		return 0, err
+	}
+	if m.slots != nil {
+		rd = &limitedReader{Reader: rd, slots: m.slots}
+	}
+	h, err := e.hash()
+	if err != nil {
+		return 0, err
+	}
+	if d := rd.Digest(); !h.IsSet() && d.IsSet() {
+		// Use the digest supplied by the source, if any.
+		if h, err = digests.New(d.Algo, d.Digest); err != nil {
+			return 0, err
+		}
+	}
+	if m.creator == nil {
+		if err := os.MkdirAll(filepath.Dir(e.Destination), 0755); err != nil {
+			return 0, err
+		}
+	}
+
+	progressCh := make(chan largefile.DownloadStats, 16)
+	progressDone := make(chan struct{})
+	go func() {
+		for st := range progressCh {
+			m.update(i, st)
+		}
+		close(progressDone)
+	}()
+	opts := append(slices.Clone(m.downloadOpts), largefile.WithDownloadProgress(progressCh))
+	if m.rateControl != nil {
+		opts = append(opts, largefile.WithDownloadRateController(m.rateControl))
+	}
+	// Note that progressCh is closed by both streamingDownload and
+	// cachingDownload, the latter relying on CachingDownloader.Run to do so.
+	var st largefile.DownloadStats
+	if m.mode == Caching {
+		st, err = m.cachingDownload(ctx, rd, e.Destination, h, progressCh, opts)
+	} else {
+		st, err = m.streamingDownload(ctx, rd, e.Destination, h, progressCh, opts)
+	}
+	<-progressDone
+	m.update(i, st)
+	size, _ := rd.ContentLengthAndBlockSize()
+	return size, err
+}
+
+func (m *Manager) streamingDownload(ctx context.Context, rd largefile.Reader, dest string, h digests.Hash, progressCh chan largefile.DownloadStats, opts []largefile.DownloadOption) (largefile.DownloadStats, error) {
+	defer close(progressCh)
+	if m.creator != nil {
+		return m.streamToWriter(ctx, rd, dest, h, opts)
+	}
+	return m.streamToFile(ctx, rd, dest, h, opts)
+}
+
+func (m *Manager) streamToWriter(ctx context.Context, rd largefile.Reader, dest string, h digests.Hash, opts []largefile.DownloadOption) (largefile.DownloadStats, error) {
+	wr, err := m.creator(ctx, dest)
+	if err != nil {
+		return largefile.DownloadStats{}, err
+	}
+	st, err := stream(ctx, rd, wr, h, opts)
+	if err == nil {
+		return st, wr.Close()
+	}
+	if cw, ok := wr.(interface{ CloseWithError(error) error }); ok {
+		cw.CloseWithError(err) //nolint:errcheck
+	} else {
+		wr.Close() //nolint:errcheck
+	}
+	return st, err
+}
+
+func (m *Manager) streamToFile(ctx context.Context, rd largefile.Reader, dest string, h digests.Hash, opts []largefile.DownloadOption) (largefile.DownloadStats, error) {
+	tmpName := dest + PartialDownloadSuffix
+	if err := os.Remove(tmpName); err != nil && !os.IsNotExist(err) {
+		return largefile.DownloadStats{}, err
+	}
+	defer os.Remove(tmpName) //nolint:errcheck
+	wr, err := os.Create(tmpName)
+	if err != nil {
+		return largefile.DownloadStats{}, err
+	}
+	defer wr.Close() //nolint:errcheck
+	st, err := stream(ctx, rd, wr, h, opts)
+	if err != nil {
+		return st, err
+	}
+	if err := wr.Close(); err != nil {
+		return st, err
+	}
+	return st, os.Rename(tmpName, dest)
+}
+
+// stream downloads rd to wr using a StreamingDownloader and verifies
+// its digest if h is set.
+func stream(ctx context.Context, rd largefile.Reader, wr io.Writer, h digests.Hash, opts []largefile.DownloadOption) (largefile.DownloadStats, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	dl := largefile.NewStreamingDownloader(rd, opts...)
+	errCh := make(chan error, 1)
+	var status largefile.StreamingStatus
+	go func() {
+		var err error
+		status, err = dl.Run(ctx)
+		errCh <- err
+	}()
+	out := wr
+	if h.IsSet() {
+		out = io.MultiWriter(wr, h)
+	}
+	n, err := io.Copy(out, dl.Reader())
+	if err != nil {
+		// Cancel the download and drain any pending writes to the stream
+		// so that Run can return.
+		cancel()
+		io.Copy(io.Discard, dl.Reader()) //nolint:errcheck
+	}
+	if rerr := <-errCh; err == nil {
+		err = rerr
+	}
+	if err != nil {
+		return status.DownloadStats, err
+	}
+	if status.DownloadSize != n {
+		return status.DownloadStats, fmt.Errorf("downloaded size mismatch: expected %d, got %d", status.DownloadSize, n)
+	}
+	if h.IsSet() && !h.Validate() {
+		return status.DownloadStats, fmt.Errorf("%v: %w", h.Algo, ErrDigestMismatch)
+	}
+	return status.DownloadStats, nil
+}
+
+func (m *Manager) cachingDownload(ctx context.Context, rd largefile.Reader, dest string, h digests.Hash, progressCh chan largefile.DownloadStats, opts []largefile.DownloadOption) (largefile.DownloadStats, error) {
+	dataName := dest + PartialDownloadSuffix
+	indexName := dataName + PartialIndexSuffix
+	cache, err := openOrCreateCache(ctx, rd, dataName, indexName)
+	if err != nil {
+		close(progressCh)
+		return largefile.DownloadStats{}, err
+	}
+	dl, err := largefile.NewCachingDownloader(rd, cache, opts...)
+	if err != nil {
+		close(progressCh)
+		cache.Close()
+		return largefile.DownloadStats{}, err
+	}
+	status, err := dl.Run(ctx)
+	if cerr := cache.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return status.DownloadStats, err
+	}
+	if !status.Complete {
+		return status.DownloadStats, fmt.Errorf("download incomplete: %d of %d blocks", status.CachedOrStreamedBlocks, status.DownloadBlocks)
+	}
+	if h.IsSet() {
+		if err := hashFile(dataName, h); err != nil {
+			return status.DownloadStats, err
+		}
+		if !h.Validate() {
+			// Remove the cache so that the file is downloaded again.
+			os.Remove(dataName)  //nolint:errcheck
+			os.Remove(indexName) //nolint:errcheck
+			return status.DownloadStats, fmt.Errorf("%v: %w", h.Algo, ErrDigestMismatch)
+		}
+	}
+	if err := os.Rename(dataName, dest); err != nil {
+		return status.DownloadStats, err
+	}
+	return status.DownloadStats, os.Remove(indexName)
+}
+
+// openOrCreateCache opens the existing cache files, if any, for the
+// download, or creates new ones if they do not exist or were created
+// for a file with a different size or block size.
+func openOrCreateCache(ctx context.Context, rd largefile.Reader, dataName, indexName string) (*largefile.LocalDownloadCache, error) {
+	size, blockSize := rd.ContentLengthAndBlockSize()
+	dataExists, indexExists, err := largefile.CacheFilesExist(dataName, indexName)
+	if err != nil {
+		return nil, err
+	}
+	if dataExists && indexExists {
+		cache, err := openCache(dataName, indexName)
+		if err == nil {
+			csize, cblock := cache.ContentLengthAndBlockSize()
+			if csize == size && cblock == blockSize {
+				return cache, nil
+			}
+			cache.Close()
+		}
+	}
+	if err := largefile.CreateNewFilesForCache(ctx, dataName, indexName, size, blockSize, runtime.NumCPU(), nil); err != nil {
+		return nil, err
+	}
+	return openCache(dataName, indexName)
+}
+
+func openCache(dataName, indexName string) (*largefile.LocalDownloadCache, error) {
+	data, index, err := largefile.OpenCacheFiles(dataName, indexName)
+	if err != nil {
+		return nil, err
+	}
+	cache, err := largefile.NewLocalDownloadCache(data, index)
+	if err != nil {
+		data.Close()
+		index.Close()
+		return nil, err
+	}
+	return cache, nil
+}
+
+// limitedReader bounds the number of requests in flight across all of
+// the readers that share the same slots. A slot is held from the call
+// to GetReader until the returned reader is closed.
+type limitedReader struct {
+	largefile.Reader
+	slots chan struct{}
+}
+
+func (lr *limitedReader) GetReader(ctx context.Context, from, to int64) (io.ReadCloser, largefile.RetryResponse, error) {
+	select {
+	case lr.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	rd, retry, err := lr.Reader.GetReader(ctx, from, to)
+	if err != nil {
+		<-lr.slots
+		return nil, retry, err
+	}
+	return &slotReader{ReadCloser: rd, slots: lr.slots}, retry, nil
+}
+
+type slotReader struct {
+	io.ReadCloser
+	slots chan struct{}
+	once  sync.Once
+}
+
+func (sr *slotReader) Close() error {
+	sr.once.Do(func() { <-sr.slots })
+	return sr.ReadCloser.Close()
+}
+
+func hashFile(name string, h digests.Hash) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(h, f)
+	return err
+}
@@ -0,0 +1,410 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package batch_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"cloudeng.io/algo/digests"
+	"cloudeng.io/algo/ratecontrol"
+	"cloudeng.io/file/checkpoint"
+	"cloudeng.io/file/largefile"
+	"cloudeng.io/file/largefile/batch"
+)
+
+type noRetry struct{}
+
+func (noRetry) IsRetryable() bool                      { return false }
+func (noRetry) BackoffDuration() (bool, time.Duration) { return false, 0 }
+
+// memFile implements largefile.Reader for an in-memory byte slice.
+type memFile struct {
+	name      string
+	data      []byte
+	blockSize int
+	failAt    int64 // fail any request for a range that includes failAt, if >= 0.
+}
+
+func (m *memFile) Name() string { return m.name }
+
+func (m *memFile) ContentLengthAndBlockSize() (int64, int) {
+	return int64(len(m.data)), m.blockSize
+}
+
+func (m *memFile) Digest() digests.Hash { return digests.Hash{} }
+
+func (m *memFile) GetReader(_ context.Context, from, to int64) (io.ReadCloser, largefile.RetryResponse, error) {
+	if m.failAt >= from && m.failAt <= to {
+		return nil, noRetry{}, errors.New("mock failure")
+	}
+	return io.NopCloser(bytes.NewReader(m.data[from : to+1])), noRetry{}, nil
+}
+
+// sources provides an Opener for a set of in-memory files.
+type sources struct {
+	sync.Mutex
+	files  map[string]*memFile
+	opened map[string]int
+}
+
+func newSources(n int) *sources {
+	s := &sources{files: map[string]*memFile{}, opened: map[string]int{}}
+	rnd := rand.New(rand.NewSource(1))
+	for i := range n {
+		name := fmt.Sprintf("src-%02d", i)
+		data := make([]byte, 1000+rnd.Intn(5000))
+		rnd.Read(data)
+		s.files[name] = &memFile{name: name, data: data, blockSize: 256, failAt: -1}
+	}
+	return s
+}
+
+func (s *sources) open(_ context.Context, source string) (largefile.Reader, error) {
+	s.Lock()
+	defer s.Unlock()
+	s.opened[source]++
+	f, ok := s.files[source]
+	if !ok {
+		return nil, fmt.Errorf("%v: not found", source)
+	}
+	return f, nil
+}
+
+func (s *sources) entries(dir string) []batch.Entry {
+	var entries []batch.Entry
+	for i := range len(s.files) {
+		name := fmt.Sprintf("src-%02d", i)
+		sum := sha256.Sum256(s.files[name].data)
+		entries = append(entries, batch.Entry{
+			Source:      name,
+			Destination: filepath.Join(dir, "sub", name),
+			Digest:      "sha256=" + digests.ToHex(sum[:]),
+		})
+	}
+	return entries
+}
+
+func (s *sources) verify(t *testing.T, entries []batch.Entry) {
+	t.Helper()
+	for _, e := range entries {
+		data, err := os.ReadFile(e.Destination)
+		if err != nil {
+			t.Errorf("%v: %v", e.Destination, err)
+			continue
+		}
+		if !bytes.Equal(data, s.files[e.Source].data) {
+			t.Errorf("%v: data mismatch", e.Destination)
+		}
+		for _, suffix := range []string{batch.PartialDownloadSuffix, batch.PartialDownloadSuffix + batch.PartialIndexSuffix} {
+			if _, err := os.Stat(e.Destination + suffix); !os.IsNotExist(err) {
+				t.Errorf("%v: partial download file exists: %v", e.Destination, err)
+			}
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	for _, mode := range []batch.Mode{batch.Streaming, batch.Caching} {
+		src := newSources(10)
+		entries := src.entries(t.TempDir())
+		rc := ratecontrol.New(ratecontrol.WithBytesPerTick(time.Millisecond, 100*1024))
+		progressCh := make(chan batch.Progress, 1000)
+		mgr, err := batch.New(src.open, entries,
+			batch.WithMode(mode),
+			batch.WithConcurrency(3),
+			batch.WithRateController(rc),
+			batch.WithProgress(progressCh),
+			batch.WithDownloadOptions(largefile.WithDownloadConcurrency(2)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := mgr.Run(ctx)
+		rc.Stop()
+		if err != nil {
+			t.Fatalf("%v: %v", mode, err)
+		}
+		src.verify(t, entries)
+		var total int64
+		for _, f := range src.files {
+			total += int64(len(f.data))
+		}
+		want := batch.Progress{Files: 10, Completed: 10, CompletedBytes: total, DownloadedBytes: total}
+		if !reflect.DeepEqual(p, want) {
+			t.Errorf("%v: got %+v, want %+v", mode, p, want)
+		}
+		close(progressCh)
+		updates := 0
+		for range progressCh {
+			updates++
+		}
+		if updates < 20 {
+			t.Errorf("%v: too few progress updates: %v", mode, updates)
+		}
+	}
+}
+
+func TestBatchResume(t *testing.T) {
+	ctx := context.Background()
+	for _, mode := range []batch.Mode{batch.Streaming, batch.Caching} {
+		tmpDir := t.TempDir()
+		src := newSources(8)
+		entries := src.entries(tmpDir)
+		src.files["src-02"].failAt = 1000
+		src.files["src-05"].failAt = 0
+		entries[6].Digest = "sha256=" + digests.ToHex(make([]byte, 32))
+
+		op := checkpoint.NewDirectoryOperation()
+		if err := op.Init(ctx, filepath.Join(tmpDir, "checkpoint")); err != nil {
+			t.Fatal(err)
+		}
+		mgr, err := batch.New(src.open, entries, batch.WithMode(mode), batch.WithCheckpoint(op))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := mgr.Run(ctx)
+		if err == nil {
+			t.Fatalf("%v: expected an error", mode)
+		}
+		if !errors.Is(err, batch.ErrDigestMismatch) {
+			t.Errorf("%v: expected a digest mismatch: %v", mode, err)
+		}
+		if got, want := p.Completed, 5; got != want {
+			t.Errorf("%v: got %v, want %v", mode, got, want)
+		}
+		if got, want := p.Failed, 3; got != want {
+			t.Errorf("%v: got %v, want %v", mode, got, want)
+		}
+		if _, err := os.Stat(entries[6].Destination); !os.IsNotExist(err) {
+			t.Errorf("%v: file with bad digest exists: %v", mode, err)
+		}
+		if mode == batch.Caching {
+			// The partially downloaded file is retained.
+			if _, err := os.Stat(entries[2].Destination + batch.PartialDownloadSuffix); err != nil {
+				t.Errorf("%v: %v", mode, err)
+			}
+		}
+
+		// Resume, only the failed files are downloaded again.
+		src.files["src-02"].failAt = -1
+		src.files["src-05"].failAt = -1
+		sum := sha256.Sum256(src.files["src-06"].data)
+		entries[6].Digest = "sha256=" + digests.ToHex(sum[:])
+		src.opened = map[string]int{}
+		mgr, err = batch.New(src.open, entries, batch.WithMode(mode), batch.WithCheckpoint(op))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err = mgr.Run(ctx)
+		if err != nil {
+			t.Fatalf("%v: %v", mode, err)
+		}
+		src.verify(t, entries)
+		if got, want := src.opened, map[string]int{"src-02": 1, "src-05": 1, "src-06": 1}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", mode, got, want)
+		}
+		if got, want := p.Completed, 8; got != want {
+			t.Errorf("%v: got %v, want %v", mode, got, want)
+		}
+		if mode == batch.Caching {
+			// Only the blocks that failed were downloaded again for src-02.
+			downloaded := int64(len(src.files["src-05"].data) + len(src.files["src-06"].data))
+			if p.DownloadedBytes >= downloaded+int64(len(src.files["src-02"].data)) {
+				t.Errorf("%v: src-02 was not resumed: %+v", mode, p)
+			}
+		}
+		// The checkpoints are removed once the batch is complete.
+		if buf, err := op.Latest(ctx); err != nil || buf != nil {
+			t.Errorf("%v: unexpected checkpoint: %s: %v", mode, buf, err)
+		}
+	}
+}
+
+// countingSources wraps the files in a sources so as to record the maximum
+// number of requests in flight across all of them.
+type countingSources struct {
+	*sources
+	mu                    sync.Mutex
+	inFlight, maxInFlight int
+}
+
+func (c *countingSources) open(ctx context.Context, source string) (largefile.Reader, error) {
+	rd, err := c.sources.open(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	return &countingFile{Reader: rd, c: c}, nil
+}
+
+type countingFile struct {
+	largefile.Reader
+	c *countingSources
+}
+
+func (f *countingFile) GetReader(ctx context.Context, from, to int64) (io.ReadCloser, largefile.RetryResponse, error) {
+	f.c.mu.Lock()
+	f.c.inFlight++
+	f.c.maxInFlight = max(f.c.maxInFlight, f.c.inFlight)
+	f.c.mu.Unlock()
+	time.Sleep(time.Millisecond)
+	rd, retry, err := f.Reader.GetReader(ctx, from, to)
+	if err != nil {
+		f.done()
+		return nil, retry, err
+	}
+	return &countingCloser{ReadCloser: rd, f: f}, retry, nil
+}
+
+func (f *countingFile) done() {
+	f.c.mu.Lock()
+	defer f.c.mu.Unlock()
+	f.c.inFlight--
+}
+
+type countingCloser struct {
+	io.ReadCloser
+	f *countingFile
+}
+
+func (c *countingCloser) Close() error {
+	c.f.done()
+	return c.ReadCloser.Close()
+}
+
+func TestBatchRequestConcurrency(t *testing.T) {
+	ctx := context.Background()
+	for _, mode := range []batch.Mode{batch.Streaming, batch.Caching} {
+		src := &countingSources{sources: newSources(10)}
+		entries := src.entries(t.TempDir())
+		mgr, err := batch.New(src.open, entries,
+			batch.WithMode(mode),
+			batch.WithConcurrency(4),
+			batch.WithRequestConcurrency(3),
+			batch.WithDownloadOptions(largefile.WithDownloadConcurrency(4)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := mgr.Run(ctx); err != nil {
+			t.Fatalf("%v: %v", mode, err)
+		}
+		src.verify(t, entries)
+		if src.maxInFlight == 0 || src.maxInFlight > 3 {
+			t.Errorf("%v: requests in flight %v, exceeds 3", mode, src.maxInFlight)
+		}
+	}
+}
+
+// memWriter records the data written to it and how it was closed.
+type memWriter struct {
+	bytes.Buffer
+	closed   bool
+	closeErr error
+}
+
+func (w *memWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func (w *memWriter) CloseWithError(err error) error {
+	w.closed, w.closeErr = true, err
+	return nil
+}
+
+func TestBatchCreator(t *testing.T) {
+	ctx := context.Background()
+	src := newSources(5)
+	entries := src.entries("dest")
+	entries[3].Digest = "sha256=" + digests.ToHex(make([]byte, 32))
+	var mu sync.Mutex
+	writers := map[string]*memWriter{}
+	creator := func(_ context.Context, dest string) (io.WriteCloser, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		w := &memWriter{}
+		writers[dest] = w
+		return w, nil
+	}
+	mgr, err := batch.New(src.open, entries, batch.WithCreator(creator))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := mgr.Run(ctx)
+	if !errors.Is(err, batch.ErrDigestMismatch) {
+		t.Errorf("expected a digest mismatch: %v", err)
+	}
+	if got, want := p.Completed, 4; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for i, e := range entries {
+		w := writers[e.Destination]
+		if w == nil || !w.closed {
+			t.Errorf("%v: writer was not closed", e.Destination)
+			continue
+		}
+		if i == 3 {
+			if !errors.Is(w.closeErr, batch.ErrDigestMismatch) {
+				t.Errorf("%v: unexpected or missing error: %v", e.Destination, w.closeErr)
+			}
+			continue
+		}
+		if w.closeErr != nil || !bytes.Equal(w.Bytes(), src.files[e.Source].data) {
+			t.Errorf("%v: data mismatch: %v", e.Destination, w.closeErr)
+		}
+	}
+	if _, err := os.Stat("dest"); !os.IsNotExist(err) {
+		t.Errorf("local destination was created: %v", err)
+	}
+	if _, err := batch.New(src.open, entries, batch.WithCreator(creator), batch.WithMode(batch.Caching)); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestBatchErrors(t *testing.T) {
+	src := newSources(2)
+	for i, entries := range [][]batch.Entry{
+		{{Source: "a"}},
+		{{Destination: "a"}},
+		{{Source: "a", Destination: "a"}, {Source: "b", Destination: "a"}},
+		{{Source: "a", Destination: "a", Digest: "sha256"}},
+		{{Source: "a", Destination: "a", Digest: "xx=00"}},
+	} {
+		if _, err := batch.New(src.open, entries); err == nil {
+			t.Errorf("%v: expected an error", i)
+		}
+	}
+}
+
+func TestManifest(t *testing.T) {
+	entries := newSources(3).entries("/tmp")
+	entries[1].Digest = ""
+	var buf bytes.Buffer
+	if err := batch.WriteManifest(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteString("\n\n")
+	got, err := batch.ReadManifest(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("got %v, want %v", got, entries)
+	}
+	if _, err := batch.ReadManifest(bytes.NewBufferString("{\n")); err == nil {
+		t.Errorf("expected an error")
+	}
+}
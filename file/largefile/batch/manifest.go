@@ -0,0 +1,81 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"cloudeng.io/algo/digests"
+)
+
+// Entry represents a single file to be downloaded.
+type Entry struct {
+	// Source is the name of the file to be downloaded, it is interpreted
+	// by the Opener supplied to New, e.g. a URL.
+	Source string `json:"source"`
+	// Destination is the local filename that the file is to be
+	// downloaded to, or the name passed to the Creator supplied
+	// via WithCreator.
+	Destination string `json:"destination"`
+	// Digest, if set, is the expected digest of the file of the form
+	// <algo>=<hex-digits>, e.g. sha256=e3b0c4....
+	Digest string `json:"digest,omitempty"`
+}
+
+func (e Entry) hash() (digests.Hash, error) {
+	if len(e.Digest) == 0 {
+		return digests.Hash{}, nil
+	}
+	algo, hexdigits, err := digests.ParseHex(e.Digest)
+	if err != nil {
+		return digests.Hash{}, err
+	}
+	digest, err := digests.FromHex(hexdigits)
+	if err != nil {
+		return digests.Hash{}, err
+	}
+	return digests.New(algo, digest)
+}
+
+// ReadManifest reads a manifest of entries, one JSON encoded Entry per
+// line. Blank lines are ignored.
+func ReadManifest(rd io.Reader) ([]Entry, error) {
+	var entries []Entry
+	sc := bufio.NewScanner(rd)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := bytes.TrimSpace(sc.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(text, &e); err != nil {
+			return nil, fmt.Errorf("manifest line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// WriteManifest writes the specified entries as a manifest that can
+// be read by ReadManifest.
+func WriteManifest(wr io.Writer, entries []Entry) error {
+	enc := json.NewEncoder(wr)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package largefile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"cloudeng.io/algo/digests"
+)
+
+// ErrBlockDigestMismatch is returned when the digest of a block does not
+// match the expected digest.
+var ErrBlockDigestMismatch = errors.New("block digest mismatch")
+
+// BlockDigests represents the per-block digests of a file, as typically
+// obtained from a sidecar manifest, that can be used to verify each block
+// of a file as it is downloaded rather than only verifying the entire file
+// once it has been downloaded. The digests may themselves be verified
+// against a trusted Merkle root using VerifyMerkleRoot.
+type BlockDigests struct {
+	algo          string
+	contentLength int64
+	blockSize     int
+	digests       [][]byte
+}
+
+// NewBlockDigests creates a new BlockDigests instance using the specified
+// algorithm and digests, one per block.
+func NewBlockDigests(algo string, contentLength int64, blockSize int, blockDigests [][]byte) (*BlockDigests, error) {
+	if !digests.IsSupported(algo) {
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", algo)
+	}
+	if contentLength <= 0 || blockSize <= 0 {
+		return nil, fmt.Errorf("content length (%d) and block size (%d) must be greater than zero", contentLength, blockSize)
+	}
+	if n := NumBlocks(contentLength, blockSize); len(blockDigests) != n {
+		return nil, fmt.Errorf("expected %d block digests, got %d", n, len(blockDigests))
+	}
+	return &BlockDigests{
+		algo:          algo,
+		contentLength: contentLength,
+		blockSize:     blockSize,
+		digests:       blockDigests,
+	}, nil
+}
+
+// ComputeBlockDigests computes the per-block digests for the specified
+// file using the specified algorithm, for example to create a manifest
+// for a file that is to be made available for download.
+func ComputeBlockDigests(ctx context.Context, rd io.ReaderAt, algo string, contentLength int64, blockSize int) (*BlockDigests, error) {
+	if contentLength <= 0 || blockSize <= 0 {
+		return nil, fmt.Errorf("content length (%d) and block size (%d) must be greater than zero", contentLength, blockSize)
+	}
+	buf := make([]byte, blockSize)
+	blockDigests := make([][]byte, 0, NumBlocks(contentLength, blockSize))
+	for off := int64(0); off < contentLength; off += int64(blockSize) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		size := min(int64(blockSize), contentLength-off)
+		if n, err := rd.ReadAt(buf[:size], off); int64(n) != size {
+			return nil, fmt.Errorf("failed to read block at offset %d: %w", off, err)
+		}
+		d, err := blockDigest(algo, buf[:size])
+		if err != nil {
+			return nil, err
+		}
+		blockDigests = append(blockDigests, d)
+	}
+	return NewBlockDigests(algo, contentLength, blockSize, blockDigests)
+}
+
+func blockDigest(algo string, data []byte) ([]byte, error) {
+	h, err := digests.New(algo, nil)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// Algo returns the digest algorithm used.
+func (bd *BlockDigests) Algo() string {
+	return bd.algo
+}
+
+// ContentLengthAndBlockSize returns the content length and block size
+// that the digests were computed for.
+func (bd *BlockDigests) ContentLengthAndBlockSize() (int64, int) {
+	return bd.contentLength, bd.blockSize
+}
+
+// Digest returns the digest for the block at the specified offset,
+// or nil if the offset is out of range.
+func (bd *BlockDigests) Digest(off int64) []byte {
+	if off < 0 || off >= bd.contentLength {
+		return nil
+	}
+	return bd.digests[off/int64(bd.blockSize)]
+}
+
+// Verify verifies the data for the block starting at the specified offset,
+// returning an error that wraps ErrBlockDigestMismatch if the data
+// does not match the expected digest.
+func (bd *BlockDigests) Verify(off int64, data []byte) error {
+	if off < 0 || off >= bd.contentLength || off%int64(bd.blockSize) != 0 {
+		return fmt.Errorf("offset %d is not a valid block offset: %w", off, ErrCacheInvalidOffset)
+	}
+	if size := min(int64(bd.blockSize), bd.contentLength-off); int64(len(data)) != size {
+		return fmt.Errorf("data size %d for offset %d: must be %d: %w", len(data), off, size, ErrCacheInvalidBlockSize)
+	}
+	d, err := blockDigest(bd.algo, data)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(d, bd.Digest(off)) {
+		return fmt.Errorf("offset %d: %v digest %x does not match %x: %w", off, bd.algo, d, bd.Digest(off), ErrBlockDigestMismatch)
+	}
+	return nil
+}
+
+// MerkleRoot returns the root of the Merkle tree computed over the block
+// digests as per digests.MerkleRoot.
+func (bd *BlockDigests) MerkleRoot() ([]byte, error) {
+	return digests.MerkleRoot(bd.algo, bd.digests)
+}
+
+// VerifyMerkleRoot verifies that the block digests match the specified,
+// trusted, Merkle root.
+func (bd *BlockDigests) VerifyMerkleRoot(root []byte) error {
+	computed, err := bd.MerkleRoot()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(computed, root) {
+		return fmt.Errorf("merkle root %x does not match %x: %w", computed, root, ErrBlockDigestMismatch)
+	}
+	return nil
+}
+
+type blockManifest struct {
+	Algo          string   `json:"algo"`
+	ContentLength string   `json:"content_length"`
+	BlockSize     int      `json:"block_size"`
+	Digests       []string `json:"digests"`
+}
+
+// MarshalJSON implements json.Marshaler. The digests are hex encoded.
+func (bd *BlockDigests) MarshalJSON() ([]byte, error) {
+	m := blockManifest{
+		Algo:          bd.algo,
+		ContentLength: strconv.FormatInt(bd.contentLength, 10),
+		BlockSize:     bd.blockSize,
+		Digests:       make([]string, len(bd.digests)),
+	}
+	for i, d := range bd.digests {
+		m.Digests[i] = digests.ToHex(d)
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (bd *BlockDigests) UnmarshalJSON(data []byte) error {
+	var m blockManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	contentLength, err := strconv.ParseInt(m.ContentLength, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid content length: %w", err)
+	}
+	blockDigests := make([][]byte, len(m.Digests))
+	for i, d := range m.Digests {
+		if blockDigests[i], err = digests.FromHex(d); err != nil {
+			return fmt.Errorf("invalid digest for block %d: %w", i, err)
+		}
+	}
+	nbd, err := NewBlockDigests(m.Algo, contentLength, m.BlockSize, blockDigests)
+	if err != nil {
+		return err
+	}
+	*bd = *nbd
+	return nil
+}
+
+// ReadBlockDigests reads a JSON encoded manifest of block digests as
+// written by WriteBlockDigests.
+func ReadBlockDigests(rd io.Reader) (*BlockDigests, error) {
+	bd := &BlockDigests{}
+	if err := json.NewDecoder(rd).Decode(bd); err != nil {
+		return nil, fmt.Errorf("failed to decode block digests manifest: %w", err)
+	}
+	return bd, nil
+}
+
+// WriteBlockDigests writes a JSON encoded manifest of block digests.
+func WriteBlockDigests(wr io.Writer, bd *BlockDigests) error {
+	return json.NewEncoder(wr).Encode(bd)
+}
+
+// VerifyCache re-checks every cached block in the specified cache against
+// the supplied block digests and returns the byte ranges of any blocks whose
+// contents do not match. LocalDownloadCache.Clear can be used to clear these
+// ranges from the cache's index so that they will be downloaded again.
+func VerifyCache(ctx context.Context, cache DownloadCache, bd *BlockDigests) ([]ByteRange, error) {
+	csize, cblock := cache.ContentLengthAndBlockSize()
+	if csize != bd.contentLength || cblock != bd.blockSize {
+		return nil, fmt.Errorf("cache size (%d) or block size (%d) does not match block digests size (%d) or block size (%d)", csize, cblock, bd.contentLength, bd.blockSize)
+	}
+	var bad []ByteRange
+	var br ByteRange
+	buf := make([]byte, bd.blockSize)
+	for n := cache.NextCached(0, &br); n != -1; n = cache.NextCached(n, &br) {
+		if err := ctx.Err(); err != nil {
+			return bad, err
+		}
+		data := buf[:br.Size()]
+		if _, err := cache.ReadAt(data, br.From); err != nil {
+			return bad, fmt.Errorf("failed to read byte range %v: %w", br, err)
+		}
+		if err := bd.Verify(br.From, data); err != nil {
+			if !errors.Is(err, ErrBlockDigestMismatch) {
+				return bad, err
+			}
+			bad = append(bad, br)
+		}
+	}
+	return bad, nil
+}
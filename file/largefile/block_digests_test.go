@@ -0,0 +1,231 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package largefile_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"cloudeng.io/algo/digests"
+	"cloudeng.io/file/largefile"
+)
+
+// corruptingReader implements largefile.Reader and corrupts the data
+// returned for the offsets in corrupt the first time they are requested.
+type corruptingReader struct {
+	data      []byte
+	blockSize int
+	mu        sync.Mutex
+	corrupt   map[int64]bool
+}
+
+func (c *corruptingReader) Name() string { return "corruptingReader" }
+
+func (c *corruptingReader) ContentLengthAndBlockSize() (int64, int) {
+	return int64(len(c.data)), c.blockSize
+}
+
+func (c *corruptingReader) Digest() digests.Hash { return digests.Hash{} }
+
+func (c *corruptingReader) GetReader(_ context.Context, from, to int64) (io.ReadCloser, largefile.RetryResponse, error) {
+	buf := bytes.Clone(c.data[from : to+1])
+	c.mu.Lock()
+	if c.corrupt[from] {
+		delete(c.corrupt, from)
+		buf[0]++
+	}
+	c.mu.Unlock()
+	return io.NopCloser(bytes.NewReader(buf)), noRetryResponse{}, nil
+}
+
+func TestBlockDigests(t *testing.T) {
+	ctx := context.Background()
+	size, blockSize := 1000, 64
+	data := newUploadData(size)
+	bd, err := largefile.ComputeBlockDigests(ctx, bytes.NewReader(data), digests.SHA256, int64(size), blockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for off := int64(0); off < int64(size); off += int64(blockSize) {
+		end := min(off+int64(blockSize), int64(size))
+		if err := bd.Verify(off, data[off:end]); err != nil {
+			t.Errorf("%v: %v", off, err)
+		}
+	}
+	bad := bytes.Clone(data[64:128])
+	bad[10]++
+	if err := bd.Verify(64, bad); !errors.Is(err, largefile.ErrBlockDigestMismatch) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	if err := bd.Verify(10, data[10:74]); !errors.Is(err, largefile.ErrCacheInvalidOffset) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	if err := bd.Verify(960, data[960:990]); !errors.Is(err, largefile.ErrCacheInvalidBlockSize) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := largefile.WriteBlockDigests(&buf, bd); err != nil {
+		t.Fatal(err)
+	}
+	nbd, err := largefile.ReadBlockDigests(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(bd, nbd) {
+		t.Errorf("got %+v, want %+v", nbd, bd)
+	}
+
+	root, err := bd.MerkleRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nbd.VerifyMerkleRoot(root); err != nil {
+		t.Error(err)
+	}
+	root[0]++
+	if err := nbd.VerifyMerkleRoot(root); !errors.Is(err, largefile.ErrBlockDigestMismatch) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+
+	if _, err := largefile.NewBlockDigests(digests.SHA256, int64(size), blockSize, nil); err == nil {
+		t.Errorf("expected an error")
+	}
+	if _, err := largefile.ReadBlockDigests(bytes.NewBufferString(`{"algo":"sha256","content_length":"100","block_size":10,"digests":["00"]}`)); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestDownloadBlockVerification(t *testing.T) {
+	ctx := context.Background()
+	size, blockSize := 1000, 64
+	data := newUploadData(size)
+	bd, err := largefile.ComputeBlockDigests(ctx, bytes.NewReader(data), digests.SHA256, int64(size), blockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpDir := t.TempDir()
+	cacheFile, indexFile := filepath.Join(tmpDir, "cache.dat"), filepath.Join(tmpDir, "cache.idx")
+	cache := createNewCache(ctx, t, cacheFile, indexFile, int64(size), blockSize, 1)
+	rd := &corruptingReader{data: data, blockSize: blockSize, corrupt: map[int64]bool{0: true, 320: true}}
+	dl, err := largefile.NewCachingDownloader(rd, cache,
+		append(defaultOpts(4),
+			largefile.WithDownloadBlockDigests(bd),
+			largefile.WithDownloadWaitForCompletion(true))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := dl.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Complete {
+		t.Errorf("download incomplete: %+v", st)
+	}
+	// Note that stats are accumulated across iterations.
+	if got, want := st.VerificationErrors, int64(2); got < want {
+		t.Errorf("got %v, want >= %v", got, want)
+	}
+	if got, want := st.Iterations, int64(2); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	bad, err := largefile.VerifyCache(ctx, cache, bd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bad) != 0 {
+		t.Errorf("unexpected bad ranges: %v", bad)
+	}
+	if err := cache.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt the cache file on disk, verify and repair it.
+	f, err := os.OpenFile(cacheFile, os.O_RDWR, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, off := range []int64{70, 960} {
+		if _, err := f.WriteAt([]byte{0xff, 0xff}, off); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	openCache := func() *largefile.LocalDownloadCache {
+		d, i, err := largefile.OpenCacheFiles(cacheFile, indexFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := largefile.NewLocalDownloadCache(d, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	cache = openCache()
+	bad, err = largefile.VerifyCache(ctx, cache, bd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := bad, []largefile.ByteRange{{From: 64, To: 127}, {From: 960, To: 999}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, br := range bad {
+		if err := cache.Clear(br.From); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cache.Complete() {
+		t.Errorf("cache should not be complete")
+	}
+	if err := cache.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cache = openCache()
+	defer cache.Close()
+	if _, blocks := cache.CachedBytesAndBlocks(); blocks != int64(largefile.NumBlocks(int64(size), blockSize))-2 {
+		t.Errorf("unexpected number of cached blocks: %v", blocks)
+	}
+	dl, err = largefile.NewCachingDownloader(rd, cache,
+		append(defaultOpts(4), largefile.WithDownloadBlockDigests(bd))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err = dl.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := st.DownloadedBlocks, int64(2); !st.Complete || got != want {
+		t.Errorf("unexpected status: %+v", st)
+	}
+	buf := make([]byte, size)
+	if _, err := cache.ReadAt(buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, data) {
+		t.Errorf("repaired data does not match")
+	}
+
+	other, _ := largefile.ComputeBlockDigests(ctx, bytes.NewReader(data), digests.SHA256, int64(size), blockSize*2)
+	if _, err := largefile.NewCachingDownloader(rd, cache, largefile.WithDownloadBlockDigests(other)); err == nil {
+		t.Errorf("expected an error")
+	}
+	sdl := largefile.NewStreamingDownloader(rd, largefile.WithDownloadBlockDigests(other))
+	if _, err := sdl.Run(ctx); err == nil {
+		t.Errorf("expected an error")
+	}
+	if _, err := io.ReadAll(sdl.Reader()); err == nil {
+		t.Errorf("expected an error")
+	}
+}
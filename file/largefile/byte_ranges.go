@@ -273,6 +273,26 @@ func (br *ByteRanges) Set(pos int64) {
 	}
 }
 
+// Clear marks the byte range for the specified position as clear.
+// It has no effect if the position is out of bounds. Clearing a range
+// that is within the contiguous range returned by Tail will shorten
+// that range, but will not notify any callers waiting on a channel
+// returned by Notify; Clear is intended for repairing an index, e.g.
+// one that records corrupted data, rather than for concurrent use
+// with Tail and Notify.
+func (br *ByteRanges) Clear(pos int64) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	blockIndex := br.Block(pos)
+	if blockIndex < 0 {
+		return
+	}
+	br.byteRanges.clear(pos)
+	if tail := br.contiguous.Tail(); tail >= 0 && blockIndex <= tail {
+		br.contiguous = bitmap.NewContiguousWithBitmap(br.bitmap, 0, br.bitmapSize)
+	}
+}
+
 // IsSet checks if the byte range for the specified position is set.
 func (br *ByteRanges) IsSet(pos int64) bool {
 	br.mu.RLock()
@@ -40,6 +40,9 @@ func NewCachingDownloader(file Reader, cache DownloadCache, opts ...DownloadOpti
 	if csize != dl.size || cblock != dl.blockSize {
 		return nil, fmt.Errorf("cache size (%d) or block size (%d) does not match file size (%d) or block size (%d)", csize, cblock, dl.size, dl.blockSize)
 	}
+	if err := dl.validateBlockDigests(); err != nil {
+		return nil, err
+	}
 	return dl, nil
 }
 
@@ -117,6 +120,9 @@ func (dl *CachingDownloader) runOnce(ctx context.Context) (DownloadStatus, error
 
 	err := g.Wait()
 	err = errors.Squash(err, context.Canceled, context.DeadlineExceeded)
+	if f, ok := dl.cache.(DownloadCacheFinalizer); ok && err == nil {
+		err = f.Finalize(ctx)
+	}
 
 	// Any errors encountered during the download are considered resumable, ie,
 	// the download can be restarted with the same cache to continue from where it left off.
@@ -878,3 +878,51 @@ func TestCachingDownloaderProgress(t *testing.T) {
 		}
 	})
 }
+
+func TestCachingDownloaderAdaptiveConcurrency(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	cacheSize := int64(64 * 200)
+	blockSize := 64
+	cache := createNewCache(ctx, t, tmpDir+"/cache.dat", tmpDir+"/cache.idx", cacheSize, blockSize, 1)
+	defer cache.Close()
+
+	minConcurrency, maxConcurrency := 2, 16
+	progressCh := make(chan largefile.DownloadStats, 1000)
+	mf := &mockLargeFile{size: cacheSize, blockSize: blockSize, failRatio: 1, withRetry: true}
+	dl, err := largefile.NewCachingDownloader(mf, cache,
+		largefile.WithDownloadRateController(&jitterRateLimiter{}),
+		largefile.WithDownloadAdaptiveConcurrency(minConcurrency, maxConcurrency),
+		largefile.WithDownloadProgress(progressCh))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	var seen []int64
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for st := range progressCh {
+			seen = append(seen, st.Concurrency)
+		}
+	}()
+	st, err := dl.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	if !st.Complete {
+		t.Errorf("download incomplete: %+v", st)
+	}
+	if st.DownloadRetries == 0 {
+		t.Errorf("expected some retries: %+v", st)
+	}
+	if len(seen) == 0 {
+		t.Fatal("no progress updates received")
+	}
+	for _, c := range append(seen, st.Concurrency) {
+		if c < int64(minConcurrency) || c > int64(maxConcurrency) {
+			t.Errorf("concurrency %v out of range [%v, %v]", c, minConcurrency, maxConcurrency)
+		}
+	}
+}
@@ -13,17 +13,20 @@ import (
 )
 
 type downloaderOptions struct {
-	concurrency       int
-	rateController    ratecontrol.Limiter
-	progressCh        chan<- DownloadStats // Channel to report download progress.
-	progressTimeout   time.Duration        // Delay between progress updates.
-	logger            *slog.Logger
-	waitForCompletion bool
+	concurrency         int
+	minConcurrency      int // Minimum concurrency when adaptive concurrency is enabled.
+	adaptiveConcurrency bool
+	rateController      ratecontrol.Limiter
+	progressCh          chan<- DownloadStats // Channel to report download progress.
+	progressTimeout     time.Duration        // Delay between progress updates.
+	logger              *slog.Logger
+	waitForCompletion   bool
 }
 
 type downloadOptions struct {
 	downloaderOptions
-	hash digests.Hash // Optional hash for computing the downloaded data as it is streamed.
+	hash         digests.Hash  // Optional hash for computing the downloaded data as it is streamed.
+	blockDigests *BlockDigests // Optional per-block digests for verifying each block as it is downloaded.
 }
 
 type DownloadOption func(*downloadOptions)
@@ -35,6 +38,23 @@ func WithDownloadConcurrency(n int) DownloadOption {
 	}
 }
 
+// WithDownloadAdaptiveConcurrency enables adaptive concurrency whereby the
+// number of concurrent in-flight requests is adjusted, between minConcurrency
+// and maxConcurrency, based on the observed latency and retry rate using an
+// AIMD (additive increase, multiplicative decrease) strategy. The download
+// starts with minConcurrency requests in flight, which is increased by one
+// for every round of successful requests and halved whenever retryable
+// errors are encountered or the latency of each request increases
+// significantly. The current concurrency is reported via DownloadStats.
+// WithDownloadAdaptiveConcurrency overrides WithDownloadConcurrency.
+func WithDownloadAdaptiveConcurrency(minConcurrency, maxConcurrency int) DownloadOption {
+	return func(o *downloadOptions) {
+		o.adaptiveConcurrency = true
+		o.minConcurrency = minConcurrency
+		o.concurrency = maxConcurrency
+	}
+}
+
 // WithDownloadRateController sets the rate controller for the download.
 func WithDownloadRateController(rc ratecontrol.Limiter) DownloadOption {
 	return func(o *downloadOptions) {
@@ -85,3 +105,14 @@ func WithDownloadDigest(h digests.Hash) DownloadOption {
 		o.hash = h
 	}
 }
+
+// WithDownloadBlockDigests sets the per-block digests to be used to verify
+// each block as it is downloaded. Blocks that fail verification are counted
+// in DownloadStats.VerificationErrors and are not cached or streamed, they will be
+// downloaded again on a subsequent iteration (see WithDownloadWaitForCompletion)
+// or when the download is resumed.
+func WithDownloadBlockDigests(bd *BlockDigests) DownloadOption {
+	return func(o *downloadOptions) {
+		o.blockDigests = bd
+	}
+}
@@ -31,7 +31,9 @@ type DownloadStats struct {
 	DownloadBlocks         int64 // Total number of blocks to download.
 	DownloadRetries        int64 // Total number of retries made during the download.
 	DownloadErrors         int64 // Total number of errors encountered during the download.
+	VerificationErrors     int64 // Total number of blocks that failed verification against their block digests.
 	Iterations             int64 // Number of iterations requiredd to complete the download.
+	Concurrency            int64 // Number of concurrent requests currently permitted when adaptive concurrency is enabled.
 }
 
 func (ds DownloadStats) updateAfterIteration(nds DownloadStats) DownloadStats {
@@ -45,9 +47,11 @@ func (ds DownloadStats) updateAfterIteration(nds DownloadStats) DownloadStats {
 		DownloadedBlocks:       ds.DownloadedBlocks + nds.DownloadedBlocks,
 		DownloadRetries:        ds.DownloadRetries + nds.DownloadRetries,
 		DownloadErrors:         ds.DownloadErrors + nds.DownloadErrors,
+		VerificationErrors:     ds.VerificationErrors + nds.VerificationErrors,
 		DownloadSize:           nds.DownloadSize,
 		DownloadBlocks:         nds.DownloadBlocks,
 		Iterations:             ds.Iterations + 1, // Increment iterations.
+		Concurrency:            nds.Concurrency,
 	}
 }
 
@@ -73,7 +77,9 @@ func (pt *progressTracker) send() {
 		DownloadBlocks:         pt.DownloadBlocks,
 		DownloadRetries:        atomic.LoadInt64(&pt.DownloadRetries),
 		DownloadErrors:         atomic.LoadInt64(&pt.DownloadErrors),
+		VerificationErrors:     atomic.LoadInt64(&pt.VerificationErrors),
 		Iterations:             atomic.LoadInt64(&pt.Iterations),
+		Concurrency:            atomic.LoadInt64(&pt.Concurrency),
 	}
 	select {
 	case pt.ch <- stats:
@@ -97,11 +103,21 @@ func (pt *progressTracker) incrementDownloadErrors() {
 	pt.send()
 }
 
+func (pt *progressTracker) incrementVerificationErrors() {
+	atomic.AddInt64(&pt.VerificationErrors, 1)
+	pt.send()
+}
+
 func (pt *progressTracker) incrementCacheErrors() {
 	atomic.AddInt64(&pt.CacheErrors, 1)
 	pt.send()
 }
 
+func (pt *progressTracker) setConcurrency(concurrency int) {
+	atomic.StoreInt64(&pt.Concurrency, int64(concurrency))
+	pt.send()
+}
+
 func (pt *progressTracker) incrementDownload(blocks int, size int64) {
 	atomic.AddInt64(&pt.DownloadedBytes, size)
 	atomic.AddInt64(&pt.DownloadedBlocks, int64(blocks))
@@ -110,11 +126,12 @@ func (pt *progressTracker) incrementDownload(blocks int, size int64) {
 
 type downloader struct {
 	downloadOptions
-	progress  *progressTracker // Progress tracker for the download.
-	file      Reader           // The large file to download.
-	size      int64            // Total size of the file in bytes.
-	blockSize int              // Size of each block in bytes.
-	bufPool   sync.Pool        // Pool for byte slices to reduce allocations.
+	progress  *progressTracker     // Progress tracker for the download.
+	file      Reader               // The large file to download.
+	size      int64                // Total size of the file in bytes.
+	blockSize int                  // Size of each block in bytes.
+	bufPool   sync.Pool            // Pool for byte slices to reduce allocations.
+	limiter   *adaptiveConcurrency // Non-nil if adaptive concurrency is enabled.
 }
 
 func newDownloader(file Reader, opts downloadOptions) *downloader {
@@ -149,9 +166,27 @@ func newDownloader(file Reader, opts downloadOptions) *downloader {
 		},
 		ch: dl.progressCh,
 	}
+	if dl.adaptiveConcurrency {
+		// dl.concurrency fetchers are always started, but the number of
+		// in-flight requests is limited by the adaptive controller.
+		dl.limiter = newAdaptiveConcurrency(dl.minConcurrency, dl.concurrency, dl.progress.setConcurrency)
+	}
 	return dl
 }
 
+// validateBlockDigests returns an error if the block digests, if any,
+// do not match the size and block size of the file being downloaded.
+func (dl *downloader) validateBlockDigests() error {
+	if dl.blockDigests == nil {
+		return nil
+	}
+	bsize, bblock := dl.blockDigests.ContentLengthAndBlockSize()
+	if bsize != dl.size || bblock != dl.blockSize {
+		return fmt.Errorf("block digests size (%d) or block size (%d) does not match file size (%d) or block size (%d)", bsize, bblock, dl.size, dl.blockSize)
+	}
+	return nil
+}
+
 type response struct {
 	data      *bytes.Buffer
 	ByteRange // The byte range that was fetched.
@@ -162,18 +197,29 @@ type request struct {
 	ByteRange // The byte range to fetch.
 }
 
-func (dl *downloader) get(ctx context.Context, req request) (io.ReadCloser, error) {
+// get returns a reader for the requested byte range, retrying any
+// retryable errors. A concurrency slot is held for each attempt, but not
+// whilst backing off, and is still held when get returns successfully;
+// the caller must release it, the returned time is when the successful
+// attempt started.
+func (dl *downloader) get(ctx context.Context, req request) (io.ReadCloser, time.Time, error) {
 	backoff := dl.rateController.Backoff()
 	retries := 0
 	for {
+		if err := dl.limiter.acquire(ctx); err != nil {
+			return nil, time.Time{}, err
+		}
+		start := time.Now()
 		rd, retry, err := dl.file.GetReader(ctx, req.From, req.To)
 		if err == nil {
-			return rd, nil
+			return rd, start, nil
 		}
+		dl.limiter.release(0, time.Since(start))
 		if retry != nil && retry.IsRetryable() {
+			dl.limiter.congested()
 			if done, _ := backoff.Wait(ctx, retry); done {
 				dl.logger.Info("getReader: backoff exhausted", "byteRange", req.ByteRange, "retries", retries, "error", err)
-				return nil, fmt.Errorf("application backoff giving up after %d retries: %w", backoff.Retries(), err)
+				return nil, time.Time{}, fmt.Errorf("application backoff giving up after %d retries: %w", backoff.Retries(), err)
 			}
 			retries++
 			dl.progress.incrementRetries(1)
@@ -181,7 +227,7 @@ func (dl *downloader) get(ctx context.Context, req request) (io.ReadCloser, erro
 		}
 		dl.progress.incrementDownloadErrors()
 		dl.logger.Info("getReader: non retryable error", "byteRange", req.ByteRange, "retries", retries, "error", err)
-		return nil, fmt.Errorf("failed to get byte range %v: %w", req.ByteRange, err)
+		return nil, time.Time{}, fmt.Errorf("failed to get byte range %v: %w", req.ByteRange, err)
 	}
 }
 
@@ -192,14 +238,15 @@ func (dl *downloader) handleGet(ctx context.Context, req request, handler respon
 	if err := dl.rateController.Wait(ctx); err != nil {
 		return err // Context was canceled or deadline exceeded, return immediately.
 	}
-	start := time.Now()
-	rd, err := dl.get(ctx, req)
+	rd, start, err := dl.get(ctx, req)
 	if err != nil {
 		return err
 	}
+	defer rd.Close()
 	buf := dl.bufPool.Get().(*bytes.Buffer)
 	buf.Reset()
 	copied, err := io.Copy(buf, rd)
+	dl.limiter.release(copied, time.Since(start))
 	dl.rateController.BytesTransferred(int(copied))
 	dl.progress.incrementDownload(1, copied)
 	if err != nil {
@@ -208,6 +255,14 @@ func (dl *downloader) handleGet(ctx context.Context, req request, handler respon
 	if copied != req.Size() {
 		return fmt.Errorf("copied %d bytes for byte range %v, expected %d bytes", copied, req.ByteRange, req.Size())
 	}
+	if dl.blockDigests != nil {
+		if err := dl.blockDigests.Verify(req.From, buf.Bytes()); err != nil {
+			dl.bufPool.Put(buf)
+			dl.progress.incrementVerificationErrors()
+			dl.logger.Info("handleGet: block verification failed", "byteRange", req.ByteRange, "error", err)
+			return fmt.Errorf("failed to verify byte range %v: %w", req.ByteRange, err)
+		}
+	}
 	var resp response
 	resp.ByteRange = req.ByteRange
 	resp.duration = time.Since(start)
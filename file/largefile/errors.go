@@ -34,6 +34,27 @@ func (e *internalError) Is(target error) bool {
 	return ok
 }
 
+// nonRetryableError is used to wrap errors that will not succeed if
+// retried, eg. a Writer error that is not retryable.
+type nonRetryableError struct {
+	err error
+}
+
+var errNonRetryable = &nonRetryableError{}
+
+func (e *nonRetryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *nonRetryableError) Unwrap() error {
+	return e.err
+}
+
+func (e *nonRetryableError) Is(target error) bool {
+	_, ok := target.(*nonRetryableError)
+	return ok
+}
+
 func newInternalCacheError(err error) error {
 	return &internalError{component: "cache", err: err}
 }
@@ -45,3 +66,7 @@ func newInternalStreamingError(err error) error {
 func newInternalDownloadError(err error) error {
 	return &internalError{component: "download", err: err}
 }
+
+func newInternalUploadError(err error) error {
+	return &internalError{component: "upload", err: err}
+}
@@ -405,6 +405,27 @@ func TestByteRanges_NotifyAndTail(t *testing.T) {
 			t.Errorf("Tail() = %v, want %v", r, want)
 		}
 	})
+
+	t.Run("Clear shortens the tail", func(t *testing.T) {
+		br := largefile.NewByteRanges(contentSize, blockSize)
+		for i := 0; i < br.NumBlocks(); i++ {
+			br.Set(int64(i * blockSize))
+		}
+		br.Clear(2048)
+		if !br.IsClear(2048) {
+			t.Errorf("block at 2048 should be clear")
+		}
+		r, ok := br.Tail()
+		want := largefile.ByteRange{From: 0, To: 2047}
+		if !ok || !reflect.DeepEqual(r, want) {
+			t.Errorf("Tail() = %v, want %v", r, want)
+		}
+		br.Set(2048)
+		r, _ = br.Tail()
+		if want := (largefile.ByteRange{From: 0, To: contentSize - 1}); !reflect.DeepEqual(r, want) {
+			t.Errorf("Tail() = %v, want %v", r, want)
+		}
+	})
 }
 
 type mockRetryResponse struct {
@@ -4,4 +4,407 @@
 
 package largefile
 
-type Uploader struct{}
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cloudeng.io/algo/ratecontrol"
+	"cloudeng.io/errors"
+	"cloudeng.io/sync/errgroup"
+)
+
+// Writer provides support for uploading very large files efficiently,
+// concurrently and to allow for resumption of partial uploads. It is the
+// upload counterpart of Reader and is typically implemented using
+// a multipart upload API, with each byte range being uploaded as a
+// separate part.
+type Writer interface {
+	Name() string // Name returns the name of the file being written.
+
+	// ContentLengthAndBlockSize returns the total length of the file in bytes
+	// and the block size to be used for uploading the file.
+	ContentLengthAndBlockSize() (int64, int)
+
+	// PutReader uploads the data for the specified byte range which is
+	// read from the supplied reader. In addition to the error, the
+	// RetryResponse is returned which indicates whether the operation can
+	// be retried and the duration to wait before retrying. PutReader may
+	// be called concurrently and may be called more than once for the
+	// same byte range, eg. when an upload is retried or resumed.
+	PutReader(ctx context.Context, from, to int64, rd io.Reader) (RetryResponse, error)
+
+	// Complete is called once all byte ranges have been successfully
+	// uploaded to finalize the upload.
+	Complete(ctx context.Context) error
+}
+
+// UploadStats statistics on the upload process and is used for progress
+// reporting.
+type UploadStats struct {
+	UploadedBytes  int64 // Total bytes uploaded, including those uploaded by a prior run.
+	UploadedBlocks int64 // Total blocks uploaded, including those uploaded by a prior run.
+	IndexErrors    int64 // Total number of errors encountered while saving the index.
+	UploadSize     int64 // Total size of the file in bytes.
+	UploadBlocks   int64 // Total number of blocks to upload.
+	UploadRetries  int64 // Total number of retries made during the upload.
+	UploadErrors   int64 // Total number of errors encountered during the upload.
+	Iterations     int64 // Number of iterations required to complete the upload.
+}
+
+// UploadStatus holds the status for a completed upload operation, including
+// the progress made, whether the upload is resumable, completed and
+// the total duration of operation.
+type UploadStatus struct {
+	UploadStats
+	Resumable bool          // Indicates if the upload can be re-run.
+	Complete  bool          // Indicates if the upload completed successfully.
+	Duration  time.Duration // Total duration of the upload.
+}
+
+type uploadProgressTracker struct {
+	UploadStats
+	mu sync.Mutex // Mutex to protect concurrent access to the stats.
+	ch chan<- UploadStats
+}
+
+func (pt *uploadProgressTracker) stats() UploadStats {
+	return UploadStats{
+		UploadedBytes:  atomic.LoadInt64(&pt.UploadedBytes),
+		UploadedBlocks: atomic.LoadInt64(&pt.UploadedBlocks),
+		IndexErrors:    atomic.LoadInt64(&pt.IndexErrors),
+		UploadSize:     pt.UploadSize,
+		UploadBlocks:   pt.UploadBlocks,
+		UploadRetries:  atomic.LoadInt64(&pt.UploadRetries),
+		UploadErrors:   atomic.LoadInt64(&pt.UploadErrors),
+		Iterations:     atomic.LoadInt64(&pt.Iterations),
+	}
+}
+
+func (pt *uploadProgressTracker) send() {
+	if pt.ch == nil {
+		return
+	}
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	select {
+	case pt.ch <- pt.stats():
+	default:
+	}
+}
+
+func (pt *uploadProgressTracker) incrementUploaded(bytes, blocks int64) {
+	atomic.AddInt64(&pt.UploadedBytes, bytes)
+	atomic.AddInt64(&pt.UploadedBlocks, blocks)
+	pt.send()
+}
+
+func (pt *uploadProgressTracker) incrementRetries(retries int) {
+	atomic.AddInt64(&pt.UploadRetries, int64(retries))
+	pt.send()
+}
+
+func (pt *uploadProgressTracker) incrementUploadErrors() {
+	atomic.AddInt64(&pt.UploadErrors, 1)
+	pt.send()
+}
+
+func (pt *uploadProgressTracker) incrementIndexErrors() {
+	atomic.AddInt64(&pt.IndexErrors, 1)
+	pt.send()
+}
+
+// Uploader uploads a large file concurrently, one block at a time, using
+// a Writer. The byte ranges that have been successfully uploaded are
+// tracked using a ByteRanges index which can optionally be persisted
+// (see WithUploadIndex) to allow for an interrupted upload to be resumed.
+type Uploader struct {
+	uploadOptions
+	mu        sync.Mutex // Serializes updates to the index.
+	file      io.ReaderAt
+	writer    Writer
+	size      int64
+	blockSize int
+	index     *indexStore
+	progress  *uploadProgressTracker
+	bufPool   sync.Pool
+}
+
+// NewUploader creates a new Uploader instance that will read the data
+// to be uploaded from the supplied file and upload it using the supplied
+// writer. The size of the file and the block size used for the upload
+// are obtained from the writer.
+func NewUploader(file io.ReaderAt, writer Writer, opts ...UploadOption) (*Uploader, error) {
+	if file == nil || writer == nil {
+		return nil, fmt.Errorf("file and writer must be non-nil")
+	}
+	up := &Uploader{file: file, writer: writer}
+	for _, opt := range opts {
+		opt(&up.uploadOptions)
+	}
+	if up.logger == nil {
+		up.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	up.logger = up.logger.With("pkg", "cloudeng.io/file/largefile", "upload", writer.Name())
+	if up.rateController == nil {
+		up.rateController = ratecontrol.New(ratecontrol.WithNoRateControl()) // Default to no rate control.
+	}
+	if up.concurrency <= 0 {
+		up.concurrency = runtime.NumCPU() // Default to number of CPU cores.
+	}
+	if up.progressTimeout == 0 {
+		up.progressTimeout = time.Second
+	}
+	up.size, up.blockSize = writer.ContentLengthAndBlockSize()
+	if up.blockSize <= 0 {
+		return nil, fmt.Errorf("invalid block size %d for %v", up.blockSize, writer.Name())
+	}
+	idx, err := loadOrCreateIndex(up.indexFile, up.size, up.blockSize)
+	if err != nil {
+		return nil, err
+	}
+	up.index = idx
+	up.bufPool = sync.Pool{
+		New: func() any {
+			return make([]byte, up.blockSize)
+		},
+	}
+	up.progress = &uploadProgressTracker{
+		UploadStats: UploadStats{
+			UploadSize:   up.size,
+			UploadBlocks: int64(NumBlocks(up.size, up.blockSize)),
+		},
+		ch: up.progressCh,
+	}
+	return up, nil
+}
+
+// loadOrCreateIndex loads the index from the supplied file, if it is
+// non-empty, or creates a new one otherwise.
+func loadOrCreateIndex(wr CacheFileReadWriter, size int64, blockSize int) (*indexStore, error) {
+	idx := &indexStore{wr: wr}
+	if wr == nil {
+		idx.ByteRanges = NewByteRanges(size, blockSize)
+		return idx, nil
+	}
+	ok, err := idx.loadIfNotEmpty()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		idx.ByteRanges = NewByteRanges(size, blockSize)
+		return idx, idx.save()
+	}
+	if idx.ContentLength() != size || idx.BlockSize() != blockSize {
+		return nil, fmt.Errorf("index file %s size (%d) or block size (%d) does not match file size (%d) or block size (%d)", wr.Name(), idx.ContentLength(), idx.BlockSize(), size, blockSize)
+	}
+	return idx, nil
+}
+
+// UploadedBytesAndBlocks returns the total number of bytes and blocks that
+// have been uploaded so far, including those uploaded by a prior run.
+func (up *Uploader) UploadedBytesAndBlocks() (bytes, blocks int64) {
+	var br ByteRange
+	for n := up.index.NextSet(0, &br); n != -1; n = up.index.NextSet(n, &br) {
+		bytes += br.Size()
+		blocks++
+	}
+	return
+}
+
+// Complete returns true if all byte ranges have been uploaded.
+func (up *Uploader) Complete() bool {
+	var br ByteRange
+	return up.index.NextClear(0, &br) == -1
+}
+
+// Run executes the upload process. If WithUploadWaitForCompletion is
+// set to true, Run will continue to iterate over the outstanding byte
+// ranges, using the rate controller's backoff between iterations, until
+// all of them have been uploaded, a non-retryable error is encountered,
+// the backoff gives up or the context is canceled. Once all byte ranges
+// have been uploaded the Writer's Complete method is called to finalize
+// the upload.
+func (up *Uploader) Run(ctx context.Context) (UploadStatus, error) {
+	uploadedBytes, uploadedBlocks := up.UploadedBytesAndBlocks()
+	up.progress.incrementUploaded(uploadedBytes, uploadedBlocks)
+
+	start := time.Now()
+	backoff := up.rateController.Backoff()
+	for {
+		st, err := up.runOnce(ctx)
+		if st.Complete && err == nil {
+			return up.finalize(st, start, nil)
+		}
+		if !up.waitForCompletion || !st.Resumable || errors.Is(err, errNonRetryable) {
+			return up.finalize(st, start, err)
+		}
+		up.logger.Info("runOnce: upload not complete, retrying", "iterations", st.Iterations, "error", err)
+		if done, berr := backoff.Wait(ctx, nil); done {
+			if berr != nil {
+				err = berr
+			}
+			return up.finalize(st, start, err)
+		}
+	}
+}
+
+func (up *Uploader) finalize(status UploadStatus, start time.Time, err error) (UploadStatus, error) {
+	status.Duration = time.Since(start)
+	status.UploadStats = up.progress.stats()
+	if up.progressCh != nil {
+		// Send the final upload state to the progress channel, taking
+		// care to ensure that a timeout is used, but also giving the
+		// receiver a chance to read the final state.
+		select {
+		case up.progressCh <- status.UploadStats:
+		case <-time.After(up.progressTimeout):
+		}
+		close(up.progressCh) // Ensure the progress channel is closed when done.
+	}
+	return status, err
+}
+
+func (up *Uploader) runOnce(ctx context.Context) (UploadStatus, error) {
+	atomic.AddInt64(&up.progress.Iterations, 1)
+	reqCh := make(chan ByteRange, up.concurrency) // Buffered channel for byte ranges to upload.
+	g, gctx := errgroup.WithContext(ctx)
+	g = errgroup.WithConcurrency(g, up.concurrency+1) // +1 for the generator goroutine
+	g.Go(func() error {
+		defer close(reqCh)
+		return up.generator(gctx, reqCh)
+	})
+	for range up.concurrency {
+		g.Go(func() error {
+			return up.uploader(gctx, reqCh)
+		})
+	}
+	err := g.Wait()
+	err = errors.Squash(err, context.Canceled, context.DeadlineExceeded)
+
+	if err == nil && up.Complete() {
+		if err = up.writer.Complete(ctx); err != nil {
+			up.progress.incrementUploadErrors()
+			err = &nonRetryableError{fmt.Errorf("failed to complete upload for %v: %w", up.writer.Name(), err)}
+		}
+	}
+
+	// Any errors encountered during the upload are considered resumable, ie,
+	// the upload can be restarted with the same index to continue from where
+	// it left off, but non-retryable errors are not retried by Run.
+	resumable := err != nil
+	if errors.Is(err, ErrInternalError) {
+		// If the error is a terminal error, we consider it non-resumable.
+		resumable = false
+	}
+	st := UploadStatus{
+		UploadStats: up.progress.stats(),
+		Complete:    up.Complete() && err == nil,
+		Resumable:   resumable,
+	}
+	return st, err
+}
+
+func (up *Uploader) generator(ctx context.Context, reqCh chan<- ByteRange) error {
+	var br ByteRange
+	// Start with the first byte range that has not been uploaded.
+	for n := up.index.NextClear(0, &br); n != -1; n = up.index.NextClear(n, &br) {
+		select {
+		case reqCh <- br:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (up *Uploader) uploader(ctx context.Context, in <-chan ByteRange) error {
+	errs := &errors.M{}
+	for {
+		select {
+		case br, ok := <-in:
+			if !ok {
+				return errs.Err()
+			}
+			if err := up.handlePut(ctx, br); err != nil {
+				if errors.Is(err, context.Canceled) ||
+					errors.Is(err, context.DeadlineExceeded) ||
+					errors.Is(err, ErrInternalError) {
+					return err
+				}
+				errs.Append(err)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (up *Uploader) handlePut(ctx context.Context, br ByteRange) error {
+	if err := up.rateController.Wait(ctx); err != nil {
+		return err // Context was canceled or deadline exceeded, return immediately.
+	}
+	buf := up.bufPool.Get().([]byte)
+	defer up.bufPool.Put(buf) //nolint:staticcheck // SA6002: buf is a slice.
+	data := buf[:br.Size()]
+	n, err := up.file.ReadAt(data, br.From)
+	if n != len(data) {
+		if err == nil || errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return newInternalUploadError(fmt.Errorf("failed to read byte range %v: read %d bytes: %w", br, n, err))
+	}
+	if err := up.put(ctx, br, data); err != nil {
+		return err
+	}
+	up.rateController.BytesTransferred(len(data))
+	if err := up.markUploaded(br); err != nil {
+		up.progress.incrementIndexErrors()
+		up.logger.Info("handlePut: index update failed", "byteRange", br, "error", err)
+		return err
+	}
+	up.progress.incrementUploaded(int64(len(data)), 1)
+	return nil
+}
+
+func (up *Uploader) put(ctx context.Context, br ByteRange, data []byte) error {
+	backoff := up.rateController.Backoff()
+	retries := 0
+	for {
+		retry, err := up.writer.PutReader(ctx, br.From, br.To, bytes.NewReader(data))
+		if err == nil {
+			return nil
+		}
+		if retry != nil && retry.IsRetryable() {
+			if done, _ := backoff.Wait(ctx, retry); done {
+				up.logger.Info("putReader: backoff exhausted", "byteRange", br, "retries", retries, "error", err)
+				return fmt.Errorf("application backoff giving up after %d retries: %w", backoff.Retries(), err)
+			}
+			retries++
+			up.progress.incrementRetries(1)
+			continue
+		}
+		up.progress.incrementUploadErrors()
+		up.logger.Info("putReader: non retryable error", "byteRange", br, "retries", retries, "error", err)
+		return &nonRetryableError{fmt.Errorf("failed to put byte range %v: %w", br, err)}
+	}
+}
+
+func (up *Uploader) markUploaded(br ByteRange) error {
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	up.index.Set(br.From)
+	if up.index.wr == nil {
+		return nil
+	}
+	if err := up.index.save(); err != nil {
+		return newInternalUploadError(err)
+	}
+	return up.index.wr.Sync()
+}
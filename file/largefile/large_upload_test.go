@@ -0,0 +1,294 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package largefile_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"cloudeng.io/algo/ratecontrol"
+	"cloudeng.io/file/largefile"
+)
+
+// memPartSink implements largefile.Writer by storing each uploaded
+// byte range as a separate, in-memory, part.
+type memPartSink struct {
+	size      int64
+	blockSize int
+
+	mu        sync.Mutex
+	parts     map[int64][]byte
+	puts      int
+	failAt    map[int64]int // offset -> number of times to fail.
+	retryable bool
+	completed bool
+	data      []byte
+}
+
+func newMemPartSink(size int64, blockSize int) *memPartSink {
+	return &memPartSink{
+		size:      size,
+		blockSize: blockSize,
+		parts:     map[int64][]byte{},
+		failAt:    map[int64]int{},
+	}
+}
+
+func (m *memPartSink) Name() string { return "memPartSink" }
+
+func (m *memPartSink) ContentLengthAndBlockSize() (int64, int) {
+	return m.size, m.blockSize
+}
+
+func (m *memPartSink) PutReader(_ context.Context, from, to int64, rd io.Reader) (largefile.RetryResponse, error) {
+	data, err := io.ReadAll(rd)
+	if err != nil {
+		return noRetryResponse{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.puts++
+	if n := m.failAt[from]; n > 0 {
+		m.failAt[from] = n - 1
+		if m.retryable {
+			return retryResponse{}, errors.New("mock retryable put failure")
+		}
+		return noRetryResponse{}, errors.New("mock put failure")
+	}
+	if int64(len(data)) != to-from+1 {
+		return noRetryResponse{}, errors.New("short part")
+	}
+	m.parts[from] = data
+	return nil, nil
+}
+
+func (m *memPartSink) Complete(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	offsets := make([]int64, 0, len(m.parts))
+	for off := range m.parts {
+		offsets = append(offsets, off)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	m.data = nil
+	for _, off := range offsets {
+		m.data = append(m.data, m.parts[off]...)
+	}
+	m.completed = true
+	return nil
+}
+
+func newUploadData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+func TestUploaderSimple(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		size, blockSize, concurrency int
+	}{
+		{1, 64, 1},
+		{64, 64, 1},
+		{1000, 64, 1},
+		{1000, 64, 8},
+		{4096, 100, 3},
+	} {
+		data := newUploadData(tc.size)
+		sink := newMemPartSink(int64(tc.size), tc.blockSize)
+		progressCh := make(chan largefile.UploadStats, 1000)
+		up, err := largefile.NewUploader(bytes.NewReader(data), sink,
+			largefile.WithUploadConcurrency(tc.concurrency),
+			largefile.WithUploadProgress(progressCh))
+		if err != nil {
+			t.Fatal(err)
+		}
+		st, err := up.Run(ctx)
+		if err != nil {
+			t.Fatalf("%v: %v", tc, err)
+		}
+		if !st.Complete || st.Resumable {
+			t.Errorf("%v: unexpected status: %+v", tc, st)
+		}
+		if got, want := st.UploadedBytes, int64(tc.size); got != want {
+			t.Errorf("%v: got %v, want %v", tc, got, want)
+		}
+		if got, want := st.UploadedBlocks, int64(largefile.NumBlocks(int64(tc.size), tc.blockSize)); got != want {
+			t.Errorf("%v: got %v, want %v", tc, got, want)
+		}
+		if got, want := st.Iterations, int64(1); got != want {
+			t.Errorf("%v: got %v, want %v", tc, got, want)
+		}
+		if !sink.completed {
+			t.Errorf("%v: upload was not completed", tc)
+		}
+		if !bytes.Equal(sink.data, data) {
+			t.Errorf("%v: uploaded data does not match", tc)
+		}
+		var last largefile.UploadStats
+		for s := range progressCh {
+			last = s
+		}
+		if got, want := last, st.UploadStats; got != want {
+			t.Errorf("%v: got %+v, want %+v", tc, got, want)
+		}
+	}
+}
+
+func TestUploaderRetries(t *testing.T) {
+	ctx := context.Background()
+	size, blockSize := 1000, 64
+	data := newUploadData(size)
+	sink := newMemPartSink(int64(size), blockSize)
+	sink.retryable = true
+	sink.failAt[0] = 2
+	sink.failAt[128] = 1
+	up, err := largefile.NewUploader(bytes.NewReader(data), sink,
+		largefile.WithUploadRateController(&jitterRateLimiter{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := up.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := st.UploadRetries, int64(3); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !st.Complete || !bytes.Equal(sink.data, data) {
+		t.Errorf("upload failed: %+v", st)
+	}
+}
+
+func TestUploaderResume(t *testing.T) {
+	ctx := context.Background()
+	size, blockSize := 1000, 64
+	data := newUploadData(size)
+	sink := newMemPartSink(int64(size), blockSize)
+	sink.failAt[128] = 1
+	sink.failAt[512] = 1
+
+	indexFile := filepath.Join(t.TempDir(), "index")
+	openIndex := func() *os.File {
+		f, err := os.OpenFile(indexFile, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	idx := openIndex()
+	up, err := largefile.NewUploader(bytes.NewReader(data), sink,
+		largefile.WithUploadIndex(idx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := up.Run(ctx)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if st.Complete || !st.Resumable || sink.completed {
+		t.Errorf("unexpected status: %+v", st)
+	}
+	if got, want := st.UploadErrors, int64(2); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	nblocks := int64(largefile.NumBlocks(int64(size), blockSize))
+	if got, want := st.UploadedBlocks, nblocks-2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	idx.Close()
+
+	// Resume the upload, only the two failed blocks should be uploaded.
+	sink.puts = 0
+	idx = openIndex()
+	defer idx.Close()
+	up, err = largefile.NewUploader(bytes.NewReader(data), sink,
+		largefile.WithUploadIndex(idx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err = up.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sink.puts, 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := st.UploadedBlocks, nblocks; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !st.Complete || !sink.completed || !bytes.Equal(sink.data, data) {
+		t.Errorf("upload failed: %+v", st)
+	}
+
+	// A mismatched index should be rejected.
+	if _, err := idx.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	_, err = largefile.NewUploader(bytes.NewReader(data), newMemPartSink(int64(size), blockSize*2),
+		largefile.WithUploadIndex(idx))
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestUploaderWaitForCompletion(t *testing.T) {
+	ctx := context.Background()
+	size, blockSize := 1000, 64
+	data := newUploadData(size)
+	sink := newMemPartSink(int64(size), blockSize)
+	sink.retryable = true
+	sink.failAt[0] = 1
+	sink.failAt[64] = 3
+	// The backoff gives up after a single retry so the failures for
+	// the block at offset 64 require a second iteration.
+	rc := ratecontrol.New(ratecontrol.WithExponentialBackoff(time.Millisecond, 1, false))
+	up, err := largefile.NewUploader(bytes.NewReader(data), sink,
+		largefile.WithUploadConcurrency(2),
+		largefile.WithUploadRateController(rc),
+		largefile.WithUploadWaitForCompletion(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := up.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := st.Iterations, int64(2); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !st.Complete || !bytes.Equal(sink.data, data) {
+		t.Errorf("upload failed: %+v", st)
+	}
+
+	// Non-retryable errors are not retried.
+	sink = newMemPartSink(int64(size), blockSize)
+	sink.failAt[64] = 1000
+	up, err = largefile.NewUploader(bytes.NewReader(data), sink,
+		largefile.WithUploadConcurrency(2),
+		largefile.WithUploadWaitForCompletion(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err = up.Run(ctx)
+	if err == nil || st.Complete {
+		t.Fatalf("expected an error: %+v", st)
+	}
+	if got, want := st.Iterations, int64(1); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
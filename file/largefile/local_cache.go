@@ -5,6 +5,7 @@
 package largefile
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -64,6 +65,14 @@ type DownloadCache interface {
 	ReadAt(data []byte, off int64) (int, error)
 }
 
+// DownloadCacheFinalizer may be implemented by a DownloadCache that must be
+// finalized once all of its byte ranges have been cached, for example to
+// complete a multipart upload. CachingDownloader calls Finalize once a
+// download has completed and all of its workers have finished.
+type DownloadCacheFinalizer interface {
+	Finalize(ctx context.Context) error
+}
+
 // LocalDownloadCache is a concrete implementation of RangeCache that uses
 // a local file to cache byte ranges of large files.
 // It allows for concurrent access.
@@ -195,7 +204,8 @@ func NewLocalDownloadCache(dataReadWriter, indexReadWriter CacheFileReadWriter)
 
 type indexStore struct {
 	*ByteRanges
-	wr CacheFileReadWriter
+	wr      CacheFileReadWriter
+	written int // size of the largest index written so far.
 }
 
 func (i *indexStore) save() error {
@@ -203,6 +213,12 @@ func (i *indexStore) save() error {
 	if err != nil {
 		return newInternalCacheError(fmt.Errorf("failed to marshal ranges to JSON: %w", err))
 	}
+	if len(data) < i.written {
+		// The index may shrink if ranges are cleared, pad it with
+		// whitespace to overwrite any previously written data.
+		data = append(data, bytes.Repeat([]byte{' '}, i.written-len(data))...)
+	}
+	i.written = len(data)
 	n, err := i.wr.WriteAt(data, 0)
 	if err != nil {
 		return newInternalCacheError(fmt.Errorf("failed to write index file %s: %w", i.wr.Name(), err))
@@ -214,14 +230,31 @@ func (i *indexStore) save() error {
 }
 
 func (i *indexStore) load() error {
+	ok, err := i.loadIfNotEmpty()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newInternalCacheError(fmt.Errorf("failed to unmarshal index file %s: %w", i.wr.Name(), io.ErrUnexpectedEOF))
+	}
+	return nil
+}
+
+// loadIfNotEmpty is like load, but returns false, rather than an error,
+// if the index file is empty.
+func (i *indexStore) loadIfNotEmpty() (bool, error) {
 	buf, err := io.ReadAll(i.wr)
 	if err != nil {
-		return newInternalCacheError(fmt.Errorf("failed to read index file %s: %w", i.wr.Name(), err))
+		return false, newInternalCacheError(fmt.Errorf("failed to read index file %s: %w", i.wr.Name(), err))
+	}
+	i.written = len(buf)
+	if len(buf) == 0 {
+		return false, nil
 	}
 	if err := json.Unmarshal(buf, &i.ByteRanges); err != nil {
-		return newInternalCacheError(fmt.Errorf("failed to unmarshal index file %s: %w", i.wr.Name(), err))
+		return false, newInternalCacheError(fmt.Errorf("failed to unmarshal index file %s: %w", i.wr.Name(), err))
 	}
-	return nil
+	return true, nil
 }
 
 // NextOutstanding implements DownloadCache. It returns the next, if any,
@@ -306,6 +339,25 @@ func (c *LocalDownloadCache) WriteAt(data []byte, off int64) (int, error) {
 	return n, nil
 }
 
+// Clear clears the block starting at the specified offset from the cache's
+// index so that it will be downloaded again, for example because it was
+// found to be corrupt by VerifyCache.
+func (c *LocalDownloadCache) Clear(off int64) error {
+	if err := c.validateOffsetAndSize(off, min(int64(c.indexStore.blockSize), c.indexStore.contentSize-off)); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.indexStore.Clear(off)
+	if off <= c.lastTailByteRange {
+		c.lastTailByteRange = off - 1
+	}
+	if err := c.indexStore.save(); err != nil {
+		return newInternalCacheError(fmt.Errorf("failed to save index file %s after clearing offset %d: %w", c.indexStore.wr.Name(), off, err))
+	}
+	return c.indexStore.wr.Sync()
+}
+
 func (c *LocalDownloadCache) validateOffset(off, size int64) error {
 	if c.indexStore == nil {
 		return newInternalCacheError(errors.New("index store is not initialized"))
@@ -0,0 +1,261 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package largefile
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"cloudeng.io/algo/digests"
+)
+
+// MirrorReader is a Reader that spreads byte range requests across
+// multiple mirrors of the same file. Requests are assigned to the mirror
+// with the best observed throughput, taking into account the number of
+// requests already in flight to each mirror, and mirrors that have not
+// yet been used are tried first. A request that fails with a non-retryable
+// error is immediately retried on the mirrors that have not yet been tried
+// for that request. Since such errors may be specific to the requested
+// byte range, a mirror is only considered to have failed, and is no longer
+// used, if it returns a non-retryable error before it has returned any
+// data, or if it returns maxConsecutiveMirrorErrors non-retryable errors
+// in a row, since such errors are assumed to apply to the whole mirror,
+// eg. the file does not exist or access is denied. Retryable
+// errors are returned to the caller, but reduce the mirror's throughput
+// estimate so that subsequent requests are likely to be directed to
+// other mirrors. MirrorReader can be used with both CachingDownloader
+// and StreamingDownloader.
+type MirrorReader struct {
+	name      string
+	size      int64
+	blockSize int
+	mu        sync.Mutex
+	mirrors   []*mirror
+}
+
+type mirror struct {
+	rd         Reader
+	inFlight   int
+	throughput float64 // exponentially weighted bytes per second.
+	failed     bool
+	errors     int // consecutive non-retryable errors.
+	stats      MirrorStats
+}
+
+// MirrorStats represents the statistics for a single mirror.
+type MirrorStats struct {
+	Name       string  // Name of the mirror.
+	Requests   int64   // Number of requests made to the mirror.
+	Bytes      int64   // Number of bytes read from the mirror.
+	Retries    int64   // Number of retryable errors returned by the mirror.
+	Errors     int64   // Number of non-retryable errors returned by the mirror.
+	Throughput float64 // Estimated throughput in bytes per second.
+	Failed     bool    // True if the mirror is no longer being used.
+}
+
+// throughputWeight is the weight given to each new throughput sample.
+const throughputWeight = 0.3
+
+// maxConsecutiveMirrorErrors is the number of consecutive non-retryable
+// errors after which a mirror that has previously returned data is
+// considered to have failed.
+const maxConsecutiveMirrorErrors = 3
+
+// NewMirrorReader creates a new MirrorReader for the specified readers,
+// all of which must report the same content length, block size and
+// digest.
+func NewMirrorReader(readers ...Reader) (*MirrorReader, error) {
+	if len(readers) == 0 {
+		return nil, fmt.Errorf("at least one mirror must be specified")
+	}
+	size, blockSize := readers[0].ContentLengthAndBlockSize()
+	digest := readers[0].Digest()
+	names := make([]string, 0, len(readers))
+	mr := &MirrorReader{size: size, blockSize: blockSize}
+	for _, rd := range readers {
+		s, b := rd.ContentLengthAndBlockSize()
+		if s != size || b != blockSize {
+			return nil, fmt.Errorf("mirror %v: size (%d) or block size (%d) does not match %v: size (%d) or block size (%d)", rd.Name(), s, b, readers[0].Name(), size, blockSize)
+		}
+		d := rd.Digest()
+		if d.Algo != digest.Algo || !bytes.Equal(d.Digest, digest.Digest) {
+			return nil, fmt.Errorf("mirror %v: digest (%v:%x) does not match %v: digest (%v:%x)", rd.Name(), d.Algo, d.Digest, readers[0].Name(), digest.Algo, digest.Digest)
+		}
+		names = append(names, rd.Name())
+		mr.mirrors = append(mr.mirrors, &mirror{rd: rd, stats: MirrorStats{Name: rd.Name()}})
+	}
+	mr.name = "mirrors(" + strings.Join(names, ", ") + ")"
+	return mr, nil
+}
+
+// Name implements Reader.
+func (mr *MirrorReader) Name() string {
+	return mr.name
+}
+
+// ContentLengthAndBlockSize implements Reader.
+func (mr *MirrorReader) ContentLengthAndBlockSize() (int64, int) {
+	return mr.size, mr.blockSize
+}
+
+// Digest implements Reader.
+func (mr *MirrorReader) Digest() digests.Hash {
+	return mr.mirrors[0].rd.Digest()
+}
+
+// Stats returns the current statistics for each mirror.
+func (mr *MirrorReader) Stats() []MirrorStats {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	stats := make([]MirrorStats, len(mr.mirrors))
+	for i, m := range mr.mirrors {
+		stats[i] = m.stats
+		stats[i].Throughput = m.throughput
+		stats[i].Failed = m.failed
+	}
+	return stats
+}
+
+// choose returns the mirror to be used for the next request, or nil if
+// all mirrors have failed or have already been tried.
+func (mr *MirrorReader) choose(tried map[*mirror]bool) *mirror {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	var best *mirror
+	var bestScore float64
+	for _, m := range mr.mirrors {
+		if m.failed || tried[m] {
+			continue
+		}
+		if m.stats.Requests == 0 {
+			best = m // Try every mirror at least once.
+			break
+		}
+		score := m.throughput / float64(m.inFlight+1)
+		if best == nil || score > bestScore {
+			best, bestScore = m, score
+		}
+	}
+	if best != nil {
+		best.inFlight++
+		best.stats.Requests++
+	}
+	return best
+}
+
+func (mr *MirrorReader) done(m *mirror, size int64, duration time.Duration) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	m.inFlight--
+	m.stats.Bytes += size
+	if size > 0 {
+		m.errors = 0
+	}
+	if size <= 0 || duration <= 0 {
+		return
+	}
+	sample := float64(size) / duration.Seconds()
+	if m.throughput == 0 {
+		m.throughput = sample
+		return
+	}
+	m.throughput = throughputWeight*sample + (1-throughputWeight)*m.throughput
+}
+
+func (mr *MirrorReader) failed(m *mirror, retryable bool) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	m.inFlight--
+	if retryable {
+		m.stats.Retries++
+		m.throughput /= 2
+		return
+	}
+	m.stats.Errors++
+	m.errors++
+	if m.stats.Bytes == 0 || m.errors >= maxConsecutiveMirrorErrors {
+		m.failed = true
+	}
+}
+
+// GetReader implements Reader. If the chosen mirror returns a non-retryable
+// error the request is retried on the remaining mirrors and an error is
+// returned only if all mirrors have failed, or have been tried, for the
+// requested byte range.
+func (mr *MirrorReader) GetReader(ctx context.Context, from, to int64) (io.ReadCloser, RetryResponse, error) {
+	var errs []string
+	tried := map[*mirror]bool{}
+	for {
+		m := mr.choose(tried)
+		if m == nil {
+			return nil, &mirrorRetryResponse{}, fmt.Errorf("%v: all mirrors have failed for %d-%d: %v", mr.name, from, to, strings.Join(errs, ", "))
+		}
+		tried[m] = true
+		start := time.Now()
+		rd, retry, err := m.rd.GetReader(ctx, from, to)
+		if err == nil {
+			return &mirrorReadCloser{ReadCloser: rd, mr: mr, m: m, start: start}, retry, nil
+		}
+		if ctx.Err() != nil {
+			mr.failed(m, true)
+			return nil, retry, err
+		}
+		if retry != nil && retry.IsRetryable() {
+			mr.failed(m, true)
+			return nil, retry, err
+		}
+		mr.failed(m, false)
+		errs = append(errs, fmt.Sprintf("%v: %v", m.rd.Name(), err))
+	}
+}
+
+type mirrorRetryResponse struct{}
+
+func (mirrorRetryResponse) IsRetryable() bool {
+	return false
+}
+
+func (mirrorRetryResponse) BackoffDuration() (bool, time.Duration) {
+	return false, 0
+}
+
+// mirrorReadCloser records the number of bytes read and the time taken
+// to read them in order to estimate each mirror's throughput. The
+// statistics are recorded when the reader returns an error, including
+// io.EOF, or is closed, whichever comes first.
+type mirrorReadCloser struct {
+	io.ReadCloser
+	mr    *MirrorReader
+	m     *mirror
+	start time.Time
+	n     int64
+	done  bool
+}
+
+func (rc *mirrorReadCloser) Read(buf []byte) (int, error) {
+	n, err := rc.ReadCloser.Read(buf)
+	rc.n += int64(n)
+	if err != nil {
+		rc.finish()
+	}
+	return n, err
+}
+
+func (rc *mirrorReadCloser) Close() error {
+	rc.finish()
+	return rc.ReadCloser.Close()
+}
+
+func (rc *mirrorReadCloser) finish() {
+	if !rc.done {
+		rc.done = true
+		rc.mr.done(rc.m, rc.n, time.Since(rc.start))
+	}
+}
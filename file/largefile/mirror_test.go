@@ -0,0 +1,232 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package largefile_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloudeng.io/algo/digests"
+	"cloudeng.io/file/largefile"
+)
+
+// mirrorSource implements largefile.Reader for an in-memory byte slice
+// with a configurable delay per request, and optionally failing every
+// request after the first failAfter requests if failing is set.
+type mirrorSource struct {
+	name      string
+	data      []byte
+	blockSize int
+	digest    digests.Hash
+	delay     time.Duration
+	failing   bool
+	failAfter int64
+	retryable bool
+	failFrom  map[int64]bool // requests starting at these offsets always fail.
+	requests  int64
+}
+
+func (m *mirrorSource) Name() string { return m.name }
+
+func (m *mirrorSource) ContentLengthAndBlockSize() (int64, int) {
+	return int64(len(m.data)), m.blockSize
+}
+
+func (m *mirrorSource) Digest() digests.Hash { return m.digest }
+
+func (m *mirrorSource) GetReader(_ context.Context, from, to int64) (io.ReadCloser, largefile.RetryResponse, error) {
+	n := atomic.AddInt64(&m.requests, 1)
+	time.Sleep(m.delay)
+	if m.failFrom[from] {
+		return nil, noRetryResponse{}, errors.New("mock range failure")
+	}
+	if m.failing && n > m.failAfter {
+		if m.retryable {
+			return nil, retryResponse{}, errors.New("mock retryable mirror failure")
+		}
+		return nil, noRetryResponse{}, errors.New("mock mirror failure")
+	}
+	return io.NopCloser(bytes.NewReader(m.data[from : to+1])), noRetryResponse{}, nil
+}
+
+func downloadFromMirrors(ctx context.Context, t *testing.T, mr *largefile.MirrorReader, size int64, blockSize int) ([]byte, error) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	cache := createNewCache(ctx, t, tmpDir+"/cache.dat", tmpDir+"/cache.idx", size, blockSize, 1)
+	defer cache.Close()
+	dl, err := largefile.NewCachingDownloader(mr, cache, defaultOpts(4)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dl.Run(ctx); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	if _, err := cache.ReadAt(buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	return buf, nil
+}
+
+func TestMirrorReader(t *testing.T) {
+	ctx := context.Background()
+	size, blockSize := 64*100, 64
+	data := newUploadData(size)
+
+	fast := &mirrorSource{name: "fast", data: data, blockSize: blockSize}
+	slow := &mirrorSource{name: "slow", data: data, blockSize: blockSize, delay: 5 * time.Millisecond}
+	failing := &mirrorSource{name: "failing", data: data, blockSize: blockSize, failing: true}
+	mr, err := largefile.NewMirrorReader(slow, failing, fast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := mr.Name(), "mirrors(slow, failing, fast)"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	buf, err := downloadFromMirrors(ctx, t, mr, int64(size), blockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, data) {
+		t.Errorf("downloaded data does not match")
+	}
+	stats := mr.Stats()
+	if got, want := len(stats), 3; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if !stats[1].Failed || stats[1].Errors != 1 {
+		t.Errorf("failing mirror was not marked as failed: %+v", stats[1])
+	}
+	if stats[0].Failed || stats[2].Failed {
+		t.Errorf("unexpected failed mirror: %+v", stats)
+	}
+	if stats[2].Requests <= stats[0].Requests {
+		t.Errorf("fast mirror was not preferred: %+v", stats)
+	}
+	var total int64
+	for _, st := range stats {
+		total += st.Bytes
+	}
+	if got, want := total, int64(size); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestMirrorReaderAllFailed(t *testing.T) {
+	ctx := context.Background()
+	size, blockSize := 64*10, 64
+	data := newUploadData(size)
+	a := &mirrorSource{name: "a", data: data, blockSize: blockSize, failing: true, failAfter: 1}
+	b := &mirrorSource{name: "b", data: data, blockSize: blockSize, failing: true, failAfter: 1}
+	mr, err := largefile.NewMirrorReader(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := downloadFromMirrors(ctx, t, mr, int64(size), blockSize); err == nil {
+		t.Fatal("expected an error")
+	}
+	_, retry, err := mr.GetReader(ctx, 0, 63)
+	if err == nil || retry.IsRetryable() {
+		t.Errorf("expected a non-retryable error: %v", err)
+	}
+}
+
+func TestMirrorReaderRangeErrors(t *testing.T) {
+	ctx := context.Background()
+	size, blockSize := 64*10, 64
+	data := newUploadData(size)
+	// Both mirrors fail for a single, but different, range and must not
+	// be retired.
+	a := &mirrorSource{name: "a", data: data, blockSize: blockSize, failFrom: map[int64]bool{128: true}}
+	b := &mirrorSource{name: "b", data: data, blockSize: blockSize, failFrom: map[int64]bool{256: true}}
+	mr, err := largefile.NewMirrorReader(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ensure that both mirrors have returned data before any range
+	// errors are encountered, each unused mirror is tried in turn.
+	for range 2 {
+		rd, _, err := mr.GetReader(ctx, 0, 63)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(io.Discard, rd); err != nil {
+			t.Fatal(err)
+		}
+		rd.Close()
+	}
+	for i := range 2 {
+		buf, err := downloadFromMirrors(ctx, t, mr, int64(size), blockSize)
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		if !bytes.Equal(buf, data) {
+			t.Errorf("%v: downloaded data does not match", i)
+		}
+	}
+	for _, st := range mr.Stats() {
+		if st.Failed {
+			t.Errorf("unexpected failed mirror: %+v", st)
+		}
+	}
+
+	// A range that fails on all mirrors returns an error without
+	// retiring either mirror.
+	a.failFrom[512], b.failFrom[512] = true, true
+	if _, retry, err := mr.GetReader(ctx, 512, 575); err == nil || retry.IsRetryable() {
+		t.Errorf("expected a non-retryable error: %v", err)
+	}
+	for _, st := range mr.Stats() {
+		if st.Failed {
+			t.Errorf("unexpected failed mirror: %+v", st)
+		}
+	}
+}
+
+func TestMirrorReaderRetryable(t *testing.T) {
+	ctx := context.Background()
+	size, blockSize := 64*10, 64
+	data := newUploadData(size)
+	a := &mirrorSource{name: "a", data: data, blockSize: blockSize, failing: true, failAfter: 1, retryable: true}
+	mr, err := largefile.NewMirrorReader(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mr.GetReader(ctx, 0, 63); err != nil {
+		t.Fatal(err)
+	}
+	_, retry, err := mr.GetReader(ctx, 0, 63)
+	if err == nil || !retry.IsRetryable() {
+		t.Errorf("expected a retryable error: %v", err)
+	}
+	if st := mr.Stats()[0]; st.Failed || st.Retries != 1 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
+
+func TestMirrorReaderMismatch(t *testing.T) {
+	data := newUploadData(100)
+	d1, _ := digests.New(digests.SHA1, bytes.Repeat([]byte{1}, 20))
+	d2, _ := digests.New(digests.SHA1, bytes.Repeat([]byte{2}, 20))
+	for i, readers := range [][]largefile.Reader{
+		{},
+		{&mirrorSource{data: data, blockSize: 10}, &mirrorSource{data: data[:50], blockSize: 10}},
+		{&mirrorSource{data: data, blockSize: 10}, &mirrorSource{data: data, blockSize: 20}},
+		{&mirrorSource{data: data, blockSize: 10, digest: d1}, &mirrorSource{data: data, blockSize: 10, digest: d2}},
+		{&mirrorSource{data: data, blockSize: 10, digest: d1}, &mirrorSource{data: data, blockSize: 10}},
+	} {
+		if _, err := largefile.NewMirrorReader(readers...); err == nil {
+			t.Errorf("%v: expected an error", i)
+		}
+	}
+	if _, err := largefile.NewMirrorReader(&mirrorSource{data: data, blockSize: 10, digest: d1}, &mirrorSource{data: data, blockSize: 10, digest: d1}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,470 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package largefile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloudeng.io/file"
+)
+
+// ObjectCacheFS represents the filesystem/object store used by
+// ObjectDownloadCache to store its index and, optionally, its data.
+type ObjectCacheFS interface {
+	file.FS
+	file.ObjectFS
+}
+
+// ObjectCacheOption represents an option to NewObjectDownloadCache.
+type ObjectCacheOption func(*objectCacheOptions)
+
+type objectCacheOptions struct {
+	writer         Writer
+	staged         bool
+	backoffInitial time.Duration
+	backoffSteps   int
+	indexInterval  time.Duration
+}
+
+// WithObjectCacheWriter specifies that cached blocks are to be written
+// directly as the parts of a multipart upload via the supplied Writer.
+// The Writer's Complete method is called when the cache is finalized,
+// see ObjectDownloadCache.Finalize. Note that ReadAt is not supported when blocks are written
+// directly since the parts of a multipart upload cannot generally be read
+// back until the upload is complete.
+func WithObjectCacheWriter(w Writer) ObjectCacheOption {
+	return func(o *objectCacheOptions) {
+		o.writer = w
+		o.staged = false
+	}
+}
+
+// WithObjectCacheStagedWriter specifies that cached blocks are to be
+// written as staged chunk objects under the cache's prefix and then
+// assembled into the destination, via the supplied Writer, when the
+// cache is finalized, see ObjectDownloadCache.Finalize. The chunk objects are deleted once the
+// destination has been completed. ReadAt is supported until the chunks
+// have been assembled.
+func WithObjectCacheStagedWriter(w Writer) ObjectCacheOption {
+	return func(o *objectCacheOptions) {
+		o.writer = w
+		o.staged = true
+	}
+}
+
+// WithObjectCacheBackoff specifies the backoff parameters used when
+// retrying retryable Writer errors, the defaults are 100ms and 5 steps.
+func WithObjectCacheBackoff(initial time.Duration, steps int) ObjectCacheOption {
+	return func(o *objectCacheOptions) {
+		o.backoffInitial = initial
+		o.backoffSteps = steps
+	}
+}
+
+// WithObjectCacheIndexInterval specifies the minimum interval between
+// writes of the index to the object store. By default the index is
+// written after every block. Any blocks cached, but not recorded in the
+// persisted index, when a download fails will be downloaded again when
+// the download is resumed. The index is always written when the cache
+// is finalized.
+func WithObjectCacheIndexInterval(d time.Duration) ObjectCacheOption {
+	return func(o *objectCacheOptions) {
+		o.indexInterval = d
+	}
+}
+
+// ObjectDownloadCache is an implementation of DownloadCache that stores
+// downloaded blocks in an object store, rather than a local file, so that
+// large files may be downloaded directly to cloud storage from hosts with
+// limited local storage. The blocks are either written directly as the
+// parts of a multipart upload (see WithObjectCacheWriter) or stored as
+// separate, staged, chunk objects under the cache's prefix and assembled
+// into the destination once all blocks have been cached (see
+// WithObjectCacheStagedWriter). The index of cached byte ranges is stored
+// alongside the data so that downloads may be resumed. The cache is
+// finalized, ie. the staged chunks are assembled and the multipart upload
+// is completed, by Finalize, which CachingDownloader calls once a download
+// has completed.
+type ObjectDownloadCache struct {
+	ctx               context.Context
+	fs                ObjectCacheFS
+	opts              objectCacheOptions
+	indexName         string
+	chunkPrefix       string
+	mu                sync.RWMutex // Protects access to the fields below.
+	finalized         bool
+	generation        int64 // Incremented every time the index is changed.
+	lastTailByteRange int64 // The last byte range returned by Tail.
+	index             *ByteRanges
+	lastBlockSize     int64 // size of last block
+	lastBlockOffset   int64 // offset of last block
+
+	finalizeMu      sync.Mutex // Serializes calls to Finalize.
+	saveMu          sync.Mutex // Serializes writes of the index.
+	savedGeneration int64      // GUARDED_BY(saveMu)
+	savedAt         time.Time  // GUARDED_BY(saveMu)
+}
+
+// objectCacheIndex is the persisted form of the ObjectDownloadCache's index.
+type objectCacheIndex struct {
+	Ranges    *ByteRanges `json:"ranges"`
+	Finalized bool        `json:"finalized"`
+}
+
+// NewObjectDownloadCache creates a new ObjectDownloadCache that stores its
+// index, and its staged chunk objects, under the specified prefix. One of
+// WithObjectCacheWriter or WithObjectCacheStagedWriter must be specified.
+// If an index already exists under that prefix it is loaded and used to
+// resume the download, otherwise a new index is created. It returns an
+// error if an existing index does not match the specified content size and
+// block size. If all blocks are cached, but the cache has not been
+// finalized, e.g. due to a previous failure, then it is finalized by
+// NewObjectDownloadCache. The supplied context is used for all subsequent
+// operations on the object store since the DownloadCache methods do not
+// accept a context.
+func NewObjectDownloadCache(ctx context.Context, fs ObjectCacheFS, prefix string, contentSize int64, blockSize int, opts ...ObjectCacheOption) (*ObjectDownloadCache, error) {
+	options := objectCacheOptions{
+		backoffInitial: 100 * time.Millisecond,
+		backoffSteps:   5,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if contentSize <= 0 || blockSize <= 0 {
+		return nil, fmt.Errorf("content size (%d) and block size (%d) must be greater than zero", contentSize, blockSize)
+	}
+	if options.writer == nil {
+		return nil, fmt.Errorf("no writer specified for %s", prefix)
+	}
+	wsize, wblock := options.writer.ContentLengthAndBlockSize()
+	if wsize != contentSize || wblock != blockSize {
+		return nil, fmt.Errorf("writer size (%d) or block size (%d) does not match content size (%d) or block size (%d)", wsize, wblock, contentSize, blockSize)
+	}
+	c := &ObjectDownloadCache{
+		ctx:         ctx,
+		fs:          fs,
+		opts:        options,
+		indexName:   fs.Join(prefix, "index.json"),
+		chunkPrefix: fs.Join(prefix, "chunks"),
+	}
+	if err := fs.EnsurePrefix(ctx, c.chunkPrefix, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create prefix %s: %w", c.chunkPrefix, err)
+	}
+	if err := c.loadOrCreateIndex(contentSize, blockSize); err != nil {
+		return nil, err
+	}
+	c.lastBlockSize = contentSize % int64(blockSize)
+	c.lastBlockOffset = contentSize - c.lastBlockSize
+	if c.lastBlockSize == 0 {
+		c.lastBlockOffset -= int64(blockSize)
+		c.lastBlockSize = int64(blockSize)
+	}
+	if c.allCached() && !c.finalized {
+		if err := c.Finalize(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *ObjectDownloadCache) loadOrCreateIndex(contentSize int64, blockSize int) error {
+	buf, err := c.fs.Get(c.ctx, c.indexName)
+	if err != nil {
+		if !c.fs.IsNotExist(err) {
+			return fmt.Errorf("failed to read index %s: %w", c.indexName, err)
+		}
+		c.index = NewByteRanges(contentSize, blockSize)
+		c.generation++
+		return c.save(true)
+	}
+	var idx objectCacheIndex
+	if err := json.Unmarshal(buf, &idx); err != nil {
+		return fmt.Errorf("failed to unmarshal index %s: %w", c.indexName, err)
+	}
+	if idx.Ranges == nil {
+		return fmt.Errorf("index %s has no byte ranges", c.indexName)
+	}
+	if idx.Ranges.ContentLength() != contentSize || idx.Ranges.BlockSize() != blockSize {
+		return fmt.Errorf("index %s size (%d) or block size (%d) does not match content size (%d) or block size (%d)", c.indexName, idx.Ranges.ContentLength(), idx.Ranges.BlockSize(), contentSize, blockSize)
+	}
+	c.index = idx.Ranges
+	c.finalized = idx.Finalized
+	return nil
+}
+
+// save writes the index to the object store, unless it has not changed
+// since it was last written or, if force is false, the configured index
+// interval has not yet elapsed. The index is marshaled whilst holding
+// a read lock but is written without holding any lock other than saveMu,
+// which serializes writes so that a stale index never overwrites a more
+// recent one.
+func (c *ObjectDownloadCache) save(force bool) error {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+	if !force && c.opts.indexInterval > 0 && time.Since(c.savedAt) < c.opts.indexInterval {
+		return nil
+	}
+	c.mu.RLock()
+	gen := c.generation
+	if gen == c.savedGeneration {
+		c.mu.RUnlock()
+		return nil
+	}
+	data, err := json.Marshal(objectCacheIndex{Ranges: c.index, Finalized: c.finalized})
+	c.mu.RUnlock()
+	if err != nil {
+		return newInternalCacheError(fmt.Errorf("failed to marshal index to JSON: %w", err))
+	}
+	if err := c.fs.Put(c.ctx, c.indexName, 0600, data); err != nil {
+		return fmt.Errorf("failed to write index %s: %w", c.indexName, err)
+	}
+	c.savedGeneration = gen
+	c.savedAt = time.Now()
+	return nil
+}
+
+func (c *ObjectDownloadCache) chunkName(off int64) string {
+	return c.fs.Join(c.chunkPrefix, fmt.Sprintf("%020d", off))
+}
+
+func (c *ObjectDownloadCache) allCached() bool {
+	var br ByteRange
+	return c.index.NextClear(0, &br) == -1
+}
+
+// put writes the specified byte range via the Writer, retrying any
+// retryable errors using the configured backoff.
+func (c *ObjectDownloadCache) put(ctx context.Context, from, to int64, data []byte) error {
+	backoff := NewBackoff(c.opts.backoffInitial, c.opts.backoffSteps)
+	for {
+		retry, err := c.opts.writer.PutReader(ctx, from, to, bytes.NewReader(data))
+		if err == nil {
+			return nil
+		}
+		if retry == nil || !retry.IsRetryable() {
+			return fmt.Errorf("failed to write part %d-%d for %s: %w", from, to, c.opts.writer.Name(), err)
+		}
+		if done, _ := backoff.Wait(ctx, retry); done {
+			return fmt.Errorf("failed to write part %d-%d for %s: backoff giving up after %d retries: %w", from, to, c.opts.writer.Name(), backoff.Retries(), err)
+		}
+	}
+}
+
+// assemble copies the staged chunks to the Writer.
+func (c *ObjectDownloadCache) assemble(ctx context.Context) error {
+	var br ByteRange
+	for n := c.index.NextSet(0, &br); n != -1; n = c.index.NextSet(n, &br) {
+		chunk, err := c.fs.Get(ctx, c.chunkName(br.From))
+		if err != nil {
+			return fmt.Errorf("failed to read chunk %s: %w", c.chunkName(br.From), err)
+		}
+		if int64(len(chunk)) != br.Size() {
+			return newInternalCacheError(fmt.Errorf("chunk %s has %d bytes, expected %d", c.chunkName(br.From), len(chunk), br.Size()))
+		}
+		if err := c.put(ctx, br.From, br.To, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Finalize implements DownloadCacheFinalizer. It assembles any staged
+// chunks into the destination, completes the Writer and records that the
+// cache has been finalized. It returns an error if not all blocks have
+// been cached and does nothing if the cache has already been finalized.
+// Finalize may be called again if it fails.
+func (c *ObjectDownloadCache) Finalize(ctx context.Context) error {
+	c.finalizeMu.Lock()
+	defer c.finalizeMu.Unlock()
+	c.mu.RLock()
+	finalized, cached := c.finalized, c.allCached()
+	c.mu.RUnlock()
+	if finalized {
+		return nil
+	}
+	if !cached {
+		return fmt.Errorf("cannot finalize %s: %w", c.opts.writer.Name(), ErrCacheUncachedRange)
+	}
+	if c.opts.staged {
+		if err := c.assemble(ctx); err != nil {
+			return err
+		}
+	}
+	if err := c.opts.writer.Complete(ctx); err != nil {
+		return fmt.Errorf("failed to complete upload for %s: %w", c.opts.writer.Name(), err)
+	}
+	c.mu.Lock()
+	c.finalized = true
+	c.generation++
+	c.mu.Unlock()
+	if err := c.save(true); err != nil {
+		return err
+	}
+	if c.opts.staged {
+		if err := c.fs.DeleteAll(ctx, c.chunkPrefix); err != nil {
+			return fmt.Errorf("failed to delete chunks %s: %w", c.chunkPrefix, err)
+		}
+	}
+	return nil
+}
+
+// NextOutstanding implements DownloadCache. It returns the next, if any,
+// uncached byte range starting from the specified index.
+func (c *ObjectDownloadCache) NextOutstanding(start int, br *ByteRange) int {
+	return c.index.NextClear(start, br)
+}
+
+// NextCached implements DownloadCache. It returns the next, if any,
+// cached byte range starting from the specified index.
+func (c *ObjectDownloadCache) NextCached(start int, br *ByteRange) int {
+	return c.index.NextSet(start, br)
+}
+
+// Complete implements DownloadCache. It returns true if all byte ranges
+// have been cached and the cache has been finalized.
+func (c *ObjectDownloadCache) Complete() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.finalized && c.allCached()
+}
+
+// CachedBytesAndBlocks implements DownloadCache.
+func (c *ObjectDownloadCache) CachedBytesAndBlocks() (bytes, blocks int64) {
+	var br ByteRange
+	for n := c.index.NextSet(0, &br); n != -1; n = c.index.NextSet(n, &br) {
+		bytes += br.Size()
+		blocks++
+	}
+	return bytes, blocks
+}
+
+// ContentLengthAndBlockSize implements DownloadCache.
+func (c *ObjectDownloadCache) ContentLengthAndBlockSize() (int64, int) {
+	return c.index.ContentLength(), c.index.BlockSize()
+}
+
+func (c *ObjectDownloadCache) validateOffsetAndSize(off, size int64) error {
+	blockSize := int64(c.index.BlockSize())
+	if off < 0 || off > c.lastBlockOffset {
+		return fmt.Errorf("%d must be between 0 and %d: %w", off, c.lastBlockOffset, ErrCacheInvalidOffset)
+	}
+	if off%blockSize != 0 {
+		return fmt.Errorf("offset %d is not aligned with block size %d: %w", off, blockSize, ErrCacheInvalidOffset)
+	}
+	if off == c.lastBlockOffset {
+		if size != c.lastBlockSize {
+			return fmt.Errorf("data size %d for last block at %d: must be %d: %w", size, off, c.lastBlockSize, ErrCacheInvalidBlockSize)
+		}
+		return nil
+	}
+	if size != blockSize {
+		return fmt.Errorf("data size %d for offset %d: must be %d: %w", size, off, blockSize, ErrCacheInvalidBlockSize)
+	}
+	return nil
+}
+
+// WriteAt implements DownloadCache. The data is written to the object
+// store, retrying any retryable Writer errors, before the index is updated.
+// The cache is not finalized by WriteAt, see Finalize.
+func (c *ObjectDownloadCache) WriteAt(data []byte, off int64) (int, error) {
+	if err := c.validateOffsetAndSize(off, int64(len(data))); err != nil {
+		return 0, err
+	}
+	if !c.opts.staged {
+		if err := c.put(c.ctx, off, off+int64(len(data))-1, data); err != nil {
+			return 0, err
+		}
+	} else if err := c.fs.Put(c.ctx, c.chunkName(off), 0600, data); err != nil {
+		return 0, fmt.Errorf("failed to write chunk %s: %w", c.chunkName(off), err)
+	}
+	c.mu.Lock()
+	c.index.Set(off)
+	c.generation++
+	force := c.allCached()
+	c.mu.Unlock()
+	// Always save the index once all blocks are cached so that a failed
+	// Finalize can be retried by NewObjectDownloadCache.
+	if err := c.save(force); err != nil {
+		return 0, fmt.Errorf("failed to save index after writing offset %d: %w", off, err)
+	}
+	return len(data), nil
+}
+
+// ReadAt implements DownloadCache. It is only supported for staged
+// chunks that have not yet been assembled.
+func (c *ObjectDownloadCache) ReadAt(data []byte, off int64) (int, error) {
+	if !c.opts.staged {
+		return 0, fmt.Errorf("ReadAt is not supported for %s: %w", c.opts.writer.Name(), errors.ErrUnsupported)
+	}
+	c.mu.RLock()
+	finalized := c.finalized
+	c.mu.RUnlock()
+	if finalized {
+		return 0, fmt.Errorf("ReadAt is not supported once chunks have been assembled into %s: %w", c.opts.writer.Name(), errors.ErrUnsupported)
+	}
+	contentSize := c.index.ContentLength()
+	size := int64(len(data))
+	if off < 0 || off >= contentSize {
+		return 0, fmt.Errorf("%d must be between 0 and %d: %w", off, contentSize, ErrCacheInvalidOffset)
+	}
+	if off+size > contentSize {
+		return 0, fmt.Errorf("offset %d with size %d exceeds content size %d: %w", off, size, contentSize, ErrCacheInvalidBlockSize)
+	}
+	blockSize := int64(c.index.BlockSize())
+	n := 0
+	for n < len(data) {
+		pos := off + int64(n)
+		blockOffset := pos - pos%blockSize
+		if c.index.IsClear(blockOffset) {
+			return n, fmt.Errorf("offset %d is not cached: %w", blockOffset, ErrCacheUncachedRange)
+		}
+		chunk, err := c.fs.Get(c.ctx, c.chunkName(blockOffset))
+		if err != nil {
+			return n, fmt.Errorf("failed to read chunk %s: %w", c.chunkName(blockOffset), err)
+		}
+		start := pos - blockOffset
+		if start >= int64(len(chunk)) {
+			return n, newInternalCacheError(fmt.Errorf("chunk %s is too short: %d bytes", c.chunkName(blockOffset), len(chunk)))
+		}
+		n += copy(data[n:], chunk[start:])
+	}
+	return n, nil
+}
+
+func (c *ObjectDownloadCache) isExtended(to int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	done := to > c.lastTailByteRange
+	if done {
+		c.lastTailByteRange = to
+	}
+	return done
+}
+
+// Tail implements DownloadCache. It returns the contiguous range of bytes
+// that have been cached so far. If this has not grown since the last call to
+// Tail, Tail will block until the tail is extended.
+func (c *ObjectDownloadCache) Tail(ctx context.Context) ByteRange {
+	if tail, ok := c.index.Tail(); ok && c.isExtended(tail.To) {
+		return tail
+	}
+	ch := c.index.Notify()
+	select {
+	case <-ctx.Done():
+		return ByteRange{From: -1, To: -1} // Return an empty range if the context is done.
+	case <-ch:
+	}
+	tail, _ := c.index.Tail() // ok will always be true after a notification is received.
+	c.mu.Lock()
+	c.lastTailByteRange = tail.To
+	c.mu.Unlock()
+	return tail
+}
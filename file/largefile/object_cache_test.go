@@ -0,0 +1,215 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package largefile_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"cloudeng.io/algo/digests"
+	"cloudeng.io/file/largefile"
+	"cloudeng.io/file/localfs"
+)
+
+// memReader implements largefile.Reader for an in-memory byte slice,
+// failing (without retries) for the offsets in failAt.
+type memReader struct {
+	data      []byte
+	blockSize int
+	mu        sync.Mutex
+	failAt    map[int64]bool
+}
+
+func (m *memReader) Name() string { return "memReader" }
+
+func (m *memReader) ContentLengthAndBlockSize() (int64, int) {
+	return int64(len(m.data)), m.blockSize
+}
+
+func (m *memReader) Digest() digests.Hash { return digests.Hash{} }
+
+func (m *memReader) GetReader(_ context.Context, from, to int64) (io.ReadCloser, largefile.RetryResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failAt[from] {
+		delete(m.failAt, from)
+		return nil, noRetryResponse{}, errors.New("mock get failure")
+	}
+	return io.NopCloser(bytes.NewReader(m.data[from : to+1])), noRetryResponse{}, nil
+}
+
+func TestObjectDownloadCache(t *testing.T) {
+	ctx := context.Background()
+	fs := localfs.New()
+	size, blockSize := 1000, 64
+	data := newUploadData(size)
+	prefix := t.TempDir()
+
+	if _, err := largefile.NewObjectDownloadCache(ctx, fs, prefix, int64(size), blockSize); err == nil {
+		t.Errorf("expected an error")
+	}
+
+	sink := newMemPartSink(int64(size), blockSize)
+	rd := &memReader{data: data, blockSize: blockSize, failAt: map[int64]bool{128: true, 640: true}}
+	cache, err := largefile.NewObjectDownloadCache(ctx, fs, prefix, int64(size), blockSize,
+		largefile.WithObjectCacheStagedWriter(sink))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dl, err := largefile.NewCachingDownloader(rd, cache, defaultOpts(4)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := dl.Run(ctx)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if st.Complete || cache.Complete() {
+		t.Errorf("unexpected status: %+v", st)
+	}
+	buf := make([]byte, 64)
+	if _, err := cache.ReadAt(buf, 128); !errors.Is(err, largefile.ErrCacheUncachedRange) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	buf = make([]byte, 100)
+	if _, err := cache.ReadAt(buf, 20); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf, data[20:120]; !bytes.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if len(sink.data) != 0 || sink.completed {
+		t.Errorf("chunks should not have been assembled yet")
+	}
+
+	// Resume the download using a new cache instance.
+	cache, err = largefile.NewObjectDownloadCache(ctx, fs, prefix, int64(size), blockSize,
+		largefile.WithObjectCacheStagedWriter(sink))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nblocks := int64(largefile.NumBlocks(int64(size), blockSize))
+	if _, blocks := cache.CachedBytesAndBlocks(); blocks != nblocks-2 {
+		t.Errorf("got %v, want %v", blocks, nblocks-2)
+	}
+	dl, err = largefile.NewCachingDownloader(rd, cache, defaultOpts(4)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err = dl.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Complete || !cache.Complete() {
+		t.Errorf("unexpected status: %+v", st)
+	}
+	if got, want := st.CachedOrStreamedBlocks, nblocks; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// The chunks are assembled into the writer and then deleted.
+	if !sink.completed || !bytes.Equal(sink.data, data) {
+		t.Errorf("assembled data does not match")
+	}
+	if entries, _ := os.ReadDir(filepath.Join(prefix, "chunks")); len(entries) != 0 {
+		t.Errorf("chunks were not deleted: %v", len(entries))
+	}
+	if _, err := cache.ReadAt(buf, 0); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+
+	if _, err := cache.WriteAt(make([]byte, 10), 64); !errors.Is(err, largefile.ErrCacheInvalidBlockSize) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	if _, err := largefile.NewObjectDownloadCache(ctx, fs, prefix, int64(size), blockSize*2,
+		largefile.WithObjectCacheStagedWriter(newMemPartSink(int64(size), blockSize*2))); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestObjectDownloadCacheWriter(t *testing.T) {
+	ctx := context.Background()
+	fs := localfs.New()
+	size, blockSize := 1000, 64
+	data := newUploadData(size)
+	prefix := t.TempDir()
+
+	sink := newMemPartSink(int64(size), blockSize)
+	sink.retryable = true
+	sink.failAt[64] = 2
+	rd := &memReader{data: data, blockSize: blockSize}
+	cache, err := largefile.NewObjectDownloadCache(ctx, fs, prefix, int64(size), blockSize,
+		largefile.WithObjectCacheWriter(sink),
+		largefile.WithObjectCacheBackoff(time.Millisecond, 3),
+		largefile.WithObjectCacheIndexInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dl, err := largefile.NewCachingDownloader(rd, cache, defaultOpts(4)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := dl.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Complete || !sink.completed {
+		t.Errorf("unexpected status: %+v", st)
+	}
+	if !bytes.Equal(sink.data, data) {
+		t.Errorf("uploaded data does not match")
+	}
+	if _, err := cache.ReadAt(make([]byte, 10), 0); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	if _, err := largefile.NewObjectDownloadCache(ctx, fs, prefix, int64(size), blockSize,
+		largefile.WithObjectCacheWriter(newMemPartSink(int64(size)*2, blockSize))); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestObjectDownloadCacheFinalize(t *testing.T) {
+	ctx := context.Background()
+	fs := localfs.New()
+	size, blockSize := 1000, 64
+	data := newUploadData(size)
+	prefix := t.TempDir()
+
+	sink := newMemPartSink(int64(size), blockSize)
+	cache, err := largefile.NewObjectDownloadCache(ctx, fs, prefix, int64(size), blockSize,
+		largefile.WithObjectCacheStagedWriter(sink))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Finalize(ctx); !errors.Is(err, largefile.ErrCacheUncachedRange) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	for off := 0; off < size; off += blockSize {
+		end := min(off+blockSize, size)
+		if _, err := cache.WriteAt(data[off:end], int64(off)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Writing the last block must not finalize the cache.
+	if sink.completed || cache.Complete() {
+		t.Errorf("cache should not have been finalized")
+	}
+	if err := cache.Finalize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !sink.completed || !cache.Complete() || !bytes.Equal(sink.data, data) {
+		t.Errorf("cache was not finalized")
+	}
+	if err := cache.Finalize(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,414 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package largefile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+
+	"cloudeng.io/algo/container/list"
+	"cloudeng.io/algo/ratecontrol"
+)
+
+// RandomReaderOption represents an option to NewRandomReader.
+type RandomReaderOption func(*randomReaderOptions)
+
+type randomReaderOptions struct {
+	downloadOptions
+	cacheBlocks int
+	prefetch    int
+	cache       DownloadCache
+}
+
+// WithRandomReaderCacheBlocks sets the maximum number of blocks to be
+// cached in memory. The default is 16.
+func WithRandomReaderCacheBlocks(n int) RandomReaderOption {
+	return func(o *randomReaderOptions) {
+		o.cacheBlocks = n
+	}
+}
+
+// WithRandomReaderPrefetch sets the number of blocks to be read ahead
+// of a sequential reader. The default is 4, a value of zero disables
+// prefetching. The number of blocks prefetched is limited to one less
+// than the number of blocks that can be cached in memory.
+func WithRandomReaderPrefetch(n int) RandomReaderOption {
+	return func(o *randomReaderOptions) {
+		o.prefetch = n
+	}
+}
+
+// WithRandomReaderDownloadCache sets a DownloadCache, typically a
+// LocalDownloadCache, to be used as a second, on-disk, tier of cache.
+// Blocks are read from this cache if present and are written to it
+// when fetched.
+func WithRandomReaderDownloadCache(cache DownloadCache) RandomReaderOption {
+	return func(o *randomReaderOptions) {
+		o.cache = cache
+	}
+}
+
+// WithRandomReaderRateController sets the rate controller, and hence the
+// backoff strategy, used for fetching blocks.
+func WithRandomReaderRateController(rc ratecontrol.Limiter) RandomReaderOption {
+	return func(o *randomReaderOptions) {
+		o.rateController = rc
+	}
+}
+
+// WithRandomReaderLogger sets the logger for the RandomReader.
+func WithRandomReaderLogger(logger *slog.Logger) RandomReaderOption {
+	return func(o *randomReaderOptions) {
+		o.logger = logger
+	}
+}
+
+// WithRandomReaderBlockDigests sets the per-block digests used to verify
+// each block as it is fetched.
+func WithRandomReaderBlockDigests(bd *BlockDigests) RandomReaderOption {
+	return func(o *randomReaderOptions) {
+		o.blockDigests = bd
+	}
+}
+
+// RandomReaderStats represents the statistics for a RandomReader.
+type RandomReaderStats struct {
+	Hits       int64 // Blocks read from the in-memory cache.
+	DiskHits   int64 // Blocks read from the DownloadCache, if any.
+	Misses     int64 // Blocks fetched on demand.
+	Prefetched int64 // Blocks fetched ahead of a sequential reader.
+	Evicted    int64 // Blocks evicted from the in-memory cache.
+}
+
+// RandomReader provides random access, via io.ReaderAt and io.ReadSeeker,
+// to a Reader. Blocks are fetched on demand, using the same retry and
+// backoff logic as CachingDownloader and StreamingDownloader, and are
+// cached in a bounded, in-memory, LRU cache, optionally backed by a
+// DownloadCache. Blocks are prefetched ahead of sequential readers.
+// RandomReader allows for remote files to be used directly by packages
+// such as archive/zip that require an io.ReaderAt.
+// RandomReader is safe for concurrent use, though concurrent use of
+// Read and Seek is not meaningful.
+type RandomReader struct {
+	dl          *downloader
+	cacheBlocks int
+	prefetch    int
+	cache       DownloadCache
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+
+	mu        sync.Mutex
+	blocks    map[int64]*randomBlock // keyed by block offset.
+	lru       *list.Double[int64]    // least recently used at the head.
+	inflight  map[int64]*blockFetch
+	offset    int64 // offset for Read and Seek.
+	lastBlock int64 // last block read, used to detect sequential access.
+	stats     RandomReaderStats
+}
+
+type randomBlock struct {
+	data []byte
+	id   list.DoubleID[int64]
+}
+
+type blockFetch struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// NewRandomReader creates a new RandomReader for the specified file.
+func NewRandomReader(file Reader, opts ...RandomReaderOption) (*RandomReader, error) {
+	options := randomReaderOptions{cacheBlocks: 16, prefetch: 4}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	dl := newDownloader(file, options.downloadOptions)
+	if options.cache != nil {
+		csize, cblock := options.cache.ContentLengthAndBlockSize()
+		if csize != dl.size || cblock != dl.blockSize {
+			return nil, fmt.Errorf("cache size (%d) or block size (%d) does not match file size (%d) or block size (%d)", csize, cblock, dl.size, dl.blockSize)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	rr := &RandomReader{
+		dl:          dl,
+		cacheBlocks: max(options.cacheBlocks, 1),
+		cache:       options.cache,
+		ctx:         ctx,
+		cancel:      cancel,
+		blocks:      map[int64]*randomBlock{},
+		lru:         list.NewDouble[int64](),
+		inflight:    map[int64]*blockFetch{},
+		lastBlock:   -1,
+	}
+	rr.prefetch = min(max(options.prefetch, 0), rr.cacheBlocks-1)
+	return rr, nil
+}
+
+// Size returns the size of the underlying file.
+func (rr *RandomReader) Size() int64 {
+	return rr.dl.size
+}
+
+// Stats returns the current statistics for the RandomReader.
+func (rr *RandomReader) Stats() RandomReaderStats {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	return rr.stats
+}
+
+// Close cancels any outstanding prefetches and waits for them to complete.
+// The in-memory cache is discarded, but any DownloadCache is not closed.
+func (rr *RandomReader) Close() error {
+	rr.cancel()
+	rr.wg.Wait()
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	rr.blocks = map[int64]*randomBlock{}
+	rr.lru.Reset()
+	return nil
+}
+
+// ReadAt implements io.ReaderAt.
+func (rr *RandomReader) ReadAt(p []byte, off int64) (int, error) {
+	return rr.readAt(rr.ctx, p, off)
+}
+
+// ReadAtCtx is like ReadAt but with a context.
+func (rr *RandomReader) ReadAtCtx(ctx context.Context, p []byte, off int64) (int, error) {
+	return rr.readAt(ctx, p, off)
+}
+
+func (rr *RandomReader) readAt(ctx context.Context, p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d: %w", off, ErrCacheInvalidOffset)
+	}
+	if off >= rr.dl.size {
+		return 0, io.EOF
+	}
+	blockSize := int64(rr.dl.blockSize)
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= rr.dl.size {
+			return n, io.EOF
+		}
+		blockOffset := pos - pos%blockSize
+		data, err := rr.block(ctx, blockOffset)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], data[pos-blockOffset:])
+	}
+	return n, nil
+}
+
+// Read implements io.Reader.
+func (rr *RandomReader) Read(p []byte) (int, error) {
+	rr.mu.Lock()
+	off := rr.offset
+	rr.mu.Unlock()
+	if off >= rr.dl.size {
+		return 0, io.EOF
+	}
+	n, err := rr.ReadAt(p, off)
+	rr.mu.Lock()
+	rr.offset = off + int64(n)
+	rr.mu.Unlock()
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (rr *RandomReader) Seek(offset int64, whence int) (int64, error) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += rr.offset
+	case io.SeekEnd:
+		offset += rr.dl.size
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset %d: %w", offset, ErrCacheInvalidOffset)
+	}
+	rr.offset = offset
+	return offset, nil
+}
+
+// block returns the data for the block at the specified offset, fetching
+// it if necessary and prefetching subsequent blocks if the access
+// appears to be sequential.
+func (rr *RandomReader) block(ctx context.Context, off int64) ([]byte, error) {
+	rr.mu.Lock()
+	blockSize := int64(rr.dl.blockSize)
+	sequential := off == rr.lastBlock+blockSize
+	rr.lastBlock = off
+	if blk, ok := rr.blocks[off]; ok {
+		rr.stats.Hits++
+		rr.touchLocked(off, blk)
+		rr.mu.Unlock()
+		if sequential {
+			rr.prefetchFrom(off + blockSize)
+		}
+		return blk.data, nil
+	}
+	rr.mu.Unlock()
+	if sequential {
+		rr.prefetchFrom(off + blockSize)
+	}
+	return rr.fetch(ctx, off)
+}
+
+func (rr *RandomReader) touchLocked(off int64, blk *randomBlock) {
+	rr.lru.RemoveItem(blk.id)
+	blk.id = rr.lru.Append(off)
+}
+
+func (rr *RandomReader) addLocked(off int64, data []byte) {
+	if blk, ok := rr.blocks[off]; ok {
+		rr.touchLocked(off, blk)
+		return
+	}
+	for rr.lru.Len() >= rr.cacheBlocks {
+		oldest := rr.lru.Head()
+		rr.lru.RemoveItem(rr.blocks[oldest].id)
+		delete(rr.blocks, oldest)
+		rr.stats.Evicted++
+	}
+	rr.blocks[off] = &randomBlock{data: data, id: rr.lru.Append(off)}
+}
+
+// fetch fetches the block at the specified offset, from the DownloadCache
+// if possible, ensuring that only a single fetch is ever in flight for
+// any given block. A caller that waits for a fetch started by another
+// caller, or by a prefetch, that fails because its context was canceled
+// retries the fetch using its own context.
+func (rr *RandomReader) fetch(ctx context.Context, off int64) ([]byte, error) {
+	for {
+		rr.mu.Lock()
+		if blk, ok := rr.blocks[off]; ok {
+			rr.mu.Unlock()
+			return blk.data, nil
+		}
+		if f, ok := rr.inflight[off]; ok {
+			rr.mu.Unlock()
+			select {
+			case <-f.done:
+				if isContextError(f.err) && ctx.Err() == nil {
+					continue
+				}
+				return f.data, f.err
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		f := rr.startFetchLocked(off)
+		rr.mu.Unlock()
+		rr.completeFetch(ctx, off, f, false)
+		return f.data, f.err
+	}
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func (rr *RandomReader) startFetchLocked(off int64) *blockFetch {
+	f := &blockFetch{done: make(chan struct{})}
+	rr.inflight[off] = f
+	return f
+}
+
+func (rr *RandomReader) completeFetch(ctx context.Context, off int64, f *blockFetch, prefetch bool) {
+	data, fromDisk, err := rr.get(ctx, off)
+	rr.mu.Lock()
+	f.data, f.err = data, err
+	delete(rr.inflight, off)
+	if err == nil {
+		switch {
+		case fromDisk:
+			rr.stats.DiskHits++
+		case prefetch:
+			rr.stats.Prefetched++
+		default:
+			rr.stats.Misses++
+		}
+		rr.addLocked(off, data)
+	}
+	rr.mu.Unlock()
+	close(f.done)
+}
+
+func (rr *RandomReader) get(ctx context.Context, off int64) ([]byte, bool, error) {
+	br := ByteRange{From: off, To: min(off+int64(rr.dl.blockSize), rr.dl.size) - 1}
+	if rr.cache != nil {
+		data := make([]byte, br.Size())
+		if _, err := rr.cache.ReadAt(data, off); err == nil {
+			return data, true, nil
+		} else if !errors.Is(err, ErrCacheUncachedRange) {
+			rr.dl.logger.Info("random reader: cache read failed", "byteRange", br, "error", err)
+		}
+	}
+	var data []byte
+	err := rr.dl.handleGet(ctx, request{ByteRange: br}, func(_ context.Context, resp response) error {
+		data = bytes.Clone(resp.data.Bytes())
+		rr.dl.bufPool.Put(resp.data)
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if rr.cache != nil {
+		if _, err := rr.cache.WriteAt(data, off); err != nil {
+			rr.dl.logger.Info("random reader: cache write failed", "byteRange", br, "error", err)
+		}
+	}
+	return data, false, nil
+}
+
+// prefetchFrom starts fetching up to rr.prefetch blocks starting at the
+// specified offset that are neither cached nor already being fetched.
+func (rr *RandomReader) prefetchFrom(off int64) {
+	blockSize := int64(rr.dl.blockSize)
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	for i := 0; i < rr.prefetch; i++ {
+		pos := off + int64(i)*blockSize
+		if pos >= rr.dl.size {
+			return
+		}
+		if _, ok := rr.blocks[pos]; ok {
+			continue
+		}
+		if _, ok := rr.inflight[pos]; ok {
+			continue
+		}
+		if rr.ctx.Err() != nil {
+			return
+		}
+		f := rr.startFetchLocked(pos)
+		rr.wg.Add(1)
+		go func() {
+			defer rr.wg.Done()
+			rr.completeFetch(rr.ctx, pos, f, true)
+			if f.err != nil {
+				rr.dl.logger.Info("random reader: prefetch failed", "offset", pos, "error", f.err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package largefile_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"cloudeng.io/file/largefile"
+)
+
+func TestRandomReader(t *testing.T) {
+	size, blockSize := 1000, 64
+	data := newUploadData(size)
+	src := &mirrorSource{data: data, blockSize: blockSize}
+	rr, err := largefile.NewRandomReader(src,
+		largefile.WithRandomReaderCacheBlocks(4),
+		largefile.WithRandomReaderPrefetch(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rr.Close()
+	if got, want := rr.Size(), int64(size); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for range 200 {
+		off := rnd.Int63n(int64(size))
+		buf := make([]byte, rnd.Intn(200)+1)
+		n, err := rr.ReadAt(buf, off)
+		want := min(int64(len(buf)), int64(size)-off)
+		if int64(n) != want {
+			t.Fatalf("offset %v: got %v, want %v", off, n, want)
+		}
+		if int64(n) < int64(len(buf)) && err != io.EOF {
+			t.Fatalf("offset %v: expected io.EOF: %v", off, err)
+		}
+		if !bytes.Equal(buf[:n], data[off:off+int64(n)]) {
+			t.Fatalf("offset %v: data mismatch", off)
+		}
+	}
+	if _, err := rr.ReadAt(make([]byte, 1), int64(size)); err != io.EOF {
+		t.Errorf("expected io.EOF: %v", err)
+	}
+	if _, err := rr.ReadAt(make([]byte, 1), -1); !errors.Is(err, largefile.ErrCacheInvalidOffset) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	st := rr.Stats()
+	if st.Hits == 0 || st.Evicted == 0 || st.Prefetched != 0 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+	if got, want := st.Misses, src.requests; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Repeated reads of a single block are served from the cache.
+	before := rr.Stats()
+	for range 10 {
+		if _, err := rr.ReadAt(make([]byte, 10), 130); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := rr.Stats().Misses-before.Misses, int64(1); got > want {
+		t.Errorf("got %v, want <= %v", got, want)
+	}
+}
+
+func TestRandomReaderSequential(t *testing.T) {
+	size, blockSize := 64*50+10, 64
+	data := newUploadData(size)
+	src := &mirrorSource{data: data, blockSize: blockSize}
+	rr, err := largefile.NewRandomReader(src,
+		largefile.WithRandomReaderCacheBlocks(8),
+		largefile.WithRandomReaderPrefetch(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rr.Close()
+	buf, err := io.ReadAll(rr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, data) {
+		t.Errorf("data mismatch")
+	}
+	st := rr.Stats()
+	if st.Prefetched == 0 {
+		t.Errorf("expected prefetching: %+v", st)
+	}
+
+	if _, err := rr.Seek(-10, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	buf, err = io.ReadAll(rr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, data[size-10:]) {
+		t.Errorf("data mismatch")
+	}
+	if _, err := rr.Seek(-1, io.SeekStart); !errors.Is(err, largefile.ErrCacheInvalidOffset) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
+
+func TestRandomReaderConcurrent(t *testing.T) {
+	size, blockSize := 64*20, 64
+	data := newUploadData(size)
+	src := &mirrorSource{data: data, blockSize: blockSize}
+	rr, err := largefile.NewRandomReader(src, largefile.WithRandomReaderCacheBlocks(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rr.Close()
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, size)
+			if _, err := rr.ReadAt(buf, 0); err != nil {
+				t.Errorf("%v: %v", i, err)
+				return
+			}
+			if !bytes.Equal(buf, data) {
+				t.Errorf("%v: data mismatch", i)
+			}
+		}()
+	}
+	wg.Wait()
+	// Each block is fetched exactly once since concurrent fetches
+	// are deduplicated and the cache can hold every block.
+	if got, want := src.requests, int64(20); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// blockingSource blocks the first request until its context is canceled.
+type blockingSource struct {
+	*mirrorSource
+	once    sync.Once
+	started chan struct{}
+}
+
+func (b *blockingSource) GetReader(ctx context.Context, from, to int64) (io.ReadCloser, largefile.RetryResponse, error) {
+	first := false
+	b.once.Do(func() { first = true })
+	if first {
+		close(b.started)
+		<-ctx.Done()
+		return nil, noRetryResponse{}, ctx.Err()
+	}
+	return b.mirrorSource.GetReader(ctx, from, to)
+}
+
+func TestRandomReaderCanceledWaiter(t *testing.T) {
+	size, blockSize := 1000, 64
+	data := newUploadData(size)
+	src := &blockingSource{
+		mirrorSource: &mirrorSource{data: data, blockSize: blockSize},
+		started:      make(chan struct{}),
+	}
+	rr, err := largefile.NewRandomReader(src, largefile.WithRandomReaderPrefetch(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rr.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := rr.ReadAtCtx(ctx, make([]byte, 10), 0)
+		errCh <- err
+	}()
+	<-src.started
+
+	// The second reader waits for the first reader's fetch, which is
+	// then canceled, and must retry the fetch using its own context.
+	type result struct {
+		buf []byte
+		err error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		buf := make([]byte, 10)
+		_, err := rr.ReadAtCtx(context.Background(), buf, 0)
+		resCh <- result{buf, err}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancellation error: %v", err)
+	}
+	res := <-resCh
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if !bytes.Equal(res.buf, data[:10]) {
+		t.Errorf("data mismatch")
+	}
+}
+
+func TestRandomReaderDownloadCache(t *testing.T) {
+	ctx := context.Background()
+	size, blockSize := 1000, 64
+	data := newUploadData(size)
+	tmpDir := t.TempDir()
+	cache := createNewCache(ctx, t, filepath.Join(tmpDir, "cache.dat"), filepath.Join(tmpDir, "cache.idx"), int64(size), blockSize, 1)
+	defer cache.Close()
+
+	rd := &memReader{data: data, blockSize: blockSize, failAt: map[int64]bool{}}
+	rr, err := largefile.NewRandomReader(rd, largefile.WithRandomReaderDownloadCache(cache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, size)
+	if _, err := rr.ReadAt(buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	rr.Close()
+	if !cache.Complete() {
+		t.Errorf("cache should be complete")
+	}
+
+	// All blocks are now read from the download cache.
+	rd.failAt = map[int64]bool{}
+	for off := int64(0); off < int64(size); off += int64(blockSize) {
+		rd.failAt[off] = true
+	}
+	rr, err = largefile.NewRandomReader(rd, largefile.WithRandomReaderDownloadCache(cache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rr.Close()
+	clear(buf)
+	if _, err := rr.ReadAt(buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, data) {
+		t.Errorf("data mismatch")
+	}
+	if got, want := rr.Stats().DiskHits, int64(largefile.NumBlocks(int64(size), blockSize)); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	other := createNewCache(ctx, t, filepath.Join(tmpDir, "o.dat"), filepath.Join(tmpDir, "o.idx"), int64(size), blockSize*2, 1)
+	defer other.Close()
+	if _, err := largefile.NewRandomReader(rd, largefile.WithRandomReaderDownloadCache(other)); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestRandomReaderZip(t *testing.T) {
+	var zbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	contents := map[string][]byte{}
+	for i := range 5 {
+		name := fmt.Sprintf("f%v.txt", i)
+		contents[name] = newUploadData(1000 * (i + 1))
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(contents[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	src := &mirrorSource{data: zbuf.Bytes(), blockSize: 256}
+	rr, err := largefile.NewRandomReader(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rr.Close()
+	zr, err := zip.NewReader(rr, rr.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(zr.File), len(contents); got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for _, f := range zr.File {
+		rd, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, err := io.ReadAll(rd)
+		rd.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf, contents[f.Name]) {
+			t.Errorf("%v: data mismatch", f.Name)
+		}
+	}
+}
@@ -44,9 +44,13 @@ type StreamingDownloader struct {
 	tracking     ByteRange
 	outOfOrder   int64
 	maxHeapSize  int64 // Maximum size of the heap during the download.
+	err          error // Set if the downloader is misconfigured.
 }
 
-// NewStreamingDownloader creates a new StreamingDownloader instance.
+// NewStreamingDownloader creates a new StreamingDownloader instance. If the
+// options are inconsistent with the file, for example the block digests
+// specified via WithDownloadBlockDigests do not match the file's size and
+// block size, Run returns an error without downloading any data.
 func NewStreamingDownloader(file Reader, opts ...DownloadOption) *StreamingDownloader {
 	dl := &StreamingDownloader{}
 	var options downloadOptions
@@ -60,6 +64,7 @@ func NewStreamingDownloader(file Reader, opts ...DownloadOption) *StreamingDownl
 	dl.outstanding = NewByteRanges(dl.size, dl.blockSize) // Create byte ranges for downloading.
 
 	dl.tracking = ByteRange{From: -1, To: -1} // Initialize tracking range to an invalid state.
+	dl.err = dl.validateBlockDigests()
 
 	if dl.waitForCompletion {
 		// Track byte ranges that need to be re-issued by the generator goroutine.
@@ -69,6 +74,10 @@ func NewStreamingDownloader(file Reader, opts ...DownloadOption) *StreamingDownl
 }
 
 func (dl *StreamingDownloader) Run(ctx context.Context) (StreamingStatus, error) {
+	if dl.err != nil {
+		dl.pipeWr.CloseWithError(dl.err)
+		return StreamingStatus{}, dl.err
+	}
 	start := time.Now()
 	g, ctx := errgroup.WithContext(ctx)
 	g = errgroup.WithConcurrency(g, dl.concurrency+1) // +1 for the generator goroutine
@@ -0,0 +1,81 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package largefile
+
+import (
+	"log/slog"
+	"time"
+
+	"cloudeng.io/algo/ratecontrol"
+)
+
+type uploadOptions struct {
+	concurrency       int
+	rateController    ratecontrol.Limiter
+	progressCh        chan<- UploadStats // Channel to report upload progress.
+	progressTimeout   time.Duration      // Delay between progress updates.
+	logger            *slog.Logger
+	waitForCompletion bool
+	indexFile         CacheFileReadWriter // Optional index file for resumable uploads.
+}
+
+type UploadOption func(*uploadOptions)
+
+// WithUploadConcurrency sets the number of concurrent upload goroutines.
+func WithUploadConcurrency(n int) UploadOption {
+	return func(o *uploadOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithUploadRateController sets the rate controller for the upload.
+func WithUploadRateController(rc ratecontrol.Limiter) UploadOption {
+	return func(o *uploadOptions) {
+		o.rateController = rc
+	}
+}
+
+// WithUploadLogger sets the logger for the upload.
+func WithUploadLogger(logger *slog.Logger) UploadOption {
+	return func(o *uploadOptions) {
+		o.logger = logger
+	}
+}
+
+// WithUploadProgress sets the channel to report upload progress.
+func WithUploadProgress(progress chan<- UploadStats) UploadOption {
+	return func(o *uploadOptions) {
+		o.progressCh = progress
+	}
+}
+
+// WithUploadProgressTimeout sets a timeout for the final progress update
+// sent when an upload is completed, see WithDownloadProgressTimeout.
+func WithUploadProgressTimeout(timeout time.Duration) UploadOption {
+	return func(o *uploadOptions) {
+		o.progressTimeout = timeout
+	}
+}
+
+// WithUploadWaitForCompletion sets whether the upload should iterate
+// until it is successfully completed or return after one iteration,
+// see WithDownloadWaitForCompletion.
+func WithUploadWaitForCompletion(wait bool) UploadOption {
+	return func(o *uploadOptions) {
+		o.waitForCompletion = wait
+	}
+}
+
+// WithUploadIndex sets the file used to persist the index of the byte
+// ranges that have been successfully uploaded. If the file is empty
+// a new index is created, otherwise the existing index is loaded and
+// only the byte ranges that have not been uploaded will be uploaded.
+// The index is saved after every successfully uploaded block and hence
+// allows for an interrupted upload to be resumed.
+func WithUploadIndex(index CacheFileReadWriter) UploadOption {
+	return func(o *uploadOptions) {
+		o.indexFile = index
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"cloudeng.io/cmdutil/boolexpr"
+	"cloudeng.io/file/content"
+)
+
+// SymlinkTargetIfc must be implemented by any values that are used with the
+// SymlinkTarget operand.
+type SymlinkTargetIfc interface {
+	SymlinkTarget() string
+}
+
+// Perm returns a 'permissions' operand. The value is not validated until a
+// matcher.T is created using New. As per the unix find command, the
+// permissions are specified in octal and may be prefixed by - to match
+// values that have all of the specified permission bits set, or by / to
+// match values that have any of the specified permission bits set, otherwise
+// the permissions must match exactly. Only the permission bits (0777)
+// are supported.
+// It requires that the value being matched implements FileModeIfc.
+func Perm(opname, value string) boolexpr.Operand {
+	return perm{text: value,
+		commonOperand: commonOperand{
+			name:     opname,
+			document: opname + "=[-/]<octal> matches permission bits exactly, or all (-) or any (/) of the specified bits",
+			requires: reflect.TypeFor[FileModeIfc](),
+		}}
+}
+
+type perm struct {
+	text     string
+	bits     fs.FileMode
+	all, any bool
+	commonOperand
+}
+
+func (op perm) Prepare() (boolexpr.Operand, error) {
+	text := op.text
+	switch {
+	case strings.HasPrefix(text, "-"):
+		op.all, text = true, text[1:]
+	case strings.HasPrefix(text, "/"):
+		op.any, text = true, text[1:]
+	}
+	bits, err := strconv.ParseUint(text, 8, 32)
+	if err != nil || bits > 0777 {
+		return op, fmt.Errorf("invalid permissions: %q, expected [-/]<octal> with a value no greater than 0777", op.text)
+	}
+	op.bits = fs.FileMode(bits)
+	return op, nil
+}
+
+func (op perm) Eval(v any) bool {
+	nt, ok := v.(FileModeIfc)
+	if !ok {
+		return false
+	}
+	p := nt.Mode().Perm()
+	switch {
+	case op.all:
+		return p&op.bits == op.bits
+	case op.any:
+		return op.bits == 0 || p&op.bits != 0
+	}
+	return p == op.bits
+}
+
+func (op perm) String() string {
+	return op.name + "=" + op.text
+}
+
+// Empty returns an 'empty' operand that matches empty regular files and
+// directories if its value is true, and non-empty regular files and
+// directories if its value is false. The value is not validated until a
+// matcher.T is created using New.
+// It requires that the value being matched implements FileTypeIfc and
+// FileSizeIfc for regular files and DirSizeIfc for directories.
+func Empty(opname, value string) boolexpr.Operand {
+	return empty{text: value,
+		commonOperand: commonOperand{
+			name:     opname,
+			document: opname + "=<true|false> matches empty, or non-empty, regular files and directories",
+			requires: reflect.TypeFor[FileTypeIfc](),
+		}}
+}
+
+type empty struct {
+	text  string
+	empty bool
+	commonOperand
+}
+
+func (op empty) Prepare() (boolexpr.Operand, error) {
+	b, err := strconv.ParseBool(op.text)
+	if err != nil {
+		return op, fmt.Errorf("invalid value: %q, expected true or false", op.text)
+	}
+	op.empty = b
+	return op, nil
+}
+
+// Needs implements boolexpr.Operand. The sizes of regular files and
+// directories are obtained via FileSizeIfc and DirSizeIfc respectively.
+func (op empty) Needs(t reflect.Type) bool {
+	return t.Implements(op.requires) ||
+		t.Implements(reflect.TypeFor[FileSizeIfc]()) ||
+		t.Implements(reflect.TypeFor[DirSizeIfc]())
+}
+
+func (op empty) Eval(v any) bool {
+	typ, ok := v.(FileTypeIfc)
+	if !ok {
+		return false
+	}
+	switch {
+	case typ.Type().IsRegular():
+		if nt, ok := v.(FileSizeIfc); ok {
+			return (nt.Size() == 0) == op.empty
+		}
+	case typ.Type().IsDir():
+		if nt, ok := v.(DirSizeIfc); ok {
+			return (nt.NumEntries() == 0) == op.empty
+		}
+	}
+	return false
+}
+
+func (op empty) String() string {
+	return op.name + "=" + op.text
+}
+
+// SymlinkTarget returns a 'symlink target' operand that matches symbolic
+// links whose targets match the specified glob pattern. The pattern is not
+// validated until a matcher.T is created using New.
+// It requires that the value being matched implements FileTypeIfc and
+// SymlinkTargetIfc.
+func SymlinkTarget(opname, value string) boolexpr.Operand {
+	return symlinkTarget{text: value,
+		commonOperand: commonOperand{
+			name:     opname,
+			document: opname + "=<glob> matches symbolic links whose target matches a glob pattern",
+			requires: reflect.TypeFor[SymlinkTargetIfc](),
+		}}
+}
+
+type symlinkTarget struct {
+	text string
+	commonOperand
+}
+
+func (op symlinkTarget) Prepare() (boolexpr.Operand, error) {
+	if _, err := filepath.Match(op.text, "foo"); err != nil {
+		return op, err
+	}
+	return op, nil
+}
+
+func (op symlinkTarget) Eval(v any) bool {
+	typ, ok := v.(FileTypeIfc)
+	if !ok || typ.Type()&fs.ModeSymlink == 0 {
+		return false
+	}
+	if nt, ok := v.(SymlinkTargetIfc); ok {
+		matched, _ := filepath.Match(op.text, nt.SymlinkTarget())
+		return matched
+	}
+	return false
+}
+
+func (op symlinkTarget) String() string {
+	return op.name + "=" + op.text
+}
+
+// ContentType returns a 'content type' operand that matches the content type
+// of the value as determined by content.TypeForPath from its name, or path
+// if it has no name. The value is a major/minor content type
+// that may contain glob patterns, eg. text/plain or image/*; any parameters
+// such as charset are ignored. The value is not validated until a matcher.T
+// is created using New.
+// It requires that the value being matched implements NameIfc or PathIfc.
+func ContentType(opname, value string) boolexpr.Operand {
+	return contentType{text: value,
+		commonOperand: commonOperand{
+			name:     opname,
+			document: opname + "=<major/minor> matches the content type implied by a file's extension, the major and minor types may be glob patterns",
+			requires: reflect.TypeFor[NameIfc](),
+		}}
+}
+
+type contentType struct {
+	text string
+	commonOperand
+}
+
+func (op contentType) Prepare() (boolexpr.Operand, error) {
+	if strings.Count(op.text, "/") != 1 {
+		return op, fmt.Errorf("invalid content type: %q, expected major/minor", op.text)
+	}
+	if _, err := path.Match(op.text, "a/b"); err != nil {
+		return op, err
+	}
+	return op, nil
+}
+
+func (op contentType) Eval(v any) bool {
+	var name string
+	if nt, ok := v.(NameIfc); ok {
+		name = nt.Name()
+	}
+	if pt, ok := v.(PathIfc); ok && len(name) == 0 {
+		name = pt.Path()
+	}
+	ctype := content.TypeForPath(name)
+	if len(ctype) == 0 {
+		return false
+	}
+	typ, err := content.ParseType(ctype)
+	if err != nil {
+		return false
+	}
+	matched, _ := path.Match(op.text, typ)
+	return matched
+}
+
+func (op contentType) String() string {
+	return op.name + "=" + op.text
+}
+
+// NewPerm returns a boolexpr.Operand that matches permission bits,
+// see Perm. The expression value must implement FileModeIfc.
+func NewPerm(n, v string) boolexpr.Operand { return Perm(n, v) }
+
+// NewEmpty returns a boolexpr.Operand that matches empty files and
+// directories, see Empty.
+func NewEmpty(n, v string) boolexpr.Operand { return Empty(n, v) }
+
+// NewSymlinkTarget returns a boolexpr.Operand that matches the targets of
+// symbolic links. The expression value must implement FileTypeIfc and
+// SymlinkTargetIfc.
+func NewSymlinkTarget(n, v string) boolexpr.Operand { return SymlinkTarget(n, v) }
+
+// NewContentType returns a boolexpr.Operand that matches content types,
+// see ContentType. The expression value must implement NameIfc or PathIfc.
+func NewContentType(n, v string) boolexpr.Operand { return ContentType(n, v) }
@@ -0,0 +1,145 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package matcher_test
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudeng.io/cmdutil/boolexpr"
+	"cloudeng.io/file"
+	"cloudeng.io/file/matcher"
+)
+
+type symlink struct {
+	fileInfo
+	target string
+}
+
+func (sl symlink) SymlinkTarget() string { return sl.target }
+
+type hardlinked struct {
+	fileInfo
+	links uint64
+}
+
+func (hl hardlinked) XAttr() file.XAttr { return file.XAttr{Hardlinks: hl.links} }
+
+func TestNewOperands(t *testing.T) {
+	now := time.Now()
+	mar2024 := time.Date(2024, 3, 31, 23, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		it     boolexpr.Operand
+		de     any
+		result bool
+	}{
+		{matcher.Perm("", "644"), fileInfo{mode: 0644}, true},
+		{matcher.Perm("", "0644"), fileInfo{mode: 0645}, false},
+		{matcher.Perm("", "-0444"), fileInfo{mode: 0644}, true},
+		{matcher.Perm("", "-0444"), fileInfo{mode: 0640}, false},
+		{matcher.Perm("", "/0111"), fileInfo{mode: 0640}, false},
+		{matcher.Perm("", "/0111"), fileInfo{mode: 0641}, true},
+		{matcher.Perm("", "/0"), fileInfo{mode: 0}, true},
+		{matcher.Perm("", "0755"), fs.ModeDir | 0755, false},
+		{matcher.Perm("", "0755"), fileInfo{mode: fs.ModeDir | 0755}, true},
+		{matcher.OlderThanParsed("", "1h"), fileInfo{modTime: now.Add(-2 * time.Hour)}, true},
+		{matcher.OlderThanParsed("", "1h"), fileInfo{modTime: now}, false},
+		{matcher.OlderThanParsed("", "30d"), fileInfo{modTime: now.Add(-29 * 24 * time.Hour)}, false},
+		{matcher.OlderThanParsed("", "30d"), fileInfo{modTime: now.Add(-31 * 24 * time.Hour)}, true},
+		{matcher.OlderThanParsed("", "2010-01-01"), fileInfo{modTime: mar2024}, false},
+		{matcher.OlderThanParsed("", "2025-01-01"), fileInfo{modTime: mar2024}, true},
+		{matcher.OlderThanTime("", mar2024), fileInfo{modTime: mar2024.Add(-time.Second)}, true},
+		{matcher.ModifiedIn("", "01/2024:03/2024"), fileInfo{modTime: mar2024}, true},
+		{matcher.ModifiedIn("", "Jan-2024:Feb-2024"), fileInfo{modTime: mar2024}, false},
+		{matcher.ModifiedIn("", "03/31/2024:04/01/2024"), fileInfo{modTime: mar2024}, true},
+		{matcher.ModifiedIn("", "03/31/2024:04/01/2024"), dirEntry{}, false},
+		{matcher.ContentType("", "text/plain"), fileInfo{name: "a.txt"}, true},
+		{matcher.ContentType("", "text/*"), fileInfo{name: "a.html"}, true},
+		{matcher.ContentType("", "image/*"), fileInfo{name: "a.html"}, false},
+		{matcher.ContentType("", "image/png"), dirEntryPath{path: "a/b.png"}, true},
+		{matcher.ContentType("", "*/*"), fileInfo{name: "no-extension"}, false},
+		{matcher.Empty("", "true"), fileInfo{size: 0}, true},
+		{matcher.Empty("", "true"), fileInfo{size: 1}, false},
+		{matcher.Empty("", "false"), fileInfo{size: 1}, true},
+		{matcher.Empty("", "true"), dirsize{0}, true},
+		{matcher.Empty("", "false"), dirsize{0}, false},
+		{matcher.Empty("", "true"), fileInfo{mode: fs.ModeSymlink}, false},
+		{matcher.SymlinkTarget("", "/data/*"), symlink{fileInfo{mode: fs.ModeSymlink}, "/data/x"}, true},
+		{matcher.SymlinkTarget("", "/data/*"), symlink{fileInfo{mode: fs.ModeSymlink}, "/other/x"}, false},
+		{matcher.SymlinkTarget("", "/data/*"), symlink{fileInfo{}, "/data/x"}, false},
+		{matcher.Hardlinks("", "2"), hardlinked{links: 2}, true},
+		{matcher.Hardlinks("", ">1"), hardlinked{links: 2}, true},
+		{matcher.Hardlinks("", ">1"), hardlinked{links: 1}, false},
+		{matcher.Hardlinks("", "<2"), hardlinked{links: 1}, true},
+		{matcher.Hardlinks("", "1"), fileInfo{}, false},
+	} {
+		expr, err := boolexpr.New(boolexpr.NewOperandItem(tc.it))
+		if err != nil {
+			t.Errorf("%v: failed to create expression: %v", tc.it, err)
+			continue
+		}
+		if got, want := expr.Eval(tc.de), tc.result; got != want {
+			t.Errorf("%v: %v: got %v, want %v", tc.it, tc.de, got, want)
+		}
+	}
+}
+
+func TestNewOperandErrors(t *testing.T) {
+	p := matcher.New()
+	for _, tc := range []struct {
+		expr string
+		err  string
+	}{
+		{"perm=0999", "invalid permissions"},
+		{"perm=01777", "invalid permissions"},
+		{"older-than=yesterday", "invalid age or time"},
+		{"modified-in=01/2024", "invalid date range"},
+		{"modified-in=03/2024:01/2024", "invalid date range"},
+		{"ctype=text", "invalid content type"},
+		{"ctype=text/[", "syntax error in pattern"},
+		{"empty=maybe", "invalid value"},
+		{"symlink-target=[", "syntax error in pattern"},
+		{"nlink=>x", "invalid number of hard links"},
+	} {
+		_, err := p.Parse(tc.expr)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%v: got %v, want %v", tc.expr, err, tc.err)
+		}
+	}
+
+	documented := map[string]bool{}
+	for _, op := range p.ListOperands() {
+		name, _, _ := strings.Cut(op.Document(), "=")
+		documented[name] = true
+	}
+	for _, name := range []string{"perm", "older-than", "modified-in", "ctype", "empty", "symlink-target", "nlink"} {
+		if !documented[name] {
+			t.Errorf("%v: not documented", name)
+		}
+	}
+
+	expr, err := p.Parse("type=f && perm=-0600 && older-than=24h && nlink=>1 && ctype='text/*'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !expr.Needs(hardlinked{}) {
+		t.Errorf("expected XAttrIfc to be needed")
+	}
+
+	expr, err = p.Parse("empty=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []any{typeOnly{}, fileInfo{}, dirSize{}} {
+		if !expr.Needs(v) {
+			t.Errorf("%T: expected to be needed", v)
+		}
+	}
+	if expr.Needs(nameOnly{}) {
+		t.Errorf("NameIfc should not be needed")
+	}
+}
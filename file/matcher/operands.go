@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"regexp/syntax"
 	"strconv"
 	"strings"
 	"time"
@@ -32,8 +33,10 @@ func (co commonOperand) Document() string {
 }
 
 type regEx struct {
-	text string
-	re   *regexp.Regexp
+	text     string
+	re       *regexp.Regexp
+	prefixes []string
+	anchored bool
 	commonOperand
 }
 
@@ -98,9 +101,19 @@ func (op regEx) Prepare() (boolexpr.Operand, error) {
 		return op, err
 	}
 	op.re = re
+	if sre, err := syntax.Parse(op.text, syntax.Perl); err == nil {
+		op.prefixes, op.anchored = anchoredPrefixes(sre)
+	}
 	return op, nil
 }
 
+// PathPrefixes implements PathPrefixOperand. Regular expressions that are
+// anchored to the start of the path, eg. ^/data/2024/.*, can only match
+// paths that start with the literal text that follows the anchor.
+func (op regEx) PathPrefixes() ([]string, bool) {
+	return op.prefixes, op.anchored
+}
+
 func (op regEx) Eval(v any) bool {
 	if nt, ok := v.(PathIfc); ok {
 		return op.re.MatchString(nt.Path())
@@ -507,6 +520,13 @@ func NewFileSizeSmaller(n, v string) boolexpr.Operand { return FileSizeSmaller(n
 //   - "dir-smaller", DirSizeSmaller
 //   - "file-larger", FileSizeGreater
 //   - "file-smaller", FileSizeSmaller
+//   - "perm", Perm
+//   - "older-than", OlderThan
+//   - "modified-in", ModifiedIn
+//   - "ctype", ContentType
+//   - "empty", Empty
+//   - "symlink-target", SymlinkTarget
+//   - "nlink", Hardlinks
 func New() *boolexpr.Parser {
 	parser := boolexpr.NewParser()
 	parser.RegisterOperand("name", NewGlob)
@@ -518,5 +538,12 @@ func New() *boolexpr.Parser {
 	parser.RegisterOperand("dir-smaller", NewDirSizeSmaller)
 	parser.RegisterOperand("file-larger", NewFileSizeLarger)
 	parser.RegisterOperand("file-smaller", NewFileSizeSmaller)
+	parser.RegisterOperand("perm", NewPerm)
+	parser.RegisterOperand("older-than", NewOlderThan)
+	parser.RegisterOperand("modified-in", NewModifiedIn)
+	parser.RegisterOperand("ctype", NewContentType)
+	parser.RegisterOperand("empty", NewEmpty)
+	parser.RegisterOperand("symlink-target", NewSymlinkTarget)
+	parser.RegisterOperand("nlink", NewHardlinks)
 	return parser
 }
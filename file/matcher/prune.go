@@ -0,0 +1,188 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"regexp/syntax"
+	"slices"
+	"strings"
+
+	"cloudeng.io/cmdutil/boolexpr"
+)
+
+// PathPrefixOperand may be implemented by operands that can only match
+// values whose paths, as returned by PathIfc, start with one of a set of
+// literal prefixes. It is used by CouldMatch and ListingPrefixes and ok
+// must be false if the operand may match any path.
+type PathPrefixOperand interface {
+	PathPrefixes() (prefixes []string, ok bool)
+}
+
+// anchoredPrefixes returns the literal prefixes that any match of re must
+// start with, if re is anchored to the start of the text.
+func anchoredPrefixes(re *syntax.Regexp) ([]string, bool) {
+	switch re.Op {
+	case syntax.OpBeginText:
+		return []string{""}, true
+	case syntax.OpAlternate:
+		var prefixes []string
+		for _, sub := range re.Sub {
+			p, ok := anchoredPrefixes(sub)
+			if !ok {
+				return nil, false
+			}
+			prefixes = append(prefixes, p...)
+		}
+		return minimalPrefixes(prefixes), true
+	case syntax.OpCapture:
+		return anchoredPrefixes(re.Sub[0])
+	case syntax.OpConcat:
+		if len(re.Sub) == 0 {
+			return nil, false
+		}
+		if re.Sub[0].Op != syntax.OpBeginText {
+			// Any literal text that follows is not appended since the
+			// prefixes returned for the first sub-expression need not
+			// be the entirety of its match.
+			return anchoredPrefixes(re.Sub[0])
+		}
+		var out strings.Builder
+		for _, sub := range re.Sub[1:] {
+			if sub.Op != syntax.OpLiteral || sub.Flags&syntax.FoldCase != 0 {
+				break
+			}
+			out.WriteString(string(sub.Rune))
+		}
+		return []string{out.String()}, true
+	}
+	return nil, false
+}
+
+// minimalPrefixes sorts and removes duplicates and any prefixes that are
+// extensions of other prefixes.
+func minimalPrefixes(prefixes []string) []string {
+	slices.Sort(prefixes)
+	out := prefixes[:0]
+	for _, p := range prefixes {
+		if len(out) > 0 && strings.HasPrefix(p, out[len(out)-1]) {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// compatible returns true if a path can start with both a and b.
+func compatible(a, b string) bool {
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
+
+type tristate int
+
+const (
+	never tristate = iota
+	maybe
+	always
+)
+
+type kleene struct{}
+
+func (kleene) And(a, b tristate) tristate { return min(a, b) }
+func (kleene) Or(a, b tristate) tristate  { return max(a, b) }
+func (kleene) Not(v tristate) tristate    { return always - v }
+
+// CouldMatch returns false if none of the paths that start with prefix can
+// match the supplied expression and true if some of them may. It can be used
+// to avoid traversing prefixes/directories that cannot contain any matches,
+// eg. for re=^/data/2024/.* && type=f, /data/2023 need not be traversed.
+// Only operands that implement PathPrefixOperand, such as the Regexp
+// operand, are used to determine that a prefix cannot match, all other
+// operands are assumed to potentially match any path. For directories,
+// prefix should include the trailing separator, ie. /data/2023/ rather
+// than /data/2023, so that /data/2023 is not considered as a prefix
+// for /data/20234.
+func CouldMatch(expr boolexpr.T, prefix string) bool {
+	return boolexpr.Reduce(expr, kleene{}, func(op boolexpr.Operand) tristate {
+		po, ok := op.(PathPrefixOperand)
+		if !ok {
+			return maybe
+		}
+		prefixes, ok := po.PathPrefixes()
+		if !ok {
+			return maybe
+		}
+		for _, p := range prefixes {
+			if compatible(p, prefix) {
+				return maybe
+			}
+		}
+		return never
+	}) != never
+}
+
+// prefixSet represents the set of paths that start with any one
+// of prefixes, or all paths if all is true.
+type prefixSet struct {
+	all      bool
+	prefixes []string
+}
+
+type prefixLogic struct{}
+
+func (prefixLogic) And(a, b prefixSet) prefixSet {
+	if a.all {
+		return b
+	}
+	if b.all {
+		return a
+	}
+	var prefixes []string
+	for _, pa := range a.prefixes {
+		for _, pb := range b.prefixes {
+			if compatible(pa, pb) {
+				prefixes = append(prefixes, max(pa, pb))
+			}
+		}
+	}
+	return prefixSet{prefixes: minimalPrefixes(prefixes)}
+}
+
+func (prefixLogic) Or(a, b prefixSet) prefixSet {
+	if a.all || b.all {
+		return prefixSet{all: true}
+	}
+	return prefixSet{prefixes: minimalPrefixes(append(slices.Clone(a.prefixes), b.prefixes...))}
+}
+
+// Not returns all paths since the complement of a set of prefixes
+// cannot be expressed as a set of prefixes.
+func (prefixLogic) Not(prefixSet) prefixSet {
+	return prefixSet{all: true}
+}
+
+// ListingPrefixes returns the smallest set of literal prefixes that any
+// path that matches the supplied expression must start with, as determined
+// by operands that implement PathPrefixOperand. This is intended to allow
+// for filesystems, such as S3, that support listing by prefix to list only
+// the prefixes that may contain matches. If ok is false then the expression
+// may match any path. An empty, non-nil, slice is returned if the
+// expression cannot match any path.
+func ListingPrefixes(expr boolexpr.T) (prefixes []string, ok bool) {
+	set := boolexpr.Reduce(expr, prefixLogic{}, func(op boolexpr.Operand) prefixSet {
+		if po, ok := op.(PathPrefixOperand); ok {
+			if prefixes, ok := po.PathPrefixes(); ok {
+				return prefixSet{prefixes: slices.Clone(prefixes)}
+			}
+		}
+		return prefixSet{all: true}
+	})
+	if set.all {
+		return nil, false
+	}
+	if set.prefixes == nil {
+		return []string{}, true
+	}
+	return set.prefixes, true
+}
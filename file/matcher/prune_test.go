@@ -0,0 +1,78 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package matcher_test
+
+import (
+	"reflect"
+	"testing"
+
+	"cloudeng.io/file/matcher"
+)
+
+func TestCouldMatch(t *testing.T) {
+	for _, tc := range []struct {
+		expr   string
+		prefix string
+		match  bool
+	}{
+		{`re=^/data/2024/.* && type=f`, "/", true},
+		{`re=^/data/2024/.* && type=f`, "/data/", true},
+		{`re=^/data/2024/.* && type=f`, "/data/2024/", true},
+		{`re=^/data/2024/.* && type=f`, "/data/2024/a/b/", true},
+		{`re=^/data/2024/.* && type=f`, "/data/2023/", false},
+		{`re=^/data/2024/.* && type=f`, "/data/20245/", false},
+		{`re=^/data/2024/.* && type=f`, "/other/", false},
+		{`re=/data/2024/.* && type=f`, "/other/", true},
+		{`re='(?m)^/data/2024/.*'`, "/other/", true},
+		{`re=^/data/2024/.* || name=*.go`, "/other/", true},
+		{`re=^/data/2024/.* || re=^/data/2025`, "/data/2025/x/", true},
+		{`re=^/data/2024/.* || re=^/data/2025`, "/data/2026/", false},
+		{`re='^/data/(2024|2025)/'`, "/data/2025/", true},
+		{`re='^/data/(2024|2025)/'`, "/other/", false},
+		{`re='^/data/20(24|25)/'`, "/data/2026/", true},
+		{`re='^/data|^/logs'`, "/logs/", true},
+		{`re='^/data|^/logs'`, "/tmp/", false},
+		{`re='^(?i)/data/'`, "/DATA/", true},
+		{`!re=^/data/`, "/other/", true},
+		{`!re=^/data/`, "/data/x/", true},
+		{`!(re=^/data/ || re=^/other/)`, "/data/x/", true},
+		{`re=^/data/ && re=^/other/`, "/data/", false},
+		{`type=f && (re=^/a/ || re=^/b/)`, "/c/", false},
+	} {
+		expr := runParser(t, tc.expr)
+		if got, want := matcher.CouldMatch(expr, tc.prefix), tc.match; got != want {
+			t.Errorf("%v: %v: got %v, want %v", tc.expr, tc.prefix, got, want)
+		}
+	}
+}
+
+func TestListingPrefixes(t *testing.T) {
+	for _, tc := range []struct {
+		expr     string
+		prefixes []string
+		ok       bool
+	}{
+		{`re=^/data/2024/.* && type=f`, []string{"/data/2024/"}, true},
+		{`re=^/data/2024/ || re=^/data/2025/`, []string{"/data/2024/", "/data/2025/"}, true},
+		{`re=^/data/ && re=^/data/2025/`, []string{"/data/2025/"}, true},
+		{`re=^/data/ || re=^/data/2025/`, []string{"/data/"}, true},
+		{`re=^/data/ && re=^/other/`, []string{}, true},
+		{`re='^/data/(a|b)/'`, []string{"/data/"}, true},
+		{`re='^/data|^/logs'`, []string{"/data", "/logs"}, true},
+		{`re=^/data/ || name=*.go`, nil, false},
+		{`!re=^/data/`, nil, false},
+		{`type=f`, nil, false},
+		{`re=data`, nil, false},
+	} {
+		expr := runParser(t, tc.expr)
+		prefixes, ok := matcher.ListingPrefixes(expr)
+		if got, want := ok, tc.ok; got != want {
+			t.Errorf("%v: got %v, want %v", tc.expr, got, want)
+		}
+		if got, want := prefixes, tc.prefixes; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %#v, want %#v", tc.expr, got, want)
+		}
+	}
+}
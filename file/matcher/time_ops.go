@@ -0,0 +1,140 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package matcher
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloudeng.io/cmdutil/boolexpr"
+	"cloudeng.io/datetime"
+)
+
+// OlderThanParsed returns an 'older than' operand. It is not validated until
+// a matcher.T is created using New. The value may be an age, expressed
+// as a time.Duration or as a number of days (eg. 36h or 30d), which is
+// relative to the time at which the matcher.T is created, or a time expressed
+// in any of the formats supported by NewerThanParsed.
+//
+// It requires that the value being matched implements ModTimeIfc.
+func OlderThanParsed(opname string, value string) boolexpr.Operand {
+	return olderThan{text: value,
+		commonOperand: commonOperand{
+			name:     opname,
+			document: opname + olderThanDoc,
+			requires: reflect.TypeFor[ModTimeIfc](),
+		}}
+}
+
+// OlderThanTime returns an 'older than' operand with the specified time.
+//
+// It requires that the value being matched implements ModTimeIfc.
+func OlderThanTime(opname string, when time.Time) boolexpr.Operand {
+	return olderThan{when: when,
+		commonOperand: commonOperand{
+			name:     opname,
+			document: opname + olderThanDoc,
+			requires: reflect.TypeFor[ModTimeIfc](),
+		}}
+}
+
+const olderThanDoc = "=<age|time> matches a time that is older than the specified age (eg. 36h or 30d) or time in time.RFC3339, time.DateTime, time.TimeOnly or time.DateOnly formats"
+
+type olderThan struct {
+	text string
+	when time.Time
+	commonOperand
+}
+
+func parseAge(text string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(text, "d"); ok {
+		n, err := strconv.ParseUint(days, 10, 32)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(text)
+}
+
+func (op olderThan) Prepare() (boolexpr.Operand, error) {
+	if !op.when.IsZero() {
+		return op, nil
+	}
+	if age, err := parseAge(op.text); err == nil {
+		op.when = time.Now().Add(-age)
+		return op, nil
+	}
+	nt, err := NewerThanParsed(op.name, op.text).Prepare()
+	if err != nil {
+		return op, fmt.Errorf("invalid age or time: %v, use a duration, a number of days or one of RFC3339, Date and Time, Date or Time only formats", op.text)
+	}
+	op.when = nt.(newerThan).when
+	return op, nil
+}
+
+func (op olderThan) Eval(v any) bool {
+	if nt, ok := v.(ModTimeIfc); ok {
+		return nt.ModTime().Before(op.when)
+	}
+	return false
+}
+
+func (op olderThan) String() string {
+	return op.name + "=" + op.text
+}
+
+// ModifiedIn returns a 'modified in' operand that matches modification
+// times that fall within a range of dates, inclusive of the first and last
+// dates. The range is specified in any of the formats supported by
+// datetime.CalendarDateRange, eg. 01/2024:03/2024 or Jan-02-2024:Mar-04-2024,
+// and is not validated until a matcher.T is created using New. The
+// modification time is interpreted in its own location.
+//
+// It requires that the value being matched implements ModTimeIfc.
+func ModifiedIn(opname string, value string) boolexpr.Operand {
+	return modifiedIn{text: value,
+		commonOperand: commonOperand{
+			name:     opname,
+			document: opname + "=<from>:<to> matches a time within the range of dates, eg. 01/2024:03/2024, 01/02/2024:03/04/2024, Jan-2024:Mar-2024 or Jan-02-2024:Mar-04-2024",
+			requires: reflect.TypeFor[ModTimeIfc](),
+		}}
+}
+
+type modifiedIn struct {
+	text   string
+	window datetime.CalendarDateRange
+	commonOperand
+}
+
+func (op modifiedIn) Prepare() (boolexpr.Operand, error) {
+	if err := op.window.Parse(op.text); err != nil {
+		return op, fmt.Errorf("invalid date range: %v: %v", op.text, err)
+	}
+	return op, nil
+}
+
+func (op modifiedIn) Eval(v any) bool {
+	if nt, ok := v.(ModTimeIfc); ok {
+		return op.window.Include(datetime.NewCalendarDateFromTime(nt.ModTime()))
+	}
+	return false
+}
+
+func (op modifiedIn) String() string {
+	return op.name + "=" + op.text
+}
+
+// NewOlderThan returns a boolexpr.Operand that matches a time that is older
+// than the specified age or time, see OlderThanParsed. The expression value
+// must implement ModTimeIfc.
+func NewOlderThan(n, v string) boolexpr.Operand { return OlderThanParsed(n, v) }
+
+// NewModifiedIn returns a boolexpr.Operand that matches a time within a range
+// of dates, see ModifiedIn. The expression value must implement ModTimeIfc.
+func NewModifiedIn(n, v string) boolexpr.Operand { return ModifiedIn(n, v) }
@@ -5,9 +5,11 @@
 package matcher
 
 import (
+	"fmt"
 	"os/user"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"cloudeng.io/cmdutil/boolexpr"
 	"cloudeng.io/file"
@@ -90,6 +92,65 @@ func NewGroup(name, value string, parser XAttrParser) boolexpr.Operand {
 		})
 }
 
+// Hardlinks returns an operand that compares the number of hard links
+// of the value being evaluated with the supplied value. The value may
+// be prefixed by > or < to match values that have more, or fewer, hard
+// links than specified, otherwise the number must match exactly. Since all
+// operands are of the form <name>=<value>, find(1)'s nlink>n is written as
+// nlink=>n. The value is not validated until a matcher.T is created using New.
+// The value being evaluated must implement the XAttrIfc interface.
+func Hardlinks(opname, value string) boolexpr.Operand {
+	return hardlinks{text: value,
+		commonOperand: commonOperand{
+			name:     opname,
+			document: opname + "=[<>]<n> matches files with exactly, more (>) or fewer (<) than <n> hard links, eg. " + opname + "=>1 rather than " + opname + ">1",
+			requires: reflect.TypeFor[XAttrIfc](),
+		}}
+}
+
+type hardlinks struct {
+	text string
+	cmp  byte
+	n    uint64
+	commonOperand
+}
+
+func (op hardlinks) Prepare() (boolexpr.Operand, error) {
+	text := op.text
+	if strings.HasPrefix(text, ">") || strings.HasPrefix(text, "<") {
+		op.cmp, text = text[0], text[1:]
+	}
+	n, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return op, fmt.Errorf("invalid number of hard links: %q, expected [<>]<n>", op.text)
+	}
+	op.n = n
+	return op, nil
+}
+
+func (op hardlinks) Eval(v any) bool {
+	nt, ok := v.(XAttrIfc)
+	if !ok {
+		return false
+	}
+	links := nt.XAttr().Hardlinks
+	switch op.cmp {
+	case '>':
+		return links > op.n
+	case '<':
+		return links < op.n
+	}
+	return links == op.n
+}
+
+func (op hardlinks) String() string {
+	return op.name + "=" + op.text
+}
+
+// NewHardlinks returns a boolexpr.Operand that compares the number of hard
+// links, see Hardlinks. The expression value must implement XAttrIfc.
+func NewHardlinks(n, v string) boolexpr.Operand { return Hardlinks(n, v) }
+
 // ParseUsernameOrID returns a file.XAttr that represents the supplied
 // name or ID.
 func ParseUsernameOrID(nameOrID string, lookup func(name string) (userid.IDInfo, error)) (file.XAttr, error) {
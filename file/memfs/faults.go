@@ -0,0 +1,73 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package memfs
+
+// Op represents the operations for which faults can be injected. Ops
+// may be or'ed together.
+type Op int
+
+const (
+	OpOpen     Op = 1 << iota // Open and OpenCtx.
+	OpStat                    // Stat and Lstat.
+	OpReadlink                // Readlink.
+	OpScan                    // LevelScanner.
+	OpRead                    // ReadFile, ReadFileCtx and Get.
+	OpWrite                   // WriteFile, WriteFileCtx, Put, Create, writes to a created file and Symlink.
+	OpDelete                  // Remove, RemoveAll, Delete and DeleteAll.
+	OpXAttr                   // XAttr.
+	OpMkdir                   // Mkdir, MkdirAll and EnsurePrefix.
+
+	OpAll = OpOpen | OpStat | OpReadlink | OpScan | OpRead | OpWrite | OpDelete | OpXAttr | OpMkdir
+)
+
+type fault struct {
+	ops   Op
+	err   error
+	count int // remaining number of times to return err, or < 0 for always.
+}
+
+// InjectFault arranges for err to be returned by the specified operations
+// on the specified path. If count is greater than zero, the fault is
+// returned only count times, otherwise it is returned until cleared
+// via ClearFaults. Paths are cleaned before being compared and hence
+// "/a/b" and "a/b/" refer to the same path. Faults are checked before any
+// other processing and hence will be returned for paths that do not exist.
+func (t *T) InjectFault(name string, ops Op, err error, count int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if count <= 0 {
+		count = -1
+	}
+	name = clean(name)
+	t.faults[name] = append(t.faults[name], fault{ops: ops, err: err, count: count})
+}
+
+// ClearFaults removes all injected faults.
+func (t *T) ClearFaults() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.faults = map[string][]fault{}
+}
+
+func (t *T) fault(name string, op Op) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.faults) == 0 {
+		return nil
+	}
+	name = clean(name)
+	faults := t.faults[name]
+	for i := range faults {
+		f := &faults[i]
+		if f.ops&op == 0 || f.count == 0 {
+			continue
+		}
+		if f.count > 0 {
+			f.count--
+		}
+		return f.err
+	}
+	return nil
+}
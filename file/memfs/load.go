@@ -0,0 +1,228 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package memfs
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cloudeng.io/cmdutil/cmdyaml"
+	"cloudeng.io/file"
+	"cloudeng.io/file/localfs"
+	"gopkg.in/yaml.v3"
+)
+
+type commonSpec struct {
+	Name   string           `yaml:"name"`
+	Mode   fs.FileMode      `yaml:"mode"`
+	Time   cmdyaml.FlexTime `yaml:"time"`
+	UID    int64            `yaml:"uid"`
+	GID    int64            `yaml:"gid"`
+	User   string           `yaml:"user"`
+	Group  string           `yaml:"group"`
+	Device uint64           `yaml:"device"`
+	FileID uint64           `yaml:"file_id"`
+}
+
+type fileSpec struct {
+	commonSpec `yaml:",inline"`
+	Size       int64  `yaml:"size"`
+	Contents   string `yaml:"contents"`
+}
+
+type linkSpec struct {
+	commonSpec `yaml:",inline"`
+	Target     string `yaml:"target"`
+}
+
+type entrySpec struct {
+	File *fileSpec `yaml:"file"`
+	Dir  *dirSpec  `yaml:"dir"`
+	Link *linkSpec `yaml:"link"`
+}
+
+type dirSpec struct {
+	commonSpec `yaml:",inline"`
+	Entries    []entrySpec `yaml:"entries"`
+}
+
+// LoadYAML adds the files, directories and symbolic links described by
+// the supplied YAML specification. The specification uses the same format
+// as filewalktestutil.WithYAMLConfig, with the addition of symbolic links
+// and user and group names, for example:
+//
+//	name: /root
+//	uid: 12
+//	entries:
+//	  - file:
+//	      name: f0
+//	      contents: hello
+//	  - link:
+//	      name: l0
+//	      target: f0
+//	  - dir:
+//	      name: d0
+//	      mode: 0700
+//	      entries:
+//	        - file:
+//	            name: f1
+//	            size: 100
+//
+// The name of the top-level directory is its path within the filesystem
+// and any missing parent directories are created. A file's size is
+// used only if no contents are specified, in which case the file will
+// consist of that many zero bytes. A zero mode results in 0700 for
+// directories and 0600 for files and a zero time in the current time.
+func (t *T) LoadYAML(cfg string) error {
+	var ds dirSpec
+	cfg = strings.ReplaceAll(cfg, "\t", "    ")
+	if err := yaml.Unmarshal([]byte(cfg), &ds); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	root := clean(ds.Name)
+	n, err := t.mkdirAllLocked(root, 0700)
+	if err != nil {
+		return err
+	}
+	t.loadYAMLDir(n, &ds)
+	return nil
+}
+
+func (t *T) applySpec(n *node, cs *commonSpec) {
+	if cs.Mode.Perm() != 0 {
+		n.mode = n.mode.Type() | cs.Mode.Perm()
+	}
+	if tm := time.Time(cs.Time); !tm.IsZero() {
+		n.modTime = tm
+	}
+	n.xattr = file.XAttr{
+		UID:    cs.UID,
+		GID:    cs.GID,
+		User:   cs.User,
+		Group:  cs.Group,
+		Device: cs.Device,
+		FileID: cs.FileID,
+	}
+}
+
+func (t *T) loadYAMLDir(d *node, ds *dirSpec) {
+	for _, es := range ds.Entries {
+		switch {
+		case es.Dir != nil:
+			n := t.newNode(fs.ModeDir | 0700)
+			t.loadYAMLDir(n, es.Dir)
+			t.applySpec(n, &es.Dir.commonSpec)
+			d.children[es.Dir.Name] = n
+		case es.File != nil:
+			n := t.newNode(0600)
+			n.data = []byte(es.File.Contents)
+			if len(n.data) == 0 && es.File.Size > 0 {
+				n.data = make([]byte, es.File.Size)
+			}
+			t.applySpec(n, &es.File.commonSpec)
+			d.children[es.File.Name] = n
+		case es.Link != nil:
+			n := t.newNode(fs.ModeSymlink | 0777)
+			n.target = es.Link.Target
+			t.applySpec(n, &es.Link.commonSpec)
+			d.children[es.Link.Name] = n
+		}
+	}
+	t.applySpec(d, &ds.commonSpec)
+}
+
+// LoadDir copies the contents of the local directory dir into the
+// filesystem under prefix, preserving the modes, modification times,
+// extended attributes and symbolic links of the original files and
+// directories. Symbolic links are copied as is and are not rewritten
+// to refer to prefix.
+func (t *T) LoadDir(ctx context.Context, dir, prefix string) error {
+	lfs := localfs.New()
+	type dirTime struct {
+		name    string
+		modTime time.Time
+	}
+	var dirTimes []dirTime
+	err := filepath.WalkDir(dir, func(filename string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, filename)
+		if err != nil {
+			return err
+		}
+		name := path.Join(clean(prefix), filepath.ToSlash(rel))
+		info, err := lfs.Lstat(ctx, filename)
+		if err != nil {
+			return err
+		}
+		xattr, err := lfs.XAttr(ctx, filename, info)
+		if err != nil {
+			return err
+		}
+		var data []byte
+		var target string
+		switch {
+		case info.IsDir():
+			dirTimes = append(dirTimes, dirTime{name, info.ModTime()})
+		case info.Mode()&fs.ModeSymlink != 0:
+			if target, err = os.Readlink(filename); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			if data, err = os.ReadFile(filename); err != nil {
+				return err
+			}
+		default:
+			// Ignore devices, pipes, sockets etc.
+			return nil
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		var n *node
+		if info.IsDir() {
+			if n, err = t.mkdirAllLocked(name, info.Mode()); err != nil {
+				return err
+			}
+		} else {
+			parent, base, err := t.parentLocked(name)
+			if err != nil {
+				return pathError("load", name, err)
+			}
+			n = t.newNode(info.Mode().Type())
+			t.addLocked(parent, base, n)
+		}
+		n.mode = info.Mode()
+		n.modTime = info.ModTime()
+		n.xattr = xattr
+		n.data = data
+		n.target = target
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Adding entries to a directory updates its modification time
+	// and hence the original times are restored once all entries
+	// have been added.
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, dt := range dirTimes {
+		if n, err := t.lookupLocked(dt.name, false); err == nil {
+			n.modTime = dt.modTime
+		}
+	}
+	return nil
+}
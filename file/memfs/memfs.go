@@ -0,0 +1,642 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package memfs provides an in-memory, read-write, filesystem that implements
+// file.FS, filewalk.FS, file.ObjectFS as well as file.ReadFileFS and
+// file.WriteFileFS. It supports directories, files and symbolic links,
+// settable extended attributes (file.XAttr) and the injection of per-path
+// faults. It is primarily intended for testing.
+//
+// All paths are slash separated and are cleaned using path.Clean, absolute
+// and relative paths are treated identically, ie. "/a/b" and "a/b" refer
+// to the same file.
+package memfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"cloudeng.io/file"
+	"cloudeng.io/file/filewalk"
+	"cloudeng.io/file/internal/fstree"
+)
+
+// T represents an in-memory filesystem.
+type T struct {
+	opts   options
+	mu     sync.Mutex
+	root   *node
+	faults map[string][]fault
+}
+
+var (
+	_ file.FS          = (*T)(nil)
+	_ filewalk.FS      = (*T)(nil)
+	_ file.ObjectFS    = (*T)(nil)
+	_ file.ReadFileFS  = (*T)(nil)
+	_ file.WriteFileFS = (*T)(nil)
+)
+
+// Option represents an option for New.
+type Option func(o *options)
+
+type options struct {
+	scheme string
+	now    func() time.Time
+}
+
+// WithScheme sets the scheme returned by Scheme, the default is "mem".
+func WithScheme(scheme string) Option {
+	return func(o *options) {
+		o.scheme = scheme
+	}
+}
+
+// WithClock sets the function used to obtain the modification time for
+// new or updated files and directories, the default is time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+type node struct {
+	mode     fs.FileMode
+	modTime  time.Time
+	xattr    file.XAttr
+	data     []byte
+	target   string
+	children map[string]*node
+}
+
+func (n *node) IsDir() bool {
+	return n.mode.IsDir()
+}
+
+func (n *node) IsSymlink() bool {
+	return n.mode&fs.ModeSymlink != 0
+}
+
+func (n *node) Target() string {
+	return n.target
+}
+
+func (n *node) Mode() fs.FileMode {
+	return n.mode
+}
+
+func (n *node) Children() map[string]*node {
+	return n.children
+}
+
+func (n *node) Info(name string) file.Info {
+	size := int64(len(n.data))
+	if n.IsSymlink() {
+		size = int64(len(n.target))
+	}
+	return file.NewInfo(name, size, n.mode, n.modTime, n.xattr)
+}
+
+// New returns a new, empty, in-memory filesystem.
+func New(opts ...Option) *T {
+	t := &T{faults: map[string][]fault{}}
+	t.opts.scheme = "mem"
+	t.opts.now = time.Now
+	for _, fn := range opts {
+		fn(&t.opts)
+	}
+	t.root = t.newNode(fs.ModeDir | 0700)
+	return t
+}
+
+func (t *T) newNode(mode fs.FileMode) *node {
+	n := &node{
+		mode:    mode,
+		modTime: t.opts.now(),
+		xattr:   file.XAttr{UID: -1, GID: -1},
+	}
+	if mode.IsDir() {
+		n.children = map[string]*node{}
+	}
+	return n
+}
+
+func clean(name string) string {
+	return path.Clean("/" + name)
+}
+
+func pathError(op, name string, err error) error {
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// lookupLocked returns the node for name, following symbolic links for
+// all but the final component and for the final component only if follow
+// is true.
+func (t *T) lookupLocked(name string, follow bool) (*node, error) {
+	return fstree.Resolve(t.root, fstree.Components(name), follow)
+}
+
+// parentLocked returns the directory node that contains name and the
+// base name of name within that directory.
+func (t *T) parentLocked(name string) (*node, string, error) {
+	name = clean(name)
+	if name == "/" {
+		return nil, "", fs.ErrInvalid
+	}
+	dir, base := path.Split(name)
+	parent, err := t.lookupLocked(dir, true)
+	if err != nil {
+		return nil, "", err
+	}
+	if !parent.IsDir() {
+		return nil, "", fs.ErrNotExist
+	}
+	return parent, base, nil
+}
+
+// Scheme implements file.FS.
+func (t *T) Scheme() string {
+	return t.opts.scheme
+}
+
+// Open implements fs.FS.
+func (t *T) Open(name string) (fs.File, error) {
+	return t.OpenCtx(context.Background(), name)
+}
+
+// OpenCtx implements file.FS. The returned file implements io.Seeker and
+// io.ReaderAt for files and fs.ReadDirFile for directories. The contents
+// of a file are those at the time that it was opened.
+func (t *T) OpenCtx(_ context.Context, name string) (fs.File, error) {
+	if err := t.fault(name, OpOpen); err != nil {
+		return nil, pathError("open", name, err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n, err := t.lookupLocked(name, true)
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+	info := n.Info(path.Base(clean(name)))
+	if n.IsDir() {
+		return fstree.NewDirHandle(info, fstree.Infos(n)), nil
+	}
+	return &fileHandle{info: info, Reader: bytes.NewReader(slices.Clone(n.data))}, nil
+}
+
+// Readlink implements file.FS.
+func (t *T) Readlink(_ context.Context, name string) (string, error) {
+	if err := t.fault(name, OpReadlink); err != nil {
+		return "", pathError("readlink", name, err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n, err := t.lookupLocked(name, false)
+	if err != nil {
+		return "", pathError("readlink", name, err)
+	}
+	if !n.IsSymlink() {
+		return "", pathError("readlink", name, fs.ErrInvalid)
+	}
+	return n.target, nil
+}
+
+// Stat implements file.FS.
+func (t *T) Stat(_ context.Context, name string) (file.Info, error) {
+	return t.stat("stat", name, true)
+}
+
+// Lstat implements file.FS.
+func (t *T) Lstat(_ context.Context, name string) (file.Info, error) {
+	return t.stat("lstat", name, false)
+}
+
+func (t *T) stat(op, name string, follow bool) (file.Info, error) {
+	if err := t.fault(name, OpStat); err != nil {
+		return file.Info{}, pathError(op, name, err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n, err := t.lookupLocked(name, follow)
+	if err != nil {
+		return file.Info{}, pathError(op, name, err)
+	}
+	return n.Info(path.Base(clean(name))), nil
+}
+
+// Join implements file.FS.
+func (t *T) Join(components ...string) string {
+	return path.Join(components...)
+}
+
+// Base implements file.FS.
+func (t *T) Base(name string) string {
+	return path.Base(name)
+}
+
+// IsPermissionError implements file.FS. Note that permissions are not
+// enforced and hence permission errors will only be returned if injected
+// via InjectFault.
+func (t *T) IsPermissionError(err error) bool {
+	return errors.Is(err, fs.ErrPermission)
+}
+
+// IsNotExist implements file.FS.
+func (t *T) IsNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// XAttr implements file.FS.
+func (t *T) XAttr(_ context.Context, name string, fi file.Info) (file.XAttr, error) {
+	if err := t.fault(name, OpXAttr); err != nil {
+		return file.XAttr{}, pathError("xattr", name, err)
+	}
+	if xattr, ok := fi.Sys().(file.XAttr); ok {
+		return xattr, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n, err := t.lookupLocked(name, false)
+	if err != nil {
+		return file.XAttr{}, pathError("xattr", name, err)
+	}
+	return n.xattr, nil
+}
+
+// SysXAttr implements file.FS. The native representation of extended
+// attributes is file.XAttr.
+func (t *T) SysXAttr(_ any, merge file.XAttr) any {
+	return merge
+}
+
+// SetXAttr sets the extended attributes for the specified file, directory
+// or symbolic link.
+func (t *T) SetXAttr(name string, xattr file.XAttr) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n, err := t.lookupLocked(name, false)
+	if err != nil {
+		return pathError("setxattr", name, err)
+	}
+	n.xattr = xattr
+	return nil
+}
+
+// Chmod changes the permission bits of the specified file or directory,
+// following symbolic links.
+func (t *T) Chmod(name string, perm fs.FileMode) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n, err := t.lookupLocked(name, true)
+	if err != nil {
+		return pathError("chmod", name, err)
+	}
+	n.mode = n.mode.Type() | perm.Perm()
+	return nil
+}
+
+// Chtimes changes the modification time of the specified file or
+// directory, following symbolic links.
+func (t *T) Chtimes(name string, modTime time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n, err := t.lookupLocked(name, true)
+	if err != nil {
+		return pathError("chtimes", name, err)
+	}
+	n.modTime = modTime
+	return nil
+}
+
+// Mkdir creates a new directory, the parent directory must exist.
+func (t *T) Mkdir(name string, perm fs.FileMode) error {
+	if err := t.fault(name, OpMkdir); err != nil {
+		return pathError("mkdir", name, err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parent, base, err := t.parentLocked(name)
+	if err != nil {
+		return pathError("mkdir", name, err)
+	}
+	if _, ok := parent.children[base]; ok {
+		return pathError("mkdir", name, fs.ErrExist)
+	}
+	t.addLocked(parent, base, t.newNode(fs.ModeDir|perm.Perm()))
+	return nil
+}
+
+// MkdirAll creates a directory and any necessary parents.
+func (t *T) MkdirAll(name string, perm fs.FileMode) error {
+	if err := t.fault(name, OpMkdir); err != nil {
+		return pathError("mkdir", name, err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := t.mkdirAllLocked(name, perm)
+	return err
+}
+
+func (t *T) mkdirAllLocked(name string, perm fs.FileMode) (*node, error) {
+	n := t.root
+	comps := fstree.Components(name)
+	for i, c := range comps {
+		child, ok := n.children[c]
+		if ok && child.IsSymlink() {
+			var err error
+			if child, err = fstree.Resolve(t.root, comps[:i+1], true); err != nil {
+				return nil, pathError("mkdir", name, err)
+			}
+		}
+		switch {
+		case child == nil:
+			child = t.newNode(fs.ModeDir | perm.Perm())
+			t.addLocked(n, c, child)
+		case !child.IsDir():
+			return nil, pathError("mkdir", name, fs.ErrExist)
+		}
+		n = child
+	}
+	return n, nil
+}
+
+func (t *T) addLocked(parent *node, name string, n *node) {
+	parent.children[name] = n
+	parent.modTime = t.opts.now()
+}
+
+// Symlink creates newname as a symbolic link to oldname. The parent
+// directory of newname must exist.
+func (t *T) Symlink(oldname, newname string) error {
+	if err := t.fault(newname, OpWrite); err != nil {
+		return pathError("symlink", newname, err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parent, base, err := t.parentLocked(newname)
+	if err != nil {
+		return pathError("symlink", newname, err)
+	}
+	if _, ok := parent.children[base]; ok {
+		return pathError("symlink", newname, fs.ErrExist)
+	}
+	n := t.newNode(fs.ModeSymlink | 0777)
+	n.target = oldname
+	t.addLocked(parent, base, n)
+	return nil
+}
+
+// Remove removes the specified file, symbolic link or empty directory.
+func (t *T) Remove(name string) error {
+	if err := t.fault(name, OpDelete); err != nil {
+		return pathError("remove", name, err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parent, base, err := t.parentLocked(name)
+	if err != nil {
+		return pathError("remove", name, err)
+	}
+	n, ok := parent.children[base]
+	if !ok {
+		return pathError("remove", name, fs.ErrNotExist)
+	}
+	if n.IsDir() && len(n.children) > 0 {
+		return pathError("remove", name, errors.New("directory not empty"))
+	}
+	t.removeLocked(parent, base)
+	return nil
+}
+
+// RemoveAll removes the specified file or directory and all of its
+// contents. It returns nil if name does not exist.
+func (t *T) RemoveAll(name string) error {
+	if err := t.fault(name, OpDelete); err != nil {
+		return pathError("remove", name, err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if clean(name) == "/" {
+		t.root.children = map[string]*node{}
+		return nil
+	}
+	parent, base, err := t.parentLocked(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return pathError("remove", name, err)
+	}
+	t.removeLocked(parent, base)
+	return nil
+}
+
+func (t *T) removeLocked(parent *node, name string) {
+	if _, ok := parent.children[name]; ok {
+		delete(parent.children, name)
+		parent.modTime = t.opts.now()
+	}
+}
+
+// ReadFile implements file.ReadFileFS.
+func (t *T) ReadFile(name string) ([]byte, error) {
+	return t.ReadFileCtx(context.Background(), name)
+}
+
+// ReadFileCtx implements file.ReadFileFS.
+func (t *T) ReadFileCtx(_ context.Context, name string) ([]byte, error) {
+	return t.readFile("read", name)
+}
+
+func (t *T) readFile(op, name string) ([]byte, error) {
+	if err := t.fault(name, OpRead); err != nil {
+		return nil, pathError(op, name, err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n, err := t.lookupLocked(name, true)
+	if err != nil {
+		return nil, pathError(op, name, err)
+	}
+	if n.IsDir() {
+		return nil, pathError(op, name, errors.New("is a directory"))
+	}
+	return slices.Clone(n.data), nil
+}
+
+// WriteFile implements file.WriteFileFS. The parent directory must exist.
+func (t *T) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return t.WriteFileCtx(context.Background(), name, data, perm)
+}
+
+// WriteFileCtx implements file.WriteFileFS. The parent directory must exist.
+func (t *T) WriteFileCtx(_ context.Context, name string, data []byte, perm fs.FileMode) error {
+	if err := t.fault(name, OpWrite); err != nil {
+		return pathError("write", name, err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parent, base, err := t.parentLocked(name)
+	if err != nil {
+		return pathError("write", name, err)
+	}
+	return t.writeLocked(parent, base, name, data, perm, false)
+}
+
+func (t *T) writeLocked(parent *node, base, name string, data []byte, perm fs.FileMode, appendData bool) error {
+	n, ok := parent.children[base]
+	if ok && n.IsSymlink() {
+		var err error
+		if n, err = t.lookupLocked(name, true); err != nil {
+			return pathError("write", name, err)
+		}
+	}
+	switch {
+	case n == nil:
+		n = t.newNode(perm.Perm())
+		t.addLocked(parent, base, n)
+	case n.IsDir():
+		return pathError("write", name, errors.New("is a directory"))
+	}
+	if appendData {
+		n.data = append(n.data, data...)
+	} else {
+		n.data = slices.Clone(data)
+	}
+	n.modTime = t.opts.now()
+	return nil
+}
+
+// Create creates a new file, returning an io.WriteCloser that can be used
+// to write to it. Each write is immediately visible to readers of the file.
+// It returns an error if the file already exists.
+func (t *T) Create(_ context.Context, name string, perm fs.FileMode) (io.WriteCloser, error) {
+	if err := t.fault(name, OpWrite); err != nil {
+		return nil, pathError("create", name, err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parent, base, err := t.parentLocked(name)
+	if err != nil {
+		return nil, pathError("create", name, err)
+	}
+	if _, ok := parent.children[base]; ok {
+		return nil, pathError("create", name, fs.ErrExist)
+	}
+	t.addLocked(parent, base, t.newNode(perm.Perm()))
+	return &writer{fs: t, name: name}, nil
+}
+
+type writer struct {
+	fs     *T
+	name   string
+	closed bool
+}
+
+// Write implements io.Writer.
+func (w *writer) Write(buf []byte) (int, error) {
+	if w.closed {
+		return 0, pathError("write", w.name, fs.ErrClosed)
+	}
+	if err := w.fs.fault(w.name, OpWrite); err != nil {
+		return 0, pathError("write", w.name, err)
+	}
+	w.fs.mu.Lock()
+	defer w.fs.mu.Unlock()
+	parent, base, err := w.fs.parentLocked(w.name)
+	if err != nil {
+		return 0, pathError("write", w.name, err)
+	}
+	if err := w.fs.writeLocked(parent, base, w.name, buf, 0600, true); err != nil {
+		return 0, err
+	}
+	return len(buf), nil
+}
+
+// Close implements io.Closer.
+func (w *writer) Close() error {
+	if w.closed {
+		return pathError("close", w.name, fs.ErrClosed)
+	}
+	w.closed = true
+	return nil
+}
+
+// Get implements file.ObjectFS.
+func (t *T) Get(_ context.Context, name string) ([]byte, error) {
+	return t.readFile("get", name)
+}
+
+// Put implements file.ObjectFS. Any missing parent directories are
+// created.
+func (t *T) Put(_ context.Context, name string, perm fs.FileMode, data []byte) error {
+	if err := t.fault(name, OpWrite); err != nil {
+		return pathError("put", name, err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	dir, base := path.Split(clean(name))
+	if len(base) == 0 {
+		return pathError("put", name, fs.ErrInvalid)
+	}
+	parent, err := t.mkdirAllLocked(dir, 0700)
+	if err != nil {
+		return err
+	}
+	return t.writeLocked(parent, base, name, data, perm, false)
+}
+
+// EnsurePrefix implements file.ObjectFS.
+func (t *T) EnsurePrefix(_ context.Context, name string, perm fs.FileMode) error {
+	return t.MkdirAll(name, perm)
+}
+
+// Delete implements file.ObjectFS.
+func (t *T) Delete(_ context.Context, name string) error {
+	return t.Remove(name)
+}
+
+// DeleteAll implements file.ObjectFS.
+func (t *T) DeleteAll(_ context.Context, prefix string) error {
+	return t.RemoveAll(prefix)
+}
+
+// LevelScanner implements filewalk.FS. The entries are returned in
+// lexicographic order and reflect the contents of the directory when
+// LevelScanner is called.
+func (t *T) LevelScanner(prefix string) filewalk.LevelScanner {
+	if err := t.fault(prefix, OpScan); err != nil {
+		return fstree.NewScanner(nil, pathError("scan", prefix, err))
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n, err := t.lookupLocked(prefix, true)
+	if err != nil {
+		return fstree.NewScanner(nil, pathError("scan", prefix, err))
+	}
+	if !n.IsDir() {
+		return fstree.NewScanner(nil, pathError("scan", prefix, errors.New("not a directory")))
+	}
+	return fstree.NewScanner(fstree.Entries(n), nil)
+}
+
+type fileHandle struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (f *fileHandle) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *fileHandle) Close() error {
+	return nil
+}
@@ -0,0 +1,418 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package memfs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"cloudeng.io/file"
+	"cloudeng.io/file/filewalk"
+	"cloudeng.io/file/memfs"
+)
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	ctx := context.Background()
+	mfs := memfs.New()
+	must(t, mfs.MkdirAll("/a/b", 0750))
+	must(t, mfs.WriteFile("/a/b/f", []byte("hello"), 0640))
+	must(t, mfs.Put(ctx, "x/y/z", 0600, []byte("world")))
+
+	for _, tc := range []struct {
+		name string
+		data string
+	}{
+		{"/a/b/f", "hello"},
+		{"a/b/f", "hello"},
+		{"/x/y/z", "world"},
+	} {
+		data, err := mfs.ReadFile(tc.name)
+		must(t, err)
+		if got, want := string(data), tc.data; got != want {
+			t.Errorf("%v: got %v, want %v", tc.name, got, want)
+		}
+		data, err = mfs.Get(ctx, tc.name)
+		must(t, err)
+		if got, want := string(data), tc.data; got != want {
+			t.Errorf("%v: got %v, want %v", tc.name, got, want)
+		}
+	}
+
+	info, err := mfs.Stat(ctx, "/a/b/f")
+	must(t, err)
+	if got, want := info.Name(), "f"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := info.Mode(), fs.FileMode(0640); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := info.Size(), int64(5); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	info, err = mfs.Stat(ctx, "/a/b")
+	must(t, err)
+	if got, want := info.Mode(), fs.ModeDir|0750; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if err := mfs.WriteFile("/nodir/f", nil, 0600); !mfs.IsNotExist(err) {
+		t.Errorf("expected a not-exist error: %v", err)
+	}
+	if err := mfs.WriteFile("/a/b", nil, 0600); err == nil {
+		t.Errorf("expected an error")
+	}
+	if _, err := mfs.ReadFile("/a/b"); err == nil {
+		t.Errorf("expected an error")
+	}
+	if err := mfs.Mkdir("/a/b", 0700); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expected an exist error: %v", err)
+	}
+
+	wr, err := mfs.Create(ctx, "/a/c", 0600)
+	must(t, err)
+	for _, s := range []string{"one", "two"} {
+		if _, err := wr.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+		data, err := mfs.ReadFile("/a/c")
+		must(t, err)
+		if got, want := string(data), "onetwo"[:len(data)]; got != want || !strings.HasSuffix(got, s) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+	must(t, wr.Close())
+	if _, err := mfs.Create(ctx, "/a/c", 0600); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expected an exist error: %v", err)
+	}
+
+	// Files are snapshotted when opened.
+	f, err := mfs.Open("/a/c")
+	must(t, err)
+	must(t, mfs.WriteFile("/a/c", []byte("changed"), 0600))
+	data, err := io.ReadAll(f)
+	must(t, err)
+	if got, want := string(data), "onetwo"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	must(t, f.Close())
+
+	if err := mfs.Remove("/a/b"); err == nil {
+		t.Errorf("expected an error removing a non-empty directory")
+	}
+	must(t, mfs.Delete(ctx, "/a/b/f"))
+	must(t, mfs.Remove("/a/b"))
+	must(t, mfs.DeleteAll(ctx, "/x"))
+	must(t, mfs.DeleteAll(ctx, "/x"))
+	for _, name := range []string{"/a/b", "/x/y/z", "/x"} {
+		if _, err := mfs.Stat(ctx, name); !mfs.IsNotExist(err) {
+			t.Errorf("%v: expected a not-exist error: %v", name, err)
+		}
+	}
+	must(t, mfs.EnsurePrefix(ctx, "/p/q", 0700))
+	if info, err := mfs.Stat(ctx, "/p/q"); err != nil || !info.IsDir() {
+		t.Errorf("%v: %v", info, err)
+	}
+}
+
+func TestSymlinks(t *testing.T) {
+	ctx := context.Background()
+	mfs := memfs.New()
+	must(t, mfs.MkdirAll("/a/b", 0700))
+	must(t, mfs.WriteFile("/a/b/f", []byte("hello"), 0600))
+	must(t, mfs.Symlink("b", "/a/lb"))
+	must(t, mfs.Symlink("/a/b/f", "/a/lf"))
+	must(t, mfs.Symlink("lf", "/a/llf"))
+	must(t, mfs.Symlink("loop2", "/a/loop1"))
+	must(t, mfs.Symlink("loop1", "/a/loop2"))
+	must(t, mfs.Symlink("missing", "/a/dangling"))
+
+	for _, name := range []string{"/a/lb/f", "/a/lf", "/a/llf"} {
+		data, err := mfs.ReadFile(name)
+		must(t, err)
+		if got, want := string(data), "hello"; got != want {
+			t.Errorf("%v: got %v, want %v", name, got, want)
+		}
+		info, err := mfs.Stat(ctx, name)
+		must(t, err)
+		if !info.Mode().IsRegular() {
+			t.Errorf("%v: not a regular file: %v", name, info.Mode())
+		}
+	}
+
+	info, err := mfs.Lstat(ctx, "/a/llf")
+	must(t, err)
+	if got, want := info.Mode()&fs.ModeSymlink, fs.ModeSymlink; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := info.Size(), int64(2); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	target, err := mfs.Readlink(ctx, "/a/llf")
+	must(t, err)
+	if got, want := target, "lf"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := mfs.Readlink(ctx, "/a/b/f"); err == nil {
+		t.Errorf("expected an error")
+	}
+	if _, err := mfs.Stat(ctx, "/a/loop1"); err == nil || !strings.Contains(err.Error(), "too many levels") {
+		t.Errorf("expected a symlink loop error: %v", err)
+	}
+	if _, err := mfs.Stat(ctx, "/a/dangling"); !mfs.IsNotExist(err) {
+		t.Errorf("expected a not-exist error: %v", err)
+	}
+	if _, err := mfs.Lstat(ctx, "/a/dangling"); err != nil {
+		t.Error(err)
+	}
+
+	// Writes via a symlink update the target.
+	must(t, mfs.WriteFile("/a/lf", []byte("updated"), 0600))
+	data, err := mfs.ReadFile("/a/b/f")
+	must(t, err)
+	if got, want := string(data), "updated"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestXAttr(t *testing.T) {
+	ctx := context.Background()
+	mfs := memfs.New()
+	must(t, mfs.WriteFile("/f", []byte("hello"), 0600))
+	xattr := file.XAttr{UID: 10, GID: 20, User: "u", Group: "g", FileID: 33, Hardlinks: 2}
+	must(t, mfs.SetXAttr("/f", xattr))
+	info, err := mfs.Lstat(ctx, "/f")
+	must(t, err)
+	got, err := mfs.XAttr(ctx, "/f", info)
+	must(t, err)
+	if !reflect.DeepEqual(got, xattr) {
+		t.Errorf("got %v, want %v", got, xattr)
+	}
+	if got := mfs.SysXAttr(nil, xattr); !reflect.DeepEqual(got, xattr) {
+		t.Errorf("got %v, want %v", got, xattr)
+	}
+	if err := mfs.SetXAttr("/missing", xattr); !mfs.IsNotExist(err) {
+		t.Errorf("expected a not-exist error: %v", err)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	ctx := context.Background()
+	mfs := memfs.New()
+	must(t, mfs.Put(ctx, "/a/f", 0600, []byte("hello")))
+
+	mfs.InjectFault("/a/f", memfs.OpStat|memfs.OpRead, fs.ErrPermission, 0)
+	mfs.InjectFault("a", memfs.OpScan, os.ErrDeadlineExceeded, 1)
+	mfs.InjectFault("/a/g", memfs.OpWrite, fs.ErrPermission, 0)
+
+	if _, err := mfs.Stat(ctx, "/a/f"); !mfs.IsPermissionError(err) {
+		t.Errorf("expected a permission error: %v", err)
+	}
+	if _, err := mfs.ReadFile("/a/f"); !mfs.IsPermissionError(err) {
+		t.Errorf("expected a permission error: %v", err)
+	}
+	if _, err := mfs.Open("/a/f"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := mfs.WriteFile("/a/g", nil, 0600); !mfs.IsPermissionError(err) {
+		t.Errorf("expected a permission error: %v", err)
+	}
+	sc := mfs.LevelScanner("/a")
+	if sc.Scan(ctx, 10) || !errors.Is(sc.Err(), os.ErrDeadlineExceeded) {
+		t.Errorf("expected a deadline error: %v", sc.Err())
+	}
+	// The scan fault is returned only once.
+	sc = mfs.LevelScanner("/a")
+	if !sc.Scan(ctx, 10) || sc.Err() != nil {
+		t.Errorf("unexpected error: %v", sc.Err())
+	}
+	mfs.ClearFaults()
+	if _, err := mfs.Stat(ctx, "/a/f"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+const yamlSpec = `
+name: /root
+uid: 12
+entries:
+  - file:
+      name: f0
+      uid: 2
+      contents: hello
+  - file:
+      name: f1
+      size: 10
+  - link:
+      name: l0
+      target: d0/f3
+  - dir:
+	  name: d0
+	  mode: 0750
+	  time: 2024-01-02T03:04:05Z
+	  entries:
+	    - file:
+			name: f3
+			contents: world
+            mode: 0644
+`
+
+type collector struct {
+	sync.Mutex
+	fs    filewalk.FS
+	names []string
+}
+
+func (c *collector) Prefix(_ context.Context, _ *struct{}, _ string, _ file.Info, err error) (bool, file.InfoList, error) {
+	return false, nil, err
+}
+
+func (c *collector) Contents(ctx context.Context, _ *struct{}, prefix string, contents []filewalk.Entry) (file.InfoList, error) {
+	c.Lock()
+	defer c.Unlock()
+	var children file.InfoList
+	for _, e := range contents {
+		name := c.fs.Join(prefix, e.Name)
+		info, err := c.fs.Lstat(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if e.IsDir() {
+			name += "/"
+			children = append(children, info)
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			name += "@"
+		}
+		c.names = append(c.names, name)
+	}
+	return children, nil
+}
+
+func (c *collector) Done(_ context.Context, _ *struct{}, _ string, err error) error {
+	return err
+}
+
+func walk(t *testing.T, fs filewalk.FS, root string) []string {
+	t.Helper()
+	c := &collector{fs: fs}
+	if err := filewalk.New(fs, c, filewalk.WithScanSize(1)).Walk(context.Background(), root); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(c.names)
+	return c.names
+}
+
+func TestYAML(t *testing.T) {
+	ctx := context.Background()
+	mfs := memfs.New()
+	must(t, mfs.LoadYAML(yamlSpec))
+
+	if got, want := walk(t, mfs, "/root"), []string{
+		"/root/d0/", "/root/d0/f3", "/root/f0", "/root/f1", "/root/l0@",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	info, err := mfs.Stat(ctx, "/root/d0")
+	must(t, err)
+	if got, want := info.Mode(), fs.ModeDir|0750; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := info.ModTime(), time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	info, err = mfs.Stat(ctx, "/root/f1")
+	must(t, err)
+	if got, want := info.Size(), int64(10); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	info, err = mfs.Lstat(ctx, "/root/f0")
+	must(t, err)
+	if xattr, err := mfs.XAttr(ctx, "/root/f0", info); err != nil || xattr.UID != 2 {
+		t.Errorf("unexpected xattr: %v: %v", xattr, err)
+	}
+	data, err := mfs.ReadFile("/root/l0")
+	must(t, err)
+	if got, want := string(data), "world"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := mfs.LoadYAML("name: [x"); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	must(t, os.MkdirAll(filepath.Join(tmpDir, "a", "b"), 0700))
+	must(t, os.WriteFile(filepath.Join(tmpDir, "a", "b", "f"), []byte("hello"), 0640))
+	must(t, os.WriteFile(filepath.Join(tmpDir, "g"), []byte("world"), 0600))
+	must(t, os.Symlink("a/b/f", filepath.Join(tmpDir, "l")))
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	must(t, os.Chtimes(filepath.Join(tmpDir, "a"), modTime, modTime))
+
+	mfs := memfs.New()
+	must(t, mfs.LoadDir(ctx, tmpDir, "/copy"))
+
+	if got, want := walk(t, mfs, "/copy"), []string{
+		"/copy/a/", "/copy/a/b/", "/copy/a/b/f", "/copy/g", "/copy/l@",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	data, err := mfs.ReadFile("/copy/l")
+	must(t, err)
+	if got, want := string(data), "hello"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	info, err := mfs.Stat(ctx, "/copy/a/b/f")
+	must(t, err)
+	if got, want := info.Mode(), fs.FileMode(0640); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	info, err = mfs.Stat(ctx, "/copy/a")
+	must(t, err)
+	if got, want := info.ModTime(), modTime; !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// validFS enforces the io/fs path rules, which memfs, like localfs,
+// does not.
+type validFS struct {
+	mfs *memfs.T
+}
+
+func (v validFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return v.mfs.Open(name)
+}
+
+func TestFSTest(t *testing.T) {
+	mfs := memfs.New()
+	must(t, mfs.LoadYAML(yamlSpec))
+	// fstest.TestFS does not support symbolic links.
+	must(t, mfs.Remove("/root/l0"))
+	must(t, fstest.TestFS(validFS{mfs}, "root/f0", "root/f1", "root/d0/f3"))
+}
@@ -0,0 +1,148 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package unionfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"cloudeng.io/file/filewalk"
+)
+
+// LevelScanner implements filewalk.FS. The listing returned is the
+// merge of the listings of prefix in all of the layers in which it is
+// visible, with entries in higher layers taking precedence over entries
+// of the same name in lower layers and with whiteouts applied. Since
+// merging requires that all of the layers' listings be read in full,
+// they are read on the first call to Scan. The merged entries are
+// returned in lexicographic order.
+func (t *T) LevelScanner(prefix string) filewalk.LevelScanner {
+	return &scanner{fs: t, prefix: clean(prefix)}
+}
+
+type scanner struct {
+	fs       *T
+	prefix   string
+	read     bool
+	entries  []filewalk.Entry
+	pos, end int
+	err      error
+}
+
+// Scan implements filewalk.LevelScanner.
+func (s *scanner) Scan(ctx context.Context, n int) bool {
+	if s.err != nil {
+		return false
+	}
+	if !s.read {
+		s.read = true
+		s.entries, s.err = s.fs.merge(ctx, s.prefix, n)
+		if s.err != nil {
+			return false
+		}
+	}
+	s.pos = s.end
+	if s.pos >= len(s.entries) {
+		return false
+	}
+	s.end = min(s.pos+n, len(s.entries))
+	return true
+}
+
+// Contents implements filewalk.LevelScanner.
+func (s *scanner) Contents() []filewalk.Entry {
+	return s.entries[s.pos:s.end]
+}
+
+// Err implements filewalk.LevelScanner.
+func (s *scanner) Err() error {
+	return s.err
+}
+
+func (t *T) merge(ctx context.Context, prefix string, n int) ([]filewalk.Entry, error) {
+	if isWhiteout(prefix) {
+		return nil, pathError("scan", prefix, fs.ErrNotExist)
+	}
+	seen := map[string]bool{}
+	var merged []filewalk.Entry
+	found := false
+	for _, l := range t.layers {
+		info, err := l.FS.Lstat(ctx, l.path(prefix))
+		switch {
+		case err == nil && !info.IsDir():
+			// A file hides any directories of the same name in
+			// lower layers.
+			if !found {
+				return nil, pathError("scan", prefix, errors.New("not a directory"))
+			}
+			return sortEntries(merged), nil
+		case err == nil:
+			found = true
+			entries, whiteouts, opaque, err := t.scanLayer(ctx, l, prefix, n)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				if !seen[e.Name] {
+					seen[e.Name] = true
+					merged = append(merged, e)
+				}
+			}
+			for _, w := range whiteouts {
+				seen[w] = true
+			}
+			if opaque {
+				return sortEntries(merged), nil
+			}
+		case !isNotExist(l, err):
+			return nil, err
+		}
+		if len(prefix) > 0 {
+			hidden, err := t.hides(ctx, l, prefix)
+			if err != nil {
+				return nil, err
+			}
+			if hidden {
+				break
+			}
+		}
+	}
+	if !found {
+		return nil, pathError("scan", prefix, fs.ErrNotExist)
+	}
+	return sortEntries(merged), nil
+}
+
+// scanLayer returns the entries and whiteouts, with WhiteoutPrefix
+// stripped, for prefix in the specified layer.
+func (t *T) scanLayer(ctx context.Context, l Layer, prefix string, n int) (entries []filewalk.Entry, whiteouts []string, opaque bool, err error) {
+	sc := l.FS.LevelScanner(l.path(prefix))
+	for sc.Scan(ctx, n) {
+		for _, e := range sc.Contents() {
+			switch {
+			case e.Name == OpaqueWhiteout:
+				opaque = true
+			case strings.HasPrefix(e.Name, WhiteoutPrefix):
+				whiteouts = append(whiteouts, strings.TrimPrefix(e.Name, WhiteoutPrefix))
+			default:
+				entries = append(entries, e)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, false, err
+	}
+	return
+}
+
+func sortEntries(entries []filewalk.Entry) []filewalk.Entry {
+	slices.SortFunc(entries, func(a, b filewalk.Entry) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return entries
+}
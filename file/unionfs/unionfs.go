@@ -0,0 +1,479 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package unionfs provides a file.FS, filewalk.FS and file.ObjectFS that
+// stacks several filesystems, or layers, to present them as a single
+// hierarchy, for example, local overrides over an S3 baseline.
+//
+// Reads are resolved top-down, that is, a file or directory in a higher
+// layer hides any file or directory of the same name in a lower layer.
+// Consequently a file in a higher layer hides the entire contents of any
+// directory of the same name in lower layers.
+// Directory listings obtained via LevelScanner are the merge of the
+// listings of the same directory in all layers. Writes via the
+// file.ObjectFS methods are always made to the top layer.
+//
+// Deletions of files or directories that exist in lower layers are
+// represented by whiteouts in the top layer, following the conventions
+// used by overlay filesystems and OCI images: a file named
+// WhiteoutPrefix+name hides name in all lower layers and a file named
+// OpaqueWhiteout within a directory hides the contents of that directory
+// in all lower layers. Whiteouts are never returned in directory listings.
+//
+// All paths are slash separated and relative to the root of each layer,
+// the union's root is denoted by "" or ".".
+package unionfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"syscall"
+
+	"cloudeng.io/file"
+	"cloudeng.io/file/filewalk"
+)
+
+const (
+	// WhiteoutPrefix is the prefix used for whiteout files.
+	WhiteoutPrefix = ".wh."
+	// OpaqueWhiteout is the name of the file used to mark a directory as
+	// opaque.
+	OpaqueWhiteout = WhiteoutPrefix + WhiteoutPrefix + ".opq"
+)
+
+// ErrReadOnly is returned for write operations when the top layer
+// does not implement file.ObjectFS.
+var ErrReadOnly = errors.New("top layer is not writable")
+
+// Layer represents a single layer in a union.
+type Layer struct {
+	// FS is the filesystem for this layer.
+	FS filewalk.FS
+	// Root is the path within FS that corresponds to the root of the union.
+	Root string
+}
+
+func (l Layer) path(name string) string {
+	if len(name) == 0 || name == "." {
+		return l.Root
+	}
+	return l.FS.Join(l.Root, name)
+}
+
+// T represents a union filesystem.
+type T struct {
+	opts   options
+	layers []Layer
+}
+
+var (
+	_ file.FS         = (*T)(nil)
+	_ filewalk.FS     = (*T)(nil)
+	_ file.ObjectFS   = (*T)(nil)
+	_ file.ReadFileFS = (*T)(nil)
+)
+
+// Option represents an option for New.
+type Option func(o *options)
+
+type options struct {
+	scheme string
+}
+
+// WithScheme sets the scheme returned by Scheme, the default is "union".
+func WithScheme(scheme string) Option {
+	return func(o *options) {
+		o.scheme = scheme
+	}
+}
+
+// New returns a new union of the specified layers, the first layer is the
+// top layer.
+func New(layers []Layer, opts ...Option) (*T, error) {
+	if len(layers) == 0 {
+		return nil, fmt.Errorf("unionfs: no layers specified")
+	}
+	t := &T{layers: slices.Clone(layers)}
+	t.opts.scheme = "union"
+	for _, fn := range opts {
+		fn(&t.opts)
+	}
+	return t, nil
+}
+
+// Layers returns the layers that comprise the union.
+func (t *T) Layers() []Layer {
+	return slices.Clone(t.layers)
+}
+
+func clean(name string) string {
+	name = path.Clean("/" + name)
+	return name[1:]
+}
+
+// ancestors returns all of the ancestors of name, not including the root.
+func ancestors(name string) []string {
+	var dirs []string
+	for d := path.Dir(name); d != "." && d != "/"; d = path.Dir(d) {
+		dirs = append(dirs, d)
+	}
+	slices.Reverse(dirs)
+	return dirs
+}
+
+// isNotExist returns true if err indicates that a file does not exist in
+// the specified layer, including the case where one of its ancestors is
+// not a directory (ENOTDIR).
+func isNotExist(l Layer, err error) bool {
+	return l.FS.IsNotExist(err) || errors.Is(err, syscall.ENOTDIR)
+}
+
+func (t *T) exists(ctx context.Context, l Layer, name string) (bool, error) {
+	_, err := l.FS.Lstat(ctx, l.path(name))
+	if err == nil {
+		return true, nil
+	}
+	if isNotExist(l, err) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (t *T) whiteout(ctx context.Context, l Layer, name string) (bool, error) {
+	dir, base := path.Split(name)
+	return t.exists(ctx, l, path.Join(dir, WhiteoutPrefix+base))
+}
+
+// hides returns true if the specified layer contains a whiteout for
+// name, or one of its ancestors, if any of the ancestors of name are
+// opaque or if any of the ancestors of name are not directories.
+func (t *T) hides(ctx context.Context, l Layer, name string) (bool, error) {
+	for _, d := range ancestors(name) {
+		if ok, err := t.whiteout(ctx, l, d); ok || err != nil {
+			return ok, err
+		}
+		info, err := l.FS.Lstat(ctx, l.path(d))
+		if err != nil {
+			if isNotExist(l, err) {
+				// Nothing below d can exist in this layer.
+				return false, nil
+			}
+			return false, err
+		}
+		if !info.IsDir() {
+			return true, nil
+		}
+		if ok, err := t.exists(ctx, l, path.Join(d, OpaqueWhiteout)); ok || err != nil {
+			return ok, err
+		}
+	}
+	return t.whiteout(ctx, l, name)
+}
+
+func isWhiteout(name string) bool {
+	return strings.HasPrefix(path.Base(name), WhiteoutPrefix)
+}
+
+// resolve returns the index of the highest layer that contains name and
+// the file.Info for it obtained via Lstat.
+func (t *T) resolve(ctx context.Context, name string) (int, file.Info, error) {
+	name = clean(name)
+	if isWhiteout(name) {
+		return -1, file.Info{}, fs.ErrNotExist
+	}
+	for i, l := range t.layers {
+		info, err := l.FS.Lstat(ctx, l.path(name))
+		if err == nil {
+			return i, info, nil
+		}
+		if !isNotExist(l, err) {
+			return -1, file.Info{}, err
+		}
+		if len(name) == 0 {
+			continue
+		}
+		hidden, err := t.hides(ctx, l, name)
+		if err != nil {
+			return -1, file.Info{}, err
+		}
+		if hidden {
+			break
+		}
+	}
+	return -1, file.Info{}, fs.ErrNotExist
+}
+
+func pathError(op, name string, err error) error {
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// Scheme implements file.FS.
+func (t *T) Scheme() string {
+	return t.opts.scheme
+}
+
+// Open implements fs.FS.
+func (t *T) Open(name string) (fs.File, error) {
+	return t.OpenCtx(context.Background(), name)
+}
+
+// OpenCtx implements file.FS. Note that directories are opened in the
+// highest layer that contains them and hence any fs.ReadDirFile
+// operations on them will not return the merged directory listing,
+// use LevelScanner instead.
+func (t *T) OpenCtx(ctx context.Context, name string) (fs.File, error) {
+	i, _, err := t.resolve(ctx, name)
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+	l := t.layers[i]
+	return l.FS.OpenCtx(ctx, l.path(clean(name)))
+}
+
+// Readlink implements file.FS.
+func (t *T) Readlink(ctx context.Context, name string) (string, error) {
+	i, _, err := t.resolve(ctx, name)
+	if err != nil {
+		return "", pathError("readlink", name, err)
+	}
+	l := t.layers[i]
+	return l.FS.Readlink(ctx, l.path(clean(name)))
+}
+
+// Stat implements file.FS. Symbolic links are followed within the layer
+// that contains them.
+func (t *T) Stat(ctx context.Context, name string) (file.Info, error) {
+	i, _, err := t.resolve(ctx, name)
+	if err != nil {
+		return file.Info{}, pathError("stat", name, err)
+	}
+	l := t.layers[i]
+	return l.FS.Stat(ctx, l.path(clean(name)))
+}
+
+// Lstat implements file.FS.
+func (t *T) Lstat(ctx context.Context, name string) (file.Info, error) {
+	_, info, err := t.resolve(ctx, name)
+	if err != nil {
+		return file.Info{}, pathError("lstat", name, err)
+	}
+	return info, nil
+}
+
+// Join implements file.FS.
+func (t *T) Join(components ...string) string {
+	return path.Join(components...)
+}
+
+// Base implements file.FS.
+func (t *T) Base(name string) string {
+	return path.Base(name)
+}
+
+// IsPermissionError implements file.FS.
+func (t *T) IsPermissionError(err error) bool {
+	if errors.Is(err, fs.ErrPermission) {
+		return true
+	}
+	for _, l := range t.layers {
+		if l.FS.IsPermissionError(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotExist implements file.FS.
+func (t *T) IsNotExist(err error) bool {
+	if errors.Is(err, fs.ErrNotExist) {
+		return true
+	}
+	for _, l := range t.layers {
+		if l.FS.IsNotExist(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// XAttr implements file.FS.
+func (t *T) XAttr(ctx context.Context, name string, fi file.Info) (file.XAttr, error) {
+	i, _, err := t.resolve(ctx, name)
+	if err != nil {
+		return file.XAttr{}, pathError("xattr", name, err)
+	}
+	l := t.layers[i]
+	return l.FS.XAttr(ctx, l.path(clean(name)), fi)
+}
+
+// SysXAttr implements file.FS using the top layer.
+func (t *T) SysXAttr(existing any, merge file.XAttr) any {
+	return t.layers[0].FS.SysXAttr(existing, merge)
+}
+
+// ReadFile implements file.ReadFileFS.
+func (t *T) ReadFile(name string) ([]byte, error) {
+	return t.ReadFileCtx(context.Background(), name)
+}
+
+// ReadFileCtx implements file.ReadFileFS.
+func (t *T) ReadFileCtx(ctx context.Context, name string) ([]byte, error) {
+	i, _, err := t.resolve(ctx, name)
+	if err != nil {
+		return nil, pathError("read", name, err)
+	}
+	l := t.layers[i]
+	if rfs, ok := l.FS.(file.ReadFileFS); ok {
+		return rfs.ReadFileCtx(ctx, l.path(clean(name)))
+	}
+	f, err := l.FS.OpenCtx(ctx, l.path(clean(name)))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+func (t *T) top() (Layer, file.ObjectFS, error) {
+	l := t.layers[0]
+	ofs, ok := l.FS.(file.ObjectFS)
+	if !ok {
+		return l, nil, ErrReadOnly
+	}
+	return l, ofs, nil
+}
+
+// Get implements file.ObjectFS.
+func (t *T) Get(ctx context.Context, name string) ([]byte, error) {
+	return t.ReadFileCtx(ctx, name)
+}
+
+// Put implements file.ObjectFS. The data is written to the top layer and
+// any whiteout for name in the top layer is removed.
+func (t *T) Put(ctx context.Context, name string, perm fs.FileMode, data []byte) error {
+	l, ofs, err := t.top()
+	if err != nil {
+		return pathError("put", name, err)
+	}
+	name = clean(name)
+	if isWhiteout(name) || len(name) == 0 {
+		return pathError("put", name, fs.ErrInvalid)
+	}
+	if err := t.removeWhiteout(ctx, l, ofs, name); err != nil {
+		return err
+	}
+	return ofs.Put(ctx, l.path(name), perm, data)
+}
+
+// EnsurePrefix implements file.ObjectFS. If the prefix was previously
+// deleted it is recreated as an opaque directory so that the contents
+// of the deleted directory in lower layers remain hidden.
+func (t *T) EnsurePrefix(ctx context.Context, name string, perm fs.FileMode) error {
+	l, ofs, err := t.top()
+	if err != nil {
+		return pathError("mkdir", name, err)
+	}
+	name = clean(name)
+	dir, base := path.Split(name)
+	whiteout := path.Join(dir, WhiteoutPrefix+base)
+	wasDeleted, err := t.exists(ctx, l, whiteout)
+	if err != nil {
+		return err
+	}
+	if err := ofs.EnsurePrefix(ctx, l.path(name), perm); err != nil {
+		return err
+	}
+	if !wasDeleted {
+		return nil
+	}
+	if err := ofs.Put(ctx, l.path(path.Join(name, OpaqueWhiteout)), 0600, nil); err != nil {
+		return err
+	}
+	return ofs.Delete(ctx, l.path(whiteout))
+}
+
+func (t *T) removeWhiteout(ctx context.Context, l Layer, ofs file.ObjectFS, name string) error {
+	dir, base := path.Split(name)
+	whiteout := path.Join(dir, WhiteoutPrefix+base)
+	ok, err := t.exists(ctx, l, whiteout)
+	if err != nil || !ok {
+		return err
+	}
+	return ofs.Delete(ctx, l.path(whiteout))
+}
+
+// visibleBelowTop returns true if name is visible in any layer below
+// the top layer.
+func (t *T) visibleBelowTop(ctx context.Context, name string) (bool, error) {
+	for i, l := range t.layers {
+		if i > 0 {
+			ok, err := t.exists(ctx, l, name)
+			if ok || err != nil {
+				return ok, err
+			}
+		}
+		hidden, err := t.hides(ctx, l, name)
+		if hidden || err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// Delete implements file.ObjectFS. The file is deleted from the top layer
+// and if it exists in any lower layer a whiteout is created in the top
+// layer to hide it.
+func (t *T) Delete(ctx context.Context, name string) error {
+	return t.delete(ctx, "delete", name, func(ofs file.ObjectFS, p string) error {
+		return ofs.Delete(ctx, p)
+	})
+}
+
+// DeleteAll implements file.ObjectFS. The prefix is deleted from the top
+// layer and if it exists in any lower layer a whiteout is created in the
+// top layer to hide it.
+func (t *T) DeleteAll(ctx context.Context, prefix string) error {
+	return t.delete(ctx, "delete", prefix, func(ofs file.ObjectFS, p string) error {
+		return ofs.DeleteAll(ctx, p)
+	})
+}
+
+func (t *T) delete(ctx context.Context, op, name string, del func(file.ObjectFS, string) error) error {
+	l, ofs, err := t.top()
+	if err != nil {
+		return pathError(op, name, err)
+	}
+	name = clean(name)
+	if len(name) == 0 {
+		return pathError(op, name, fs.ErrInvalid)
+	}
+	_, _, err = t.resolve(ctx, name)
+	if err != nil {
+		return pathError(op, name, err)
+	}
+	inTop, err := t.exists(ctx, l, name)
+	if err != nil {
+		return err
+	}
+	if inTop {
+		if err := del(ofs, l.path(name)); err != nil {
+			return err
+		}
+	}
+	below, err := t.visibleBelowTop(ctx, name)
+	if err != nil || !below {
+		return err
+	}
+	dir, base := path.Split(name)
+	if err := ofs.EnsurePrefix(ctx, l.path(dir), 0700); err != nil {
+		return err
+	}
+	return ofs.Put(ctx, l.path(path.Join(dir, WhiteoutPrefix+base)), 0600, nil)
+}
@@ -0,0 +1,341 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package unionfs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+
+	"cloudeng.io/file"
+	"cloudeng.io/file/filewalk"
+	"cloudeng.io/file/filewalk/filewalktestutil"
+	"cloudeng.io/file/localfs"
+	"cloudeng.io/file/memfs"
+	"cloudeng.io/file/unionfs"
+)
+
+const baseSpec = `
+name: /base
+entries:
+  - dir:
+      name: a
+      entries:
+        - file:
+            name: f1
+            contents: base-f1
+        - file:
+            name: f2
+            contents: base-f2
+  - dir:
+      name: b
+      entries:
+        - file:
+            name: g
+  - file:
+      name: c
+      contents: base-c
+  - dir:
+      name: d
+      entries:
+        - file:
+            name: e
+            contents: base-e
+  - dir:
+      name: g
+      entries:
+        - file:
+            name: h
+            contents: base-h
+`
+
+const topSpec = `
+name: /top
+entries:
+  - dir:
+      name: a
+      entries:
+        - file:
+            name: f1
+            contents: top-f1
+        - file:
+            name: f3
+            contents: top-f3
+  - file:
+      name: .wh.b
+  - dir:
+      name: c
+      entries:
+        - file:
+            name: h
+            contents: top-h
+  - file:
+      name: g
+      contents: top-g
+`
+
+func newUnion(t *testing.T) (*unionfs.T, *memfs.T) {
+	t.Helper()
+	top, base := memfs.New(), memfs.New()
+	if err := top.LoadYAML(topSpec); err != nil {
+		t.Fatal(err)
+	}
+	if err := base.LoadYAML(baseSpec); err != nil {
+		t.Fatal(err)
+	}
+	ufs, err := unionfs.New([]unionfs.Layer{
+		{FS: top, Root: "/top"},
+		{FS: base, Root: "/base"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ufs, top
+}
+
+type collector struct {
+	sync.Mutex
+	fs    filewalk.FS
+	names []string
+}
+
+func (c *collector) Prefix(_ context.Context, _ *struct{}, _ string, _ file.Info, err error) (bool, file.InfoList, error) {
+	return false, nil, err
+}
+
+func (c *collector) Contents(ctx context.Context, _ *struct{}, prefix string, contents []filewalk.Entry) (file.InfoList, error) {
+	c.Lock()
+	defer c.Unlock()
+	var children file.InfoList
+	for _, e := range contents {
+		name := c.fs.Join(prefix, e.Name)
+		if e.IsDir() {
+			info, err := c.fs.Lstat(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, info)
+			name += "/"
+		}
+		c.names = append(c.names, name)
+	}
+	return children, nil
+}
+
+func (c *collector) Done(_ context.Context, _ *struct{}, _ string, err error) error {
+	return err
+}
+
+func walk(t *testing.T, fs filewalk.FS) []string {
+	t.Helper()
+	c := &collector{fs: fs}
+	if err := filewalk.New(fs, c, filewalk.WithScanSize(2)).Walk(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(c.names)
+	return c.names
+}
+
+func readFile(t *testing.T, ufs *unionfs.T, name string) string {
+	t.Helper()
+	data, err := ufs.ReadFile(name)
+	if err != nil {
+		t.Fatalf("%v: %v", name, err)
+	}
+	f, err := ufs.Open(name)
+	if err != nil {
+		t.Fatalf("%v: %v", name, err)
+	}
+	defer f.Close()
+	opened, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("%v: %v", name, err)
+	}
+	if got, want := string(opened), string(data); got != want {
+		t.Errorf("%v: got %v, want %v", name, got, want)
+	}
+	return string(data)
+}
+
+func TestRead(t *testing.T) {
+	ctx := context.Background()
+	ufs, _ := newUnion(t)
+
+	for _, tc := range []struct {
+		name, contents string
+	}{
+		{"a/f1", "top-f1"},
+		{"/a/f2", "base-f2"},
+		{"a/f3", "top-f3"},
+		{"c/h", "top-h"},
+		{"d/e", "base-e"},
+		{"g", "top-g"},
+	} {
+		if got, want := readFile(t, ufs, tc.name), tc.contents; got != want {
+			t.Errorf("%v: got %v, want %v", tc.name, got, want)
+		}
+	}
+
+	// A file in the top layer hides the contents of a directory of the
+	// same name in the base layer.
+	for _, name := range []string{"b", "b/g", ".wh.b", "x", "c/x", "g/h"} {
+		if _, err := ufs.Stat(ctx, name); !ufs.IsNotExist(err) {
+			t.Errorf("%v: expected a not-exist error: %v", name, err)
+		}
+		if _, err := ufs.Open(name); !ufs.IsNotExist(err) {
+			t.Errorf("%v: expected a not-exist error: %v", name, err)
+		}
+	}
+	info, err := ufs.Lstat(ctx, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.IsDir() {
+		t.Errorf("c should be a directory")
+	}
+
+	if got, want := walk(t, ufs), []string{
+		"a/", "a/f1", "a/f2", "a/f3", "c/", "c/h", "d/", "d/e", "g",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	sc := ufs.LevelScanner("b")
+	if sc.Scan(ctx, 10) || !ufs.IsNotExist(sc.Err()) {
+		t.Errorf("expected a not-exist error: %v", sc.Err())
+	}
+	sc = ufs.LevelScanner("a/f1")
+	if sc.Scan(ctx, 10) || sc.Err() == nil {
+		t.Errorf("expected an error")
+	}
+	sc = ufs.LevelScanner("g/h")
+	if sc.Scan(ctx, 10) || !ufs.IsNotExist(sc.Err()) {
+		t.Errorf("expected a not-exist error: %v", sc.Err())
+	}
+}
+
+func TestLocalLayers(t *testing.T) {
+	ctx := context.Background()
+	top, base := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(top, "a"), []byte("top-a"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(base, "a"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(base, "a", "b"), []byte("base-b"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	fs := localfs.New()
+	ufs, err := unionfs.New([]unionfs.Layer{{FS: fs, Root: top}, {FS: fs, Root: base}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readFile(t, ufs, "a"), "top-a"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// localfs returns ENOTDIR for a/b in the top layer.
+	if _, err := ufs.Stat(ctx, "a/b"); !ufs.IsNotExist(err) {
+		t.Errorf("expected a not-exist error: %v", err)
+	}
+	sc := ufs.LevelScanner("a/b")
+	if sc.Scan(ctx, 10) || !ufs.IsNotExist(sc.Err()) {
+		t.Errorf("expected a not-exist error: %v", sc.Err())
+	}
+}
+
+func TestWrite(t *testing.T) {
+	ctx := context.Background()
+	ufs, top := newUnion(t)
+
+	if err := ufs.Put(ctx, "d/new", 0600, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := top.ReadFile("/top/d/new"); err != nil || string(data) != "new" {
+		t.Errorf("unexpected contents in top layer: %s: %v", data, err)
+	}
+
+	// a/f1 is in both layers, a/f2 only in the base and a/f3 only in top.
+	for _, name := range []string{"a/f1", "a/f2", "a/f3"} {
+		if err := ufs.Delete(ctx, name); err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		if _, err := ufs.Stat(ctx, name); !ufs.IsNotExist(err) {
+			t.Errorf("%v: expected a not-exist error: %v", name, err)
+		}
+	}
+	if err := ufs.Delete(ctx, "a/f1"); !ufs.IsNotExist(err) {
+		t.Errorf("expected a not-exist error: %v", err)
+	}
+	if _, err := top.Stat(ctx, "/top/a/.wh.f3"); !top.IsNotExist(err) {
+		t.Errorf("unexpected whiteout for a file only in the top layer: %v", err)
+	}
+
+	if err := ufs.DeleteAll(ctx, "d"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := walk(t, ufs), []string{"a/", "c/", "c/h", "g"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Recreating a deleted directory makes it opaque.
+	if err := ufs.EnsurePrefix(ctx, "d", 0700); err != nil {
+		t.Fatal(err)
+	}
+	// Writing a deleted file removes its whiteout.
+	if err := ufs.Put(ctx, "a/f2", 0600, []byte("again")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := walk(t, ufs), []string{"a/", "a/f2", "c/", "c/h", "d/", "g"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := readFile(t, ufs, "a/f2"), "again"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	data, err := ufs.Get(ctx, "a/f2")
+	if err != nil || string(data) != "again" {
+		t.Errorf("unexpected contents: %s: %v", data, err)
+	}
+
+	if err := ufs.Put(ctx, "a/.wh.f1", 0600, nil); err == nil {
+		t.Errorf("expected an error writing a whiteout")
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	ctx := context.Background()
+	mock, err := filewalktestutil.NewMockFS("/ro", filewalktestutil.WithYAMLConfig(`
+name: ro
+entries:
+  - file:
+      name: f
+      contents: ro-f
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unionfs.New(nil); err == nil {
+		t.Errorf("expected an error")
+	}
+	ufs, err := unionfs.New([]unionfs.Layer{{FS: mock, Root: "/ro"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readFile(t, ufs, "f"), "ro-f"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := ufs.Put(ctx, "g", 0600, nil); !errors.Is(err, unionfs.ErrReadOnly) {
+		t.Errorf("expected a read-only error: %v", err)
+	}
+	if err := ufs.Delete(ctx, "f"); !errors.Is(err, unionfs.ErrReadOnly) {
+		t.Errorf("expected a read-only error: %v", err)
+	}
+}
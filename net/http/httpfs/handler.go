@@ -0,0 +1,452 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package httpfs
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloudeng.io/algo/container/list"
+	"cloudeng.io/algo/digests"
+	"cloudeng.io/file"
+	"cloudeng.io/net/http/httpfs/rfc9530"
+)
+
+// HandlerOption represents an option to NewHandler.
+type HandlerOption func(o *handlerOptions)
+
+type handlerOptions struct {
+	algos          []string
+	logger         *slog.Logger
+	maxCachedDigs  int
+	syncDigestSize int64
+}
+
+// WithHandlerDigestAlgorithms sets the digest algorithms, in order of
+// preference, used for the Repr-Digest and Content-Digest headers when the
+// client does not request specific algorithms via the Want-Repr-Digest or
+// Want-Content-Digest headers. Only the algorithms that are listed as
+// active by RFC 9530, namely "sha-256" and "sha-512", are supported and
+// any others are ignored. The default is "sha-256". If no algorithms are
+// specified then digests are only returned when requested by the client.
+func WithHandlerDigestAlgorithms(algos ...string) HandlerOption {
+	return func(o *handlerOptions) {
+		o.algos = slices.DeleteFunc(slices.Clone(algos), func(a string) bool {
+			return !isHandlerAlgo(a)
+		})
+	}
+}
+
+// WithHandlerLogger sets the logger. If not set, a discard logger is used.
+func WithHandlerLogger(logger *slog.Logger) HandlerOption {
+	return func(o *handlerOptions) {
+		o.logger = logger
+	}
+}
+
+// WithHandlerDigestCacheSize sets the maximum number of files for which
+// digests are cached, the least recently used files are evicted first.
+// The default is 1024.
+func WithHandlerDigestCacheSize(n int) HandlerOption {
+	return func(o *handlerOptions) {
+		o.maxCachedDigs = max(n, 1)
+	}
+}
+
+// DefaultHandlerSyncDigestSize is the default size of the largest file
+// whose digests are computed whilst serving a request.
+const DefaultHandlerSyncDigestSize = 16 * 1024 * 1024
+
+// WithHandlerSyncDigestSize sets the size of the largest file whose digests
+// are computed whilst serving the first request for it, the digests for
+// larger files are computed in the background and such files are served
+// without digests until they are available. Zero means that all digests
+// are computed in the background. The default is
+// DefaultHandlerSyncDigestSize.
+func WithHandlerSyncDigestSize(size int64) HandlerOption {
+	return func(o *handlerOptions) {
+		o.syncDigestSize = size
+	}
+}
+
+// maxBackgroundDigests is the maximum number of files whose digests are
+// computed concurrently in the background.
+const maxBackgroundDigests = 2
+
+func isHandlerAlgo(algo string) bool {
+	return algo == "sha-256" || algo == "sha-512"
+}
+
+// Handler is an http.Handler that serves the files in a file.FS with
+// support for single and multi-range requests, conditional requests
+// using ETag/If-Range/If-None-Match and RFC 9530 digests. Range and
+// conditional requests are handled by http.ServeContent and hence
+// require that the files opened by the file.FS implement io.Seeker or
+// io.ReaderAt; files that implement neither are served in their entirety
+// without range support.
+//
+// The Repr-Digest header, the digest of the entire file, is returned for
+// all successful requests and the algorithms used may be negotiated via
+// the Want-Repr-Digest header. The Content-Digest header, the digest of
+// the response body, is returned for complete (200) responses and for
+// partial (206) responses if requested via the Want-Content-Digest header.
+// Since the content of a partial response is not known until it has been
+// written, its Content-Digest is sent as a trailer and the response is
+// sent using chunked encoding. Digests are computed when a file is first
+// requested and cached, keyed by name, size and modification time. Since
+// computing a digest requires reading the entire file, only the digests
+// of files of up to the size set via WithHandlerSyncDigestSize are
+// computed whilst serving a request, those of larger files are computed
+// in the background and are returned once available.
+//
+// The ETag is derived from the digest of the file using the first of the
+// configured algorithms, or from its size and modification time if no
+// algorithms are configured or the digest is not yet available. Note
+// that the ETag for a large file will therefore change once its digest
+// has been computed, which results in conditional requests made using
+// the prior ETag being treated as unconditional.
+type Handler struct {
+	handlerOptions
+	fs   file.FS
+	root string
+
+	mu         sync.Mutex
+	digests    map[digestKey]*digestEntry // GUARDED_BY(mu)
+	lru        *list.Double[digestKey]    // GUARDED_BY(mu), least recently used at the head.
+	pending    map[digestKey]bool         // GUARDED_BY(mu)
+	background int                        // GUARDED_BY(mu)
+}
+
+type digestKey struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+type digestEntry struct {
+	digests map[string][]byte // never modified once cached.
+	id      list.DoubleID[digestKey]
+}
+
+// NewHandler returns a new Handler that serves the files in fs rooted
+// at root.
+func NewHandler(fs file.FS, root string, opts ...HandlerOption) *Handler {
+	h := &Handler{
+		fs:      fs,
+		root:    root,
+		digests: map[digestKey]*digestEntry{},
+		lru:     list.NewDouble[digestKey](),
+		pending: map[digestKey]bool{},
+	}
+	h.algos = []string{"sha-256"}
+	h.maxCachedDigs = 1024
+	h.syncDigestSize = DefaultHandlerSyncDigestSize
+	for _, opt := range opts {
+		opt(&h.handlerOptions)
+	}
+	if h.logger == nil {
+		h.logger = slog.New(slog.DiscardHandler)
+	}
+	h.logger = h.logger.With("pkg", "cloudeng.io/net/http/httpfs")
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+	name := h.fs.Join(h.root, path.Clean("/" + r.URL.Path)[1:])
+	info, err := h.fs.Stat(ctx, name)
+	if err != nil {
+		h.httpError(w, name, err)
+		return
+	}
+	if info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	reprAlgos := h.wantAlgos(r, rfc9530.WantReprDigestHeader, h.algos)
+	contentAlgos := h.wantAlgos(r, rfc9530.WantContentDigestHeader, nil)
+	wantContent := len(contentAlgos) > 0
+	if !wantContent {
+		contentAlgos = h.algos
+	}
+	etagAlgos := h.algos[:min(len(h.algos), 1)]
+	digs, err := h.digestsFor(ctx, name, info, slices.Concat(etagAlgos, reprAlgos, contentAlgos))
+	if err != nil {
+		h.httpError(w, name, err)
+		return
+	}
+	if len(etagAlgos) > 0 && digs[etagAlgos[0]] != nil {
+		w.Header().Set("ETag", strconv.Quote(digests.ToHex(digs[etagAlgos[0]])))
+	} else {
+		w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	}
+	setDigestHeader(w.Header(), rfc9530.ReprDigestHeader, digs, reprAlgos)
+
+	f, err := h.fs.OpenCtx(ctx, name)
+	if err != nil {
+		h.httpError(w, name, err)
+		return
+	}
+	defer f.Close()
+
+	var rs io.ReadSeeker
+	switch v := f.(type) {
+	case io.ReadSeeker:
+		rs = v
+	case io.ReaderAt:
+		rs = io.NewSectionReader(v, 0, info.Size())
+	default:
+		h.serveUnseekable(w, r, f, info, digs, contentAlgos)
+		return
+	}
+	cw := &contentDigestWriter{
+		ResponseWriter: w,
+		head:           r.Method == http.MethodHead,
+		digests:        digs,
+		algos:          contentAlgos,
+		wantPartial:    wantContent,
+	}
+	http.ServeContent(cw, r, h.fs.Base(name), info.ModTime(), rs)
+	cw.setTrailers()
+}
+
+func (h *Handler) serveUnseekable(w http.ResponseWriter, r *http.Request, f io.Reader, info file.Info, digs map[string][]byte, contentAlgos []string) {
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	setDigestHeader(w.Header(), rfc9530.ContentDigestHeader, digs, contentAlgos)
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, f); err != nil {
+		h.logger.Info("failed to write response", "name", info.Name(), "error", err)
+	}
+}
+
+func (h *Handler) httpError(w http.ResponseWriter, name string, err error) {
+	switch {
+	case h.fs.IsNotExist(err):
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	case h.fs.IsPermissionError(err):
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		h.logger.Info("failed to serve file", "name", name, "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// wantAlgos returns the supported algorithms requested via the specified
+// Want-* header, or def if the header is not present, is malformed or
+// requests no supported algorithms.
+func (h *Handler) wantAlgos(r *http.Request, header string, def []string) []string {
+	value := r.Header.Get(header)
+	if len(value) == 0 {
+		return def
+	}
+	algos, err := rfc9530.ParseWantDigest(value)
+	if err != nil {
+		h.logger.Info("ignoring malformed header", "header", header, "value", value, "error", err)
+		return def
+	}
+	algos = slices.DeleteFunc(algos, func(a string) bool { return !isHandlerAlgo(a) })
+	if len(algos) == 0 {
+		return def
+	}
+	return algos
+}
+
+// digestsFor returns the digests for the specified file using the specified
+// algorithms. Any that are not already cached are computed in a single
+// pass over the file, either immediately or, for files larger than
+// syncDigestSize, in the background in which case only the cached digests,
+// if any, are returned.
+func (h *Handler) digestsFor(ctx context.Context, name string, info file.Info, algos []string) (map[string][]byte, error) {
+	key := digestKey{name: name, size: info.Size(), modTime: info.ModTime()}
+	cached := h.lookup(key)
+	var missing []string
+	for _, algo := range algos {
+		if _, ok := cached[algo]; !ok && !slices.Contains(missing, algo) {
+			missing = append(missing, algo)
+		}
+	}
+	if len(missing) == 0 {
+		return cached, nil
+	}
+	if info.Size() > h.syncDigestSize {
+		h.digestInBackground(context.WithoutCancel(ctx), key, missing)
+		return cached, nil
+	}
+	digs, err := h.computeDigests(ctx, name, missing)
+	if err != nil {
+		return nil, err
+	}
+	return h.store(key, digs), nil
+}
+
+// digestInBackground computes the specified digests in the background
+// unless they are already being computed or too many background
+// computations are already in progress, in which case they will be
+// scheduled by a subsequent request.
+func (h *Handler) digestInBackground(ctx context.Context, key digestKey, algos []string) {
+	h.mu.Lock()
+	if h.pending[key] || h.background >= maxBackgroundDigests {
+		h.mu.Unlock()
+		return
+	}
+	h.pending[key] = true
+	h.background++
+	h.mu.Unlock()
+	go func() {
+		digs, err := h.computeDigests(ctx, key.name, algos)
+		if err != nil {
+			h.logger.Info("failed to compute digests", "name", key.name, "error", err)
+		} else {
+			h.store(key, digs)
+		}
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.pending, key)
+		h.background--
+	}()
+}
+
+func (h *Handler) computeDigests(ctx context.Context, name string, algos []string) (map[string][]byte, error) {
+	hashes := make([]hash.Hash, len(algos))
+	writers := make([]io.Writer, len(algos))
+	for i, algo := range algos {
+		d, err := digests.New(algo, nil)
+		if err != nil {
+			return nil, err
+		}
+		hashes[i], writers[i] = d.Hash, d.Hash
+	}
+	f, err := h.fs.OpenCtx(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := io.Copy(io.MultiWriter(writers...), f); err != nil {
+		return nil, fmt.Errorf("failed to compute digests for %v: %w", name, err)
+	}
+	digs := make(map[string][]byte, len(algos))
+	for i, algo := range algos {
+		digs[algo] = hashes[i].Sum(nil)
+	}
+	return digs, nil
+}
+
+// lookup returns the cached digests for key, if any, and marks them
+// as the most recently used.
+func (h *Handler) lookup(key digestKey) map[string][]byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry, ok := h.digests[key]
+	if !ok {
+		return nil
+	}
+	h.lru.RemoveItem(entry.id)
+	entry.id = h.lru.Append(key)
+	return entry.digests
+}
+
+// store adds digs to any digests already cached for key, evicting the
+// least recently used entries as required, and returns the result.
+func (h *Handler) store(key digestKey, digs map[string][]byte) map[string][]byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if entry, ok := h.digests[key]; ok {
+		merged := maps.Clone(entry.digests)
+		maps.Copy(merged, digs)
+		entry.digests = merged
+		h.lru.RemoveItem(entry.id)
+		entry.id = h.lru.Append(key)
+		return merged
+	}
+	for h.lru.Len() >= h.maxCachedDigs {
+		oldest := h.lru.Head()
+		h.lru.RemoveItem(h.digests[oldest].id)
+		delete(h.digests, oldest)
+	}
+	h.digests[key] = &digestEntry{digests: digs, id: h.lru.Append(key)}
+	return digs
+}
+
+func setDigestHeader(hdr http.Header, header string, digs map[string][]byte, algos []string) {
+	if value := rfc9530.AsHeaderValues(base64Digests(digs), algos...); len(value) > 0 {
+		hdr.Set(header, value)
+	}
+}
+
+func base64Digests(digs map[string][]byte) map[string]string {
+	b64 := make(map[string]string, len(digs))
+	for algo, d := range digs {
+		b64[algo] = digests.ToBase64(d)
+	}
+	return b64
+}
+
+// contentDigestWriter adds the Content-Digest header to complete responses,
+// for which it is the same as the Repr-Digest, and computes it for
+// partial responses, if requested, to be sent as a trailer.
+type contentDigestWriter struct {
+	http.ResponseWriter
+	head        bool
+	digests     map[string][]byte
+	algos       []string
+	wantPartial bool
+	hashes      []hash.Hash
+}
+
+func (cw *contentDigestWriter) WriteHeader(code int) {
+	if !cw.head && len(cw.algos) > 0 {
+		switch {
+		case code == http.StatusOK:
+			setDigestHeader(cw.Header(), rfc9530.ContentDigestHeader, cw.digests, cw.algos)
+		case code == http.StatusPartialContent && cw.wantPartial:
+			// Trailers cannot be sent with a Content-Length.
+			cw.Header().Del("Content-Length")
+			cw.Header().Set("Trailer", rfc9530.ContentDigestHeader)
+			for _, algo := range cw.algos {
+				d, _ := digests.New(algo, nil)
+				cw.hashes = append(cw.hashes, d.Hash)
+			}
+		}
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *contentDigestWriter) Write(p []byte) (int, error) {
+	for _, hs := range cw.hashes {
+		hs.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+func (cw *contentDigestWriter) setTrailers() {
+	if len(cw.hashes) == 0 {
+		return
+	}
+	digs := make(map[string]string, len(cw.algos))
+	for i, algo := range cw.algos {
+		digs[algo] = digests.ToBase64(cw.hashes[i].Sum(nil))
+	}
+	cw.Header().Set(rfc9530.ContentDigestHeader, rfc9530.AsHeaderValues(digs, cw.algos...))
+}
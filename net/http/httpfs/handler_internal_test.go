@@ -0,0 +1,36 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package httpfs
+
+import (
+	"testing"
+)
+
+func TestDigestCacheLRU(t *testing.T) {
+	h := NewHandler(nil, "", WithHandlerDigestCacheSize(2))
+	key := func(name string) digestKey { return digestKey{name: name} }
+	h.store(key("a"), map[string][]byte{"sha-256": {1}})
+	h.store(key("b"), map[string][]byte{"sha-256": {2}})
+	// Using a makes b the least recently used.
+	if h.lookup(key("a")) == nil {
+		t.Fatalf("a should be cached")
+	}
+	h.store(key("c"), map[string][]byte{"sha-256": {3}})
+	if h.lookup(key("b")) != nil {
+		t.Errorf("b should have been evicted")
+	}
+	for _, name := range []string{"a", "c"} {
+		if h.lookup(key(name)) == nil {
+			t.Errorf("%v should be cached", name)
+		}
+	}
+	digs := h.store(key("a"), map[string][]byte{"sha-512": {4}})
+	if got, want := len(digs), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(h.digests), 2; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
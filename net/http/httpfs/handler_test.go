@@ -0,0 +1,313 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package httpfs_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"cloudeng.io/algo/digests"
+	"cloudeng.io/file"
+	"cloudeng.io/file/largefile"
+	"cloudeng.io/net/http/httpfs"
+	"cloudeng.io/net/http/httpfs/rfc9530"
+)
+
+// mapFS implements file.FS for an fstest.MapFS.
+type mapFS struct {
+	fstest.MapFS
+}
+
+func (m mapFS) Scheme() string { return "mapfs" }
+
+func (m mapFS) OpenCtx(_ context.Context, name string) (fs.File, error) {
+	return m.Open(name)
+}
+
+func (m mapFS) Readlink(_ context.Context, name string) (string, error) {
+	return m.ReadLink(name)
+}
+
+func (m mapFS) Stat(_ context.Context, name string) (file.Info, error) {
+	fi, err := m.MapFS.Stat(name)
+	if err != nil {
+		return file.Info{}, err
+	}
+	return file.NewInfoFromFileInfo(fi), nil
+}
+
+func (m mapFS) Lstat(_ context.Context, name string) (file.Info, error) {
+	fi, err := m.MapFS.Lstat(name)
+	if err != nil {
+		return file.Info{}, err
+	}
+	return file.NewInfoFromFileInfo(fi), nil
+}
+
+func (m mapFS) Join(components ...string) string { return path.Join(components...) }
+
+func (m mapFS) Base(name string) string { return path.Base(name) }
+
+func (m mapFS) IsPermissionError(err error) bool { return errors.Is(err, fs.ErrPermission) }
+
+func (m mapFS) IsNotExist(err error) bool { return errors.Is(err, fs.ErrNotExist) }
+
+func (m mapFS) XAttr(context.Context, string, file.Info) (file.XAttr, error) {
+	return file.XAttr{}, nil
+}
+
+func (m mapFS) SysXAttr(existing any, _ file.XAttr) any { return existing }
+
+func newHandlerServer(t *testing.T, size int, opts ...httpfs.HandlerOption) (*httptest.Server, []byte) {
+	t.Helper()
+	data := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(data)
+	fs := mapFS{fstest.MapFS{
+		"data": &fstest.MapFile{Data: data, Mode: 0600},
+		"dir":  &fstest.MapFile{Mode: fs.ModeDir | 0700},
+	}}
+	ts := httptest.NewServer(httpfs.NewHandler(fs, ".", opts...))
+	t.Cleanup(ts.Close)
+	return ts, data
+}
+
+func doRequest(t *testing.T, method, url string, headers ...string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, body
+}
+
+func TestHandlerLargeFile(t *testing.T) {
+	ctx := context.Background()
+	ts, data := newHandlerServer(t, 100*1024+10)
+
+	lf, err := httpfs.NewLargeFile(ctx, ts.URL+"/data",
+		httpfs.WithLargeFileTransport(newHTTTransport()),
+		httpfs.WithLargeFileBlockSize(4096))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size, _ := lf.ContentLengthAndBlockSize(); size != int64(len(data)) {
+		t.Errorf("got %v, want %v", size, len(data))
+	}
+	dig := lf.Digest()
+	if sum := sha256.Sum256(data); dig.Algo != "sha-256" || !bytes.Equal(dig.Digest, sum[:]) {
+		t.Errorf("unexpected digest: %v %x", dig.Algo, dig.Digest)
+	}
+	rr, err := largefile.NewRandomReader(lf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rr.Close()
+	buf, err := io.ReadAll(rr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, data) {
+		t.Errorf("downloaded data does not match")
+	}
+	if _, err := dig.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !dig.Validate() {
+		t.Errorf("digest does not validate")
+	}
+}
+
+func TestHandlerRanges(t *testing.T) {
+	ts, data := newHandlerServer(t, 1000)
+	url := ts.URL + "/data"
+
+	resp, body := doRequest(t, "GET", url)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Fatalf("unexpected response: %v", resp.Status)
+	}
+	sum := sha256.Sum256(data)
+	reprDigest := rfc9530.AsHeaderValue("sha-256", digests.ToBase64(sum[:]))
+	if got := resp.Header.Get(rfc9530.ReprDigestHeader); got != reprDigest {
+		t.Errorf("got %v, want %v", got, reprDigest)
+	}
+	if got := resp.Header.Get(rfc9530.ContentDigestHeader); got != reprDigest {
+		t.Errorf("got %v, want %v", got, reprDigest)
+	}
+	etag := resp.Header.Get("ETag")
+	if got, want := etag, `"`+digests.ToHex(sum[:])+`"`; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	resp, body = doRequest(t, "GET", url, "Range", "bytes=10-19")
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, data[10:20]) {
+		t.Errorf("unexpected response: %v", resp.Status)
+	}
+	if got := resp.Header.Get(rfc9530.ReprDigestHeader); got != reprDigest {
+		t.Errorf("got %v, want %v", got, reprDigest)
+	}
+	if got := resp.Header.Get(rfc9530.ContentDigestHeader); got != "" {
+		t.Errorf("unexpected content digest: %v", got)
+	}
+
+	// Multi-range.
+	resp, body = doRequest(t, "GET", url, "Range", "bytes=0-9,500-509,990-")
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatalf("unexpected response: %v", resp.Status)
+	}
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("unexpected content type: %v: %v", mediaType, err)
+	}
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for _, want := range [][]byte{data[0:10], data[500:510], data[990:]} {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := io.ReadAll(part)
+		if !bytes.Equal(got, want) {
+			t.Errorf("multipart data mismatch")
+		}
+	}
+
+	// If-Range.
+	resp, body = doRequest(t, "GET", url, "Range", "bytes=0-9", "If-Range", etag)
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, data[:10]) {
+		t.Errorf("unexpected response: %v", resp.Status)
+	}
+	resp, body = doRequest(t, "GET", url, "Range", "bytes=0-9", "If-Range", `"stale"`)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Errorf("unexpected response: %v", resp.Status)
+	}
+	resp, _ = doRequest(t, "GET", url, "If-None-Match", etag)
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("unexpected response: %v", resp.Status)
+	}
+	resp, _ = doRequest(t, "GET", url, "Range", "bytes=2000-")
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("unexpected response: %v", resp.Status)
+	}
+
+	for _, tc := range []struct {
+		method, path string
+		status       int
+	}{
+		{"GET", "/missing", http.StatusNotFound},
+		{"GET", "/dir", http.StatusNotFound},
+		{"GET", "/../../data", http.StatusOK},
+		{"POST", "/data", http.StatusMethodNotAllowed},
+	} {
+		resp, _ := doRequest(t, tc.method, ts.URL+tc.path)
+		if got, want := resp.StatusCode, tc.status; got != want {
+			t.Errorf("%v %v: got %v, want %v", tc.method, tc.path, got, want)
+		}
+	}
+}
+
+func TestHandlerWantDigest(t *testing.T) {
+	ts, data := newHandlerServer(t, 1000)
+	url := ts.URL + "/data"
+
+	sum512 := sha512.Sum512(data)
+	resp, _ := doRequest(t, "HEAD", url, rfc9530.WantReprDigestHeader, "sha-256=1, sha-512=5, md5=10")
+	digs, err := rfc9530.ParseReprDigest(resp.Header.Get(rfc9530.ReprDigestHeader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := digs["sha-512"], digests.ToBase64(sum512[:]); got != want || len(digs) != 2 {
+		t.Errorf("got %v, want %v", digs, want)
+	}
+	if got := resp.Header.Get(rfc9530.ReprDigestHeader); !strings.HasPrefix(got, "sha-512=") {
+		t.Errorf("sha-512 should be first: %v", got)
+	}
+
+	// Content-Digest for a partial response is sent as a trailer.
+	resp, body := doRequest(t, "GET", url, "Range", "bytes=100-199", rfc9530.WantContentDigestHeader, "sha-512=3")
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, data[100:200]) {
+		t.Fatalf("unexpected response: %v", resp.Status)
+	}
+	partial := sha512.Sum512(data[100:200])
+	if got, want := resp.Trailer.Get(rfc9530.ContentDigestHeader), rfc9530.AsHeaderValue("sha-512", digests.ToBase64(partial[:])); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// No digests unless requested.
+	ts, _ = newHandlerServer(t, 1000, httpfs.WithHandlerDigestAlgorithms())
+	resp, _ = doRequest(t, "GET", ts.URL+"/data")
+	if got := resp.Header.Get(rfc9530.ReprDigestHeader); got != "" {
+		t.Errorf("unexpected digest: %v", got)
+	}
+	if resp.Header.Get("ETag") == "" {
+		t.Errorf("missing etag")
+	}
+	resp, _ = doRequest(t, "GET", ts.URL+"/data", rfc9530.WantReprDigestHeader, "sha-512=1")
+	if got, want := resp.Header.Get(rfc9530.ReprDigestHeader), rfc9530.AsHeaderValue("sha-512", digests.ToBase64(sum512[:])); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestHandlerBackgroundDigests(t *testing.T) {
+	ts, data := newHandlerServer(t, 1000, httpfs.WithHandlerSyncDigestSize(100))
+	url := ts.URL + "/data"
+	sum := sha256.Sum256(data)
+	reprDigest := rfc9530.AsHeaderValue("sha-256", digests.ToBase64(sum[:]))
+	etag := `"` + digests.ToHex(sum[:]) + `"`
+
+	// The file is served without digests until they have been computed.
+	resp, _ := doRequest(t, "HEAD", url)
+	if got := resp.Header.Get(rfc9530.ReprDigestHeader); got != "" {
+		t.Errorf("unexpected digest: %v", got)
+	}
+	if got := resp.Header.Get("ETag"); got == "" || got == etag {
+		t.Errorf("unexpected etag: %v", got)
+	}
+	for range 500 {
+		resp, _ = doRequest(t, "HEAD", url)
+		if len(resp.Header.Get(rfc9530.ReprDigestHeader)) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := resp.Header.Get(rfc9530.ReprDigestHeader); got != reprDigest {
+		t.Fatalf("got %v, want %v", got, reprDigest)
+	}
+	resp, body := doRequest(t, "GET", url)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Fatalf("unexpected response: %v", resp.Status)
+	}
+	if got := resp.Header.Get(rfc9530.ContentDigestHeader); got != reprDigest {
+		t.Errorf("got %v, want %v", got, reprDigest)
+	}
+	if got := resp.Header.Get("ETag"); got != etag {
+		t.Errorf("got %v, want %v", got, etag)
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"cloudeng.io/file"
+	"cloudeng.io/file/download"
 	"cloudeng.io/net/http/httperror"
 )
 
@@ -55,17 +56,44 @@ func (fs *FS) Open(name string) (fs.File, error) {
 	return fs.OpenCtx(context.Background(), name)
 }
 
-// OpenCtx implements file.FS.
+// OpenCtx implements file.FS. An error that wraps both an httperror.T and
+// download.ErrTooManyRequests is returned if the server responds with
+// http.StatusTooManyRequests.
 func (fs *FS) OpenCtx(ctx context.Context, name string) (fs.File, error) {
-	req, err := http.NewRequest("GET", name, nil)
+	return fs.open(ctx, name, download.Validators{})
+}
+
+// OpenIfChanged implements download.ConditionalFS. The If-None-Match and
+// If-Modified-Since headers are set from the supplied validators and
+// an error that wraps download.ErrNotModified is returned if the server
+// responds with http.StatusNotModified.
+func (fs *FS) OpenIfChanged(ctx context.Context, name string, validators download.Validators) (fs.File, error) {
+	return fs.open(ctx, name, validators)
+}
+
+func (fs *FS) open(ctx context.Context, name string, validators download.Validators) (fs.File, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", name, nil)
 	if err != nil {
 		return nil, err
 	}
 	if req.URL.Scheme != fs.scheme {
 		return nil, fmt.Errorf("%v: %w", req.URL.Scheme, file.ErrSchemeNotSupported)
 	}
-	req = req.WithContext(ctx)
+	if len(validators.ETag) > 0 {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if !validators.LastModified.IsZero() {
+		req.Header.Set("If-Modified-Since", validators.LastModified.UTC().Format(http.TimeFormat))
+	}
 	resp, err := fs.client.Do(req) //nolint:gosec // G704 is overly restrictive here.
+	if err == nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%v: %w", name, download.ErrNotModified)
+	}
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %w", httperror.CheckResponse(err, resp), download.ErrTooManyRequests)
+	}
 	if err := httperror.CheckResponse(err, resp); err != nil {
 		return nil, err
 	}
@@ -106,7 +134,7 @@ func (fs *FS) IsPermissionError(err error) bool {
 func (fs *FS) IsNotExist(err error) bool {
 	var httpErr *httperror.T
 	if errors.As(err, &httpErr) {
-		return httpErr.StatusCode == http.StatusNotFound
+		return httpErr.StatusCode == http.StatusNotFound || httpErr.StatusCode == http.StatusGone
 	}
 	return false
 }
@@ -180,6 +208,16 @@ func (r *Response) fromHTTPResponse(hr *http.Response) {
 	r.TransferEncoding = hr.TransferEncoding
 }
 
+// Validators implements download.ValidatorsFile.
+func (f *httpFile) Validators() download.Validators {
+	var v download.Validators
+	v.ETag = f.resp.Header.Get("ETag")
+	if mt := f.resp.Header.Get("Last-Modified"); len(mt) > 0 {
+		v.LastModified, _ = http.ParseTime(mt)
+	}
+	return v
+}
+
 func (f *httpFile) Stat() (fs.FileInfo, error) {
 	var lmt time.Time
 	if mt := f.resp.Header.Get("Last-Modified"); len(mt) > 0 {
@@ -5,15 +5,20 @@
 package httpfs_test
 
 import (
+	"context"
 	"embed"
 	"errors"
 	"io"
 	"io/fs"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"cloudeng.io/file"
+	"cloudeng.io/file/download"
 	"cloudeng.io/net/http/httperror"
 	"cloudeng.io/net/http/httpfs"
 )
@@ -134,3 +139,75 @@ func TestIsNotExist(t *testing.T) {
 		t.Errorf("expected IsNotExist to return false for unrelated error")
 	}
 }
+
+func TestHTTPFSConditional(t *testing.T) {
+	modTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "doc.html", modTime, strings.NewReader("<html></html>"))
+	}))
+	defer srv.Close()
+
+	hfs := httpfs.New(http.DefaultClient, httpfs.WithHTTPScheme())
+	f, err := hfs.Open(srv.URL + "/doc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	validators := f.(download.ValidatorsFile).Validators()
+	if got, want := validators, (download.Validators{ETag: `"v1"`, LastModified: modTime}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	f.Close()
+
+	for _, v := range []download.Validators{
+		{ETag: `"v1"`},
+		{LastModified: modTime},
+		{ETag: `"v1"`, LastModified: modTime.Add(-time.Hour)},
+	} {
+		_, err := hfs.OpenIfChanged(context.Background(), srv.URL+"/doc", v)
+		if !errors.Is(err, download.ErrNotModified) {
+			t.Errorf("%v: unexpected or missing error: %v", v, err)
+		}
+	}
+
+	for _, v := range []download.Validators{
+		{ETag: `"v0"`},
+		{LastModified: modTime.Add(-time.Hour)},
+		{},
+	} {
+		f, err := hfs.OpenIfChanged(context.Background(), srv.URL+"/doc", v)
+		if err != nil {
+			t.Fatalf("%v: %v", v, err)
+		}
+		buf, _ := io.ReadAll(f)
+		f.Close()
+		if got, want := string(buf), "<html></html>"; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+
+	_, err = hfs.OpenCtx(context.Background(), srv.URL+"/gone")
+	if !hfs.IsNotExist(err) {
+		t.Errorf("expected a not exist error: %v", err)
+	}
+}
+
+func TestHTTPFSTooManyRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	hfs := httpfs.New(http.DefaultClient, httpfs.WithHTTPScheme())
+	_, err := hfs.OpenCtx(context.Background(), srv.URL+"/doc")
+	if !errors.Is(err, download.ErrTooManyRequests) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+	if !errors.Is(err, httperror.AsT(http.StatusTooManyRequests)) {
+		t.Errorf("unexpected or missing error: %v", err)
+	}
+}
@@ -5,7 +5,9 @@
 // Package rfc9530 provides utilities for working with RFC 9530.
 // It includes functions for parsing the Repr-Digest header as defined in RFC 9530.
 // The Repr-Digest header is used to convey the digest values of representations
-// in a format that allows multiple algorithms to be specified.
+// in a format that allows multiple algorithms to be specified. The
+// Want-Repr-Digest and Want-Content-Digest headers, used to negotiate the
+// algorithms to be used, are also supported.
 package rfc9530
 
 import (
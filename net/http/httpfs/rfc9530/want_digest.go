@@ -0,0 +1,75 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package rfc9530
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	ContentDigestHeader     = "Content-Digest"
+	WantReprDigestHeader    = "Want-Repr-Digest"
+	WantContentDigestHeader = "Want-Content-Digest"
+)
+
+// ParseWantDigest parses the value of a Want-Repr-Digest or
+// Want-Content-Digest header, e.g. "sha-512=3, sha-256=10". It returns the
+// requested algorithms ordered by decreasing preference, algorithms with
+// the same preference retain the order in which they appear in the header.
+// Algorithms with a preference of 0, which indicates that they are
+// not acceptable, are omitted.
+func ParseWantDigest(headerValue string) ([]string, error) {
+	type pref struct {
+		algo string
+		pref int
+	}
+	var prefs []pref
+	for part := range strings.SplitSeq(headerValue, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		algo, value, found := strings.Cut(part, "=")
+		if !found {
+			return nil, fmt.Errorf("malformed want digest value: %q; missing '=' separator", part)
+		}
+		algo = strings.TrimSpace(strings.ToLower(algo))
+		if algo == "" {
+			return nil, fmt.Errorf("malformed want digest value: %q; algorithm is missing", part)
+		}
+		p, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || p < 0 || p > 10 {
+			return nil, fmt.Errorf("malformed want digest value: %q; preference must be an integer in the range 0-10", part)
+		}
+		if p == 0 {
+			continue
+		}
+		prefs = append(prefs, pref{algo: algo, pref: p})
+	}
+	slices.SortStableFunc(prefs, func(a, b pref) int {
+		return b.pref - a.pref
+	})
+	algos := make([]string, len(prefs))
+	for i, p := range prefs {
+		algos[i] = p.algo
+	}
+	return algos, nil
+}
+
+// AsHeaderValues formats the supplied map of algorithm to base64 digest
+// into a Repr-Digest or Content-Digest header value, with the algorithms
+// in the order specified.
+func AsHeaderValues(digests map[string]string, algos ...string) string {
+	values := make([]string, 0, len(algos))
+	for _, algo := range algos {
+		if digest, ok := digests[algo]; ok {
+			values = append(values, AsHeaderValue(algo, digest))
+		}
+	}
+	return strings.Join(values, ", ")
+}
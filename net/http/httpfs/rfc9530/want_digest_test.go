@@ -0,0 +1,65 @@
+// Copyright 2026 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package rfc9530_test
+
+import (
+	"slices"
+	"testing"
+
+	"cloudeng.io/net/http/httpfs/rfc9530"
+)
+
+func TestParseWantDigest(t *testing.T) {
+	for i, tc := range []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{}},
+		{"sha-256=1", []string{"sha-256"}},
+		{"sha-512=3, sha-256=10, unixsum=0", []string{"sha-256", "sha-512"}},
+		{"SHA-512=5,sha-256=5", []string{"sha-512", "sha-256"}},
+		{" sha-256 = 2 , ", []string{"sha-256"}},
+	} {
+		got, err := rfc9530.ParseWantDigest(tc.header)
+		if err != nil {
+			t.Errorf("%v: %v", i, err)
+			continue
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%v: got %v, want %v", i, got, tc.want)
+		}
+	}
+
+	for i, header := range []string{
+		"sha-256",
+		"=3",
+		"sha-256=11",
+		"sha-256=-1",
+		"sha-256=x",
+	} {
+		if _, err := rfc9530.ParseWantDigest(header); err == nil {
+			t.Errorf("%v: %q: expected an error", i, header)
+		}
+	}
+}
+
+func TestAsHeaderValues(t *testing.T) {
+	digests := map[string]string{
+		"sha-256": sha256EmptyStringB64,
+		"sha-512": sha512EmptyStringB64,
+	}
+	got := rfc9530.AsHeaderValues(digests, "sha-512", "md5", "sha-256")
+	want := "sha-512=:" + sha512EmptyStringB64 + ":, sha-256=:" + sha256EmptyStringB64 + ":"
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	parsed, err := rfc9530.ParseReprDigest(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != 2 {
+		t.Errorf("got %v, want 2 digests", parsed)
+	}
+}